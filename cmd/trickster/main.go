@@ -41,6 +41,9 @@ const (
 func main() {
 	appinfo.Set(applicationName, applicationVersion,
 		applicationBuildTime, applicationGitCommitID)
+	if len(os.Args) > 1 && os.Args[1] == warmCommand {
+		os.Exit(runWarm(context.Background(), os.Args[2:], os.Stdout, os.Stderr))
+	}
 	err := daemon.Start(context.Background(), os.Args[1:]...)
 	if err != nil {
 		logger.Fatal(1, "trickster daemon failed to start",
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/warming/grafana"
+)
+
+const warmCommand = "warm"
+
+const warmUsage = `Usage: trickster warm -url <datasource>=<trickster-url> [options] dashboard.json [...]
+
+Prewarms Trickster caches by issuing the Prometheus, InfluxDB and ClickHouse
+panel queries of exported Grafana dashboards against a running Trickster.
+
+<datasource> is a datasource UID or name, or a provider (prometheus, influxdb
+or clickhouse) matching any datasource of that type. Example:
+
+  trickster warm -url prometheus=http://trickster:8480/prom1 \
+    -range 6h,24h,7d -var cluster=prod dashboards/*.json
+
+Options:
+`
+
+// stringList is a flag.Value that collects the values of a repeated flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+type warmFlags struct {
+	urls          stringList
+	ranges        stringList
+	vars          stringList
+	headers       stringList
+	concurrency   int
+	maxDataPoints int
+	minInterval   time.Duration
+	timeout       time.Duration
+	dryRun        bool
+}
+
+// runWarm runs the warm subcommand and returns the process exit code
+func runWarm(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	f := &warmFlags{}
+	fs := flag.NewFlagSet(warmCommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, warmUsage)
+		fs.PrintDefaults()
+	}
+	fs.Var(&f.urls, "url",
+		"datasource=URL mapping to the Trickster backend serving that datasource (repeatable)")
+	fs.Var(&f.ranges, "range",
+		"query range(s) ending now, e.g. 6h,7d (repeatable); defaults to the dashboard time range")
+	fs.Var(&f.vars, "var",
+		"template variable value as name=value; repeat a name for multiple values")
+	fs.Var(&f.headers, "header",
+		"request header as 'Name: value', e.g. for authentication (repeatable)")
+	fs.IntVar(&f.concurrency, "concurrency", 4, "maximum number of requests in flight")
+	fs.IntVar(&f.maxDataPoints, "max-data-points", grafana.DefaultMaxDataPoints,
+		"max data points for panels that don't set their own")
+	fs.DurationVar(&f.minInterval, "min-interval", grafana.DefaultMinInterval,
+		"minimum query step for panels that don't set their own")
+	fs.DurationVar(&f.timeout, "timeout", 60*time.Second, "per-request timeout")
+	fs.BoolVar(&f.dryRun, "dry-run", false, "print the request URLs without sending them")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 || len(f.urls) == 0 {
+		fs.Usage()
+		return 2
+	}
+	bases, err := parseURLs(f.urls)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	o := &grafana.Options{
+		Variables:     make(map[string][]string),
+		MaxDataPoints: f.maxDataPoints,
+		MinInterval:   f.minInterval,
+		Now:           time.Now(),
+	}
+	for _, s := range splitAll(f.ranges) {
+		d, err := timeconv.ParseDuration(s)
+		if err != nil || d <= 0 {
+			fmt.Fprintf(stderr, "invalid range: %s\n", s)
+			return 2
+		}
+		o.Ranges = append(o.Ranges, d)
+	}
+	for _, s := range f.vars {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			fmt.Fprintf(stderr, "invalid variable: %s\n", s)
+			return 2
+		}
+		o.Variables[k] = append(o.Variables[k], v)
+	}
+	header := make(http.Header)
+	for _, s := range f.headers {
+		k, v, ok := strings.Cut(s, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			fmt.Fprintf(stderr, "invalid header: %s\n", s)
+			return 2
+		}
+		header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+
+	var reqs []*http.Request
+	var queries []*grafana.Query
+	unmapped := make(map[string]struct{})
+	for _, path := range fs.Args() {
+		d, err := grafana.Load(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "unable to load dashboard %s: %v\n", path, err)
+			return 1
+		}
+		for _, q := range d.Expand(o) {
+			base, ok := bases[q.Datasource]
+			if !ok || q.Datasource == "" {
+				base, ok = bases[q.Provider]
+			}
+			if !ok {
+				label := q.Provider
+				if q.Datasource != "" {
+					label = q.Datasource + " (" + q.Provider + ")"
+				}
+				unmapped[label] = struct{}{}
+				continue
+			}
+			r, err := q.Request(ctx, base)
+			if err != nil {
+				fmt.Fprintf(stderr, "%s / %s: %v\n", q.Dashboard, q.Panel, err)
+				continue
+			}
+			r.Header = header.Clone()
+			reqs = append(reqs, r)
+			queries = append(queries, q)
+		}
+	}
+	for k := range unmapped {
+		fmt.Fprintf(stderr, "skipping queries for unmapped datasource %s\n", k)
+	}
+	if f.dryRun {
+		for _, r := range reqs {
+			fmt.Fprintln(stdout, r.URL.String())
+		}
+		return 0
+	}
+
+	start := time.Now()
+	errs := grafana.Run(ctx, &http.Client{Timeout: f.timeout}, reqs, f.concurrency)
+	var failed int
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			q := queries[i]
+			fmt.Fprintf(stderr, "%s / %s / %s: %v\n", q.Dashboard, q.Panel, q.RefID, err)
+		}
+	}
+	fmt.Fprintf(stdout, "warmed %d of %d queries in %s\n", len(reqs)-failed,
+		len(reqs), time.Since(start).Round(time.Millisecond))
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func parseURLs(l stringList) (map[string]*url.URL, error) {
+	out := make(map[string]*url.URL, len(l))
+	for _, s := range l {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid url mapping: %s", s)
+		}
+		u, err := url.Parse(v)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid url mapping: %s", s)
+		}
+		out[k] = u
+	}
+	return out, nil
+}
+
+func splitAll(l stringList) []string {
+	var out []string
+	for _, s := range l {
+		for _, p := range strings.Split(s, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
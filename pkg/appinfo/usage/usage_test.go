@@ -49,6 +49,9 @@ func ExamplePrintUsage() {
 	//  Using origin-url and provider:
 	//   trickster -origin-url https://example.com -provider reverseproxycache [-log-level DEBUG|INFO|WARN|ERROR] [-proxy-port 8480] [-metrics-port 8481]
 	//
+	//  Prewarming caches from Grafana dashboards:
+	//   trickster warm -url prometheus=http://localhost:8480/prom1 [-range 6h,7d] [-var name=value] dashboard.json [...]
+	//
 	// ------
 	//
 	//  Simple HTTP Reverse Proxy Cache listening on 8080:
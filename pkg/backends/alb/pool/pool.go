@@ -120,7 +120,14 @@ func (p *pool) ConfiguredTargets() Targets {
 	return append(Targets(nil), p.targets...)
 }
 
+// Targets returns the live targets, excluding those whose circuit breaker is
+// open. When every live target's circuit is open, all live targets are
+// returned so that they can serve cached content.
 func (p *pool) Targets() Targets {
+	return withoutOpenCircuits(p.liveHealthy())
+}
+
+func (p *pool) liveHealthy() Targets {
 	if lt := p.liveTargets.Load(); lt != nil && !p.refreshPending.Load() {
 		cached := *lt
 		allLive := true
@@ -19,8 +19,10 @@ package pool
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/trickstercache/trickster/v2/pkg/backends/healthcheck"
+	cb "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker"
 )
 
 func TestNewTarget(t *testing.T) {
@@ -77,6 +79,40 @@ func TestSetHealthyUpdatesTargets(t *testing.T) {
 	}
 }
 
+func TestTargetsWithoutOpenCircuits(t *testing.T) {
+	newTarget := func() *Target {
+		s := &healthcheck.Status{}
+		s.Set(healthcheck.StatusPassing)
+		tgt := NewTarget(http.NotFoundHandler(), s, nil)
+		tgt.breaker = cb.New(cb.Config{
+			Window: time.Second, MinRequests: 1, FailureRate: 1,
+			OpenDuration: time.Minute, HalfOpenProbes: 1,
+		}, nil)
+		return tgt
+	}
+	openCircuit := func(tgt *Target) {
+		done, _ := tgt.breaker.Allow()
+		done(cb.OutcomeFailure, 0)
+	}
+	t1, t2 := newTarget(), newTarget()
+	p := New(Targets{t1, t2}, 1)
+	defer p.Stop()
+	p.RefreshHealthy()
+
+	if got := len(p.Targets()); got != 2 {
+		t.Fatalf("expected 2 targets, got %d", got)
+	}
+	openCircuit(t1)
+	if got := p.Targets(); len(got) != 1 || got[0] != t2 {
+		t.Fatalf("expected only the closed target, got %d targets", len(got))
+	}
+	// when every circuit is open, all targets are returned
+	openCircuit(t2)
+	if got := len(p.Targets()); got != 2 {
+		t.Errorf("expected 2 targets, got %d", got)
+	}
+}
+
 func TestStopIdempotent(t *testing.T) {
 	s := &healthcheck.Status{}
 	tgt := NewTarget(http.NotFoundHandler(), s, nil)
@@ -21,6 +21,7 @@ import (
 
 	"github.com/trickstercache/trickster/v2/pkg/backends"
 	"github.com/trickstercache/trickster/v2/pkg/backends/healthcheck"
+	cb "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker"
 )
 
 // Target defines an alb pool target
@@ -28,6 +29,7 @@ type Target struct {
 	hcStatus *healthcheck.Status
 	handler  http.Handler
 	backend  backends.Backend
+	breaker  *cb.Breaker
 	name     string
 	group    string
 }
@@ -68,6 +70,9 @@ func NewTarget(handler http.Handler, hcStatus *healthcheck.Status,
 	}
 	if backend != nil {
 		t.name, t.group = backendIdentity(backend)
+		if cfg := backend.Configuration(); cfg != nil {
+			t.breaker = cfg.CircuitBreaker.Breaker()
+		}
 	}
 	if t.group == "" {
 		t.group = t.name
@@ -86,6 +91,32 @@ func backendIdentity(backend backends.Backend) (name, group string) {
 	return
 }
 
+// CircuitOpen returns true if the target's circuit breaker is open
+func (t *Target) CircuitOpen() bool {
+	return t.breaker.IsOpen()
+}
+
+// withoutOpenCircuits returns the targets whose circuit breaker is not open,
+// or all of the targets if every one is open
+func withoutOpenCircuits(targets Targets) Targets {
+	var open int
+	for _, t := range targets {
+		if t.CircuitOpen() {
+			open++
+		}
+	}
+	if open == 0 || open == len(targets) {
+		return targets
+	}
+	out := make(Targets, 0, len(targets)-open)
+	for _, t := range targets {
+		if !t.CircuitOpen() {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
 func (t *Target) HealthStatus() *healthcheck.Status {
 	return t.hcStatus
 }
@@ -79,7 +79,11 @@ func (c *Client) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuer
 		sqlQuery = p[0]
 	}
 
-	trq, ro, canOPC, err := parse(sqlQuery, c.observeAnalysis)
+	if qi == nil {
+		qi = r.URL.Query()
+	}
+	trq, ro, canOPC, err := parseWithParameters(sqlQuery, queryParameters(qi),
+		c.observeAnalysis)
 	if err != nil {
 		return trq, ro, canOPC, err
 	}
@@ -0,0 +1,501 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/decimal256"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// arrowTypeMetadataKey is the Arrow field metadata key under which the
+// column's ClickHouse type is stored, so it survives a round trip
+const arrowTypeMetadataKey = "clickhouse.type"
+
+// arrowContainer enumerates the Arrow-based output formats
+type arrowContainer int
+
+const (
+	arrowFile = arrowContainer(iota)
+	arrowStream
+	arrowParquet
+)
+
+var arrowFormatNames = map[arrowContainer]string{
+	arrowFile:    "Arrow",
+	arrowStream:  "ArrowStream",
+	arrowParquet: "Parquet",
+}
+
+// writerOnly hides any Close method of the underlying writer from encoders
+// that close their sink when finished
+type writerOnly struct {
+	io.Writer
+}
+
+// marshalTimeseriesArrow writes the DataSet in the ClickHouse Arrow,
+// ArrowStream or Parquet format
+func marshalTimeseriesArrow(w io.Writer, ds *dataset.DataSet,
+	_ *timeseries.RequestOptions, container arrowContainer,
+) error {
+	if hw, ok := w.(http.ResponseWriter); ok && hw != nil {
+		hw.Header().Set(headers.NameContentType, headers.ValueApplicationOctetStream)
+		hw.Header().Set(formatHeader, arrowFormatNames[container])
+	}
+	cols, rows, err := toTable(ds)
+	if err != nil {
+		return err
+	}
+	rec, err := toArrowRecord(cols, rows)
+	if err != nil {
+		return err
+	}
+	defer rec.Release()
+	return writeArrowRecord(w, rec, container)
+}
+
+// writeArrowRecord writes the record to w in the container's format
+func writeArrowRecord(w io.Writer, rec arrow.Record,
+	container arrowContainer,
+) error {
+	switch container {
+	case arrowStream:
+		aw := ipc.NewWriter(writerOnly{w}, ipc.WithSchema(rec.Schema()))
+		if err := aw.Write(rec); err != nil {
+			return err
+		}
+		return aw.Close()
+	case arrowParquet:
+		pw, err := pqarrow.NewFileWriter(rec.Schema(), writerOnly{w},
+			parquet.NewWriterProperties(),
+			pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+		if err != nil {
+			return err
+		}
+		if err := pw.Write(rec); err != nil {
+			return err
+		}
+		return pw.Close()
+	}
+	aw, err := ipc.NewFileWriter(writerOnly{w}, ipc.WithSchema(rec.Schema()))
+	if err != nil {
+		return err
+	}
+	if err := aw.Write(rec); err != nil {
+		return err
+	}
+	return aw.Close()
+}
+
+// arrowDataType returns the Arrow type that ClickHouse uses for the type
+// when writing Arrow and Parquet output
+func arrowDataType(t chType) arrow.DataType {
+	switch t.base {
+	case "Int8", "Enum8":
+		return arrow.PrimitiveTypes.Int8
+	case "Int16", "Enum16":
+		return arrow.PrimitiveTypes.Int16
+	case "Int32":
+		return arrow.PrimitiveTypes.Int32
+	case "Int64":
+		return arrow.PrimitiveTypes.Int64
+	case "UInt8":
+		return arrow.PrimitiveTypes.Uint8
+	case "UInt16", "Date":
+		return arrow.PrimitiveTypes.Uint16
+	case "UInt32", "DateTime":
+		return arrow.PrimitiveTypes.Uint32
+	case "UInt64":
+		return arrow.PrimitiveTypes.Uint64
+	case "Float32":
+		return arrow.PrimitiveTypes.Float32
+	case "Float64":
+		return arrow.PrimitiveTypes.Float64
+	case "Bool":
+		return arrow.FixedWidthTypes.Boolean
+	case "Date32":
+		return arrow.FixedWidthTypes.Date32
+	case "DateTime64":
+		var tz string
+		if len(t.args) > 1 {
+			tz = t.args[1]
+		}
+		return &arrow.TimestampType{Unit: timeUnit(t.precision()), TimeZone: tz}
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		p, s := t.decimal()
+		if p > 38 {
+			return &arrow.Decimal256Type{Precision: int32(p), Scale: int32(s)}
+		}
+		return &arrow.Decimal128Type{Precision: int32(p), Scale: int32(s)}
+	}
+	return arrow.BinaryTypes.String
+}
+
+func timeUnit(precision int) arrow.TimeUnit {
+	switch {
+	case precision <= 0:
+		return arrow.Second
+	case precision <= 3:
+		return arrow.Millisecond
+	case precision <= 6:
+		return arrow.Microsecond
+	}
+	return arrow.Nanosecond
+}
+
+func toArrowRecord(cols []column, rows [][]any) (arrow.Record, error) {
+	fields := make([]arrow.Field, len(cols))
+	for i, c := range cols {
+		fields[i] = arrow.Field{
+			Name:     c.name,
+			Type:     arrowDataType(c.typ),
+			Nullable: c.typ.nullable,
+			Metadata: arrow.NewMetadata([]string{arrowTypeMetadataKey},
+				[]string{c.typ.name}),
+		}
+	}
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+	defer rb.Release()
+	rb.Reserve(len(rows))
+	for _, row := range rows {
+		for i, c := range cols {
+			if err := appendArrow(rb.Field(i), c.typ, row[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rb.NewRecord(), nil
+}
+
+func appendArrow(b array.Builder, t chType, v any) error {
+	if v == nil {
+		if t.nullable {
+			b.AppendNull()
+			return nil
+		}
+		b.AppendEmptyValue()
+		return nil
+	}
+	switch t.base {
+	case "Date", "DateTime", "Enum8", "Enum16":
+		// these are written as integers, so convert them first
+		switch t.base {
+		case "Enum8", "Enum16":
+			if s, ok := v.(string); ok {
+				if ev, ok := t.enumValues()[s]; ok {
+					v = ev
+				}
+			}
+		default:
+			ts, ok := asTime(v, t)
+			if !ok {
+				return timeseries.ErrInvalidTimeFormat
+			}
+			if t.base == "Date" {
+				v = ts.Unix() / 86400
+			} else {
+				v = ts.Unix()
+			}
+		}
+	}
+	switch ab := b.(type) {
+	case *array.Int8Builder:
+		i, _ := asInt64(v)
+		ab.Append(int8(i))
+	case *array.Int16Builder:
+		i, _ := asInt64(v)
+		ab.Append(int16(i))
+	case *array.Int32Builder:
+		i, _ := asInt64(v)
+		ab.Append(int32(i))
+	case *array.Int64Builder:
+		i, _ := asInt64(v)
+		ab.Append(i)
+	case *array.Uint8Builder:
+		i, _ := asUint64(v)
+		ab.Append(uint8(i))
+	case *array.Uint16Builder:
+		i, _ := asUint64(v)
+		ab.Append(uint16(i))
+	case *array.Uint32Builder:
+		i, _ := asUint64(v)
+		ab.Append(uint32(i))
+	case *array.Uint64Builder:
+		i, _ := asUint64(v)
+		ab.Append(i)
+	case *array.Float32Builder:
+		f, _ := asFloat64(v)
+		ab.Append(float32(f))
+	case *array.Float64Builder:
+		f, _ := asFloat64(v)
+		ab.Append(f)
+	case *array.BooleanBuilder:
+		ab.Append(asBool(v))
+	case *array.Date32Builder:
+		ts, ok := asTime(v, t)
+		if !ok {
+			return timeseries.ErrInvalidTimeFormat
+		}
+		ab.Append(arrow.Date32FromTime(ts))
+	case *array.TimestampBuilder:
+		ts, ok := asTime(v, t)
+		if !ok {
+			return timeseries.ErrInvalidTimeFormat
+		}
+		at, err := arrow.TimestampFromTime(ts, timeUnit(t.precision()))
+		if err != nil {
+			return err
+		}
+		ab.Append(at)
+	case *array.Decimal128Builder:
+		_, s := t.decimal()
+		ab.Append(decimal128.FromBigInt(asBigInt(v, s)))
+	case *array.Decimal256Builder:
+		_, s := t.decimal()
+		ab.Append(decimal256.FromBigInt(asBigInt(v, s)))
+	case *array.StringBuilder:
+		ab.Append(formatCell(v, t))
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, t.name)
+	}
+	return nil
+}
+
+// readArrow decodes an Arrow, ArrowStream or Parquet document into a matrix
+// of names, types and text-formatted data rows
+func readArrow(reader io.Reader, container arrowContainer) ([][]string, error) {
+	var schema *arrow.Schema
+	var recs []arrow.Record
+	defer func() {
+		for _, r := range recs {
+			r.Release()
+		}
+	}()
+	switch container {
+	case arrowStream:
+		ar, err := ipc.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer ar.Release()
+		schema = ar.Schema()
+		for ar.Next() {
+			rec := ar.Record()
+			rec.Retain()
+			recs = append(recs, rec)
+		}
+		if err := ar.Err(); err != nil && err != io.EOF {
+			return nil, err
+		}
+	default:
+		b, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		if container == arrowParquet {
+			tbl, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(b),
+				parquet.NewReaderProperties(memory.DefaultAllocator),
+				pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+			if err != nil {
+				return nil, err
+			}
+			defer tbl.Release()
+			schema = tbl.Schema()
+			tr := array.NewTableReader(tbl, -1)
+			defer tr.Release()
+			for tr.Next() {
+				rec := tr.Record()
+				rec.Retain()
+				recs = append(recs, rec)
+			}
+			break
+		}
+		ar, err := ipc.NewFileReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer ar.Close()
+		schema = ar.Schema()
+		for i := range ar.NumRecords() {
+			rec, err := ar.Record(i)
+			if err != nil {
+				return nil, err
+			}
+			rec.Retain()
+			recs = append(recs, rec)
+		}
+	}
+	fields := schema.Fields()
+	names := make([]string, len(fields))
+	types := make([]string, len(fields))
+	cts := make([]chType, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+		types[i] = arrowFieldType(f)
+		cts[i] = parseType(types[i])
+	}
+	var rows [][]string
+	for _, rec := range recs {
+		for r := range int(rec.NumRows()) {
+			row := make([]string, len(fields))
+			for i := range fields {
+				row[i] = formatCell(arrowValue(rec.Column(i), r), cts[i])
+			}
+			rows = append(rows, row)
+		}
+	}
+	return toMatrix(names, types, rows), nil
+}
+
+// arrowFieldType returns the field's ClickHouse type from its metadata or,
+// when absent, the type ClickHouse would map the Arrow type to
+func arrowFieldType(f arrow.Field) string {
+	if i := f.Metadata.FindKey(arrowTypeMetadataKey); i >= 0 {
+		return f.Metadata.Values()[i]
+	}
+	t := clickHouseType(f.Type)
+	if f.Nullable {
+		return "Nullable(" + t + ")"
+	}
+	return t
+}
+
+func clickHouseType(dt arrow.DataType) string {
+	switch t := dt.(type) {
+	case *arrow.Int8Type:
+		return "Int8"
+	case *arrow.Int16Type:
+		return "Int16"
+	case *arrow.Int32Type:
+		return "Int32"
+	case *arrow.Int64Type:
+		return "Int64"
+	case *arrow.Uint8Type:
+		return "UInt8"
+	case *arrow.Uint16Type:
+		return "UInt16"
+	case *arrow.Uint32Type:
+		return "UInt32"
+	case *arrow.Uint64Type:
+		return "UInt64"
+	case *arrow.Float32Type:
+		return "Float32"
+	case *arrow.Float64Type:
+		return "Float64"
+	case *arrow.BooleanType:
+		return "Bool"
+	case *arrow.Date32Type:
+		return "Date32"
+	case *arrow.Date64Type:
+		return "DateTime64(3)"
+	case *arrow.FixedSizeBinaryType:
+		return "FixedString(" + strconv.Itoa(t.ByteWidth) + ")"
+	case *arrow.TimestampType:
+		var p int
+		switch t.Unit {
+		case arrow.Millisecond:
+			p = 3
+		case arrow.Microsecond:
+			p = 6
+		case arrow.Nanosecond:
+			p = 9
+		}
+		if t.TimeZone != "" {
+			return fmt.Sprintf("DateTime64(%d, '%s')", p, t.TimeZone)
+		}
+		return fmt.Sprintf("DateTime64(%d)", p)
+	case *arrow.Decimal128Type:
+		return fmt.Sprintf("Decimal(%d, %d)", t.Precision, t.Scale)
+	case *arrow.Decimal256Type:
+		return fmt.Sprintf("Decimal(%d, %d)", t.Precision, t.Scale)
+	case *arrow.DictionaryType:
+		return clickHouseType(t.ValueType)
+	}
+	return "String"
+}
+
+// arrowValue returns the native value of an Arrow array element
+func arrowValue(arr arrow.Array, i int) any {
+	if arr.IsNull(i) {
+		return nil
+	}
+	switch a := arr.(type) {
+	case *array.Int8:
+		return int64(a.Value(i))
+	case *array.Int16:
+		return int64(a.Value(i))
+	case *array.Int32:
+		return int64(a.Value(i))
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Uint8:
+		return uint64(a.Value(i))
+	case *array.Uint16:
+		return uint64(a.Value(i))
+	case *array.Uint32:
+		return uint64(a.Value(i))
+	case *array.Uint64:
+		return a.Value(i)
+	case *array.Float32:
+		return float64(a.Value(i))
+	case *array.Float64:
+		return a.Value(i)
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.LargeString:
+		return a.Value(i)
+	case *array.Binary:
+		return string(a.Value(i))
+	case *array.LargeBinary:
+		return string(a.Value(i))
+	case *array.FixedSizeBinary:
+		return string(bytes.TrimRight(a.Value(i), "\x00"))
+	case *array.Date32:
+		return a.Value(i).ToTime()
+	case *array.Date64:
+		return a.Value(i).ToTime()
+	case *array.Timestamp:
+		return a.Value(i).ToTime(a.DataType().(*arrow.TimestampType).Unit)
+	case *array.Decimal128:
+		return formatDecimal(a.Value(i).BigInt(),
+			int(a.DataType().(*arrow.Decimal128Type).Scale))
+	case *array.Decimal256:
+		return formatDecimal(a.Value(i).BigInt(),
+			int(a.DataType().(*arrow.Decimal256Type).Scale))
+	case *array.Dictionary:
+		return arrowValue(a.Dictionary(), a.GetValueIndex(i))
+	}
+	return arr.ValueStr(i)
+}
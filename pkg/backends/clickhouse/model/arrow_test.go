@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestArrowTypes(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC)
+	cols := []column{
+		{name: "t", typ: parseType("DateTime")},
+		{name: "d", typ: parseType("Date")},
+		{name: "t64", typ: parseType("DateTime64(3)")},
+		{name: "n", typ: parseType("Nullable(Int32)")},
+		{name: "dec", typ: parseType("Decimal(10, 2)")},
+		{name: "e", typ: parseType("Enum16('x' = 7)")},
+		{name: "s", typ: parseType("LowCardinality(String)")},
+	}
+	rows := [][]any{{ts, ts, ts, nil, float64(1.25), "x", "v"}}
+	for _, container := range []arrowContainer{arrowFile, arrowStream, arrowParquet} {
+		rec, err := toArrowRecord(cols, rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		err = writeArrowRecord(buf, rec, container)
+		rec.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := readArrow(buf, container)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"2024-01-02 03:04:05", "2024-01-02",
+			"2024-01-02 03:04:05.123", "", "1.25", "x", "v"}
+		if len(m) != 3 {
+			t.Fatalf("expected 3 rows got %d", len(m))
+		}
+		for i, w := range want {
+			if m[2][i] != w {
+				t.Errorf("column %s: expected %s got %s", m[0][i], w, m[2][i])
+			}
+		}
+		if m[1][3] != "Nullable(Int32)" {
+			t.Errorf("expected Nullable(Int32) got %s", m[1][3])
+		}
+	}
+}
+
+func TestReadArrowWithoutMetadata(t *testing.T) {
+	// a stream produced by another Arrow writer has no clickhouse.type
+	// metadata, so types are derived from the Arrow schema
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "t", Type: &arrow.TimestampType{Unit: arrow.Millisecond}},
+		{Name: "hostname", Type: arrow.BinaryTypes.Binary},
+		{Name: "avg_query", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	}, nil)
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer rb.Release()
+	rb.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(1577836800000))
+	rb.Field(1).(*array.BinaryBuilder).Append([]byte("localhost"))
+	rb.Field(2).(*array.Float64Builder).Append(1.5)
+	rec := rb.NewRecord()
+	defer rec.Release()
+	buf := new(bytes.Buffer)
+	if err := writeArrowRecord(buf, rec, arrowStream); err != nil {
+		t.Fatal(err)
+	}
+
+	trq := testTRQ.Clone()
+	trq.TimestampDefinition.DataType = timeseries.DateTimeSQL
+	ts, err := unmarshalFormat(buf, 8, trq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := MarshalTimeseries(ts, &timeseries.RequestOptions{OutputFormat: 5}, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const expected = "t\thostname\tavg_query\nDateTime64(3)\tString\tNullable(Float64)\n" +
+		"2020-01-01 00:00:00\tlocalhost\t1.5\n"
+	if string(b) != expected {
+		t.Errorf("unexpected output:\n%s", string(b))
+	}
+}
+
+func TestReadArrowInvalid(t *testing.T) {
+	for _, c := range []arrowContainer{arrowFile, arrowStream, arrowParquet} {
+		if _, err := readArrow(bytes.NewReader([]byte("invalid")), c); err == nil {
+			t.Errorf("expected error for container %d", c)
+		}
+	}
+}
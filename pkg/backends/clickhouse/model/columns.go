@@ -0,0 +1,496 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/epoch"
+)
+
+// ErrUnsupportedType indicates a column's ClickHouse data type can't be
+// represented in the requested binary format
+var ErrUnsupportedType = errors.New("unsupported clickhouse data type")
+
+// chType is a parsed ClickHouse data type name, such as
+// Nullable(DateTime64(3, 'UTC'))
+type chType struct {
+	// name is the full type name as reported by ClickHouse
+	name string
+	// base is the innermost type name without Nullable / LowCardinality
+	// wrappers or parameters (e.g., DateTime64)
+	base     string
+	nullable bool
+	// args are the base type's parameters with any quotes removed
+	args []string
+}
+
+func parseType(name string) chType {
+	t := chType{name: name}
+	inner := strings.TrimSpace(name)
+	for {
+		if s, ok := unwrapType(inner, "Nullable"); ok {
+			t.nullable = true
+			inner = s
+			continue
+		}
+		if s, ok := unwrapType(inner, "LowCardinality"); ok {
+			inner = s
+			continue
+		}
+		break
+	}
+	t.base = stripSize(inner)
+	if i := strings.Index(inner, "("); i > 0 && strings.HasSuffix(inner, ")") {
+		t.args = splitTypeArgs(inner[i+1 : len(inner)-1])
+	}
+	return t
+}
+
+// unwrapType returns the inner type when input is wrapper(inner)
+func unwrapType(input, wrapper string) (string, bool) {
+	if !strings.HasPrefix(input, wrapper+"(") || !strings.HasSuffix(input, ")") {
+		return input, false
+	}
+	return strings.TrimSpace(input[len(wrapper)+1 : len(input)-1]), true
+}
+
+// splitTypeArgs splits a type's parameter list on commas that are outside of
+// quotes and parentheses, and unquotes each parameter
+func splitTypeArgs(input string) []string {
+	var out []string
+	var depth int
+	var quoted bool
+	var start int
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			i++
+		case '\'':
+			quoted = !quoted
+		case '(':
+			if !quoted {
+				depth++
+			}
+		case ')':
+			if !quoted {
+				depth--
+			}
+		case ',':
+			if !quoted && depth == 0 {
+				out = append(out, unquoteArg(input[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(out, unquoteArg(input[start:]))
+}
+
+func unquoteArg(input string) string {
+	input = strings.TrimSpace(input)
+	if len(input) >= 2 && input[0] == '\'' && input[len(input)-1] == '\'' {
+		return input[1 : len(input)-1]
+	}
+	return input
+}
+
+func (t chType) intArg(i, def int) int {
+	if i >= len(t.args) {
+		return def
+	}
+	v, err := strconv.Atoi(t.args[i])
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// precision returns the sub-second precision of a DateTime64 type
+func (t chType) precision() int {
+	return t.intArg(0, 3)
+}
+
+// decimal returns the precision and scale of a Decimal type
+func (t chType) decimal() (int, int) {
+	switch t.base {
+	case "Decimal32":
+		return 9, t.intArg(0, 0)
+	case "Decimal64":
+		return 18, t.intArg(0, 0)
+	case "Decimal128":
+		return 38, t.intArg(0, 0)
+	case "Decimal256":
+		return 76, t.intArg(0, 0)
+	}
+	return t.intArg(0, 10), t.intArg(1, 0)
+}
+
+// decimalSize returns the byte width of a Decimal type's underlying integer
+func (t chType) decimalSize() int {
+	p, _ := t.decimal()
+	switch {
+	case p <= 9:
+		return 4
+	case p <= 18:
+		return 8
+	case p <= 38:
+		return 16
+	}
+	return 32
+}
+
+// enumValues returns the name-to-value mapping of an Enum8 or Enum16 type
+func (t chType) enumValues() map[string]int64 {
+	out := make(map[string]int64, len(t.args))
+	for _, a := range t.args {
+		i := strings.LastIndex(a, "=")
+		if i < 0 {
+			continue
+		}
+		v, err := strconv.ParseInt(strings.TrimSpace(a[i+1:]), 10, 64)
+		if err != nil {
+			continue
+		}
+		out[unquoteArg(a[:i])] = v
+	}
+	return out
+}
+
+func (t chType) enumName(v int64) string {
+	for k, ev := range t.enumValues() {
+		if ev == v {
+			return k
+		}
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// column describes a field in a tabular rendering of a DataSet
+type column struct {
+	name string
+	typ  chType
+	fd   timeseries.FieldDefinition
+}
+
+// toTable renders a DataSet as a list of columns and rows of native values,
+// in the output order of the DataSet's fields. Timestamps are time.Time,
+// except when the timestamp field is a numeric epoch.
+func toTable(ds *dataset.DataSet) ([]column, [][]any, error) {
+	fds, tags, vals, tfd := ds.FieldDefinitions()
+	if (len(tags) == 0 && len(vals) == 0) || tfd.DataType < 1 {
+		return nil, nil, timeseries.ErrNoTimerangeQuery
+	}
+	fieldCount := len(fds)
+	cols := make([]column, 0, fieldCount)
+	pos := make([]int, 0, fieldCount)
+	for _, fd := range fds {
+		if fd.OutputPosition >= fieldCount || fd.OutputPosition < 0 {
+			continue
+		}
+		cols = append(cols, column{name: fd.Name, typ: parseType(fd.SDataType), fd: fd})
+		pos = append(pos, fd.OutputPosition)
+	}
+	// order columns by output position
+	ordered := make([]column, len(cols))
+	for i, c := range cols {
+		p := pos[i]
+		if p >= len(ordered) {
+			return nil, nil, timeseries.ErrTableHeader
+		}
+		ordered[p] = c
+	}
+	if len(ds.Results) == 0 {
+		return ordered, nil, nil
+	}
+	var n int
+	for _, s := range ds.Results[0].SeriesList {
+		n += len(s.Points)
+	}
+	rows := make([][]any, 0, n)
+	for _, s := range ds.Results[0].SeriesList {
+		for _, p := range s.Points {
+			row := make([]any, len(ordered))
+			var i int
+			for j, c := range ordered {
+				switch c.fd.Role {
+				case timeseries.RoleTimestamp:
+					row[j] = epochValue(p.Epoch, c.fd)
+				case timeseries.RoleUntracked:
+					if c.fd.DefaultValue != "" {
+						row[j] = c.fd.DefaultValue
+					}
+				case timeseries.RoleTag:
+					if v, ok := s.Header.Tags[c.fd.Name]; ok {
+						row[j] = v
+					}
+				case timeseries.RoleValue:
+					if i < len(p.Values) {
+						row[j] = p.Values[i]
+						i++
+					}
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	return ordered, rows, nil
+}
+
+func epochValue(e epoch.Epoch, fd timeseries.FieldDefinition) any {
+	switch fd.DataType {
+	case timeseries.DateTimeUnixSecs:
+		return int64(e / epoch.BillionNS)
+	case timeseries.DateTimeUnixMilli:
+		return int64(e / epoch.MillionNS)
+	case timeseries.DateTimeUnixNano:
+		return int64(e)
+	}
+	return time.Unix(0, int64(e)).UTC()
+}
+
+// toMatrix converts the column names, types and text-formatted rows decoded
+// from a binary format into the TSVWithNamesAndTypes-style matrix consumed
+// by the DataSet parser
+func toMatrix(names, types []string, rows [][]string) [][]string {
+	out := make([][]string, 0, len(rows)+dataStartRow)
+	out = append(out, names, types)
+	return append(out, rows...)
+}
+
+var decimalScales = [...]float64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8,
+	1e9, 1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18}
+
+func pow10(n int) float64 {
+	if n >= 0 && n < len(decimalScales) {
+		return decimalScales[n]
+	}
+	return math.Pow10(n)
+}
+
+// formatDecimal renders an unscaled decimal integer with the provided scale
+func formatDecimal(v *big.Int, scale int) string {
+	s := new(big.Int).Abs(v).String()
+	if scale > 0 {
+		if len(s) <= scale {
+			s = strings.Repeat("0", scale-len(s)+1) + s
+		}
+		s = s[:len(s)-scale] + "." + s[len(s)-scale:]
+	}
+	if v.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// formatCell renders a decoded value in the same text format ClickHouse uses
+// for the type in TSV output. nil values render as empty strings.
+func formatCell(v any, t chType) string {
+	if v == nil {
+		return ""
+	}
+	switch t.base {
+	case "Date", "Date32", "DateTime", "DateTime64":
+		ts, ok := asTime(v, t)
+		if !ok {
+			break
+		}
+		switch t.base {
+		case "Date", "Date32":
+			return ts.Format(timeconv.SQLDateLayout)
+		case "DateTime64":
+			if p := t.precision(); p > 0 {
+				return ts.Format(timeconv.SQLDateTimeLayout + "." +
+					strings.Repeat("0", min(p, 9)))
+			}
+		}
+		return ts.Format(timeconv.SQLDateTimeLayout)
+	case "Enum8", "Enum16":
+		if i, ok := v.(int64); ok {
+			return t.enumName(i)
+		}
+	}
+	switch x := v.(type) {
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'g', -1, 32)
+	case bool:
+		return strconv.FormatBool(x)
+	case time.Time:
+		return x.UTC().Format(timeconv.SQLDateTimeLayout)
+	case *big.Int:
+		return x.String()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// asTime converts a value to a time.Time according to the type, treating
+// integers as days for Date types, seconds for DateTime and ticks for
+// DateTime64
+func asTime(v any, t chType) (time.Time, bool) {
+	switch x := v.(type) {
+	case time.Time:
+		return x.UTC(), true
+	case string:
+		if t.base == "Date" || t.base == "Date32" {
+			ts, err := time.Parse(timeconv.SQLDateLayout, x)
+			if err == nil {
+				return ts, true
+			}
+		}
+		ts, err := parseClickHouseTimestamp("", x)
+		return ts, err == nil
+	}
+	i, ok := asInt64(v)
+	if !ok {
+		return time.Time{}, false
+	}
+	switch t.base {
+	case "Date", "Date32":
+		return time.Unix(i*86400, 0).UTC(), true
+	case "DateTime64":
+		p := t.precision()
+		if p > 9 {
+			p = 9
+		}
+		return time.Unix(0, i*int64(pow10(9-p))).UTC(), true
+	}
+	return time.Unix(i, 0).UTC(), true
+}
+
+// asInt64 converts a value to an int64
+func asInt64(v any) (int64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return x, true
+	case uint64:
+		return int64(x), true
+	case float64:
+		return int64(x), true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		i, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			f, err := strconv.ParseFloat(x, 64)
+			if err != nil {
+				return 0, false
+			}
+			return int64(f), true
+		}
+		return i, true
+	case time.Time:
+		return x.Unix(), true
+	}
+	return 0, false
+}
+
+// asUint64 converts a value to a uint64
+func asUint64(v any) (uint64, bool) {
+	if x, ok := v.(uint64); ok {
+		return x, true
+	}
+	if x, ok := v.(string); ok {
+		if i, err := strconv.ParseUint(x, 10, 64); err == nil {
+			return i, true
+		}
+	}
+	i, ok := asInt64(v)
+	return uint64(i), ok
+}
+
+// asFloat64 converts a value to a float64
+func asFloat64(v any) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		return f, err == nil
+	}
+	i, ok := asInt64(v)
+	return float64(i), ok
+}
+
+// asBool converts a value to a bool
+func asBool(v any) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case string:
+		b, _ := strconv.ParseBool(x)
+		return b
+	}
+	i, _ := asInt64(v)
+	return i != 0
+}
+
+// asBigInt converts a value to a big.Int, applying the decimal scale
+func asBigInt(v any, scale int) *big.Int {
+	switch x := v.(type) {
+	case *big.Int:
+		return x
+	case string:
+		if scale == 0 {
+			if i, ok := new(big.Int).SetString(x, 10); ok {
+				return i
+			}
+		}
+		if f, ok := new(big.Float).SetString(x); ok {
+			f.Mul(f, new(big.Float).SetFloat64(pow10(scale)))
+			i, _ := f.Int(nil)
+			return i
+		}
+		return new(big.Int)
+	case int64:
+		if scale == 0 {
+			return big.NewInt(x)
+		}
+	case uint64:
+		if scale == 0 {
+			return new(big.Int).SetUint64(x)
+		}
+	}
+	f, _ := asFloat64(v)
+	bf := new(big.Float).SetFloat64(math.Round(f * pow10(scale)))
+	i, _ := bf.Int(nil)
+	return i
+}
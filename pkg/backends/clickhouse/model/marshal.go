@@ -62,6 +62,14 @@ func MarshalTimeseriesWriter(ts timeseries.Timeseries,
 		return marshalTimeseriesXSV(w, ds, rlo, true, false, '\t')
 	case 5:
 		return marshalTimeseriesXSV(w, ds, rlo, true, true, '\t')
+	case 6:
+		return marshalTimeseriesRowBinary(w, ds, rlo)
+	case 7:
+		return marshalTimeseriesArrow(w, ds, rlo, arrowFile)
+	case 8:
+		return marshalTimeseriesArrow(w, ds, rlo, arrowStream)
+	case 9:
+		return marshalTimeseriesArrow(w, ds, rlo, arrowParquet)
 	}
 	return timeseries.ErrUnknownFormat
 }
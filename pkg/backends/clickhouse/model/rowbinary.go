@@ -0,0 +1,447 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+)
+
+// limits on the sizes decoded from a RowBinary document, which are read from
+// the untrusted document itself
+const (
+	// maxRowBinaryColumns is the maximum number of columns in a document
+	maxRowBinaryColumns = 1 << 16
+	// maxRowBinaryFieldSize is the maximum size in bytes of a single String
+	// or FixedString value
+	maxRowBinaryFieldSize = 64 << 20
+)
+
+// marshalTimeseriesRowBinary writes the DataSet in the ClickHouse
+// RowBinaryWithNamesAndTypes format
+func marshalTimeseriesRowBinary(w io.Writer, ds *dataset.DataSet,
+	_ *timeseries.RequestOptions,
+) error {
+	if hw, ok := w.(http.ResponseWriter); ok && hw != nil {
+		hw.Header().Set(headers.NameContentType, headers.ValueApplicationOctetStream)
+		hw.Header().Set(formatHeader, "RowBinaryWithNamesAndTypes")
+	}
+	cols, rows, err := toTable(ds)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	writeUvarint(bw, uint64(len(cols)))
+	for _, c := range cols {
+		writeString(bw, c.name)
+	}
+	for _, c := range cols {
+		writeString(bw, c.typ.name)
+	}
+	for _, row := range rows {
+		for i, c := range cols {
+			if err := encodeRowBinary(bw, c.typ, row[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// readRowBinary decodes a RowBinaryWithNamesAndTypes document into a matrix
+// of names, types and text-formatted data rows
+func readRowBinary(reader io.Reader) ([][]string, error) {
+	br := bufio.NewReader(reader)
+	n, err := binary.ReadUvarint(br)
+	if err != nil || n > maxRowBinaryColumns {
+		return nil, timeseries.ErrInvalidBody
+	}
+	names := make([]string, n)
+	types := make([]string, n)
+	cts := make([]chType, n)
+	for i := range names {
+		if names[i], err = readString(br); err != nil {
+			return nil, timeseries.ErrInvalidBody
+		}
+	}
+	for i := range types {
+		if types[i], err = readString(br); err != nil {
+			return nil, timeseries.ErrInvalidBody
+		}
+		cts[i] = parseType(types[i])
+	}
+	var rows [][]string
+	for {
+		if _, err := br.Peek(1); err == io.EOF {
+			break
+		}
+		row := make([]string, n)
+		for i, t := range cts {
+			v, err := decodeRowBinary(br, t)
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			row[i] = formatCell(v, t)
+		}
+		rows = append(rows, row)
+	}
+	return toMatrix(names, types, rows), nil
+}
+
+func writeUvarint(w *bufio.Writer, v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+func writeString(w *bufio.Writer, s string) {
+	writeUvarint(w, uint64(len(s)))
+	w.WriteString(s)
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > maxRowBinaryFieldSize {
+		return "", timeseries.ErrInvalidBody
+	}
+	b, err := readBytes(r, int(n))
+	return string(b), err
+}
+
+// readBytes reads the next n bytes. Since n is read from the document, a
+// buffer larger than what is already buffered grows only as the bytes arrive,
+// so a bad length can't allocate more than the remaining document.
+func readBytes(r *bufio.Reader, n int) ([]byte, error) {
+	if n < 0 || n > maxRowBinaryFieldSize {
+		return nil, timeseries.ErrInvalidBody
+	}
+	if n <= r.Buffered() {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	b, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return b, nil
+}
+
+// intSize returns the byte width and signedness of an integer-encoded type
+func intSize(base string) (int, bool, bool) {
+	switch base {
+	case "Int8", "Enum8":
+		return 1, true, true
+	case "UInt8", "Bool":
+		return 1, false, true
+	case "Int16", "Enum16":
+		return 2, true, true
+	case "UInt16", "Date":
+		return 2, false, true
+	case "Int32", "Date32":
+		return 4, true, true
+	case "UInt32", "DateTime", "IPv4":
+		return 4, false, true
+	case "Int64", "DateTime64":
+		return 8, true, true
+	case "UInt64":
+		return 8, false, true
+	case "Int128":
+		return 16, true, true
+	case "UInt128":
+		return 16, false, true
+	case "Int256":
+		return 32, true, true
+	case "UInt256":
+		return 32, false, true
+	}
+	return 0, false, false
+}
+
+// decodeRowBinary reads a single value of the provided type. Integers decode
+// to int64 or uint64 (*big.Int when wider than 64 bits), Decimals and wide
+// integers to their text representation, and nulls to nil.
+func decodeRowBinary(r *bufio.Reader, t chType) (any, error) {
+	if t.nullable {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b != 0 {
+			return nil, nil
+		}
+	}
+	switch t.base {
+	case "String":
+		return readString(r)
+	case "FixedString":
+		b, err := readBytes(r, t.intArg(0, 0))
+		return strings.TrimRight(string(b), "\x00"), err
+	case "Float32":
+		b, err := readBytes(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))), nil
+	case "Float64":
+		b, err := readBytes(r, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case "Bool":
+		b, err := r.ReadByte()
+		return b != 0, err
+	case "UUID":
+		b, err := readBytes(r, 16)
+		if err != nil {
+			return nil, err
+		}
+		return formatUUID(b), nil
+	case "IPv6":
+		b, err := readBytes(r, 16)
+		return net.IP(b).String(), err
+	case "IPv4":
+		b, err := readBytes(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		return net.IPv4(b[3], b[2], b[1], b[0]).String(), nil
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		b, err := readBytes(r, t.decimalSize())
+		if err != nil {
+			return nil, err
+		}
+		_, scale := t.decimal()
+		return formatDecimal(leToBigInt(b, true), scale), nil
+	}
+	size, signed, ok := intSize(t.base)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, t.name)
+	}
+	b, err := readBytes(r, size)
+	if err != nil {
+		return nil, err
+	}
+	switch size {
+	case 1:
+		if signed {
+			return int64(int8(b[0])), nil
+		}
+		return uint64(b[0]), nil
+	case 2:
+		if signed {
+			return int64(int16(binary.LittleEndian.Uint16(b))), nil
+		}
+		return uint64(binary.LittleEndian.Uint16(b)), nil
+	case 4:
+		if signed {
+			return int64(int32(binary.LittleEndian.Uint32(b))), nil
+		}
+		return uint64(binary.LittleEndian.Uint32(b)), nil
+	case 8:
+		if signed {
+			return int64(binary.LittleEndian.Uint64(b)), nil
+		}
+		return binary.LittleEndian.Uint64(b), nil
+	}
+	return leToBigInt(b, signed), nil
+}
+
+// encodeRowBinary writes a single value of the provided type
+func encodeRowBinary(w *bufio.Writer, t chType, v any) error {
+	if t.nullable {
+		if v == nil {
+			return w.WriteByte(1)
+		}
+		w.WriteByte(0)
+	}
+	var b [8]byte
+	switch t.base {
+	case "String":
+		writeString(w, formatCell(v, t))
+		return nil
+	case "FixedString":
+		n := t.intArg(0, 0)
+		if n < 0 || n > maxRowBinaryFieldSize {
+			return fmt.Errorf("%w: %s", ErrUnsupportedType, t.name)
+		}
+		fb := make([]byte, n)
+		copy(fb, formatCell(v, t))
+		_, err := w.Write(fb)
+		return err
+	case "Float32":
+		f, _ := asFloat64(v)
+		binary.LittleEndian.PutUint32(b[:4], math.Float32bits(float32(f)))
+		_, err := w.Write(b[:4])
+		return err
+	case "Float64":
+		f, _ := asFloat64(v)
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+		_, err := w.Write(b[:])
+		return err
+	case "Bool":
+		var x byte
+		if v != nil && asBool(v) {
+			x = 1
+		}
+		return w.WriteByte(x)
+	case "UUID":
+		u, err := parseUUID(formatCell(v, t))
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(u)
+		return err
+	case "IPv6":
+		ip := net.ParseIP(formatCell(v, t)).To16()
+		if ip == nil {
+			ip = make(net.IP, 16)
+		}
+		_, err := w.Write(ip)
+		return err
+	case "IPv4":
+		ip := net.ParseIP(formatCell(v, t)).To4()
+		if ip == nil {
+			ip = make(net.IP, 4)
+		}
+		_, err := w.Write([]byte{ip[3], ip[2], ip[1], ip[0]})
+		return err
+	case "Decimal", "Decimal32", "Decimal64", "Decimal128", "Decimal256":
+		_, scale := t.decimal()
+		if v == nil {
+			v = int64(0)
+		}
+		_, err := w.Write(bigIntToLE(asBigInt(v, scale), t.decimalSize()))
+		return err
+	case "Enum8", "Enum16":
+		if s, ok := v.(string); ok {
+			if ev, ok := t.enumValues()[s]; ok {
+				v = ev
+			}
+		}
+	case "Date", "Date32", "DateTime", "DateTime64":
+		if v != nil {
+			ts, ok := asTime(v, t)
+			if !ok {
+				return timeseries.ErrInvalidTimeFormat
+			}
+			switch t.base {
+			case "Date", "Date32":
+				v = ts.Unix() / 86400
+			case "DateTime":
+				v = ts.Unix()
+			default:
+				p := min(t.precision(), 9)
+				v = ts.UnixNano() / int64(pow10(9-p))
+			}
+		}
+	}
+	size, _, ok := intSize(t.base)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, t.name)
+	}
+	if v == nil {
+		v = int64(0)
+	}
+	if size > 8 {
+		_, err := w.Write(bigIntToLE(asBigInt(v, 0), size))
+		return err
+	}
+	var x uint64
+	if u, ok := v.(uint64); ok {
+		x = u
+	} else {
+		i, _ := asInt64(v)
+		x = uint64(i)
+	}
+	binary.LittleEndian.PutUint64(b[:], x)
+	_, err := w.Write(b[:size])
+	return err
+}
+
+// leToBigInt decodes a little-endian (two's complement when signed) integer
+func leToBigInt(b []byte, signed bool) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	v := new(big.Int).SetBytes(be)
+	if signed && len(b) > 0 && b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	return v
+}
+
+// bigIntToLE encodes an integer as little-endian two's complement of size bytes
+func bigIntToLE(v *big.Int, size int) []byte {
+	x := new(big.Int).Set(v)
+	if x.Sign() < 0 {
+		x.Add(x, new(big.Int).Lsh(big.NewInt(1), uint(size*8)))
+	}
+	be := x.Bytes()
+	out := make([]byte, size)
+	for i := 0; i < len(be) && i < size; i++ {
+		out[i] = be[len(be)-1-i]
+	}
+	return out
+}
+
+// ClickHouse encodes a UUID as two little-endian UInt64 halves
+func formatUUID(b []byte) string {
+	u := make([]byte, 16)
+	for i := range 8 {
+		u[i] = b[7-i]
+		u[8+i] = b[15-i]
+	}
+	h := hex.EncodeToString(u)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func parseUUID(s string) ([]byte, error) {
+	u, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(u) != 16 {
+		return nil, timeseries.ErrInvalidBody
+	}
+	b := make([]byte, 16)
+	for i := range 8 {
+		b[i] = u[7-i]
+		b[8+i] = u[15-i]
+	}
+	return b, nil
+}
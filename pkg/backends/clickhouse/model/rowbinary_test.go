@@ -0,0 +1,191 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/parsing/sqlanalyzer"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+)
+
+func TestBinaryFormatsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		format byte
+		header string
+	}{
+		{name: "rowbinary", format: 6, header: "RowBinaryWithNamesAndTypes"},
+		{name: "arrow", format: 7, header: "Arrow"},
+		{name: "arrow stream", format: 8, header: "ArrowStream"},
+		{name: "parquet", format: 9, header: "Parquet"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			err := MarshalTimeseriesWriter(testDataSet(),
+				&timeseries.RequestOptions{OutputFormat: tc.format}, 200, w)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := w.Header().Get(formatHeader); v != tc.header {
+				t.Errorf("expected %s got %s", tc.header, v)
+			}
+			trq := testTRQ.Clone()
+			trq.ParsedQuery = &sqlanalyzer.QueryPlan{OutputFormat: tc.format}
+			ts, err := UnmarshalTimeseries(w.Body.Bytes(), trq)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := MarshalTimeseries(ts, &timeseries.RequestOptions{OutputFormat: 5}, 200)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != testDataTSVWithNamesAndTypes {
+				t.Errorf("unexpected output:\n%s", string(b))
+			}
+		})
+	}
+}
+
+func TestRowBinaryTypes(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 123000000, time.UTC)
+	tests := []struct {
+		typ   string
+		value any
+		want  string
+	}{
+		{"UInt8", uint64(200), "200"},
+		{"Int16", int64(-300), "-300"},
+		{"UInt32", uint64(70000), "70000"},
+		{"Int64", int64(-5), "-5"},
+		{"UInt128", uint64(12345), "12345"},
+		{"Int256", int64(-12345), "-12345"},
+		{"Float32", float64(1.5), "1.5"},
+		{"Float64", float64(-2.25), "-2.25"},
+		{"Bool", true, "true"},
+		{"String", "hello", "hello"},
+		{"FixedString(8)", "abc", "abc"},
+		{"LowCardinality(String)", "lc", "lc"},
+		{"Nullable(Float64)", nil, ""},
+		{"Nullable(Float64)", float64(3), "3"},
+		{"Date", ts, "2024-01-02"},
+		{"Date32", ts, "2024-01-02"},
+		{"DateTime('UTC')", ts, "2024-01-02 03:04:05"},
+		{"DateTime64(3, 'UTC')", ts, "2024-01-02 03:04:05.123"},
+		{"Decimal(9, 2)", float64(12.34), "12.34"},
+		{"Decimal64(3)", "-1.5", "-1.500"},
+		{"Decimal128(4)", float64(0.0001), "0.0001"},
+		{"Enum8('a' = 1, 'b' = 2)", "b", "b"},
+		{"UUID", "61f0c404-5cb3-11e7-907b-a6006ad3dba0",
+			"61f0c404-5cb3-11e7-907b-a6006ad3dba0"},
+		{"IPv4", "10.1.2.3", "10.1.2.3"},
+		{"IPv6", "2001:db8::1", "2001:db8::1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.typ, func(t *testing.T) {
+			ct := parseType(tc.typ)
+			buf := new(bytes.Buffer)
+			bw := bufio.NewWriter(buf)
+			if err := encodeRowBinary(bw, ct, tc.value); err != nil {
+				t.Fatal(err)
+			}
+			bw.Flush()
+			v, err := decodeRowBinary(bufio.NewReader(buf), ct)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatCell(v, ct); got != tc.want {
+				t.Errorf("expected %s got %s", tc.want, got)
+			}
+			if buf.Len() != 0 {
+				t.Errorf("expected all bytes to be consumed, %d remain", buf.Len())
+			}
+		})
+	}
+}
+
+func TestRowBinaryUnsupportedType(t *testing.T) {
+	bw := bufio.NewWriter(new(bytes.Buffer))
+	err := encodeRowBinary(bw, parseType("Array(String)"), "x")
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected %v got %v", ErrUnsupportedType, err)
+	}
+	_, err = decodeRowBinary(bufio.NewReader(bytes.NewReader([]byte{0})),
+		parseType("Map(String, String)"))
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected %v got %v", ErrUnsupportedType, err)
+	}
+}
+
+func TestReadRowBinaryErrors(t *testing.T) {
+	if _, err := readRowBinary(bytes.NewReader(nil)); err != timeseries.ErrInvalidBody {
+		t.Errorf("expected %v got %v", timeseries.ErrInvalidBody, err)
+	}
+	// 1 column named "a" of type UInt32, with a truncated row
+	b := []byte{1, 1, 'a', 6, 'U', 'I', 'n', 't', '3', '2', 1, 2}
+	if _, err := readRowBinary(bytes.NewReader(b)); err == nil {
+		t.Error("expected error for truncated row")
+	}
+	// a column count beyond the limit
+	b = binary.AppendUvarint(nil, maxRowBinaryColumns+1)
+	if _, err := readRowBinary(bytes.NewReader(b)); err != timeseries.ErrInvalidBody {
+		t.Errorf("expected %v got %v", timeseries.ErrInvalidBody, err)
+	}
+	// a column name length beyond the limit
+	b = binary.AppendUvarint([]byte{1}, math.MaxUint64)
+	if _, err := readRowBinary(bytes.NewReader(b)); err != timeseries.ErrInvalidBody {
+		t.Errorf("expected %v got %v", timeseries.ErrInvalidBody, err)
+	}
+	// a column name length within the limit but beyond the document
+	b = binary.AppendUvarint([]byte{1}, maxRowBinaryFieldSize)
+	if _, err := readRowBinary(bytes.NewReader(b)); err != timeseries.ErrInvalidBody {
+		t.Errorf("expected %v got %v", timeseries.ErrInvalidBody, err)
+	}
+	_, err := decodeRowBinary(bufio.NewReader(bytes.NewReader(nil)),
+		parseType("FixedString(1000000000)"))
+	if err != timeseries.ErrInvalidBody {
+		t.Errorf("expected %v got %v", timeseries.ErrInvalidBody, err)
+	}
+	bw := bufio.NewWriter(new(bytes.Buffer))
+	err = encodeRowBinary(bw, parseType("FixedString(1000000000)"), "x")
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected %v got %v", ErrUnsupportedType, err)
+	}
+}
+
+func TestParseType(t *testing.T) {
+	ct := parseType("Nullable(DateTime64(6, 'America/New_York'))")
+	if !ct.nullable || ct.base != "DateTime64" || ct.precision() != 6 ||
+		len(ct.args) != 2 || ct.args[1] != "America/New_York" {
+		t.Errorf("unexpected type %+v", ct)
+	}
+	ct = parseType("Enum8('a,b' = 1, 'c' = 2)")
+	if ev := ct.enumValues(); len(ev) != 2 || ev["a,b"] != 1 || ev["c"] != 2 {
+		t.Errorf("unexpected enum values %v", ev)
+	}
+	if p, s := parseType("Decimal(20, 4)").decimal(); p != 20 || s != 4 {
+		t.Errorf("unexpected decimal %d %d", p, s)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/trickstercache/trickster/v2/pkg/parsing/sqlanalyzer"
 	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
 	"github.com/trickstercache/trickster/v2/pkg/timeseries"
 	dcsv "github.com/trickstercache/trickster/v2/pkg/timeseries/dataset/csv"
@@ -36,18 +37,56 @@ import (
 // ended and the data rows have started. We use TSVWithNamesAndTypes so it's 2.
 const dataStartRow = 2
 
-// UnmarshalTimeseries converts a TSV blob into a Timeseries
+// UnmarshalTimeseries converts a blob in the output format requested by the
+// query into a Timeseries. This decodes responses that Trickster has already
+// marshaled for the client, such as those of ALB pool members.
 func UnmarshalTimeseries(data []byte, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
 	buf := bytes.NewReader(data)
-	return UnmarshalTimeseriesReader(buf, trq)
+	var of byte
+	if trq != nil {
+		if plan, ok := trq.ParsedQuery.(*sqlanalyzer.QueryPlan); ok && plan != nil {
+			of = plan.OutputFormat
+		}
+	}
+	return unmarshalFormat(buf, of, trq)
 }
 
 // parser is safe for concurrency
 var parser = dcsv.NewParserMust(buildFieldDefinitions, typeToFieldDataType,
 	parseTimeField, dataStartRow)
 
-// UnmarshalTimeseriesReader converts a TSV blob into a Timeseries via io.Reader
+// UnmarshalTimeseriesReader converts a TSVWithNamesAndTypes blob, which is the
+// format Trickster requests from ClickHouse, into a Timeseries via io.Reader
 func UnmarshalTimeseriesReader(reader io.Reader, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
+	return unmarshalTSV(reader, trq)
+}
+
+// unmarshalFormat converts a blob in the provided output format into a
+// Timeseries. Formats without a reader are decoded as TSVWithNamesAndTypes.
+func unmarshalFormat(reader io.Reader, format byte,
+	trq *timeseries.TimeRangeQuery,
+) (timeseries.Timeseries, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case 6:
+		rows, err = readRowBinary(reader)
+	case 7:
+		rows, err = readArrow(reader, arrowFile)
+	case 8:
+		rows, err = readArrow(reader, arrowStream)
+	case 9:
+		rows, err = readArrow(reader, arrowParquet)
+	default:
+		return unmarshalTSV(reader, trq)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parser.ToDataSet(rows, trq)
+}
+
+func unmarshalTSV(reader io.Reader, trq *timeseries.TimeRangeQuery) (timeseries.Timeseries, error) {
 	cr := csv.NewReader(reader)
 	cr.Comma = '\t'
 	rows, err := cr.ReadAll()
@@ -118,10 +157,10 @@ func loadFieldDef(fieldName, dataType string, col int,
 		return timeseries.FieldDefinition{OutputPosition: -1}
 	case trq.TimestampDefinition.Name:
 		fd.Role = timeseries.RoleTimestamp
-		switch fd.SDataType {
+		switch parseType(fd.SDataType).base {
 		case "DateTime":
 			fd.DataType = timeseries.DateTimeSQL
-		case "Date":
+		case "Date", "Date32":
 			fd.DataType = timeseries.DateSQL
 		default:
 			if trq.TimestampDefinition.DataType > timeseries.Uint64 &&
@@ -149,9 +188,9 @@ func stripSize(input string) string {
 
 // typeToFieldDataType is the DataTypeParserFunc passed to the Parser
 func typeToFieldDataType(input string) timeseries.FieldDataType {
-	input = stripSize(input)
+	input = parseType(input).base
 	switch input {
-	case "String", "UUID", "FixedString":
+	case "String", "UUID", "FixedString", "Enum8", "Enum16", "IPv4", "IPv6":
 		return timeseries.String
 	case "Int8", "Int16", "Int32", "Int64":
 		return timeseries.Int64
@@ -161,8 +200,10 @@ func typeToFieldDataType(input string) timeseries.FieldDataType {
 		return timeseries.Float64
 	case "DateTime", "DateTime64":
 		return timeseries.DateTimeSQL
-	case "Date":
+	case "Date", "Date32":
 		return timeseries.DateSQL
+	case "Bool":
+		return timeseries.Bool
 	case "Nothing":
 		return timeseries.Null
 	}
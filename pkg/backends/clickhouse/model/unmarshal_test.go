@@ -53,6 +53,21 @@ func TestUnmarshalTimeseries(t *testing.T) {
 	}
 }
 
+func TestUnmarshalTimeseriesLeadingTab(t *testing.T) {
+	// a TSV document whose first column name is empty starts with a control
+	// character, and must not be mistaken for RowBinary
+	const data = "\tt\thostname\tavg_query\n" +
+		"String\tUInt64\tString\tFloat64\n" +
+		"\t1577836800000\tlocalhost\t1\n"
+	ts, err := UnmarshalTimeseriesReader(strings.NewReader(data), testTRQ.Clone())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.SeriesCount() != 1 {
+		t.Errorf("expected 1 series got %d", ts.SeriesCount())
+	}
+}
+
 func TestUnmarshalTimeseriesReaderErrors(t *testing.T) {
 	_, err := UnmarshalTimeseriesReader(strings.NewReader("only-one-row"), testTRQ.Clone())
 	if err != timeseries.ErrInvalidBody {
@@ -96,7 +96,17 @@ func maskLegacyLineComments(statement string) string {
 	return string(source)
 }
 
-func (analyzer) Analyze(statement string, now time.Time) sqlanalyzer.Analysis {
+func (a analyzer) Analyze(statement string, now time.Time) sqlanalyzer.Analysis {
+	return a.AnalyzeWithParameters(statement, nil, now)
+}
+
+// AnalyzeWithParameters implements sqlanalyzer.ParameterizedAnalyzer. Time
+// bounds written as typed query parameters ({name:Type}) are resolved from
+// params, which maps parameter names to the values of their param_<name>
+// URL parameters.
+func (analyzer) AnalyzeWithParameters(statement string, params map[string]string,
+	now time.Time,
+) sqlanalyzer.Analysis {
 	if strings.TrimSpace(statement) == "" {
 		return sqlanalyzer.Analysis{Reason: sqlanalyzer.ReasonInvalidSQL, Err: ErrNotTimeRangeQuery}
 	}
@@ -130,7 +140,7 @@ func (analyzer) Analyze(statement string, now time.Time) sqlanalyzer.Analysis {
 	if err != nil {
 		return objectAnalysis(sqlanalyzer.ReasonUnsupportedGrouping, err)
 	}
-	ranges, err := analyzeRanges(selectQuery, bucket, constants, now)
+	ranges, err := analyzeRanges(selectQuery, bucket, constants, params, now)
 	if err != nil {
 		reason := sqlanalyzer.ReasonNotTimeRange
 		if errors.Is(err, ErrUnsafePredicate) {
@@ -145,7 +155,10 @@ func (analyzer) Analyze(statement string, now time.Time) sqlanalyzer.Analysis {
 		return objectAnalysis(sqlanalyzer.ReasonUnsupportedFormat, err)
 	}
 
+	referenced := queryParamNames(selectQuery)
 	canonical, renderer := buildQueryArtifacts(selectQuery, ranges, bucket.step)
+	renderer.boundParameters, renderer.parameters = splitParameters(referenced,
+		queryParamNames(selectQuery), params)
 	plan := &sqlanalyzer.QueryPlan{
 		CanonicalSQL: canonical,
 		TimeColumn:   bucket.timeColumn,
@@ -160,6 +173,7 @@ func (analyzer) Analyze(statement string, now time.Time) sqlanalyzer.Analysis {
 		GroupColumns: groups,
 		OutputFormat: outputFormat,
 		Renderer:     renderer,
+		Parameters:   renderer.parameters,
 	}
 	if ranges.upper != nil {
 		plan.UpperBound = &sqlanalyzer.Bound{
@@ -184,13 +198,35 @@ func hasSetOperation(query *chast.SelectQuery) bool {
 func parse(
 	statement string,
 	observe func(sqlanalyzer.Analysis),
+) (*timeseries.TimeRangeQuery, *timeseries.RequestOptions, bool, error) {
+	return parseWithParameters(statement, nil, observe)
+}
+
+// parseWithParameters is parse for statements that reference typed query
+// parameters, whose values are provided in params
+func parseWithParameters(
+	statement string,
+	params map[string]string,
+	observe func(sqlanalyzer.Analysis),
 ) (*timeseries.TimeRangeQuery, *timeseries.RequestOptions, bool, error) {
 	now := time.Now()
-	analysis := dialectAnalyzer.Analyze(statement, now)
+	var analysis sqlanalyzer.Analysis
+	if pa, ok := dialectAnalyzer.(sqlanalyzer.ParameterizedAnalyzer); ok {
+		analysis = pa.AnalyzeWithParameters(statement, params, now)
+	} else {
+		analysis = dialectAnalyzer.Analyze(statement, now)
+	}
 	if observe != nil {
 		observe(analysis)
 	}
-	return parseAnalysis(statement, now, analysis)
+	trq, ro, canOPC, err := parseAnalysis(statement, now, analysis)
+	if trq != nil && (analysis.Mode != sqlanalyzer.CacheModeDelta || analysis.Plan == nil) {
+		// object-cached responses vary by every provided parameter value
+		for k, v := range params {
+			trq.CacheKeyElements[paramPrefix+k] = v
+		}
+	}
+	return trq, ro, canOPC, err
 }
 
 func parseAnalysis(
@@ -213,6 +249,11 @@ func parseAnalysis(
 	plan := analysis.Plan
 	trq.Statement = plan.CanonicalSQL
 	trq.CacheKeyElements["query"] = plan.CanonicalSQL
+	// parameters resolved into the time bounds are replaced by the extent
+	// placeholders, while the values of the rest must vary the cache key
+	for k, v := range plan.Parameters {
+		trq.CacheKeyElements[paramPrefix+k] = v
+	}
 	trq.Step = plan.Step
 	trq.StepNS = plan.Step.Nanoseconds()
 	trq.Phase = plan.Phase
@@ -252,6 +293,11 @@ func parseAnalysis(
 type clickHouseRenderer struct {
 	template string
 	bounds   []rendererBound
+	// boundParameters are the names of query parameters that were resolved
+	// into time bounds and are no longer referenced by the template
+	boundParameters []string
+	// parameters are the query parameters still referenced by the template
+	parameters map[string]string
 }
 
 func (r *clickHouseRenderer) RenderExtent(extent timeseries.Extent) (string, error) {
@@ -310,6 +356,10 @@ var supportedFormats = map[string]byte{
 	"tsvwithnames":                  4,
 	"tabseparatedwithnamesandtypes": 5,
 	"tsvwithnamesandtypes":          5,
+	"rowbinarywithnamesandtypes":    6,
+	"arrow":                         7,
+	"arrowstream":                   8,
+	"parquet":                       9,
 }
 
 type bucketSpec struct {
@@ -771,6 +821,7 @@ func analyzeRanges(
 	query *chast.SelectQuery,
 	bucket bucketSpec,
 	constants map[string]int64,
+	params map[string]string,
 	now time.Time,
 ) (rangeAnalysis, error) {
 	result := rangeAnalysis{timeColumn: bucket.timeColumn}
@@ -791,7 +842,7 @@ func analyzeRanges(
 			return result, err
 		}
 		for _, condition := range conditions {
-			predicate, ok, err := analyzePredicate(condition, bucket, constants, now)
+			predicate, ok, err := analyzePredicate(condition, bucket, constants, params, now)
 			if err != nil {
 				return result, err
 			}
@@ -983,6 +1034,7 @@ func analyzePredicate(
 	expression chast.Expr,
 	bucket bucketSpec,
 	constants map[string]int64,
+	params map[string]string,
 	now time.Time,
 ) (predicateBound, bool, error) {
 	expression = unwrapColumnExpr(expression)
@@ -996,11 +1048,11 @@ func analyzePredicate(
 			return predicateBound{}, false, nil
 		}
 		numericStyle := numericBoundStyle(field, bucket)
-		lower, ok := evaluateBound(value.Between, true, numericStyle, constants, now)
+		lower, ok := evaluateBound(value.Between, true, numericStyle, constants, params, now)
 		if !ok {
 			return predicateBound{}, false, nil
 		}
-		upper, ok := evaluateBound(value.And, true, numericStyle, constants, now)
+		upper, ok := evaluateBound(value.And, true, numericStyle, constants, params, now)
 		if !ok {
 			return predicateBound{}, false, nil
 		}
@@ -1030,7 +1082,7 @@ func analyzePredicate(
 		}
 		inclusive := operator == ">=" || operator == "<="
 		bound, ok := evaluateBound(
-			boundExpression, inclusive, numericBoundStyle(field, bucket), constants, now,
+			boundExpression, inclusive, numericBoundStyle(field, bucket), constants, params, now,
 		)
 		if !ok {
 			return predicateBound{}, false, nil
@@ -1120,6 +1172,7 @@ func evaluateBound(
 	inclusive bool,
 	numericStyle boundStyle,
 	constants map[string]int64,
+	params map[string]string,
 	now time.Time,
 ) (analyzedBound, bool) {
 	expression = unwrapColumnExpr(expression)
@@ -1145,7 +1198,7 @@ func evaluateBound(
 			if len(args) != 1 {
 				return analyzedBound{}, false
 			}
-			inner, ok := evaluateBound(args[0], inclusive, boundUnixSeconds, constants, now)
+			inner, ok := evaluateBound(args[0], inclusive, boundUnixSeconds, constants, params, now)
 			if !ok {
 				return analyzedBound{}, false
 			}
@@ -1156,6 +1209,8 @@ func evaluateBound(
 			}
 			return inner, true
 		}
+	case *chast.QueryParam:
+		return evaluateQueryParam(value, inclusive, numericStyle, params)
 	case *chast.Ident, *chast.NestedIdentifier:
 		name, ok := sourceColumn(value)
 		if !ok {
@@ -1166,7 +1221,7 @@ func evaluateBound(
 			value: timeFromInteger(integer, numericStyle), inclusive: inclusive, style: numericStyle,
 		}, exists
 	case *chast.BinaryOperation:
-		left, leftOK := evaluateBound(value.LeftExpr, inclusive, numericStyle, constants, now)
+		left, leftOK := evaluateBound(value.LeftExpr, inclusive, numericStyle, constants, params, now)
 		right, rightOK := evalInteger(value.RightExpr, constants)
 		if !leftOK || !rightOK {
 			return analyzedBound{}, false
@@ -1186,7 +1241,8 @@ func evaluateBound(
 
 func parseSQLTime(value string) (time.Time, bool) {
 	value = strings.ReplaceAll(value, "''", "'")
-	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02", time.RFC3339} {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02", time.RFC3339,
+		"2006-01-02 15:04:05.999999999"} {
 		parsed, err := time.ParseInLocation(layout, value, time.UTC)
 		if err == nil {
 			return parsed, true
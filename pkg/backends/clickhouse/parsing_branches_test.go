@@ -313,7 +313,7 @@ func TestEvaluateBoundBranches(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			got, ok := evaluateBound(test.expr, true, test.style, constants, now)
+			got, ok := evaluateBound(test.expr, true, test.style, constants, nil, now)
 			if ok != test.ok {
 				t.Fatalf("evaluateBound() ok = %t, want %t; bound=%+v", ok, test.ok, got)
 			}
@@ -342,7 +342,7 @@ func TestUnsafeAndUnsupportedQueriesUseObjectCache(t *testing.T) {
 		{"unsupported interval", `SELECT toStartOfInterval(ts, INTERVAL 1 year) AS t, count() FROM events WHERE ts >= 100 AND ts < 200 GROUP BY t FORMAT JSON`, sqlanalyzer.ReasonUnsupportedBucket},
 		{"unsupported week mode", `SELECT toStartOfWeek(ts, 1) AS t, count() FROM events WHERE ts >= 100 AND ts < 200 GROUP BY t`, sqlanalyzer.ReasonUnsupportedBucket},
 		{"missing group by", `SELECT toStartOfMinute(ts) AS t, count() FROM events WHERE ts >= 100 AND ts < 200 FORMAT JSON`, sqlanalyzer.ReasonUnsupportedGrouping},
-		{"unsupported format", `SELECT toStartOfMinute(ts) AS t, count() FROM events WHERE ts >= 120 AND ts < 240 GROUP BY t FORMAT Parquet`, sqlanalyzer.ReasonUnsupportedFormat},
+		{"unsupported format", `SELECT toStartOfMinute(ts) AS t, count() FROM events WHERE ts >= 120 AND ts < 240 GROUP BY t FORMAT Native`, sqlanalyzer.ReasonUnsupportedFormat},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
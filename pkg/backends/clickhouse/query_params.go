@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	chast "github.com/AfterShip/clickhouse-sql-parser/parser"
+)
+
+// paramPrefix prefixes the URL parameter names that provide values for the
+// typed query parameters ({name:Type}) of a ClickHouse query
+const paramPrefix = "param_"
+
+// queryParameters returns the typed query parameter values provided in the
+// URL, keyed by parameter name
+func queryParameters(qp url.Values) map[string]string {
+	var out map[string]string
+	for k, v := range qp {
+		name, ok := strings.CutPrefix(k, paramPrefix)
+		if !ok || name == "" || len(v) == 0 {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string)
+		}
+		out[name] = v[0]
+	}
+	return out
+}
+
+// queryParamNames returns the names of the typed query parameters referenced
+// by the query
+func queryParamNames(query *chast.SelectQuery) []string {
+	var out []string
+	chast.Walk(query, func(node chast.Expr) bool {
+		if qp, ok := node.(*chast.QueryParam); ok && qp.Name != nil &&
+			!slices.Contains(out, qp.Name.Name) {
+			out = append(out, qp.Name.Name)
+		}
+		return true
+	})
+	return out
+}
+
+// splitParameters separates the parameters that were referenced before the
+// time bounds were tokenized into those that were only used as time bounds
+// and the values of those the rendered query still references
+func splitParameters(referenced, remaining []string,
+	params map[string]string,
+) ([]string, map[string]string) {
+	var bound []string
+	var values map[string]string
+	for _, name := range referenced {
+		if !slices.Contains(remaining, name) {
+			bound = append(bound, name)
+			continue
+		}
+		v, ok := params[name]
+		if !ok {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[name] = v
+	}
+	return bound, values
+}
+
+// queryParamBaseType returns a parameter's type without Nullable or
+// LowCardinality wrappers or type arguments, in lower case
+func queryParamBaseType(param *chast.QueryParam) string {
+	if param.Type == nil {
+		return ""
+	}
+	t := strings.ToLower(chast.Format(param.Type))
+	for _, wrapper := range []string{"nullable(", "lowcardinality("} {
+		if s, ok := strings.CutPrefix(t, wrapper); ok {
+			t = strings.TrimSuffix(s, ")")
+		}
+	}
+	if i := strings.Index(t, "("); i > 0 {
+		t = t[:i]
+	}
+	return strings.TrimSpace(t)
+}
+
+// evaluateQueryParam resolves a typed query parameter used as a time bound.
+// The rendered bound replaces the parameter with an equivalent literal.
+func evaluateQueryParam(
+	param *chast.QueryParam,
+	inclusive bool,
+	numericStyle boundStyle,
+	params map[string]string,
+) (analyzedBound, bool) {
+	if param.Name == nil {
+		return analyzedBound{}, false
+	}
+	raw, ok := params[param.Name.Name]
+	if !ok {
+		return analyzedBound{}, false
+	}
+	raw = strings.TrimSpace(raw)
+	switch t := queryParamBaseType(param); t {
+	case "datetime", "datetime64", "date", "date32":
+		var value time.Time
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			// DateTime parameters accept unix seconds
+			sec, frac := splitSeconds(f)
+			value = time.Unix(sec, frac)
+		} else if value, ok = parseSQLTime(raw); !ok {
+			return analyzedBound{}, false
+		}
+		style := boundToDateTime
+		if t == "date" || t == "date32" {
+			style = boundToDate
+		}
+		return analyzedBound{value: value, inclusive: inclusive, style: style}, true
+	case "string":
+		value, ok := parseSQLTime(raw)
+		return analyzedBound{value: value, inclusive: inclusive, style: boundSQLDateTime}, ok
+	case "int32", "int64", "uint32", "uint64":
+		integer, err := strconv.ParseInt(raw, 10, 64)
+		return analyzedBound{
+			value: timeFromInteger(integer, numericStyle), inclusive: inclusive, style: numericStyle,
+		}, err == nil
+	}
+	return analyzedBound{}, false
+}
+
+func splitSeconds(f float64) (int64, int64) {
+	sec := int64(f)
+	return sec, int64((f - float64(sec)) * float64(time.Second))
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clickhouse
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+
+	chast "github.com/AfterShip/clickhouse-sql-parser/parser"
+)
+
+const paramQuery = `SELECT toStartOfMinute(ts) AS t, count() FROM events ` +
+	`WHERE ts >= {from:DateTime} AND ts < {to:DateTime} AND host = {host:String} ` +
+	`GROUP BY t FORMAT JSON`
+
+func TestParseWithParameters(t *testing.T) {
+	params := map[string]string{
+		"from": "1577836800", "to": "2020-01-01 01:00:00", "host": "a",
+	}
+	trq, _, _, err := parseWithParameters(paramQuery, params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trq.Extent.Start.Unix() != 1577836800 || trq.Extent.End.Unix() != 1577840340 {
+		t.Errorf("unexpected extent %s", trq.Extent)
+	}
+	if !strings.Contains(trq.Statement, "<$TS1$>") || !strings.Contains(trq.Statement, "<$TS2$>") ||
+		!strings.Contains(trq.Statement, "{host: String}") {
+		t.Errorf("unexpected canonical statement: %s", trq.Statement)
+	}
+	if trq.CacheKeyElements["param_host"] != "a" {
+		t.Errorf("expected param_host in cache key elements: %v", trq.CacheKeyElements)
+	}
+	if _, ok := trq.CacheKeyElements["param_from"]; ok {
+		t.Errorf("unexpected param_from in cache key elements: %v", trq.CacheKeyElements)
+	}
+
+	// a different time range maps to the same cache identity
+	params["from"], params["to"] = "2020-01-01 00:30:00", "1577840400"
+	second, _, _, err := parseWithParameters(paramQuery, params, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Statement != trq.Statement || second.CacheKeyElements["param_host"] != "a" {
+		t.Error("different time parameters produced a different cache identity")
+	}
+
+	// a missing lower bound parameter fails closed to the object cache, keyed
+	// by every provided parameter
+	delete(params, "from")
+	trq, _, canOPC, err := parseWithParameters(paramQuery, params, nil)
+	if err == nil || !canOPC {
+		t.Fatalf("expected object cache fallback, got (%t, %v)", canOPC, err)
+	}
+	if trq.CacheKeyElements["param_to"] != params["to"] ||
+		trq.CacheKeyElements["param_host"] != "a" {
+		t.Errorf("unexpected cache key elements: %v", trq.CacheKeyElements)
+	}
+}
+
+func TestSetExtentWithParameters(t *testing.T) {
+	params := url.Values{
+		"param_from": {"1577836800"},
+		"param_to":   {"1577840400"},
+		"param_host": {"a"},
+	}
+	trq, _, _, err := parseWithParameters(paramQuery, queryParameters(params), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Set(upQuery, trq.Statement)
+	r, err := http.NewRequest(http.MethodGet, "http://example/?"+params.Encode(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extent := &timeseries.Extent{Start: time.Unix(1577837400, 0), End: time.Unix(1577838000, 0)}
+	if err := (&Client{}).SetExtent(r, trq, extent); err != nil {
+		t.Fatal(err)
+	}
+	qp := r.URL.Query()
+	rendered := qp.Get(upQuery)
+	if !strings.Contains(rendered, "ts >= toDateTime(1577837400)") ||
+		!strings.Contains(rendered, "ts < toDateTime(1577838060)") ||
+		!strings.Contains(rendered, "host = {host: String}") {
+		t.Errorf("unexpected rendered query: %s", rendered)
+	}
+	if qp.Has("param_from") || qp.Has("param_to") || qp.Get("param_host") != "a" {
+		t.Errorf("unexpected upstream parameters: %v", qp)
+	}
+}
+
+func TestEvaluateQueryParam(t *testing.T) {
+	param := func(name, typ string) *chast.QueryParam {
+		return &chast.QueryParam{Name: &chast.Ident{Name: name},
+			Type: &chast.ScalarType{Name: &chast.Ident{Name: typ}}}
+	}
+	params := map[string]string{
+		"unix":    "1577836800",
+		"sql":     "2020-01-01 00:00:00",
+		"frac":    "1577836800.5",
+		"day":     "2020-01-01",
+		"invalid": "not-a-time",
+	}
+	tests := []struct {
+		name  string
+		param *chast.QueryParam
+		style boundStyle
+		want  time.Time
+		ok    bool
+	}{
+		{"unix DateTime", param("unix", "DateTime"), boundToDateTime, time.Unix(1577836800, 0), true},
+		{"sql DateTime", param("sql", "DateTime"), boundToDateTime, time.Unix(1577836800, 0), true},
+		{"fractional DateTime64", param("frac", "DateTime64"), boundToDateTime,
+			time.Unix(1577836800, int64(time.Second/2)), true},
+		{"Date", param("day", "Date"), boundToDate, time.Unix(1577836800, 0), true},
+		{"String", param("sql", "String"), boundSQLDateTime, time.Unix(1577836800, 0), true},
+		{"UInt32", param("unix", "UInt32"), boundUnixSeconds, time.Unix(1577836800, 0), true},
+		{"invalid value", param("invalid", "DateTime"), 0, time.Time{}, false},
+		{"unsupported type", param("unix", "Float64"), 0, time.Time{}, false},
+		{"missing value", param("missing", "DateTime"), 0, time.Time{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := evaluateQueryParam(test.param, true, boundUnixSeconds, params)
+			if ok != test.ok {
+				t.Fatalf("evaluateQueryParam() ok = %t, want %t", ok, test.ok)
+			}
+			if !ok {
+				return
+			}
+			if !got.value.Equal(test.want) || got.style != test.style || !got.inclusive {
+				t.Errorf("evaluateQueryParam() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestQueryParameters(t *testing.T) {
+	params := queryParameters(url.Values{
+		"param_a": {"1"}, "param_": {"x"}, "query": {"SELECT 1"}, "param_b": {},
+	})
+	if len(params) != 1 || params["a"] != "1" {
+		t.Errorf("unexpected parameters %v", params)
+	}
+	if queryParameters(url.Values{"query": {"SELECT 1"}}) != nil {
+		t.Error("expected nil parameters")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 
 	"github.com/trickstercache/trickster/v2/pkg/parsing/sqlanalyzer"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/methods"
@@ -56,8 +57,17 @@ func (c *Client) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery,
 		c.observeRewriteFailure("render_error")
 		return fmt.Errorf("render ClickHouse extent: %w", err)
 	}
+	var boundParameters []string
+	if cr, ok := plan.Renderer.(*clickHouseRenderer); ok {
+		boundParameters = cr.boundParameters
+	}
 	if methods.HasBody(r.Method) {
 		request.SetBody(r, []byte(query))
+		if r.URL != nil && len(boundParameters) > 0 {
+			parameters := r.URL.Query()
+			removeBoundParameters(parameters, boundParameters)
+			r.URL.RawQuery = parameters.Encode()
+		}
 		return nil
 	}
 	if r.URL == nil {
@@ -66,6 +76,16 @@ func (c *Client) SetExtent(r *http.Request, trq *timeseries.TimeRangeQuery,
 	}
 	parameters := r.URL.Query()
 	parameters.Set(upQuery, query)
+	removeBoundParameters(parameters, boundParameters)
 	r.URL.RawQuery = parameters.Encode()
 	return nil
 }
+
+// removeBoundParameters removes the values of query parameters that the
+// rendered query no longer references, since their time bounds were
+// replaced with literals for the requested extent
+func removeBoundParameters(parameters url.Values, names []string) {
+	for _, name := range names {
+		parameters.Del(paramPrefix + name)
+	}
+}
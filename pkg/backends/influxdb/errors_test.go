@@ -0,0 +1,39 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	b, err := NewClient("test", nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	b.(*Client).WriteError(w, http.StatusBadRequest, `too many "points"`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, w.Code)
+	}
+	const expected = `{"error":"too many \"points\""}`
+	if w.Body.String() != expected {
+		t.Errorf("expected %s got %s", expected, w.Body.String())
+	}
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/influxdb/influxql"
+	"github.com/trickstercache/trickster/v2/pkg/encoding/providers"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/engines"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/failures"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/urls"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+
+	"github.com/influxdata/influxdb/models"
+)
+
+// Write Endpoint URL Parameter Names
+const (
+	paramBucket    = "bucket"
+	paramPrecision = "precision"
+)
+
+// WriteHandler proxies line protocol writes to the origin and, when the write
+// succeeds, invalidates the cached queries whose time ranges overlap any
+// late-arriving points in the write
+func (c *Client) WriteHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := request.GetBody(r)
+	if err != nil {
+		failures.HandleBadRequestResponse(w, r)
+		return
+	}
+	qp := r.URL.Query()
+	db := qp.Get(paramBucket)
+	if db == "" {
+		db = qp.Get(influxql.ParamDB)
+	}
+	precision := qp.Get(paramPrecision)
+	ce := r.Header.Get(headers.NameContentEncoding)
+
+	r.URL = urls.BuildUpstreamURL(r, c.BaseUpstreamURL())
+	resp := engines.DoProxy(w, r, true)
+	if resp == nil || resp.StatusCode < http.StatusOK ||
+		resp.StatusCode >= http.StatusMultipleChoices {
+		return
+	}
+	c.invalidateWrite(db, precision, ce, body, time.Now())
+}
+
+func (c *Client) invalidateWrite(db, precision, contentEncoding string,
+	body []byte, now time.Time) {
+	if c.index == nil || len(body) == 0 {
+		return
+	}
+	if contentEncoding != "" {
+		di := providers.GetDecoderInitializer(contentEncoding)
+		if di == nil {
+			return
+		}
+		b, err := io.ReadAll(di(io.NopCloser(bytes.NewReader(body))))
+		if err != nil {
+			return
+		}
+		body = b
+	}
+	// points newer than the backfill tolerance are already re-requested from
+	// the origin, so only older (late-arriving) points invalidate the cache
+	var bt time.Duration
+	if o := c.Configuration(); o != nil {
+		bt = time.Duration(o.BackfillTolerance)
+	}
+	late := lateExtents(body, precision, now, now.Add(-bt))
+	if len(late) == 0 {
+		return
+	}
+	keys := c.index.invalidate(db, late)
+	if len(keys) == 0 {
+		return
+	}
+	if cache := c.Cache(); cache != nil {
+		for _, k := range keys {
+			cache.Remove(k)
+		}
+	}
+	logger.Debug("invalidated cached queries for late-arriving writes",
+		logging.Pairs{"backendName": c.Name(), "db": db, "count": len(keys)})
+}
+
+// lateExtents parses line protocol and returns, for each measurement, the
+// time range of its points that are older than cutoff. Points without a
+// timestamp are assigned now. Lines that fail to parse are ignored.
+func lateExtents(body []byte, precision string, now,
+	cutoff time.Time) map[string]timeseries.Extent {
+	switch precision {
+	case "ns":
+		precision = "n"
+	case "us", "µ":
+		precision = "u"
+	}
+	// ParsePointsWithPrecision returns the successfully-parsed points along
+	// with an error describing any lines that failed
+	points, _ := models.ParsePointsWithPrecision(body, now, precision)
+	out := make(map[string]timeseries.Extent)
+	for _, p := range points {
+		t := p.Time()
+		if !t.Before(cutoff) {
+			continue
+		}
+		m := string(p.Name())
+		e, ok := out[m]
+		if !ok {
+			out[m] = timeseries.Extent{Start: t, End: t}
+			continue
+		}
+		if t.Before(e.Start) {
+			e.Start = t
+		}
+		if t.After(e.End) {
+			e.End = t
+		}
+		out[m] = e
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	tu "github.com/trickstercache/trickster/v2/pkg/testutil"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+)
+
+func TestWriteHandler(t *testing.T) {
+	backendClient, err := NewClient("test", nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, w, r, _, err := tu.NewTestInstance("", backendClient.DefaultPathConfigs, 204, "",
+		nil, providers.InfluxDB, "/write?db=telegraf&precision=s", "debug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+	rsc := request.GetResources(r)
+	backendClient, err = NewClient("test", rsc.BackendOptions, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := backendClient.(*Client)
+	rsc.BackendClient = client
+	rsc.BackendOptions.HTTPClient = backendClient.HTTPClient()
+
+	client.index.observe("cpu-key", &indexEntry{db: "telegraf",
+		measurements: []string{"cpu"},
+		extent:       timeseries.Extent{Start: time.Unix(0, 0), End: time.Unix(3600, 0)}})
+	client.index.observe("mem-key", &indexEntry{db: "telegraf",
+		measurements: []string{"mem"},
+		extent:       timeseries.Extent{Start: time.Unix(0, 0), End: time.Unix(3600, 0)}})
+
+	r.Method = http.MethodPost
+	r.Body = io.NopCloser(bytes.NewReader([]byte("cpu,host=a value=1 1800\n")))
+
+	client.WriteHandler(w, r)
+	resp := w.Result()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected %d got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if _, ok := client.index.entries["cpu-key"]; ok {
+		t.Error("expected cpu-key to be invalidated")
+	}
+	if _, ok := client.index.entries["mem-key"]; !ok {
+		t.Error("expected mem-key to remain indexed")
+	}
+}
+
+func TestLateExtents(t *testing.T) {
+	now := time.Unix(7200, 0)
+	body := []byte("cpu value=1 100\ncpu value=2 50\nmem value=3 7100\n" +
+		"bad line\nnet value=4\n")
+	out := lateExtents(body, "s", now, now.Add(-time.Minute*5))
+	if len(out) != 1 {
+		t.Fatalf("expected 1 measurement got %d", len(out))
+	}
+	e, ok := out["cpu"]
+	if !ok {
+		t.Fatal("expected cpu measurement")
+	}
+	if e.Start.Unix() != 50 || e.End.Unix() != 100 {
+		t.Errorf("unexpected extent %v", e)
+	}
+	out = lateExtents([]byte("cpu value=1 100000000\n"), "us", now, now)
+	if e := out["cpu"]; e.Start.Unix() != 100 {
+		t.Errorf("expected 100 got %d", e.Start.Unix())
+	}
+}
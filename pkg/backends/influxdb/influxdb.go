@@ -26,11 +26,15 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/cache"
 )
 
-var _ backends.TimeseriesBackend = (*Client)(nil)
+var (
+	_ backends.TimeseriesBackend = (*Client)(nil)
+	_ backends.ErrorWriter       = (*Client)(nil)
+)
 
 // Client Implements the Proxy Client Interface
 type Client struct {
 	backends.TimeseriesBackend
+	index *queryIndex
 }
 
 var _ types.NewBackendClientFunc = NewClient
@@ -42,7 +46,7 @@ func NewClient(name string, o *bo.Options, router http.Handler,
 	if o != nil {
 		o.FastForwardDisable = true
 	}
-	c := &Client{}
+	c := &Client{index: newQueryIndex()}
 	b, err := backends.NewTimeseriesBackend(name, o, c.RegisterHandlers,
 		router, cache, NewModeler())
 	c.TimeseriesBackend = b
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"container/list"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/influxdb/influxql"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/params"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+
+	iql "github.com/influxdata/influxql"
+)
+
+// maxIndexedQueries bounds the number of cached queries tracked for write
+// invalidation per backend
+const maxIndexedQueries = 10000
+
+var (
+	fluxBucketRE      = regexp.MustCompile(`from\s*\(\s*bucket\s*:\s*"([^"]*)"`)
+	fluxMeasurementRE = regexp.MustCompile(`_measurement"?\]?\s*==\s*"([^"]*)"`)
+)
+
+// queryIndex tracks the databases, measurements and time ranges of the
+// queries cached by the Delta Proxy Cache, so writes of late-arriving data
+// can invalidate the cache entries they affect. Entries are kept in order of
+// use, so the least recently used entry is evicted when the index is full
+type queryIndex struct {
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type indexEntry struct {
+	key          string
+	db           string
+	measurements []string
+	patterns     []*regexp.Regexp
+	// all is true when the query's measurements can't be determined, so
+	// the entry is matched by writes to any measurement in its db
+	all    bool
+	extent timeseries.Extent
+}
+
+func newQueryIndex() *queryIndex {
+	return &queryIndex{entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// ObserveCachedQuery implements backends.CachedQueryObserver by indexing the
+// cache key with the query's database, measurements and time range
+func (c *Client) ObserveCachedQuery(r *http.Request, key string,
+	trq *timeseries.TimeRangeQuery) {
+	if c.index == nil || r == nil || trq == nil || key == "" {
+		return
+	}
+	var e *indexEntry
+	switch q := trq.ParsedQuery.(type) {
+	case *iql.Query:
+		qp, _, _ := params.GetRequestValues(r)
+		e = influxQLEntry(qp.Get(influxql.ParamDB), q)
+	default:
+		e = fluxEntry(trq.Statement)
+	}
+	e.extent = trq.Extent
+	c.index.observe(key, e)
+}
+
+func influxQLEntry(db string, q *iql.Query) *indexEntry {
+	e := &indexEntry{db: db}
+	for _, st := range q.Statements {
+		sel, ok := st.(*iql.SelectStatement)
+		if !ok {
+			continue
+		}
+		for _, m := range sel.Sources.Measurements() {
+			if m.Database != "" {
+				e.db = m.Database
+			}
+			switch {
+			case m.Regex != nil && m.Regex.Val != nil:
+				e.patterns = append(e.patterns, m.Regex.Val)
+			case m.Name != "":
+				e.measurements = append(e.measurements, m.Name)
+			}
+		}
+	}
+	e.all = len(e.measurements) == 0 && len(e.patterns) == 0
+	return e
+}
+
+func fluxEntry(stmt string) *indexEntry {
+	e := &indexEntry{}
+	if m := fluxBucketRE.FindStringSubmatch(stmt); m != nil {
+		e.db = m[1]
+	}
+	for _, m := range fluxMeasurementRE.FindAllStringSubmatch(stmt, -1) {
+		e.measurements = append(e.measurements, m[1])
+	}
+	e.all = len(e.measurements) == 0
+	return e
+}
+
+func (qi *queryIndex) observe(key string, e *indexEntry) {
+	qi.mtx.Lock()
+	defer qi.mtx.Unlock()
+	e.key = key
+	if el, ok := qi.entries[key]; ok {
+		// the cached timeseries holds the union of the requested extents
+		prev := el.Value.(*indexEntry)
+		if prev.extent.Start.Before(e.extent.Start) {
+			e.extent.Start = prev.extent.Start
+		}
+		if prev.extent.End.After(e.extent.End) {
+			e.extent.End = prev.extent.End
+		}
+		el.Value = e
+		qi.lru.MoveToFront(el)
+		return
+	}
+	if qi.lru.Len() >= maxIndexedQueries {
+		oldest := qi.lru.Back()
+		qi.lru.Remove(oldest)
+		delete(qi.entries, oldest.Value.(*indexEntry).key)
+	}
+	qi.entries[key] = qi.lru.PushFront(e)
+}
+
+// invalidate removes and returns the keys of the indexed queries whose
+// database and measurements match the write, and whose time ranges overlap
+// the written points' time range for the measurement
+func (qi *queryIndex) invalidate(db string,
+	written map[string]timeseries.Extent) []string {
+	qi.mtx.Lock()
+	defer qi.mtx.Unlock()
+	var keys []string
+	for k, el := range qi.entries {
+		e := el.Value.(*indexEntry)
+		if !sameDatabase(db, e.db) {
+			continue
+		}
+		for m, ex := range written {
+			if e.matches(m) && !ex.Start.After(e.extent.End) &&
+				!ex.End.Before(e.extent.Start) {
+				keys = append(keys, k)
+				qi.lru.Remove(el)
+				delete(qi.entries, k)
+				break
+			}
+		}
+	}
+	return keys
+}
+
+func (e *indexEntry) matches(measurement string) bool {
+	if e.all {
+		return true
+	}
+	for _, m := range e.measurements {
+		if m == measurement {
+			return true
+		}
+	}
+	for _, p := range e.patterns {
+		if p.MatchString(measurement) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameDatabase compares a v1 database (db or db/rp) to a v2 bucket, treating
+// an unknown database on either side as a match
+func sameDatabase(a, b string) bool {
+	if a == "" || b == "" {
+		return true
+	}
+	a, _, _ = strings.Cut(a, "/")
+	b, _, _ = strings.Cut(b, "/")
+	return a == b
+}
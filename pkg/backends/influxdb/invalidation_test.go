@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package influxdb
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+
+	iql "github.com/influxdata/influxql"
+)
+
+func TestInfluxQLEntry(t *testing.T) {
+	q, err := iql.ParseQuery(`SELECT mean(value) FROM "db2"."autogen"."cpu", /^disk/ WHERE time > now() - 1h`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := influxQLEntry("db1", q)
+	if e.db != "db2" {
+		t.Errorf("expected db2 got %s", e.db)
+	}
+	if e.all || !e.matches("cpu") || !e.matches("disk_io") || e.matches("mem") {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestFluxEntry(t *testing.T) {
+	e := fluxEntry(`from(bucket: "telegraf/autogen")
+  |> range(start: -1h)
+  |> filter(fn: (r) => r._measurement == "cpu" or r["_measurement"] == "mem")`)
+	if e.db != "telegraf/autogen" {
+		t.Errorf("expected telegraf/autogen got %s", e.db)
+	}
+	if !slices.Equal(e.measurements, []string{"cpu", "mem"}) {
+		t.Errorf("unexpected measurements %v", e.measurements)
+	}
+	e = fluxEntry(`from(bucket: "telegraf") |> range(start: -1h)`)
+	if !e.all || !e.matches("anything") {
+		t.Error("expected entry to match all measurements")
+	}
+}
+
+func TestObserveCachedQuery(t *testing.T) {
+	c := &Client{index: newQueryIndex()}
+	q, _ := iql.ParseQuery(`SELECT value FROM cpu`)
+	r, _ := http.NewRequest(http.MethodGet, "http://0/query?db=telegraf", nil)
+	ex := timeseries.Extent{Start: time.Unix(0, 0), End: time.Unix(60, 0)}
+	c.ObserveCachedQuery(r, "key", &timeseries.TimeRangeQuery{ParsedQuery: q, Extent: ex})
+	ex = timeseries.Extent{Start: time.Unix(30, 0), End: time.Unix(120, 0)}
+	c.ObserveCachedQuery(r, "key", &timeseries.TimeRangeQuery{ParsedQuery: q, Extent: ex})
+	el, ok := c.index.entries["key"]
+	if !ok {
+		t.Fatal("expected key to be indexed")
+	}
+	e := el.Value.(*indexEntry)
+	if e.db != "telegraf" || e.extent.Start.Unix() != 0 || e.extent.End.Unix() != 120 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+}
+
+func TestQueryIndexInvalidate(t *testing.T) {
+	qi := newQueryIndex()
+	ex := timeseries.Extent{Start: time.Unix(100, 0), End: time.Unix(200, 0)}
+	qi.observe("a", &indexEntry{db: "telegraf", measurements: []string{"cpu"}, extent: ex})
+	qi.observe("b", &indexEntry{db: "other", measurements: []string{"cpu"}, extent: ex})
+	qi.observe("c", &indexEntry{db: "telegraf", all: true, extent: ex})
+
+	keys := qi.invalidate("telegraf/autogen", map[string]timeseries.Extent{
+		"cpu": {Start: time.Unix(300, 0), End: time.Unix(400, 0)},
+	})
+	if len(keys) != 0 {
+		t.Errorf("expected no keys got %v", keys)
+	}
+	keys = qi.invalidate("telegraf", map[string]timeseries.Extent{
+		"cpu": {Start: time.Unix(150, 0), End: time.Unix(400, 0)},
+	})
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"a", "c"}) {
+		t.Errorf("expected [a c] got %v", keys)
+	}
+	if len(qi.entries) != 1 || qi.lru.Len() != 1 {
+		t.Errorf("expected 1 entry got %d", len(qi.entries))
+	}
+}
+
+func TestQueryIndexEviction(t *testing.T) {
+	qi := newQueryIndex()
+	qi.observe("oldest", &indexEntry{})
+	qi.observe("reused", &indexEntry{})
+	for i := range maxIndexedQueries - 2 {
+		qi.observe(strconv.Itoa(i), &indexEntry{})
+	}
+	// observing an indexed query marks it as recently used
+	qi.observe("reused", &indexEntry{})
+	qi.observe("new", &indexEntry{})
+	if _, ok := qi.entries["oldest"]; ok {
+		t.Error("expected oldest entry to be evicted")
+	}
+	if _, ok := qi.entries["reused"]; !ok {
+		t.Error("expected reused entry to be retained")
+	}
+	if len(qi.entries) != maxIndexedQueries || qi.lru.Len() != maxIndexedQueries {
+		t.Errorf("expected %d got %d", maxIndexedQueries, len(qi.entries))
+	}
+}
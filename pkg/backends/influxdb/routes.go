@@ -35,6 +35,7 @@ func (c *Client) RegisterHandlers(handlers.Lookup) {
 			// and are able to be referenced by name (map key) in Config Files
 			"health":        http.HandlerFunc(c.HealthHandler),
 			"query":         http.HandlerFunc(c.QueryHandler),
+			"write":         http.HandlerFunc(c.WriteHandler),
 			providers.Proxy: http.HandlerFunc(c.ProxyHandler),
 		},
 	)
@@ -61,6 +62,20 @@ func (c *Client) DefaultPathConfigs(_ *bo.Options) po.List {
 			MatchTypeName:   matching.PathMatchNameExact,
 			MatchType:       matching.PathMatchTypeExact,
 		},
+		{
+			Path:          "/" + mnWrite,
+			HandlerName:   mnWrite,
+			Methods:       []string{http.MethodPost},
+			MatchTypeName: matching.PathMatchNameExact,
+			MatchType:     matching.PathMatchTypeExact,
+		},
+		{
+			Path:          "/" + apiv2Write,
+			HandlerName:   mnWrite,
+			Methods:       []string{http.MethodPost},
+			MatchTypeName: matching.PathMatchNameExact,
+			MatchType:     matching.PathMatchTypeExact,
+		},
 		{
 			Path:          "/",
 			HandlerName:   providers.Proxy,
@@ -65,7 +65,7 @@ func TestDefaultPathConfigs(t *testing.T) {
 		t.Errorf("expected to find path named: %s", "/")
 	}
 
-	const expectedLen = 3
+	const expectedLen = 5
 	if len(rsc.BackendOptions.Paths) != expectedLen {
 		t.Errorf("expected ordered length to be: %d, got: %d", expectedLen, len(rsc.BackendOptions.Paths))
 	}
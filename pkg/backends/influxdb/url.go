@@ -32,6 +32,8 @@ import (
 const (
 	mnQuery    = "query"
 	apiv2Query = "api/v2/query"
+	mnWrite    = "write"
+	apiv2Write = "api/v2/write"
 )
 
 // SetExtent will change the upstream request query to use the provided Extent
@@ -34,6 +34,11 @@ var ErrInvalidMaxShardSizeTime = errors.New(
 var ErrInvalidMaxShardSize = errors.New(
 	"'shard_max_size_time' and 'shard_max_size_points' cannot both be non-zero")
 
+// ErrOAuth2WithSigV4 is an error for when both 'oauth2' and 'sigv4' are
+// configured on the same backend
+var ErrOAuth2WithSigV4 = errors.New(
+	"'oauth2' and 'sigv4' cannot both be configured on the same backend")
+
 // ErrMissingProvider is an error type for missing provider
 type ErrMissingProvider struct {
 	error
@@ -146,3 +151,16 @@ func NewErrInvalidRewriterName(rewriterName, backendName string) error {
 			rewriterName, backendName),
 	}
 }
+
+// ErrInvalidShadowBackendName is an error type for invalid shadow backend name
+type ErrInvalidShadowBackendName struct {
+	error
+}
+
+// NewErrInvalidShadowBackendName returns a new invalid shadow backend name error
+func NewErrInvalidShadowBackendName(shadowName, backendName string) error {
+	return &ErrInvalidShadowBackendName{
+		error: fmt.Errorf(`invalid shadow backend "%s" provided in backend options "%s"`,
+			shadowName, backendName),
+	}
+}
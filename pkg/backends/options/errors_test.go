@@ -83,3 +83,12 @@ func TestInvalidRewriterName(t *testing.T) {
 		t.Error("invalid type assertion")
 	}
 }
+
+func TestInvalidShadowBackendName(t *testing.T) {
+	err := NewErrInvalidShadowBackendName("testShadow", "testBackend")
+	var e *ErrInvalidShadowBackendName
+	ok := errors.As(err, &e)
+	if !ok {
+		t.Error("invalid type assertion")
+	}
+}
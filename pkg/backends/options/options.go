@@ -41,13 +41,23 @@ import (
 	tro "github.com/trickstercache/trickster/v2/pkg/observability/tracing/options"
 	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
 	autho "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	cbo "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker/options"
 	corso "github.com/trickstercache/trickster/v2/pkg/proxy/cors/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	oauth2o "github.com/trickstercache/trickster/v2/pkg/proxy/oauth2/options"
 	po "github.com/trickstercache/trickster/v2/pkg/proxy/paths/options"
+	qco "github.com/trickstercache/trickster/v2/pkg/proxy/querycost/options"
+	qo "github.com/trickstercache/trickster/v2/pkg/proxy/queue/options"
+	rlo "github.com/trickstercache/trickster/v2/pkg/proxy/ratelimit/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter"
 	rwopts "github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter/options"
+	rto "github.com/trickstercache/trickster/v2/pkg/proxy/retry/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/router"
+	sho "github.com/trickstercache/trickster/v2/pkg/proxy/shadow/options"
+	tno "github.com/trickstercache/trickster/v2/pkg/proxy/tenancy/options"
 	to "github.com/trickstercache/trickster/v2/pkg/proxy/tls/options"
+	vo "github.com/trickstercache/trickster/v2/pkg/proxy/verification/options"
+	wo "github.com/trickstercache/trickster/v2/pkg/proxy/warming/options"
 	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
 	"github.com/trickstercache/trickster/v2/pkg/util/sets"
 
@@ -167,6 +177,15 @@ type Options struct {
 	ShardStep timeconv.Duration `yaml:"shard_step,omitempty"`
 	// ProxyOnly, when true, will cause this backend to bypass caching while handling the request
 	ProxyOnly bool `yaml:"proxy_only,omitempty"`
+	// ServeStaleOnError, when true, will cause timeseries requests that are partially cached to be
+	// served from the cache, with the uncached ranges missing, when the origin is unavailable
+	ServeStaleOnError bool `yaml:"serve_stale_on_error,omitempty"`
+	// Warming configures background warming of this backend's most frequently
+	// requested timeseries queries. Warming is disabled when nil.
+	Warming *wo.Options `yaml:"warming,omitempty"`
+	// Tenancy partitions this backend's cache entries by the tenant identity
+	// of each request. Tenant partitioning is disabled when nil.
+	Tenancy *tno.Options `yaml:"tenancy,omitempty"`
 
 	// ALBOptions holds the options for ALBs
 	ALBOptions *ao.Options `yaml:"alb,omitempty"`
@@ -203,6 +222,31 @@ type Options struct {
 	AuthenticatorName string `yaml:"authenticator_name,omitempty"`
 	// AWS SigV4
 	SigV4 *sigv4.SigV4Config `yaml:"sigv4,omitempty"`
+	// OAuth2 configures an OAuth2 Client Credentials grant whose bearer tokens
+	// are set on all upstream requests, including health checks
+	OAuth2 *oauth2o.Options `yaml:"oauth2,omitempty"`
+	// RateLimit configures per-client token bucket rate limits for requests
+	// to this Backend, and can be overridden at the Path level
+	RateLimit *rlo.Options `yaml:"rate_limit,omitempty"`
+	// UpstreamQueue limits the number of concurrent upstream requests to
+	// this Backend, queueing excess requests by priority class
+	UpstreamQueue *qo.Options `yaml:"upstream_queue,omitempty"`
+	// CircuitBreaker stops sending requests to this Backend's origin when
+	// its upstream error rate or latency is too high, serving cached content
+	// where possible until the origin recovers
+	CircuitBreaker *cbo.Options `yaml:"circuit_breaker,omitempty"`
+	// Retry retries idempotent upstream requests that fail with connection
+	// errors or retryable status codes, and optionally hedges slow requests
+	Retry *rto.Options `yaml:"retry,omitempty"`
+	// Shadow asynchronously mirrors a sample of this Backend's requests to
+	// another Backend, optionally comparing the responses
+	Shadow *sho.Options `yaml:"shadow,omitempty"`
+	// CacheVerification re-fetches a sample of timeseries cache hits from the
+	// origin and reports divergences from the cached data
+	CacheVerification *vo.Options `yaml:"cache_verification,omitempty"`
+	// QueryCost limits the points, series and response size of this Backend's
+	// timeseries queries
+	QueryCost *qco.Options `yaml:"query_cost,omitempty"`
 
 	// Simulated Latency
 	// When LatencyMin > 0 and LatencyMaxMS < LatencyMin (e.g., 0), then LatencyMin of latency
@@ -217,6 +261,9 @@ type Options struct {
 
 	// MaxQueryRange specifies the maximum range for a query allowed on this backend (e.g., '14d')
 	MaxQueryRange timeconv.Duration `yaml:"max_query_range,omitempty"`
+	// MaxPointsPerSeries specifies the maximum number of points per series that a
+	// timeseries query may request, beyond which its step is raised to fit
+	MaxPointsPerSeries int `yaml:"max_points_per_series,omitempty"`
 
 	// Synthesized Configurations
 	// These configurations are parsed versions of those defined above, and are what Trickster uses internally
@@ -343,6 +390,46 @@ func (o *Options) Clone() *Options {
 		out.AuthOptions = o.AuthOptions.Clone()
 	}
 
+	if o.Warming != nil {
+		out.Warming = o.Warming.Clone()
+	}
+
+	if o.Tenancy != nil {
+		out.Tenancy = o.Tenancy.Clone()
+	}
+
+	if o.OAuth2 != nil {
+		out.OAuth2 = o.OAuth2.Clone()
+	}
+
+	if o.RateLimit != nil {
+		out.RateLimit = o.RateLimit.Clone()
+	}
+
+	if o.UpstreamQueue != nil {
+		out.UpstreamQueue = o.UpstreamQueue.Clone()
+	}
+
+	if o.CircuitBreaker != nil {
+		out.CircuitBreaker = o.CircuitBreaker.Clone()
+	}
+
+	if o.Retry != nil {
+		out.Retry = o.Retry.Clone()
+	}
+
+	if o.Shadow != nil {
+		out.Shadow = o.Shadow.Clone()
+	}
+
+	if o.CacheVerification != nil {
+		out.CacheVerification = o.CacheVerification.Clone()
+	}
+
+	if o.QueryCost != nil {
+		out.QueryCost = o.QueryCost.Clone()
+	}
+
 	return out
 }
 
@@ -380,6 +467,54 @@ func (o *Options) Validate() (bool, error) {
 			return false, err
 		}
 	}
+	if o.Tenancy != nil {
+		if _, err := o.Tenancy.Validate(); err != nil {
+			return false, err
+		}
+	}
+	if o.OAuth2 != nil {
+		if o.SigV4 != nil {
+			return false, ErrOAuth2WithSigV4
+		}
+		if _, err := o.OAuth2.Validate(); err != nil {
+			return false, err
+		}
+	}
+	if o.RateLimit != nil {
+		if _, err := o.RateLimit.Validate(); err != nil {
+			return false, err
+		}
+	}
+	if o.UpstreamQueue != nil {
+		if _, err := o.UpstreamQueue.Validate(); err != nil {
+			return false, err
+		}
+	}
+	if o.CircuitBreaker != nil {
+		if _, err := o.CircuitBreaker.Validate(); err != nil {
+			return false, err
+		}
+	}
+	if o.Retry != nil {
+		if _, err := o.Retry.Validate(); err != nil {
+			return false, err
+		}
+	}
+	if o.Shadow != nil {
+		if _, err := o.Shadow.Validate(); err != nil {
+			return false, err
+		}
+	}
+	if o.CacheVerification != nil {
+		if _, err := o.CacheVerification.Validate(); err != nil {
+			return false, err
+		}
+	}
+	if o.QueryCost != nil {
+		if _, err := o.QueryCost.Validate(); err != nil {
+			return false, err
+		}
+	}
 
 	if o.HealthCheck != nil {
 		_, err := o.HealthCheck.Validate()
@@ -387,6 +522,11 @@ func (o *Options) Validate() (bool, error) {
 			return false, err
 		}
 	}
+	if o.Warming != nil {
+		if _, err := o.Warming.Validate(); err != nil {
+			return false, err
+		}
+	}
 	return true, nil
 }
 
@@ -507,6 +647,12 @@ func (l Lookup) ValidateConfigMappings(c co.Lookup, ncl negative.Lookups,
 			}
 			o.NegativeCache = nc
 		}
+		if o.Shadow != nil {
+			if so, ok := l[o.Shadow.Backend]; !ok || so == nil ||
+				o.Shadow.Backend == o.Name {
+				return NewErrInvalidShadowBackendName(o.Shadow.Backend, o.Name)
+			}
+		}
 		switch o.Provider {
 		case providers.Rule:
 			// Rule Type Validations
@@ -615,6 +761,10 @@ func (o *Options) Initialize(name string) error {
 		return errors.New("invalid max_query_range: value must be greater than or equal to 0")
 	}
 
+	if o.MaxPointsPerSeries < 0 {
+		return errors.New("invalid max_points_per_series: value must be greater than or equal to 0")
+	}
+
 	if o.OriginURL != "" {
 		parsedURL, err := url.Parse(o.OriginURL)
 		if err != nil {
@@ -673,6 +823,56 @@ func (o *Options) Initialize(name string) error {
 			return err
 		}
 	}
+	if o.Warming != nil {
+		if err := o.Warming.Initialize(""); err != nil {
+			return err
+		}
+	}
+	if o.Tenancy != nil {
+		if err := o.Tenancy.Initialize(""); err != nil {
+			return err
+		}
+	}
+	if o.OAuth2 != nil {
+		if err := o.OAuth2.Initialize(""); err != nil {
+			return err
+		}
+	}
+	if o.RateLimit != nil {
+		if err := o.RateLimit.Initialize(""); err != nil {
+			return err
+		}
+	}
+	if o.UpstreamQueue != nil {
+		if err := o.UpstreamQueue.Initialize(name); err != nil {
+			return err
+		}
+	}
+	if o.CircuitBreaker != nil {
+		if err := o.CircuitBreaker.Initialize(name); err != nil {
+			return err
+		}
+	}
+	if o.Retry != nil {
+		if err := o.Retry.Initialize(name); err != nil {
+			return err
+		}
+	}
+	if o.Shadow != nil {
+		if err := o.Shadow.Initialize(name); err != nil {
+			return err
+		}
+	}
+	if o.CacheVerification != nil {
+		if err := o.CacheVerification.Initialize(name); err != nil {
+			return err
+		}
+	}
+	if o.QueryCost != nil {
+		if err := o.QueryCost.Initialize(name); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -696,6 +896,9 @@ func (o *Options) CloneYAMLSafe() *Options {
 		// also strip out potentially sensitive headers
 		headers.HideAuthorizationCredentials(co.HealthCheck.Headers)
 	}
+	if co.OAuth2 != nil {
+		co.OAuth2 = co.OAuth2.CloneYAMLSafe()
+	}
 	return co
 }
 
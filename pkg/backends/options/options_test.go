@@ -35,9 +35,11 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	po "github.com/trickstercache/trickster/v2/pkg/proxy/paths/options"
 	rwopts "github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter/options"
+	sho "github.com/trickstercache/trickster/v2/pkg/proxy/shadow/options"
 	tlstest "github.com/trickstercache/trickster/v2/pkg/testutil/tls"
 	"github.com/trickstercache/trickster/v2/pkg/util/sets"
 
+	"github.com/prometheus/common/sigv4"
 	"github.com/stretchr/testify/require"
 	"go.yaml.in/yaml/v3"
 )
@@ -119,6 +121,53 @@ backends:
 	}
 }
 
+func TestOAuth2OptionsYAML(t *testing.T) {
+	const conf = `
+backends:
+  test:
+    provider: reverseproxycache
+    origin_url: http://example.com
+    oauth2:
+      token_url: https://idp.example.com/oauth2/token
+      client_id: trickster
+      client_secret: ${TRICKSTER_TEST_OAUTH2_SECRET}
+      scopes: [metrics.read]
+`
+	t.Setenv("TRICKSTER_TEST_OAUTH2_SECRET", "secret")
+	o, err := fromYAML(conf, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Initialize("test"); err != nil {
+		t.Fatal(err)
+	}
+	if o.OAuth2 == nil || o.OAuth2.ClientSecret != "secret" {
+		t.Fatalf("backend oauth2 = %v, want expanded client secret", o.OAuth2)
+	}
+	if ok, err := o.Validate(); !ok || err != nil {
+		t.Fatalf("Validate() = %v, %v", ok, err)
+	}
+	if o.Clone().OAuth2 == o.OAuth2 {
+		t.Fatal("clone must not share oauth2 options")
+	}
+	if got := o.CloneYAMLSafe().OAuth2.ClientSecret; got != "*****" {
+		t.Fatalf("YAML-safe client secret = %q, want *****", got)
+	}
+	if o.OAuth2.ClientSecret != "secret" {
+		t.Fatal("CloneYAMLSafe mutated the backend oauth2 options")
+	}
+
+	o.SigV4 = &sigv4.SigV4Config{Region: "us-east-1"}
+	if _, err := o.Validate(); !errors.Is(err, ErrOAuth2WithSigV4) {
+		t.Fatalf("Validate() error = %v, want %v", err, ErrOAuth2WithSigV4)
+	}
+	o.SigV4 = nil
+	o.OAuth2.ClientID = ""
+	if _, err := o.Validate(); err == nil {
+		t.Fatal("expected validation error for missing oauth2 client_id")
+	}
+}
+
 func TestClone(t *testing.T) {
 	p := po.New()
 	o := New()
@@ -264,6 +313,23 @@ func TestValidateConfigMappings(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+
+	o.Shadow = sho.New()
+	o.Shadow.Backend = o.Name
+	err = ol.ValidateConfigMappings(co.Lookup{"test": nil}, negative.Lookups{},
+		ro.Lookup{"test": new(ro.Options)}, rwopts.Lookup{}, autho.Lookup{},
+		tro.Lookup{})
+	var se *ErrInvalidShadowBackendName
+	if !errors.As(err, &se) {
+		t.Errorf("expected error for self-referencing shadow backend, got %v", err)
+	}
+	o.Shadow.Backend = "test_pool_member"
+	err = ol.ValidateConfigMappings(co.Lookup{"test": nil}, negative.Lookups{},
+		ro.Lookup{"test": new(ro.Options)}, rwopts.Lookup{}, autho.Lookup{},
+		tro.Lookup{})
+	if err != nil {
+		t.Error(err)
+	}
 }
 
 func testStringValueValidationError(to *testOptions, location *string, testValue string) error {
@@ -458,6 +524,12 @@ func TestInitialize(t *testing.T) {
 		t.Error("expected error for negative max_query_range, got nil")
 	}
 
+	oInvalid = *o
+	oInvalid.MaxPointsPerSeries = -1
+	if err := oInvalid.Initialize("test_invalid"); err == nil {
+		t.Error("expected error for negative max_points_per_series, got nil")
+	}
+
 	o2, err := fromTestYAMLWithDefault()
 	if err != nil {
 		t.Error(err)
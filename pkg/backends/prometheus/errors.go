@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"net/http"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/prometheus/model"
+)
+
+// Prometheus API error types
+const (
+	errorTypeBadData   = "bad_data"
+	errorTypeExecution = "execution"
+	errorTypeInternal  = "internal"
+)
+
+// WriteError implements backends.ErrorWriter by writing a Prometheus API
+// error response
+func (c *Client) WriteError(w http.ResponseWriter, code int, msg string) {
+	e := &model.Envelope{
+		Status:    "error",
+		ErrorType: errorType(code),
+		Error:     msg,
+	}
+	e.StartMarshal(w, code)
+	w.Write([]byte("}"))
+}
+
+// errorType returns the Prometheus API error type for an HTTP status code
+func errorType(code int) string {
+	switch {
+	case code == http.StatusUnprocessableEntity:
+		return errorTypeExecution
+	case code >= http.StatusBadRequest && code < http.StatusInternalServerError:
+		return errorTypeBadData
+	}
+	return errorTypeInternal
+}
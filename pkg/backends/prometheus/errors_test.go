@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package prometheus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	b, err := NewClient("test", nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := b.(*Client)
+
+	tests := []struct {
+		code     int
+		expected string
+	}{
+		{http.StatusBadRequest,
+			`{"status":"error","error":"too many points","errorType":"bad_data"}`},
+		{http.StatusUnprocessableEntity,
+			`{"status":"error","error":"too many points","errorType":"execution"}`},
+		{http.StatusInternalServerError,
+			`{"status":"error","error":"too many points","errorType":"internal"}`},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		c.WriteError(w, test.code, "too many points")
+		if w.Code != test.code {
+			t.Errorf("expected %d got %d", test.code, w.Code)
+		}
+		if w.Body.String() != test.expected {
+			t.Errorf("expected %s got %s", test.expected, w.Body.String())
+		}
+	}
+}
@@ -42,6 +42,8 @@ import (
 var (
 	_ backends.TimeseriesBackend          = (*Client)(nil)
 	_ backends.MergeableTimeseriesBackend = (*Client)(nil)
+	_ backends.ErrorWriter                = (*Client)(nil)
+	_ backends.StepSetter                 = (*Client)(nil)
 )
 
 // Prometheus API
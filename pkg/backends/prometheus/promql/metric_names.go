@@ -0,0 +1,255 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package promql
+
+import (
+	"strconv"
+	"strings"
+)
+
+// metricNameLabel is the label holding a series' metric name
+const metricNameLabel = "__name__"
+
+// MetricNames returns the distinct metric names referenced by the vector
+// selectors in query. The bool is false when a selector's metric name cannot
+// be determined exactly (e.g., {job="x"} or {__name__=~"node_.*"}) or the
+// query is malformed, so callers enforcing metric restrictions can fail closed.
+func MetricNames(query string) ([]string, bool) {
+	var names []string
+	seen := make(map[string]struct{})
+	add := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	}
+	// named is true when the most recent token was a metric name, so that a
+	// following label matcher block is known to have a name
+	var named bool
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case isPromQLSpace(c):
+			i++
+			continue
+		case c == '"' || c == '\'' || c == '`':
+			end, ok := skipPromQLString(query, i)
+			if !ok {
+				return nil, false
+			}
+			i = end
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '{':
+			end, ok := closingBrace(query, i)
+			if !ok {
+				return nil, false
+			}
+			if !named {
+				name, ok := matcherMetricName(query[i+1 : end])
+				if !ok {
+					return nil, false
+				}
+				add(name)
+			}
+			i = end + 1
+		case c == '[':
+			end := strings.IndexByte(query[i:], ']')
+			if end < 0 {
+				return nil, false
+			}
+			i += end + 1
+		case isPromQLDigit(c) || c == '.':
+			// numbers and durations, including unit suffixes (5m, 1h30m, 1e3)
+			for i < len(query) && (isPromQLGroupingIdentifierPart(query[i]) ||
+				query[i] == '.') {
+				i++
+			}
+		case isPromQLGroupingIdentifierStart(c):
+			start := i
+			for i < len(query) && isPromQLGroupingIdentifierPart(query[i]) {
+				i++
+			}
+			ident := query[start:i]
+			j := i
+			for j < len(query) && isPromQLSpace(query[j]) {
+				j++
+			}
+			next := byte(0)
+			if j < len(query) {
+				next = query[j]
+			}
+			switch {
+			case isLabelListKeyword(ident):
+				// skip the parenthesized list of grouping label names
+				if next == '(' {
+					end := strings.IndexByte(query[j:], ')')
+					if end < 0 {
+						return nil, false
+					}
+					i = j + end + 1
+				}
+			case next == '(' || isPromQLKeyword(ident) ||
+				isPromQLAggregator(ident) && isLabelListKeyword(leadingIdentifier(query[j:])):
+				// functions, aggregators and operators are not metric names
+			default:
+				add(ident)
+				named = true
+				continue
+			}
+		default:
+			i++
+		}
+		named = false
+	}
+	return names, true
+}
+
+// matcherMetricName returns the metric name from the body of a label matcher
+// block that is not preceded by a metric name
+func matcherMetricName(matchers string) (string, bool) {
+	var name string
+	for _, m := range splitMatchers(matchers) {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		// a bare quoted string is a (UTF-8) metric name
+		if s, err := strconv.Unquote(m); err == nil && m[0] != '`' {
+			name = s
+			continue
+		}
+		label, value, ok := strings.Cut(m, "=")
+		if !ok || strings.HasSuffix(label, "!") {
+			continue
+		}
+		label = strings.Trim(strings.TrimSpace(label), `"`)
+		if label != metricNameLabel {
+			continue
+		}
+		// __name__=~ is a regex and cannot be resolved to exact names
+		if strings.HasPrefix(value, "~") {
+			return "", false
+		}
+		s, err := strconv.Unquote(strings.TrimSpace(value))
+		if err != nil {
+			return "", false
+		}
+		name = s
+	}
+	return name, name != ""
+}
+
+// splitMatchers splits a label matcher block body on commas outside of quotes
+func splitMatchers(s string) []string {
+	var out []string
+	var quote byte
+	var escaped bool
+	start := 0
+	for i := range len(s) {
+		c := s[i]
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\' && quote != '`':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'', '`':
+			quote = c
+		case ',':
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+// closingBrace returns the index of the '}' closing the label matcher block
+// that opens at i, ignoring any braces within quoted matcher values
+func closingBrace(query string, i int) (int, bool) {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '"', '\'', '`':
+			end, ok := skipPromQLString(query, j)
+			if !ok {
+				return 0, false
+			}
+			j = end - 1
+		case '}':
+			return j, true
+		}
+	}
+	return 0, false
+}
+
+// skipPromQLString returns the index after the string literal starting at i
+func skipPromQLString(query string, i int) (int, bool) {
+	quote := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if quote != '`' {
+				j++
+			}
+		case quote:
+			return j + 1, true
+		}
+	}
+	return 0, false
+}
+
+func isLabelListKeyword(ident string) bool {
+	switch strings.ToLower(ident) {
+	case "by", "without", "on", "ignoring", "group_left", "group_right":
+		return true
+	}
+	return false
+}
+
+// leadingIdentifier returns the identifier at the start of s, if any
+func leadingIdentifier(s string) string {
+	i := 0
+	for i < len(s) && isPromQLGroupingIdentifierPart(s[i]) {
+		i++
+	}
+	return s[:i]
+}
+
+func isPromQLAggregator(ident string) bool {
+	switch strings.ToLower(ident) {
+	case "sum", "min", "max", "avg", "group", "stddev", "stdvar", "count",
+		"count_values", "bottomk", "topk", "quantile", "limitk", "limit_ratio":
+		return true
+	}
+	return false
+}
+
+func isPromQLKeyword(ident string) bool {
+	switch strings.ToLower(ident) {
+	case "and", "or", "unless", "atan2", "bool", "offset", "inf", "nan":
+		return true
+	}
+	return false
+}
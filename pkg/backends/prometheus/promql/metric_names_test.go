@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package promql
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMetricNames(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+		ok       bool
+	}{
+		{"up", []string{"up"}, true},
+		{`up{job="api"}`, []string{"up"}, true},
+		{`sum by (job) (rate(http_requests_total{code=~"5.."}[5m]))`, []string{"http_requests_total"}, true},
+		{`sum(rate(a[1h30m] offset 5m)) without (instance) / on(job) group_left(env) b`, []string{"a", "b"}, true},
+		{`histogram_quantile(0.9, sum by (le) (rate(req_duration_seconds_bucket[5m])))`, []string{"req_duration_seconds_bucket"}, true},
+		{`max_over_time(deriv(rate(x[5m])[30m:1m])[1h:]) > bool 0 and Inf < NaN`, []string{"x"}, true},
+		{`{__name__="node_load1", instance="a"}`, []string{"node_load1"}, true},
+		{`{"my.dotted.metric", job="x"}`, []string{"my.dotted.metric"}, true},
+		{`label_replace(up{path="}"}, "dst", "$1", "src", "(.*)")`, []string{"up"}, true},
+		{`up @ start() + up @ end()`, []string{"up"}, true},
+		{`node:cpu:rate5m`, []string{"node:cpu:rate5m"}, true},
+		{`vector(1) + scalar(time())`, nil, true},
+		{`{job="api"}`, nil, false},
+		{`{__name__=~"node_.*"}`, nil, false},
+		{`{__name__!="up"}`, nil, false},
+		{`up{job="api"`, nil, false},
+		{`up{job="api}`, nil, false},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			names, ok := MetricNames(test.query)
+			if ok != test.ok {
+				t.Fatalf("expected ok=%t got %t (%v)", test.ok, ok, names)
+			}
+			if ok && !slices.Equal(names, test.expected) {
+				t.Errorf("expected %v got %v", test.expected, names)
+			}
+		})
+	}
+}
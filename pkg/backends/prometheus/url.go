@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/trickstercache/trickster/v2/pkg/proxy/params"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
@@ -37,6 +38,17 @@ func (c *Client) SetExtent(r *http.Request, _ *timeseries.TimeRangeQuery,
 	return nil
 }
 
+// SetStep implements backends.StepSetter by changing the request query to use
+// the provided step
+func (c *Client) SetStep(r *http.Request, _ *timeseries.TimeRangeQuery,
+	step time.Duration,
+) error {
+	v, _, _ := params.GetRequestValues(r)
+	v.Set(upStep, strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	params.SetRequestValues(r, v)
+	return nil
+}
+
 // FastForwardRequest returns an *http.Request crafted to collect Fast Forward
 // data from the Origin, based on the provided HTTP Request
 func (c *Client) FastForwardRequest(r *http.Request) (*http.Request, error) {
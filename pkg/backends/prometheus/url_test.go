@@ -81,6 +81,21 @@ func TestSetExtent(t *testing.T) {
 	}
 }
 
+func TestSetStep(t *testing.T) {
+	b, err := NewClient("default", nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := http.NewRequest(http.MethodGet, "http://0/api/v1/query_range?q=up&step=15", nil)
+	if err := b.(*Client).SetStep(r, nil, 5*time.Minute); err != nil {
+		t.Error(err)
+	}
+	const expected = "q=up&step=300"
+	if r.URL.RawQuery != expected {
+		t.Errorf("expected %s got %s", expected, r.URL.RawQuery)
+	}
+}
+
 func TestFastForwardURL(t *testing.T) {
 	expected := "q=up&time=1"
 
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"cel.dev/cel-go/cel"
+	"cel.dev/cel-go/common/ast"
+	"cel.dev/cel-go/common/types"
+	"cel.dev/cel-go/common/types/ref"
+	"cel.dev/cel-go/interpreter"
+	"cel.dev/cel-go/parser"
+)
+
+// expressionVariables are the request attributes available as string
+// variables in rule expressions, named after their input sources
+var expressionVariables = []string{
+	"method", "url", "url_no_params", "scheme", "host", "hostname", "port",
+	"path", "params", "username", clientIPSource,
+}
+
+// expressionFunctions are the request attributes available as single-argument
+// string functions in rule expressions, named after their input sources
+var expressionFunctions = []string{"param", "header", "form", "cookie", "body_json"}
+
+// requestVar is the hidden variable through which expression functions access
+// the request. Each function call like header("X-Team") is expanded by a macro
+// to a call with the request as its first argument.
+const requestVar = "__request__"
+
+var requestType = cel.OpaqueType("trickster.Request")
+
+var ErrExpressionNotBool = errors.New("expression must evaluate to a bool")
+
+var expressionEnv = sync.OnceValues(newExpressionEnv)
+
+func newExpressionEnv() (*cel.Env, error) {
+	opts := []cel.EnvOption{cel.Variable(requestVar, requestType)}
+	for _, name := range expressionVariables {
+		opts = append(opts, cel.Variable(name, cel.StringType))
+	}
+	macros := make([]cel.Macro, 0, len(expressionFunctions))
+	for _, name := range expressionFunctions {
+		f := sourceExtractionFuncs[inputType(name)]
+		opts = append(opts, cel.Function(name,
+			cel.Overload(name+"_request_string",
+				[]*cel.Type{requestType, cel.StringType}, cel.StringType,
+				cel.BinaryBinding(func(rv, arg ref.Val) ref.Val {
+					r, _ := rv.Value().(*http.Request)
+					return types.String(f(r, string(arg.(types.String))))
+				}),
+			),
+		))
+		macros = append(macros, cel.GlobalMacro(name, 1,
+			func(eh parser.ExprHelper, _ ast.Expr, args []ast.Expr) (ast.Expr, *cel.Error) {
+				return eh.NewCall(name, eh.NewIdent(requestVar), args[0]), nil
+			}))
+	}
+	opts = append(opts, cel.Macros(macros...))
+	return cel.NewEnv(opts...)
+}
+
+// compileExpression parses and type-checks a rule case expression
+func compileExpression(expr string) (cel.Program, error) {
+	env, err := expressionEnv()
+	if err != nil {
+		return nil, err
+	}
+	a, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if !a.OutputType().IsExactType(cel.BoolType) {
+		return nil, fmt.Errorf("%w, got %s", ErrExpressionNotBool, a.OutputType())
+	}
+	return env.Program(a)
+}
+
+// requestActivation resolves expression variables from the request as they
+// are referenced
+type requestActivation struct {
+	r                *http.Request
+	forwardedHeaders string
+}
+
+func (a *requestActivation) ResolveName(name string) (any, bool) {
+	if name == requestVar {
+		return requestVal{r: a.r}, true
+	}
+	f, ok := sourceExtractionFuncs[inputType(name)]
+	if !ok {
+		return nil, false
+	}
+	var arg string
+	if name == clientIPSource {
+		arg = a.forwardedHeaders
+	}
+	return f(a.r, arg), true
+}
+
+func (a *requestActivation) Parent() interpreter.Activation {
+	return nil
+}
+
+// requestVal wraps the request as an opaque expression value
+type requestVal struct {
+	r *http.Request
+}
+
+func (v requestVal) ConvertToNative(typeDesc reflect.Type) (any, error) {
+	if reflect.TypeOf(v.r).AssignableTo(typeDesc) {
+		return v.r, nil
+	}
+	return nil, fmt.Errorf("unsupported conversion from request to %v", typeDesc)
+}
+
+func (v requestVal) ConvertToType(typeVal ref.Type) ref.Val {
+	if typeVal == types.TypeType {
+		return requestType
+	}
+	return types.NewErr("unsupported conversion from request to %v", typeVal)
+}
+
+func (v requestVal) Equal(other ref.Val) ref.Val {
+	o, ok := other.(requestVal)
+	return types.Bool(ok && o.r == v.r)
+}
+
+func (v requestVal) Type() ref.Type {
+	return requestType
+}
+
+func (v requestVal) Value() any {
+	return v.r
+}
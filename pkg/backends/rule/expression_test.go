@@ -0,0 +1,223 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rule
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends"
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	ro "github.com/trickstercache/trickster/v2/pkg/backends/rule/options"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	at "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	tc "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCompileExpression(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{`header("X-Team") == "sre" && path.startsWith("/api/v1/query")`, ""},
+		{`form("query").contains("rate(") || body_json("/query") != ""`, ""},
+		{`cookie("canary") == "1" || username in ["alice", "bob"]`, ""},
+		{`method == "POST" && param("step").size() > 0 && client_ip.startsWith("10.")`, ""},
+		{`scheme + "://" + host + url_no_params == url || port == "" || hostname == params`, ""},
+		{`path`, "must evaluate to a bool"},
+		{`path == 1`, "found no matching overload"},
+		{`header("X-Team", "extra") == ""`, "found no matching overload"},
+		{`unknown == "a"`, "undeclared reference"},
+		{`path ==`, "Syntax error"},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := compileExpression(test.expr)
+			if test.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+// trustTestProxies trusts the forwarding headers of requests from the test
+// request addresses for the duration of the test
+func trustTestProxies(t *testing.T, cidrs ...string) {
+	t.Helper()
+	prefixes, err := headers.ParseTrustedProxies(append(cidrs,
+		"192.0.2.0/24", "192.168.0.0/16"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers.SetTrustedProxies(prefixes)
+	t.Cleanup(func() { headers.SetTrustedProxies(nil) })
+}
+
+func TestExpressionEvaluation(t *testing.T) {
+	trustTestProxies(t)
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost,
+			"http://example.com:8480/api/v1/query_range?step=15",
+			strings.NewReader("query=sum(rate(x[5m]))"))
+		r.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+		r.Header.Set("X-Team", "sre")
+		r.Header.Set(headers.NameXForwardedFor, "10.1.1.1")
+		r.AddCookie(&http.Cookie{Name: "canary", Value: "1"})
+		return request.SetResources(r,
+			&request.Resources{AuthResult: &at.AuthResult{Username: "alice",
+				Status: at.AuthSuccess}})
+	}
+	tests := []struct {
+		expr     string
+		fh       string
+		expected bool
+	}{
+		{`header("X-Team") == "sre" && path.startsWith("/api/v1/query")`, "", true},
+		{`header("x-team") == "dev"`, "", false},
+		{`form("query").contains("rate(") && param("step") == "15"`, "", true},
+		{`cookie("canary") == "1" && username == "alice"`, "", true},
+		{`method == "POST" && port == "8480" && hostname == "example.com"`, "", true},
+		{`client_ip == "10.1.1.1"`, "x", true},
+		{`client_ip == "10.1.1.1"`, "none", false},
+		{`body_json("/query") == ""`, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			prg, err := compileExpression(test.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, _, err := prg.Eval(&requestActivation{r: newRequest(), forwardedHeaders: test.fh})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := out.Value().(bool); got != test.expected {
+				t.Errorf("expected %t got %t", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestEvaluateExpressions(t *testing.T) {
+	o := bo.New()
+	o.ForwardedHeaders = "x"
+	routed := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("X-Routed-To", name)
+		})
+	}
+	cl1, _ := NewClient("test-backend-1", nil, routed("backend-1"), nil, nil, nil)
+	cl2, _ := NewClient("test-backend-2", nil, routed("backend-2"), nil, nil, nil)
+	clients := backends.Backends{"test-backend-1": cl1, "test-backend-2": cl2}
+	backendClient, err := NewClient("test-client", o, nil, nil, clients, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := backendClient.(*Client)
+	ropts := &ro.Options{
+		Name:              "expr-rule",
+		NextRoute:         "test-backend-1",
+		MaxRuleExecutions: ro.DefaultMaxRuleExecutions,
+		CaseOptions: ro.CaseOptionsList{
+			{
+				Name:       "sre-queries",
+				Expression: `header("X-Team") == "sre" && path.startsWith("/api/v1/query")`,
+				NextRoute:  "test-backend-2",
+			},
+			{
+				Expression:  `client_ip.startsWith("10.")`,
+				RedirectURL: "http://trickstercache.org",
+			},
+			{
+				// never evaluated for sre queries, since the first match wins
+				Expression: `header("X-Team") == "sre"`,
+				NextRoute:  "test-backend-1",
+			},
+		},
+	}
+	if err := c.parseOptions(ropts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	counter := func(label string) float64 {
+		return testutil.ToFloat64(metrics.RuleCaseMatches.WithLabelValues("expr-rule", label))
+	}
+	before := map[string]float64{"sre-queries": counter("sre-queries"),
+		"1": counter("1"), "default": counter("default")}
+	trustTestProxies(t)
+
+	route := func(team, clientIP string) string {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/api/v1/query_range", nil)
+		r = r.WithContext(tc.WithHops(context.Background(), 0, 20))
+		r.Header.Set("X-Team", team)
+		if clientIP != "" {
+			r.Header.Set(headers.NameXForwardedFor, clientIP)
+		}
+		h, r, err := c.rule.evaluatorFunc(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Header().Get("X-Routed-To") == "" {
+			return w.Header().Get(headers.NameLocation)
+		}
+		return w.Header().Get("X-Routed-To")
+	}
+
+	if got := route("sre", ""); got != "backend-2" {
+		t.Errorf("expected backend-2 got %s", got)
+	}
+	if got := route("dev", "10.0.0.1"); got != "http://trickstercache.org" {
+		t.Errorf("expected redirect got %s", got)
+	}
+	if got := route("dev", ""); got != "backend-1" {
+		t.Errorf("expected backend-1 got %s", got)
+	}
+	for label, want := range map[string]float64{"sre-queries": 1, "1": 1, "default": 1} {
+		if got := counter(label) - before[label]; got != want {
+			t.Errorf("case %s: expected %f matches got %f", label, want, got)
+		}
+	}
+
+	ropts.CaseOptions[0].Expression = `path.size()`
+	err = c.parseOptions(ropts, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid expression in rule expr-rule case 0") {
+		t.Errorf("expected invalid expression error, got %v", err)
+	}
+	ropts.CaseOptions[0].Expression = `path == "/"`
+	ropts.CaseOptions[0].Name = defaultCaseLabel
+	err = c.parseOptions(ropts, nil)
+	if err == nil || !strings.Contains(err.Error(), "reserved name") {
+		t.Errorf("expected reserved name error, got %v", err)
+	}
+	ropts.CaseOptions[0].Name = "sre-queries"
+	ropts.CaseOptions[0].Matches = []string{"a"}
+	if err = c.parseOptions(ropts, nil); err == nil {
+		t.Error("expected error for case with matches and expression")
+	}
+}
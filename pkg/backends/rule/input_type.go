@@ -17,9 +17,15 @@
 package rule
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/params"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/urls"
 )
 
@@ -40,8 +46,17 @@ var sourceExtractionFuncs = map[inputType]extractionFunc{
 	"params":        extractParamsFromSource,
 	"param":         extractParamFromSource,
 	"header":        extractHeaderFromSource,
+	"body_json":     extractBodyJSONFromSource,
+	"form":          extractFormFromSource,
+	"cookie":        extractCookieFromSource,
+	"username":      extractUsernameFromSource,
+	"client_ip":     extractClientIPFromSource,
 }
 
+// clientIPSource is the input source name for the client IP, whose extraction
+// argument is the backend's forwarded_headers type rather than the input_key
+const clientIPSource = "client_ip"
+
 // IsValidSourceName returns true only if the provided source name is supported by the Rules engine
 func isValidSourceName(source string) (extractionFunc, bool) {
 	f, ok := sourceExtractionFuncs[inputType(source)]
@@ -127,6 +142,101 @@ func extractHeaderFromSource(r *http.Request, headerName string) string {
 	return ""
 }
 
+// extractBodyJSONFromSource returns the value at the provided JSON Pointer
+// (RFC 6901) in the request body. String values are returned unquoted, while
+// other values are returned as compact JSON.
+func extractBodyJSONFromSource(r *http.Request, pointer string) string {
+	if r == nil {
+		return ""
+	}
+	b, err := request.GetBody(r)
+	if err != nil || len(b) == 0 {
+		return ""
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return ""
+	}
+	v, ok := resolveJSONPointer(v, pointer)
+	if !ok {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+func resolveJSONPointer(v any, pointer string) (any, bool) {
+	if pointer == "" {
+		return v, true
+	}
+	if pointer[0] != '/' {
+		return nil, false
+	}
+	for token := range strings.SplitSeq(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch t := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = t[token]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			v = t[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// extractFormFromSource returns the named field from the request's query
+// parameters or form-encoded body, such as the query field of a POSTed
+// Prometheus query
+func extractFormFromSource(r *http.Request, fieldName string) string {
+	if r == nil || r.URL == nil {
+		return ""
+	}
+	v, _, _ := params.GetRequestValues(r)
+	return v.Get(fieldName)
+}
+
+func extractCookieFromSource(r *http.Request, cookieName string) string {
+	if r == nil || r.Header == nil {
+		return ""
+	}
+	c, err := r.Cookie(cookieName)
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+func extractUsernameFromSource(r *http.Request, _ string) string {
+	if r == nil {
+		return ""
+	}
+	// observe-only authenticators record a username that was not verified
+	if rsc := request.GetResources(r); rsc != nil && rsc.AuthResult != nil &&
+		rsc.AuthResult.Status == types.AuthSuccess {
+		return rsc.AuthResult.Username
+	}
+	return ""
+}
+
+func extractClientIPFromSource(r *http.Request, forwardedHeaders string) string {
+	return headers.ClientIP(r, forwardedHeaders)
+}
+
 // assumes delimiter is not empty string, and part is >= 0
 func extractSourcePart(input, delimiter string, part int) string {
 	if input == "" || len(delimiter) > len(input) {
@@ -17,9 +17,18 @@
 package rule
 
 import (
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends"
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	ro "github.com/trickstercache/trickster/v2/pkg/backends/rule/options"
+	at "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
 )
 
 func TestExtractions(t *testing.T) {
@@ -82,6 +91,120 @@ func TestExtractions(t *testing.T) {
 	}
 }
 
+func TestRequestContentExtractions(t *testing.T) {
+	const jsonBody = `{"query":"up","options":{"a/b":1,"m~n":true},"matches":["x","y"]}`
+	newJSONRequest := func() *http.Request {
+		r, _ := http.NewRequest(http.MethodPost, "http://example.com/api",
+			strings.NewReader(jsonBody))
+		r.Header.Set(headers.NameContentType, headers.ValueApplicationJSON)
+		return r
+	}
+	formRequest := func() *http.Request {
+		r, _ := http.NewRequest(http.MethodPost, "http://example.com/api/v1/query_range?step=15",
+			strings.NewReader("query=sum(rate(x[5m]))&start=0"))
+		r.Header.Set(headers.NameContentType, headers.ValueXFormURLEncoded)
+		return r
+	}
+	getRequest, _ := http.NewRequest(http.MethodGet,
+		"http://example.com/api/v1/query?query=up", nil)
+	getRequest.AddCookie(&http.Cookie{Name: "tenant", Value: "team-a"})
+	authRequest := request.SetResources(getRequest.Clone(getRequest.Context()),
+		&request.Resources{AuthResult: &at.AuthResult{Username: "alice",
+			Status: at.AuthSuccess}})
+	observedRequest := request.SetResources(getRequest.Clone(getRequest.Context()),
+		&request.Resources{AuthResult: &at.AuthResult{Username: "alice",
+			Status: at.AuthObserved}})
+
+	tests := []struct {
+		name     string
+		source   string
+		inputKey string
+		expected string
+		request  *http.Request
+	}{
+		{"json string", "body_json", "/query", "up", newJSONRequest()},
+		{"json escaped slash", "body_json", "/options/a~1b", "1", newJSONRequest()},
+		{"json escaped tilde", "body_json", "/options/m~0n", "true", newJSONRequest()},
+		{"json array index", "body_json", "/matches/1", "y", newJSONRequest()},
+		{"json array", "body_json", "/matches", `["x","y"]`, newJSONRequest()},
+		{"json missing", "body_json", "/missing", "", newJSONRequest()},
+		{"json bad index", "body_json", "/matches/2", "", newJSONRequest()},
+		{"json bad pointer", "body_json", "query", "", newJSONRequest()},
+		{"json no body", "body_json", "/query", "", getRequest},
+		{"json nil", "body_json", "/query", "", nil},
+		{"form body", "form", "query", "sum(rate(x[5m]))", formRequest()},
+		{"form url", "form", "step", "15", formRequest()},
+		{"form get", "form", "query", "up", getRequest},
+		{"form nil", "form", "query", "", nil},
+		{"cookie", "cookie", "tenant", "team-a", getRequest},
+		{"cookie missing", "cookie", "other", "", getRequest},
+		{"cookie nil", "cookie", "tenant", "", nil},
+		{"username", "username", "", "alice", authRequest},
+		{"username anonymous", "username", "", "", getRequest},
+		{"username observed", "username", "", "", observedRequest},
+		{"username nil", "username", "", "", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f, ok := isValidSourceName(test.source)
+			if !ok {
+				t.Fatalf("unknown source %v", test.source)
+			}
+			if got := f(test.request, test.inputKey); got != test.expected {
+				t.Errorf("\ngot      %s\nexpected %s", got, test.expected)
+			}
+		})
+	}
+
+	// the body must remain readable after extraction
+	r := formRequest()
+	extractFormFromSource(r, "query")
+	b, _ := io.ReadAll(r.Body)
+	if string(b) != "query=sum(rate(x[5m]))&start=0" {
+		t.Errorf("unexpected request body after extraction: %s", string(b))
+	}
+}
+
+func TestParseClientIPSource(t *testing.T) {
+	o := bo.New()
+	o.ForwardedHeaders = "x"
+	cl1, err := NewClient("test-backend-1", nil, testMux1, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backendClient, err := NewClient("test-client", o, nil, nil,
+		backends.Backends{"test-backend-1": cl1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := backendClient.(*Client)
+	ropts := &ro.Options{
+		Name:        "test-rule",
+		InputType:   "string",
+		InputSource: "client_ip",
+		Operation:   "prefix",
+		NextRoute:   "test-backend-1",
+	}
+	if err := c.parseOptions(ropts, nil); err != nil {
+		t.Fatal(err)
+	}
+	trustTestProxies(t, "10.0.0.4")
+	r, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	r.RemoteAddr = "192.168.1.10:51234"
+	r.Header.Set(headers.NameXForwardedFor, "10.0.0.3, 10.0.0.4")
+	r.Header.Set(headers.NameForwarded, "for=10.0.0.1")
+	if got := c.rule.extractionFunc(r, c.rule.extractionArg); got != "10.0.0.3" {
+		t.Errorf("expected %s got %s", "10.0.0.3", got)
+	}
+	c.Configuration().ForwardedHeaders = "none"
+	if err := c.parseOptions(ropts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.rule.extractionFunc(r, c.rule.extractionArg); got != "192.168.1.10" {
+		t.Errorf("expected %s got %s", "192.168.1.10", got)
+	}
+}
+
 func TestIsValidSourceName(t *testing.T) {
 	tests := []struct {
 		source   string
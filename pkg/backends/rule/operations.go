@@ -17,13 +17,20 @@
 package rule
 
 import (
+	"errors"
+	"fmt"
+	"net/netip"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/trickstercache/trickster/v2/pkg/checksum/md5"
 	"github.com/trickstercache/trickster/v2/pkg/checksum/sha1"
 	"github.com/trickstercache/trickster/v2/pkg/encoding/base64"
+
+	"github.com/cespare/xxhash/v2"
 )
 
 type (
@@ -45,6 +52,10 @@ var operationFuncs = map[operation]operationFunc{
 	"string-base64": opStringBase64,
 	"string-modulo": opStringModulo,
 
+	"string-ip-in-cidr":     opStringIPInCIDR,
+	"string-time-between":   opStringTimeBetween,
+	"string-percent-bucket": opStringPercentBucket,
+
 	"num-eq": opNumEquality,
 	"num-gt": opNumGreaterThan,
 	"num-lt": opNumLessThan,
@@ -57,6 +68,38 @@ var operationFuncs = map[operation]operationFunc{
 	"bool-eq": opBoolEquality,
 }
 
+// operationArgParsers validate and parse the arguments of operations whose
+// arguments have a structure beyond a plain string or number, so that invalid
+// arguments are rejected when the rule is loaded
+var operationArgParsers = map[operation]func(string) (any, error){
+	"string-ip-in-cidr":     parseCIDRList,
+	"string-time-between":   parseTimeWindow,
+	"string-percent-bucket": parsePercentRange,
+}
+
+// parsedOperationArgs caches the parsed arguments of operations listed in
+// operationArgParsers, keyed by operation and argument
+var parsedOperationArgs sync.Map
+
+func parsedOperationArg[T any](op operation, arg string) (T, bool) {
+	var zero T
+	key := string(op) + "\x00" + arg
+	if v, ok := parsedOperationArgs.Load(key); ok {
+		t, ok := v.(T)
+		return t, ok
+	}
+	v, err := operationArgParsers[op](arg)
+	if err != nil {
+		return zero, false
+	}
+	parsedOperationArgs.Store(key, v)
+	t, ok := v.(T)
+	return t, ok
+}
+
+// now is swapped in tests to evaluate time-based operations deterministically
+var now = time.Now
+
 func btos(t bool, negate bool) string {
 	if negate {
 		t = !t
@@ -221,3 +264,207 @@ func opBoolEquality(input, arg string, negate bool) string {
 	}
 	return ""
 }
+
+// parseCIDRList parses a comma-separated list of CIDRs or IP addresses
+func parseCIDRList(arg string) (any, error) {
+	var out []netip.Prefix
+	for part := range strings.SplitSeq(arg, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, err
+		}
+		if p.Addr().Is4In6() {
+			if p.Bits() < 96 {
+				return nil, fmt.Errorf("invalid cidr %q", part)
+			}
+			p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+		}
+		out = append(out, p.Masked())
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no cidrs provided")
+	}
+	return out, nil
+}
+
+func opStringIPInCIDR(input, arg string, negate bool) string {
+	addr, err := netip.ParseAddr(input)
+	if err != nil {
+		return ""
+	}
+	prefixes, ok := parsedOperationArg[[]netip.Prefix]("string-ip-in-cidr", arg)
+	if !ok {
+		return ""
+	}
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return btos(true, negate)
+		}
+	}
+	return btos(false, negate)
+}
+
+// timeWindow is a daily time-of-day window, optionally limited to certain days
+// of the week, in a time zone. start and end are minutes since midnight; when
+// end <= start, the window spans midnight.
+type timeWindow struct {
+	days       [7]bool
+	start, end int
+	loc        *time.Location
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseTimeWindow parses a time window in the format
+// "[days] HH:MM-HH:MM [time zone]", such as "Mon-Fri 09:00-17:00 America/New_York".
+// days is a comma-separated list of days or day ranges and defaults to every
+// day; the time zone defaults to UTC.
+func parseTimeWindow(arg string) (any, error) {
+	fields := strings.Fields(arg)
+	w := &timeWindow{loc: time.UTC}
+	var i int
+	if len(fields) > 0 && !strings.Contains(fields[0], ":") {
+		if err := w.parseDays(fields[0]); err != nil {
+			return nil, err
+		}
+		i++
+	} else {
+		w.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	if i >= len(fields) {
+		return nil, fmt.Errorf("missing time range in %q", arg)
+	}
+	start, end, ok := strings.Cut(fields[i], "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid time range %q", fields[i])
+	}
+	var err error
+	if w.start, err = parseTimeOfDay(start, false); err != nil {
+		return nil, err
+	}
+	if w.end, err = parseTimeOfDay(end, true); err != nil {
+		return nil, err
+	}
+	i++
+	if i < len(fields) {
+		if w.loc, err = time.LoadLocation(fields[i]); err != nil {
+			return nil, err
+		}
+		i++
+	}
+	if i < len(fields) {
+		return nil, fmt.Errorf("unexpected value %q in time window", fields[i])
+	}
+	return w, nil
+}
+
+func (w *timeWindow) parseDays(input string) error {
+	for part := range strings.SplitSeq(strings.ToLower(input), ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		d1, ok := weekdays[from]
+		if !ok {
+			return fmt.Errorf("invalid day %q", from)
+		}
+		d2 := d1
+		if isRange {
+			if d2, ok = weekdays[to]; !ok {
+				return fmt.Errorf("invalid day %q", to)
+			}
+		}
+		for d := d1; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == d2 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseTimeOfDay(input string, allow24 bool) (int, error) {
+	hh, mm, ok := strings.Cut(input, ":")
+	h, err1 := strconv.Atoi(hh)
+	m, err2 := strconv.Atoi(mm)
+	if !ok || err1 != nil || err2 != nil || h < 0 || m < 0 || m > 59 ||
+		h > 24 || (h == 24 && (!allow24 || m != 0)) {
+		return 0, fmt.Errorf("invalid time of day %q", input)
+	}
+	return h*60 + m, nil
+}
+
+func (w *timeWindow) contains(t time.Time) bool {
+	t = t.In(w.loc)
+	if !w.days[t.Weekday()] {
+		return false
+	}
+	m := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// opStringTimeBetween evaluates whether the current time is within the time
+// window provided in arg. The input is not used.
+func opStringTimeBetween(_, arg string, negate bool) string {
+	w, ok := parsedOperationArg[*timeWindow]("string-time-between", arg)
+	if !ok {
+		return ""
+	}
+	return btos(w.contains(now()), negate)
+}
+
+// percentRange is a half-open range of percentage buckets
+type percentRange struct {
+	from, to float64
+}
+
+// parsePercentRange parses a percentage ("10", equivalent to "0-10") or a
+// range of percentages ("10-20")
+func parsePercentRange(arg string) (any, error) {
+	from, to, isRange := strings.Cut(strings.TrimSpace(arg), "-")
+	if !isRange {
+		from, to = "0", from
+	}
+	f, t, ok := areNums(strings.TrimSpace(from), strings.TrimSpace(to))
+	if !ok || f < 0 || t > 100 || f > t {
+		return nil, fmt.Errorf("invalid percentage range %q", arg)
+	}
+	return percentRange{from: f, to: t}, nil
+}
+
+// percentBucket stably hashes the input into a bucket from 0 to 99.99
+func percentBucket(input string) float64 {
+	return float64(xxhash.Sum64String(input)%10000) / 100
+}
+
+// opStringPercentBucket evaluates whether the input's percentage bucket is
+// within the range provided in arg. An empty input is not placed into a bucket.
+func opStringPercentBucket(input, arg string, negate bool) string {
+	if input == "" {
+		return ""
+	}
+	r, ok := parsedOperationArg[percentRange]("string-percent-bucket", arg)
+	if !ok {
+		return ""
+	}
+	b := percentBucket(input)
+	return btos(b >= r.from && b < r.to, negate)
+}
@@ -17,8 +17,10 @@
 package rule
 
 import (
+	"fmt"
 	"strconv"
 	"testing"
+	"time"
 )
 
 func TestBToS(t *testing.T) {
@@ -91,6 +93,22 @@ func TestOperations(t *testing.T) {
 		{"bool-eq", "true", "true", false, "true"},
 		{"bool-eq", "a", "true", false, ""},
 		{"bool-eq", "true", "a", false, ""},
+		{"string-ip-in-cidr", "10.1.2.3", "10.0.0.0/8", false, "true"},
+		{"string-ip-in-cidr", "10.1.2.3", "10.0.0.0/8", true, "false"},
+		{"string-ip-in-cidr", "192.168.1.5", "10.0.0.0/8, 192.168.0.0/16", false, "true"},
+		{"string-ip-in-cidr", "172.16.0.1", "10.0.0.0/8,192.168.0.0/16", false, "false"},
+		{"string-ip-in-cidr", "172.16.0.1", "172.16.0.1", false, "true"},
+		{"string-ip-in-cidr", "::ffff:10.0.0.1", "10.0.0.0/8", false, "true"},
+		{"string-ip-in-cidr", "2001:db8::1", "2001:db8::/32", false, "true"},
+		{"string-ip-in-cidr", "2001:db8::1", "10.0.0.0/8", false, "false"},
+		{"string-ip-in-cidr", "not-an-ip", "10.0.0.0/8", false, ""},
+		{"string-ip-in-cidr", "10.0.0.1", "10.0.0.0/33", false, ""},
+		{"string-percent-bucket", "user-a", "100", false, "true"},
+		{"string-percent-bucket", "user-a", "0", false, "false"},
+		{"string-percent-bucket", "user-a", "0-100", true, "false"},
+		{"string-percent-bucket", "", "100", false, ""},
+		{"string-percent-bucket", "user-a", "101", false, ""},
+		{"string-percent-bucket", "user-a", "20-10", false, ""},
 	}
 	for i, test := range tests {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
@@ -105,3 +123,76 @@ func TestOperations(t *testing.T) {
 		})
 	}
 }
+
+func TestOpStringTimeBetween(t *testing.T) {
+	// Wednesday, 2024-01-10 15:30 UTC
+	wed := time.Date(2024, 1, 10, 15, 30, 0, 0, time.UTC)
+	defer func() { now = time.Now }()
+	now = func() time.Time { return wed }
+
+	tests := []struct {
+		arg      string
+		negate   bool
+		expected string
+	}{
+		{"09:00-17:00", false, "true"},
+		{"09:00-17:00", true, "false"},
+		{"15:31-17:00", false, "false"},
+		{"09:00-15:30", false, "false"},
+		{"15:30-24:00", false, "true"},
+		{"22:00-16:00", false, "true"},
+		{"22:00-15:00", false, "false"},
+		{"Mon-Fri 09:00-17:00", false, "true"},
+		{"sat,sun 09:00-17:00", false, "false"},
+		{"Fri-Wed 09:00-17:00", false, "true"},
+		{"Thu-Tue 09:00-17:00", false, "false"},
+		{"Mon,Wed 15:00-16:00", false, "true"},
+		{"Mon-Fri 09:00-17:00 America/New_York", false, "true"},
+		{"Mon-Fri 11:00-17:00 America/New_York", false, "false"},
+		{"Wed 00:00-02:00 Asia/Tokyo", false, "false"},
+		{"Thu 00:00-01:00 Asia/Tokyo", false, "true"},
+		{"", false, ""},
+		{"Mon-Fri", false, ""},
+		{"Mon-Fry 09:00-17:00", false, ""},
+		{"9-17", false, ""},
+		{"09:00-25:00", false, ""},
+		{"24:00-09:00", false, ""},
+		{"09:00-17:00 Not/AZone", false, ""},
+		{"09:00-17:00 UTC extra", false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.arg, func(t *testing.T) {
+			if got := opStringTimeBetween("", test.arg, test.negate); got != test.expected {
+				t.Errorf("expected %s got %s", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestOpStringPercentBucket(t *testing.T) {
+	const n = 10000
+	var inTen, inRange int
+	for i := range n {
+		input := fmt.Sprintf("user-%d", i)
+		if opStringPercentBucket(input, "10", false) == trueValue {
+			inTen++
+		}
+		if opStringPercentBucket(input, "10-30", false) == trueValue {
+			inRange++
+			// a stable hash places the same input in the same bucket
+			if opStringPercentBucket(input, "10", false) == trueValue {
+				t.Fatalf("input %s is in overlapping cohorts", input)
+			}
+		}
+	}
+	if inTen < n*8/100 || inTen > n*12/100 {
+		t.Errorf("expected ~10%% of inputs in the 10%% bucket, got %d of %d", inTen, n)
+	}
+	if inRange < n*18/100 || inRange > n*22/100 {
+		t.Errorf("expected ~20%% of inputs in the 10-30%% buckets, got %d of %d", inRange, n)
+	}
+	b := percentBucket("user-a")
+	if b < 0 || b >= 100 || b != percentBucket("user-a") {
+		t.Errorf("unexpected bucket %f", b)
+	}
+}
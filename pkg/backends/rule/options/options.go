@@ -59,10 +59,17 @@ type Options struct {
 	//  params           ?param1=value
 	//  param            [must be used with InputKey as described below]
 	//  header           [must be used with InputKey as described below]
+	//  body_json        [must be used with InputKey as described below]
+	//  form             [must be used with InputKey as described below]
+	//  cookie           [must be used with InputKey as described below]
+	//  username         alice (the Username of the request's AuthResult)
+	//  client_ip        192.168.1.10 (honors the backend's forwarded_headers setting)
 	InputSource string `yaml:"input_source,omitempty"`
 	//
 	// InputKey is optional and provides extra information for locating the data source
-	// when the InputSource is header or param, the input key must be the target header or param name
+	// when the InputSource is header, param, form or cookie, the input key must be the target
+	// header, param, form field or cookie name. When the InputSource is body_json, the input key
+	// is a JSON Pointer (e.g., /query) to the value in the request body
 	InputKey string `yaml:"input_key,omitempty"`
 	// InputType is optional, defaulting to string, and indicates the type of input:
 	// string, num (treated internally as float64), or bool
@@ -79,7 +86,8 @@ type Options struct {
 	//
 	// Operation specifies what action to take on the input, whose result is used to
 	// determine if any case is matched. Possible options are as follows.
-	// string:   eq, contains, suffix, prefix, md5, sha1, base64, modulo
+	// string:   eq, contains, suffix, prefix, md5, sha1, base64, modulo, rmatch,
+	//           ip-in-cidr, time-between, percent-bucket
 	// num:      eq, gt, lt, ge, le, bt (inclusive), modulo
 	// bool:     eq
 	// any boolean operation (everything but md5, sha1, base64, modulo) can be prefixed with !
@@ -100,8 +108,16 @@ type Options struct {
 
 // CaseOptions defines the options for a given evaluation case
 type CaseOptions struct {
+	// Name optionally identifies the case in metrics. The default is the case's
+	// zero-based index in the case list
+	Name string `yaml:"name,omitempty"`
 	// Matches indicates the values matching the rule execution's output that apply to this case
 	Matches []string `yaml:"matches,omitempty"`
+	// Expression is a boolean CEL expression over request attributes that, when true, applies
+	// this case (e.g., header("X-Team") == "sre" && path.startsWith("/api/v1/query")).
+	// When cases use expressions, the rule's input and operation settings are not used, and
+	// the first case whose expression is true is applied
+	Expression string `yaml:"expression,omitempty"`
 	// ReqRewriterName is the name of a configured Rewriter that will modify the request in this case
 	// prior to handing off to the NextRoute
 	ReqRewriterName string `yaml:"req_rewriter_name,omitempty"`
@@ -122,6 +138,9 @@ var _ types.ConfigOptions[Options] = &Options{}
 var (
 	ErrInvalidName  = errors.New("invalid rule name")
 	restrictedNames = sets.New([]string{"", "none"})
+
+	ErrMixedCaseTypes        = errors.New("rule cases must all use either matches or expression")
+	ErrExpressionWithMatches = errors.New("rule case cannot have both matches and expression")
 )
 
 // New returns a new Rule Options with default values
@@ -177,9 +196,28 @@ func (o *Options) Validate() (bool, error) {
 	if restrictedNames.Contains(o.Name) {
 		return false, ErrInvalidName
 	}
+	var exprs int
+	for _, c := range o.CaseOptions {
+		if c == nil || c.Expression == "" {
+			continue
+		}
+		if len(c.Matches) > 0 {
+			return false, ErrExpressionWithMatches
+		}
+		exprs++
+	}
+	if exprs > 0 && exprs != len(o.CaseOptions) {
+		return false, ErrMixedCaseTypes
+	}
 	return true, nil
 }
 
+// UsesExpressions returns true if the rule's cases are expressions
+func (o *Options) UsesExpressions() bool {
+	return len(o.CaseOptions) > 0 && o.CaseOptions[0] != nil &&
+		o.CaseOptions[0].Expression != ""
+}
+
 func (l Lookup) Initialize() error {
 	for k, o := range l {
 		if o == nil { // error on empty rule
@@ -113,6 +113,40 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateExpressionCases(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		cases   CaseOptionsList
+		wantErr error
+	}{
+		{name: "expressions", cases: CaseOptionsList{
+			{Expression: `path == "/a"`}, {Expression: `method == "GET"`},
+		}},
+		{name: "matches", cases: CaseOptionsList{{Matches: []string{"a"}}}},
+		{name: "both", cases: CaseOptionsList{
+			{Expression: `path == "/a"`, Matches: []string{"a"}},
+		}, wantErr: ErrExpressionWithMatches},
+		{name: "mixed", cases: CaseOptionsList{
+			{Matches: []string{"a"}}, {Expression: `path == "/a"`},
+		}, wantErr: ErrMixedCaseTypes},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := New()
+			o.Name = "test"
+			o.CaseOptions = test.cases
+			if _, err := o.Validate(); err != test.wantErr {
+				t.Fatalf("Validate() error = %v, want %v", err, test.wantErr)
+			}
+			if got := o.UsesExpressions(); got != (test.cases[0].Expression != "") {
+				t.Fatalf("UsesExpressions() = %v", got)
+			}
+		})
+	}
+}
+
 func TestLookupInitializeAndValidate(t *testing.T) {
 	t.Parallel()
 
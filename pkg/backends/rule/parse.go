@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 
 	ro "github.com/trickstercache/trickster/v2/pkg/backends/rule/options"
@@ -37,15 +38,17 @@ func (c *Client) parseOptions(o *ro.Options, rwi rewriter.InstructionsLookup) er
 		return fmt.Errorf("rule client %s failed to parse nil options", name)
 	}
 
-	if o.InputSource == "" {
+	usesExpressions := o.UsesExpressions()
+
+	if o.InputSource == "" && !usesExpressions {
 		return fmt.Errorf("rule client %s options missing input_source", name)
 	}
 
-	if o.InputType == "" {
+	if o.InputType == "" && !usesExpressions {
 		return fmt.Errorf("rule client %s options missing input_type", name)
 	}
 
-	if o.Operation == "" {
+	if o.Operation == "" && !usesExpressions {
 		return fmt.Errorf("rule client %s options missing operation", name)
 	}
 
@@ -54,7 +57,7 @@ func (c *Client) parseOptions(o *ro.Options, rwi rewriter.InstructionsLookup) er
 	}
 
 	var nr http.Handler
-	r := &rule{maxRuleExecutions: o.MaxRuleExecutions}
+	r := &rule{name: o.Name, maxRuleExecutions: o.MaxRuleExecutions}
 
 	if o.EgressReqRewriterName != "" {
 		ri, ok := rwi[o.EgressReqRewriterName]
@@ -103,12 +106,25 @@ func (c *Client) parseOptions(o *ro.Options, rwi rewriter.InstructionsLookup) er
 
 	r.defaultRouter = nr
 
+	if usesExpressions {
+		if err := c.parseExpressionCases(o, r, rwi); err != nil {
+			return err
+		}
+		c.rule = r
+		return nil
+	}
+
 	exf, ok := isValidSourceName(o.InputSource)
 	if !ok {
 		return fmt.Errorf("invalid source name %s in rule %s", o.InputSource, o.Name)
 	}
 	r.extractionFunc = exf
 	r.extractionArg = o.InputKey
+	if o.InputSource == clientIPSource {
+		if cfg := c.Configuration(); cfg != nil {
+			r.extractionArg = cfg.ForwardedHeaders
+		}
+	}
 
 	// if the user only wants a part of the response
 	if o.InputIndex > -1 && o.InputDelimiter != "" {
@@ -141,6 +157,13 @@ func (c *Client) parseOptions(o *ro.Options, rwi rewriter.InstructionsLookup) er
 	}
 	r.operationFunc = of
 	r.operationArg = o.OperationArg
+	argParser := operationArgParsers[operation(o.InputType+"-"+o.Operation)]
+	if argParser != nil && r.operationArg != "" {
+		if _, err := argParser(r.operationArg); err != nil {
+			return fmt.Errorf("invalid operation_arg for %s in rule %s: %w",
+				o.Operation, o.Name, err)
+		}
+	}
 	if r.operationArg == "" {
 		r.evaluatorFunc = r.EvaluateCaseArg
 	} else {
@@ -194,6 +217,12 @@ func (c *Client) parseOptions(o *ro.Options, rwi rewriter.InstructionsLookup) er
 			}
 
 			for _, m := range v.Matches {
+				if argParser != nil && r.operationArg == "" {
+					if _, err := argParser(m); err != nil {
+						return fmt.Errorf("invalid match %q for %s in rule %s case %d: %w",
+							m, o.Operation, o.Name, k, err)
+					}
+				}
 				rc := &ruleCase{
 					matchValue:   m,
 					router:       nr,
@@ -219,3 +248,61 @@ func (c *Client) parseOptions(o *ro.Options, rwi rewriter.InstructionsLookup) er
 	c.rule = r
 	return nil
 }
+
+// parseExpressionCases compiles the rule's case expressions, so that invalid
+// expressions are rejected when the rule is loaded
+func (c *Client) parseExpressionCases(o *ro.Options, r *rule,
+	rwi rewriter.InstructionsLookup,
+) error {
+	if cfg := c.Configuration(); cfg != nil {
+		r.forwardedHeaders = cfg.ForwardedHeaders
+	}
+	r.cases = make(caseList, 0, len(o.CaseOptions))
+	for k, v := range o.CaseOptions {
+		if v == nil || v.Expression == "" {
+			return fmt.Errorf("missing expression in rule %s case %d", o.Name, k)
+		}
+		if len(v.Matches) > 0 {
+			return fmt.Errorf("rule %s case %d cannot have both matches and expression",
+				o.Name, k)
+		}
+		if v.NextRoute == "" && v.RedirectURL == "" && v.ReqRewriterName == "" {
+			return fmt.Errorf("missing next_route in rule %s case %d", o.Name, k)
+		}
+		if v.Name == defaultCaseLabel {
+			// the default case label is reserved for requests matching no case
+			return fmt.Errorf("reserved name %q in rule %s case %d",
+				defaultCaseLabel, o.Name, k)
+		}
+		prg, err := compileExpression(v.Expression)
+		if err != nil {
+			return fmt.Errorf("invalid expression in rule %s case %d: %w", o.Name, k, err)
+		}
+		rc := &ruleCase{label: v.Name, program: prg, router: r.defaultRouter}
+		if rc.label == "" {
+			rc.label = strconv.Itoa(k)
+		}
+		if v.ReqRewriterName != "" {
+			ri, ok := rwi[v.ReqRewriterName]
+			if !ok {
+				return fmt.Errorf("invalid rewriter %d in rule %s", k, o.Name)
+			}
+			rc.rewriter = ri
+		}
+		if v.RedirectURL != "" {
+			rc.redirectURL = v.RedirectURL
+			rc.redirectCode = 302
+			rc.router = http.HandlerFunc(redirect.HandleRedirectResponse)
+		} else if v.NextRoute != "" {
+			no, ok := c.clients[v.NextRoute]
+			if !ok {
+				return fmt.Errorf("unknown next_route %s in rule %s case %d",
+					v.NextRoute, o.Name, k)
+			}
+			rc.router = no.Router()
+		}
+		r.cases = append(r.cases, rc)
+	}
+	r.evaluatorFunc = r.EvaluateExpressions
+	return nil
+}
@@ -19,6 +19,8 @@ package rule
 import (
 	"strings"
 	"testing"
+
+	ro "github.com/trickstercache/trickster/v2/pkg/backends/rule/options"
 )
 
 func TestParseOptions(t *testing.T) {
@@ -229,3 +231,46 @@ func TestParseOptions(t *testing.T) {
 		t.Errorf("expected error for %s", expected)
 	}
 }
+
+func TestParseOptionsOperationArgs(t *testing.T) {
+	c, err := newTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rwi := newTestRewriterInstructions()
+
+	newOpts := func(op, arg string, matches ...string) *ro.Options {
+		o := newTestRuleOpts()
+		o.Operation = op
+		o.OperationArg = arg
+		o.CaseOptions = ro.CaseOptionsList{{Matches: matches, NextRoute: "test-backend-2"}}
+		return o
+	}
+
+	tests := []struct {
+		name    string
+		opts    *ro.Options
+		wantErr string
+	}{
+		{"cidr op arg", newOpts("ip-in-cidr", "10.0.0.0/8", "true"), ""},
+		{"cidr case arg", newOpts("!ip-in-cidr", "", "10.0.0.0/8,192.168.0.0/16"), ""},
+		{"bad cidr op arg", newOpts("ip-in-cidr", "10.0.0.0/99", "true"), "invalid operation_arg"},
+		{"bad cidr case arg", newOpts("ip-in-cidr", "", "10.0.0.0/8", "nope"), "invalid match"},
+		{"time window", newOpts("time-between", "", "Mon-Fri 09:00-17:00 UTC"), ""},
+		{"bad time window", newOpts("time-between", "", "Mon-Fri"), "invalid match"},
+		{"percent", newOpts("percent-bucket", "", "0-10", "10-20"), ""},
+		{"bad percent", newOpts("percent-bucket", "", "200"), "invalid match"},
+		{"ignores other operation args", newOpts("eq", "", "Mon-Fri"), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := c.parseOptions(test.opts, rwi)
+			if test.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
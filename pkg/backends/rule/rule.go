@@ -21,10 +21,13 @@ import (
 	"regexp"
 	"strconv"
 
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/context"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/failures"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/redirect"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter"
+
+	"cel.dev/cel-go/cel"
 )
 
 const (
@@ -51,6 +54,7 @@ func handleMatchedCase(c *ruleCase, hr *http.Request) (http.Handler, *http.Reque
 }
 
 type rule struct {
+	name           string
 	defaultRouter  http.Handler
 	extractionFunc extractionFunc
 	operationFunc  operationFunc
@@ -72,10 +76,16 @@ type rule struct {
 	egressReqRewriter  rewriter.RewriteInstructions
 
 	maxRuleExecutions int32
+
+	// forwardedHeaders is the rule backend's forwarded_headers setting, used
+	// to resolve client_ip in expressions
+	forwardedHeaders string
 }
 
 type ruleCase struct {
+	label        string
 	matchValue   string
+	program      cel.Program
 	router       http.Handler
 	redirectURL  string
 	redirectCode int
@@ -216,3 +226,62 @@ func (r *rule) EvaluateCaseArg(hr *http.Request) (http.Handler, *http.Request, e
 
 	return h, hr, nil
 }
+
+// defaultCaseLabel is the case label recorded when no expression case matches
+const defaultCaseLabel = "default"
+
+// EvaluateExpressions applies the first case whose expression is true for the
+// request, or the default route when no case expression is true
+func (r *rule) EvaluateExpressions(hr *http.Request) (http.Handler, *http.Request, error) {
+	currentHops, maxHops := context.Hops(hr.Context())
+	if r.maxRuleExecutions < maxHops {
+		maxHops = r.maxRuleExecutions
+	}
+
+	if currentHops >= maxHops {
+		return badRequestHandler, hr, nil
+	}
+
+	// if this case includes ingress rewriter instructions, execute those now
+	if len(r.ingressReqRewriter) > 0 {
+		r.ingressReqRewriter.Execute(hr)
+	}
+
+	h := r.defaultRouter
+	var matched *ruleCase
+	act := &requestActivation{r: hr, forwardedHeaders: r.forwardedHeaders}
+	for _, c := range r.cases {
+		out, _, err := c.program.Eval(act)
+		if err != nil {
+			continue
+		}
+		if b, ok := out.Value().(bool); ok && b {
+			matched = c
+			break
+		}
+	}
+
+	if matched != nil {
+		metrics.RuleCaseMatches.WithLabelValues(r.name, matched.label).Inc()
+		h, hr = handleMatchedCase(matched, hr)
+	} else {
+		metrics.RuleCaseMatches.WithLabelValues(r.name, defaultCaseLabel).Inc()
+		if r.defaultRewriter != nil {
+			r.defaultRewriter.Execute(hr)
+		}
+	}
+
+	// if this case includes egress rewriter instructions, execute those now
+	if len(r.egressReqRewriter) > 0 {
+		r.egressReqRewriter.Execute(hr)
+	}
+
+	if matched == nil && r.defaultRedirectCode > 0 {
+		hr = hr.WithContext(redirect.WithRedirects(hr.Context(),
+			r.defaultRedirectCode, r.defaultRedirectURL))
+	}
+
+	hr = hr.WithContext(context.WithHops(hr.Context(), currentHops+1, maxHops))
+
+	return h, hr, nil
+}
@@ -19,6 +19,7 @@ package backends
 import (
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/trickstercache/trickster/v2/pkg/backends/healthcheck"
 	ho "github.com/trickstercache/trickster/v2/pkg/backends/healthcheck/options"
@@ -77,6 +78,40 @@ type TimeseriesBackend interface {
 	ProcessTransformations(timeseries.Timeseries)
 }
 
+// CachedQueryObserver is optionally implemented by TimeseriesBackends that track
+// the cache keys written by the Delta Proxy Cache for their queries, e.g., to
+// invalidate them when the origin receives late-arriving data
+type CachedQueryObserver interface {
+	// ObserveCachedQuery is called with the request, cache key and time range
+	// query each time the Delta Proxy Cache serves a cacheable request
+	ObserveCachedQuery(*http.Request, string, *timeseries.TimeRangeQuery)
+}
+
+// ErrorWriter is optionally implemented by TimeseriesBackends whose origins
+// have a native error response format, so that errors Trickster returns on
+// the origin's behalf are understood by its clients
+type ErrorWriter interface {
+	// WriteError writes an error response with the status code and message
+	WriteError(http.ResponseWriter, int, string)
+}
+
+// WriteError writes an error response in the client's native format when it
+// is an ErrorWriter, or as plain text otherwise
+func WriteError(w http.ResponseWriter, client Backend, code int, msg string) {
+	if ew, ok := client.(ErrorWriter); ok {
+		ew.WriteError(w, code, msg)
+		return
+	}
+	http.Error(w, msg, code)
+}
+
+// StepSetter is optionally implemented by TimeseriesBackends whose query
+// step can be changed without rewriting the query statement
+type StepSetter interface {
+	// SetStep updates a request's step parameter to the provided step
+	SetStep(*http.Request, *timeseries.TimeRangeQuery, time.Duration) error
+}
+
 // MergeableTimeseriesBackend defines the interface for mergeable time series
 type MergeableTimeseriesBackend interface {
 	// MergePaths should return a slice of HTTP Paths that are safe to merge with
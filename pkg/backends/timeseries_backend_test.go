@@ -17,6 +17,8 @@
 package backends
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
@@ -64,3 +66,26 @@ func TestModeler(t *testing.T) {
 		t.Error("name mismatch")
 	}
 }
+
+type testErrorWriter struct {
+	TimeseriesBackend
+}
+
+func (testErrorWriter) WriteError(w http.ResponseWriter, code int, msg string) {
+	w.WriteHeader(code)
+	w.Write([]byte(`{"error":"` + msg + `"}`))
+}
+
+func TestWriteError(t *testing.T) {
+	tb, _ := NewTimeseriesBackend("test1", nil, nil, nil, nil, nil)
+	w := httptest.NewRecorder()
+	WriteError(w, tb, http.StatusBadRequest, "too many points")
+	if w.Code != http.StatusBadRequest || w.Body.String() != "too many points\n" {
+		t.Errorf("unexpected plain text error %d %q", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	WriteError(w, testErrorWriter{tb}, http.StatusBadRequest, "too many points")
+	if w.Code != http.StatusBadRequest || w.Body.String() != `{"error":"too many points"}` {
+		t.Errorf("unexpected native error %d %q", w.Code, w.Body.String())
+	}
+}
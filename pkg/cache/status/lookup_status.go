@@ -49,8 +49,11 @@ const (
 	LookupStatusError
 	// LookupStatusProxyHit indicates that the request joined an existing proxy download of the same object
 	LookupStatusProxyHit
+	// LookupStatusStale indicates that the origin was unavailable, so the request was served
+	// from the cached data alone, with any uncached ranges missing from the response
+	LookupStatusStale
 	// maxLookupStatus is the maximum LookupStatus value
-	maxLookupStatus = LookupStatusProxyHit
+	maxLookupStatus = LookupStatusStale
 )
 
 // Return the maximum LookupStatus value
@@ -73,6 +76,7 @@ var cacheLookupStatusValues = []struct {
 	{LookupStatusNegativeCacheHit, "nchit"},
 	{LookupStatusError, "error"},
 	{LookupStatusProxyHit, "proxy-hit"},
+	{LookupStatusStale, "stale"},
 }
 
 func (s LookupStatus) String() string {
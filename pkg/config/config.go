@@ -37,6 +37,7 @@ import (
 	mo "github.com/trickstercache/trickster/v2/pkg/observability/metrics/options"
 	tracing "github.com/trickstercache/trickster/v2/pkg/observability/tracing/options"
 	auth "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	authz "github.com/trickstercache/trickster/v2/pkg/proxy/authorization/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter"
 	rwopts "github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter/options"
 
@@ -75,6 +76,8 @@ type Config struct {
 	MgmtConfig *mgmt.Options `yaml:"mgmt,omitempty"`
 	// Authenticators provides configurations for Authenticating users
 	Authenticators auth.Lookup `yaml:"authenticators,omitempty"`
+	// Authorization provides policies for Authorizing authenticated users
+	Authorization *authz.Options `yaml:"authorization,omitempty"`
 
 	// Flags contains a compiled version of the CLI flags
 	Flags *Flags `yaml:"-"`
@@ -103,6 +106,9 @@ type MainConfig struct {
 	ServerName string `yaml:"server_name,omitempty"`
 	// ConfigIncludeDirectory optionally overrides the default sibling conf.d directory.
 	ConfigIncludeDirectory string `yaml:"config_include_directory,omitempty"`
+	// TrustedProxies is the list of CIDRs or IP addresses of the proxies whose
+	// forwarding headers are honored when determining a request's client IP
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"`
 
 	configFilePath          string
 	configSourcePlan        configSourcePlan
@@ -259,6 +265,7 @@ func (c *Config) Clone() *Config {
 	nc.Main.InstanceID = c.Main.InstanceID
 	nc.Main.ServerName = c.Main.ServerName
 	nc.Main.ConfigIncludeDirectory = c.Main.ConfigIncludeDirectory
+	nc.Main.TrustedProxies = append([]string(nil), c.Main.TrustedProxies...)
 
 	nc.MgmtConfig = c.MgmtConfig.Clone()
 	nc.Listeners = c.Listeners.Clone()
@@ -329,6 +336,10 @@ func (c *Config) Clone() *Config {
 		}
 	}
 
+	if c.Authorization != nil {
+		nc.Authorization = c.Authorization.Clone()
+	}
+
 	return nc
 }
 
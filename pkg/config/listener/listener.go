@@ -19,11 +19,13 @@ package listener
 
 import (
 	"fmt"
+	"slices"
 
 	"github.com/trickstercache/trickster/v2/pkg/config/mgmt"
 	frontend "github.com/trickstercache/trickster/v2/pkg/frontend/options"
 	metrics "github.com/trickstercache/trickster/v2/pkg/observability/metrics/options"
 	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	tlsopts "github.com/trickstercache/trickster/v2/pkg/proxy/tls/options"
 
 	"go.yaml.in/yaml/v3"
 )
@@ -57,6 +59,10 @@ type Options struct {
 	TruncateRequestBodyTooLarge bool `yaml:"truncate_request_body_too_large"`
 	// ReadHeaderTimeout is the amount of time allowed to read request headers.
 	ReadHeaderTimeout timeconv.Duration `yaml:"read_header_timeout,omitempty"`
+	// TLSClientAuth is the client certificate policy for this listener's TLS endpoint.
+	TLSClientAuth tlsopts.ClientAuth `yaml:"tls_client_auth,omitempty"`
+	// TLSClientCAPaths lists the CA bundles used to verify client certificates.
+	TLSClientCAPaths []string `yaml:"tls_client_ca_paths,omitempty"`
 	// Protocol selects the protocol served by this listener.
 	Protocol string `yaml:"protocol,omitempty"`
 	// ServeTLS indicates that this listener has at least one usable certificate.
@@ -168,6 +174,7 @@ func (o *Options) Clone() *Options {
 	if o.MaxRequestBodySizeBytes != nil {
 		out.MaxRequestBodySizeBytes = new(*o.MaxRequestBodySizeBytes)
 	}
+	out.TLSClientCAPaths = slices.Clone(o.TLSClientCAPaths)
 	return &out
 }
 
@@ -181,7 +188,8 @@ func (o *Options) Equal(other *Options) bool {
 		o.TLSListenAddress != other.TLSListenAddress || o.TLSListenPort != other.TLSListenPort ||
 		o.ConnectionsLimit != other.ConnectionsLimit ||
 		o.TruncateRequestBodyTooLarge != other.TruncateRequestBodyTooLarge ||
-		o.ReadHeaderTimeout != other.ReadHeaderTimeout || o.ServeTLS != other.ServeTLS {
+		o.ReadHeaderTimeout != other.ReadHeaderTimeout || o.ServeTLS != other.ServeTLS ||
+		!o.TLSClientAuthEqual(other) {
 		return false
 	}
 	if o.MaxRequestBodySizeBytes == nil || other.MaxRequestBodySizeBytes == nil {
@@ -190,6 +198,13 @@ func (o *Options) Equal(other *Options) bool {
 	return *o.MaxRequestBodySizeBytes == *other.MaxRequestBodySizeBytes
 }
 
+// TLSClientAuthEqual reports whether both options have the same TLS client
+// certificate policy.
+func (o *Options) TLSClientAuthEqual(other *Options) bool {
+	return o.TLSClientAuth == other.TLSClientAuth &&
+		slices.Equal(o.TLSClientCAPaths, other.TLSClientCAPaths)
+}
+
 // UnmarshalYAML overlays configured listeners onto the built-in defaults.
 func (l *Lookup) UnmarshalYAML(value *yaml.Node) error {
 	raw := make(map[string]yaml.Node)
@@ -198,3 +198,26 @@ listeners:
 		t.Fatalf("unexpected merged listener: %#v", custom)
 	}
 }
+
+func TestTLSClientAuthCloneEqual(t *testing.T) {
+	t.Parallel()
+	o := New(DefaultFrontendName)
+	o.TLSClientAuth = "require_and_verify"
+	o.TLSClientCAPaths = []string{"/path/to/ca.pem"}
+	c := o.Clone()
+	if !o.Equal(c) {
+		t.Fatal("clone should be equal")
+	}
+	c.TLSClientCAPaths[0] = "/path/to/other.pem"
+	if o.TLSClientCAPaths[0] != "/path/to/ca.pem" {
+		t.Fatal("Clone should deep-copy TLSClientCAPaths")
+	}
+	if o.Equal(c) || o.TLSClientAuthEqual(c) {
+		t.Fatal("options with different client CA paths should not be equal")
+	}
+	c = o.Clone()
+	c.TLSClientAuth = "verify_if_given"
+	if o.Equal(c) {
+		t.Fatal("options with different client auth modes should not be equal")
+	}
+}
@@ -124,6 +124,12 @@ func Load(args []string) (*Config, error) {
 		}
 	}
 
+	if c.Authorization != nil {
+		if err := c.Authorization.Initialize(); err != nil {
+			return nil, err
+		}
+	}
+
 	if len(c.Caches) > 0 {
 		activeCaches := sets.NewStringSet()
 		for _, backend := range c.Backends {
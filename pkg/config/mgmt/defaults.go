@@ -35,6 +35,8 @@ const (
 	DefaultPurgeByKeyHandlerPath = "/trickster/purge/key/"
 	// DefaultPurgeByPathHandlerPath defines the default path for the Cache Purge (by Path) Handler
 	DefaultPurgeByPathHandlerPath = "/trickster/purge/path/"
+	// DefaultPurgeByTenantHandlerPath defines the default path for the Cache Purge (by Tenant) Handler
+	DefaultPurgeByTenantHandlerPath = "/trickster/purge/tenant/"
 	// DefaultPprofListenerName defines the default Pprof Listener Name
 	DefaultPprofListenerName = ListenerNameBoth
 	// DefaultDrainTimeout is the default time that is allowed for an old configuration's requests to drain
@@ -44,4 +46,7 @@ const (
 	DefaultRateLimit = 3 * time.Second
 	// DefaultReloadHandlerPath defines the default path for the Reload Handler
 	DefaultReloadHandlerPath = "/trickster/config/reload"
+	// DefaultForwardedHeaders defines the default class of forwarding headers
+	// that the audit log reads the client address from
+	DefaultForwardedHeaders = "none"
 )
@@ -18,8 +18,10 @@ package mgmt
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
 )
 
@@ -45,6 +47,8 @@ type Options struct {
 	PurgeByKeyHandlerPath string `yaml:"purge_by_key_path,omitempty"`
 	// PurgeByKeyHandlerPath provides the base Cache Purge-by-Path Handler path
 	PurgeByPathHandlerPath string `yaml:"purge_by_path_path,omitempty"`
+	// PurgeByTenantHandlerPath provides the base Cache Purge-by-Tenant Handler path
+	PurgeByTenantHandlerPath string `yaml:"purge_by_tenant_path,omitempty"`
 	// PprofListener provides the name of the http listener that will host the pprof debugging routes
 	// Options are: "metrics", "mgmt", "both", or "off"; default is both
 	PprofListener string `yaml:"pprof_listener,omitempty"`
@@ -59,6 +63,13 @@ type Options struct {
 	// This prevents a bad actor from stating the config file with millions of concurrent requests
 	// The rate limit does not apply to SIGHUP-based reload requests
 	ReloadRateLimit timeconv.Duration `yaml:"reload_rate_limit,omitempty"`
+	// AuthenticatorName provides the name of an optional Authenticator that
+	// protects the config, reload and purge routes
+	AuthenticatorName string `yaml:"authenticator_name,omitempty"`
+	// ForwardedHeaders provides the class of forwarding headers that the audit
+	// log reads the client address from, for requests received from a trusted
+	// proxy. Options are: "standard", "x", "both", or "none"; default is none
+	ForwardedHeaders string `yaml:"forwarded_headers,omitempty"`
 }
 
 // ErrInvalidPprofListenerName returns an error for invalid pprof listener name
@@ -67,25 +78,42 @@ var ErrInvalidPprofListenerName = errors.New("invalid pprof listener name")
 // ErrInvalidConfigHandlerListenerName returns an error for an invalid config handler listener name
 var ErrInvalidConfigHandlerListenerName = errors.New("invalid config handler listener name")
 
+// ErrInvalidAuthenticatorName returns an error for an authenticator name that
+// does not match a configured authenticator
+var ErrInvalidAuthenticatorName = errors.New("invalid mgmt authenticator name")
+
+// ErrInvalidForwardedHeaders returns an error for an invalid forwarding
+// header type
+var ErrInvalidForwardedHeaders = errors.New("invalid mgmt forwarded_headers")
+
 // New returns a new Options references with Default Values set
 func New() *Options {
 	return &Options{
-		ListenPort:             DefaultPort,
-		ListenAddress:          DefaultAddress,
-		ConfigHandlerPath:      DefaultConfigHandlerPath,
-		ConfigHandlerListener:  DefaultConfigHandlerListenerName,
-		PingHandlerPath:        DefaultPingHandlerPath,
-		HealthHandlerPath:      DefaultHealthHandlerPath,
-		PurgeByKeyHandlerPath:  DefaultPurgeByKeyHandlerPath,
-		PurgeByPathHandlerPath: DefaultPurgeByPathHandlerPath,
-		PprofListener:          DefaultPprofListenerName,
-		ReloadHandlerPath:      DefaultReloadHandlerPath,
-		ReloadDrainTimeout:     timeconv.Duration(DefaultDrainTimeout),
-		ReloadRateLimit:        timeconv.Duration(DefaultRateLimit),
+		ListenPort:               DefaultPort,
+		ListenAddress:            DefaultAddress,
+		ConfigHandlerPath:        DefaultConfigHandlerPath,
+		ConfigHandlerListener:    DefaultConfigHandlerListenerName,
+		PingHandlerPath:          DefaultPingHandlerPath,
+		HealthHandlerPath:        DefaultHealthHandlerPath,
+		PurgeByKeyHandlerPath:    DefaultPurgeByKeyHandlerPath,
+		PurgeByPathHandlerPath:   DefaultPurgeByPathHandlerPath,
+		PurgeByTenantHandlerPath: DefaultPurgeByTenantHandlerPath,
+		PprofListener:            DefaultPprofListenerName,
+		ReloadHandlerPath:        DefaultReloadHandlerPath,
+		ReloadDrainTimeout:       timeconv.Duration(DefaultDrainTimeout),
+		ReloadRateLimit:          timeconv.Duration(DefaultRateLimit),
+		ForwardedHeaders:         DefaultForwardedHeaders,
 	}
 }
 
 func (o *Options) Validate() error {
+	switch {
+	case o.ForwardedHeaders == "":
+		o.ForwardedHeaders = DefaultForwardedHeaders
+	case !headers.IsValidForwardingType(o.ForwardedHeaders):
+		return fmt.Errorf("%w: %s", ErrInvalidForwardedHeaders, o.ForwardedHeaders)
+	}
+
 	switch o.ConfigHandlerListener {
 	case ListenerNameMetrics, ListenerNameMgmt, ListenerNameOff, ListenerNameBoth:
 	case "":
@@ -16,7 +16,10 @@
 
 package mgmt
 
-import "testing"
+import (
+	"errors"
+	"testing"
+)
 
 func TestValidate(t *testing.T) {
 	c := New()
@@ -53,6 +56,25 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateForwardedHeaders(t *testing.T) {
+	c := New()
+	c.ForwardedHeaders = ""
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+	if c.ForwardedHeaders != DefaultForwardedHeaders {
+		t.Errorf("expected %s got %s", DefaultForwardedHeaders, c.ForwardedHeaders)
+	}
+	c.ForwardedHeaders = "x"
+	if err := c.Validate(); err != nil {
+		t.Error(err)
+	}
+	c.ForwardedHeaders = "forwarded"
+	if err := c.Validate(); !errors.Is(err, ErrInvalidForwardedHeaders) {
+		t.Errorf("expected invalid forwarded headers error, got %v", err)
+	}
+}
+
 func TestValidatePprofListenerNames(t *testing.T) {
 	for _, name := range []string{ListenerNameMetrics, ListenerNameMgmt, ListenerNameBoth, ListenerNameOff} {
 		c := New()
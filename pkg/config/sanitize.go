@@ -31,6 +31,7 @@ import (
 	tracing "github.com/trickstercache/trickster/v2/pkg/observability/tracing/options"
 	tp "github.com/trickstercache/trickster/v2/pkg/observability/tracing/providers"
 	auth "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	authz "github.com/trickstercache/trickster/v2/pkg/proxy/authorization/options"
 	rwopts "github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter/options"
 )
 
@@ -91,6 +92,9 @@ func (c *Config) SanitizedClone() *Config {
 			if opts.CacheKeyPrefix != "" {
 				opts.CacheKeyPrefix = sanitizedEndpoint
 			}
+			if opts.OAuth2 != nil && opts.OAuth2.TokenURL != "" {
+				opts.OAuth2.TokenURL = sanitizedEndpoint
+			}
 			if newCacheName, ok := cacheNameMap[opts.CacheName]; ok {
 				opts.CacheName = newCacheName
 			}
@@ -121,6 +125,15 @@ func (c *Config) SanitizedClone() *Config {
 		renamedAuthenticators[newName] = opts
 	}
 	cp.Authenticators = renamedAuthenticators
+	if cp.MgmtConfig != nil {
+		if newName, ok := authNameMap[cp.MgmtConfig.AuthenticatorName]; ok {
+			cp.MgmtConfig.AuthenticatorName = newName
+		}
+	}
+
+	if cp.Authorization != nil {
+		sanitizeAuthorization(cp.Authorization, backendNameMap)
+	}
 
 	renamedTracing := make(tracing.Lookup, len(cp.TracingOptions))
 	for oldName, opts := range cp.TracingOptions {
@@ -349,6 +362,39 @@ func sanitizeAuthenticatorUsers(opts *auth.Options) {
 	opts.Users = users
 }
 
+func sanitizeAuthorization(opts *authz.Options, backendNameMap map[string]string) {
+	userNames := make(map[string]string)
+	redact := func(names []string) []string {
+		out := make([]string, len(names))
+		for i, name := range names {
+			if name == authz.Wildcard {
+				out[i] = name
+				continue
+			}
+			if _, ok := userNames[name]; !ok {
+				userNames[name] = fmt.Sprintf("user%d", len(userNames)+1)
+			}
+			out[i] = userNames[name]
+		}
+		return out
+	}
+	for _, g := range sortedKeys(opts.Groups) {
+		opts.Groups[g] = redact(opts.Groups[g])
+	}
+	for _, k := range sortedKeys(opts.Policies) {
+		p := opts.Policies[k]
+		if p == nil {
+			continue
+		}
+		p.Users = redact(p.Users)
+		for i, b := range p.Backends {
+			if newName, ok := backendNameMap[b]; ok {
+				p.Backends[i] = newName
+			}
+		}
+	}
+}
+
 func sanitizeRequestRewriters(rewriters map[string]*rwopts.Options) {
 	for _, opts := range rewriters {
 		if opts == nil {
@@ -46,6 +46,18 @@ authenticators:
     provider: basic
     users:
       charlie: secret-c
+authorization:
+  groups:
+    admins:
+      - alice
+  policies:
+    ops:
+      users:
+        - charlie
+      groups:
+        - admins
+      backends:
+        - prom-a
 tracing:
   traces-a:
     provider: otlp
@@ -103,6 +115,10 @@ backends:
     origin_url: http://prom-b.private.example:9090/private/path
     cache_name: cache-b
     tracing_name: traces-a
+    oauth2:
+      token_url: https://idp.private.example/oauth2/token
+      client_id: trickster
+      client_secret: oauth2-private-secret
   rule-main:
     provider: rule
     rule_name: route-rule
@@ -168,6 +184,8 @@ request_rewriters:
 		"- - header\n      - replace\n      - host\n      - example.com\n      - example.com",
 		"should-remain.private.example",
 		"origin_url: example.com",
+		"token_url: example.com",
+		"client_secret: '*****'",
 		"cache_name: memory-1",
 		"cache_name: memory-2",
 		"- prom-1",
@@ -226,6 +244,8 @@ request_rewriters:
 		"private-org",
 		"private-env",
 		"private-ha-shard",
+		"idp.private.example",
+		"oauth2-private-secret",
 	} {
 		if strings.Contains(out, privateValue) {
 			t.Errorf("expected sanitized config not to contain %q; got:\n%s", privateValue, out)
@@ -250,6 +270,14 @@ request_rewriters:
 	if conf.Authenticators["auth-a"].Users["alice"] != "secret-a" {
 		t.Errorf("expected original authenticator users to remain unchanged")
 	}
+	if conf.Authorization.Policies["ops"].Backends[0] != "prom-a" ||
+		conf.Authorization.Policies["ops"].Users[0] != "charlie" ||
+		conf.Authorization.Groups["admins"][0] != "alice" {
+		t.Errorf("expected original authorization policies to remain unchanged")
+	}
+	if conf.Backends["prom-b"].OAuth2.TokenURL != "https://idp.private.example/oauth2/token" {
+		t.Errorf("expected original oauth2 token url to remain unchanged")
+	}
 	if conf.Backends["prom-a"].TracingConfigName != "traces-b" {
 		t.Errorf("expected original backend tracing reference to remain unchanged")
 	}
@@ -335,3 +363,24 @@ request_rewriters:
 		t.Errorf("expected empty/unknown names to be anonymized; got:\n%s", out)
 	}
 }
+
+func TestSanitizedCloneMgmtAuthenticator(t *testing.T) {
+	conf := NewConfig()
+	err := conf.loadYAMLConfig(`
+authenticators:
+  private-mgmt-auth:
+    provider: basic
+mgmt:
+  authenticator_name: private-mgmt-auth
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := conf.SanitizedClone()
+	if cp.MgmtConfig.AuthenticatorName != "auth1" {
+		t.Errorf("expected auth1 got %s", cp.MgmtConfig.AuthenticatorName)
+	}
+	if conf.MgmtConfig.AuthenticatorName != "private-mgmt-auth" {
+		t.Errorf("expected original mgmt authenticator reference to remain unchanged")
+	}
+}
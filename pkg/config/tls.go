@@ -21,6 +21,7 @@ import (
 
 	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
 	"github.com/trickstercache/trickster/v2/pkg/config/listener"
+	tlsopts "github.com/trickstercache/trickster/v2/pkg/proxy/tls/options"
 )
 
 // TLSCertConfig returns the crypto/tls configuration object with a list of name-bound
@@ -80,5 +81,12 @@ func (c *Config) tlsCertConfig(listenerName string) (*tls.Config, error) {
 		}
 	}
 
+	if lo, ok := c.Listeners[listenerName]; ok && lo != nil {
+		err = tlsopts.ApplyClientAuth(tlsConfig, lo.TLSClientAuth, lo.TLSClientCAPaths)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return tlsConfig, nil
 }
@@ -17,6 +17,9 @@
 package validate
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -24,7 +27,11 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/config"
 	"github.com/trickstercache/trickster/v2/pkg/config/listener"
 	"github.com/trickstercache/trickster/v2/pkg/config/mgmt"
+	authopt "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/providers/mtls"
+	po "github.com/trickstercache/trickster/v2/pkg/proxy/paths/options"
 	tlsopts "github.com/trickstercache/trickster/v2/pkg/proxy/tls/options"
+	tlstest "github.com/trickstercache/trickster/v2/pkg/testutil/tls"
 )
 
 func TestListenersBackendMappings(t *testing.T) {
@@ -225,6 +232,62 @@ func TestListenersEdgeCases(t *testing.T) {
 	})
 }
 
+func TestListenersMTLSClientAuth(t *testing.T) {
+	_, ca, err := tlstest.GetTestKeyAndCert(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caPath, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	newConfig := func(mode tlsopts.ClientAuth, onPath bool) *config.Config {
+		c := config.NewConfig()
+		ao := authopt.New()
+		ao.Provider = mtls.ID
+		c.Authenticators = authopt.Lookup{"certs": ao}
+		backend := bo.New()
+		if onPath {
+			p := po.New()
+			p.AuthenticatorName = "certs"
+			backend.Paths = po.List{p}
+		} else {
+			backend.AuthenticatorName = "certs"
+		}
+		c.Backends = bo.Lookup{"test": backend}
+		lo := c.Listeners[listener.DefaultFrontendName]
+		lo.TLSClientAuth = mode
+		if mode.Verifies() {
+			lo.TLSClientCAPaths = []string{caPath}
+		}
+		return c
+	}
+	for _, mode := range []tlsopts.ClientAuth{"", tlsopts.ClientAuthRequest,
+		tlsopts.ClientAuthRequireAny} {
+		for _, onPath := range []bool{false, true} {
+			err := Listeners(newConfig(mode, onPath))
+			if !errors.Is(err, tlsopts.ErrClientAuthNotVerified) {
+				t.Errorf("%q: expected %v got %v", mode, tlsopts.ErrClientAuthNotVerified, err)
+			}
+		}
+	}
+	for _, mode := range []tlsopts.ClientAuth{tlsopts.ClientAuthVerifyIfGiven,
+		tlsopts.ClientAuthRequireAndVerify} {
+		if err := Listeners(newConfig(mode, false)); err != nil {
+			t.Errorf("%q: %v", mode, err)
+		}
+	}
+
+	c := config.NewConfig()
+	ao := authopt.New()
+	ao.Provider = mtls.ID
+	c.Authenticators = authopt.Lookup{"certs": ao}
+	c.MgmtConfig.AuthenticatorName = "certs"
+	if err := Listeners(c); !errors.Is(err, tlsopts.ErrClientAuthNotVerified) {
+		t.Errorf("expected %v got %v", tlsopts.ErrClientAuthNotVerified, err)
+	}
+}
+
 func warningsContain(warnings []string, substring string) bool {
 	for _, warning := range warnings {
 		if strings.Contains(warning, substring) {
@@ -19,11 +19,13 @@ package validate
 import (
 	stderrors "errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strings"
 
 	"github.com/trickstercache/trickster/v2/pkg/backends"
 	"github.com/trickstercache/trickster/v2/pkg/backends/alb"
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
 	"github.com/trickstercache/trickster/v2/pkg/backends/rule"
 	"github.com/trickstercache/trickster/v2/pkg/cache"
 	"github.com/trickstercache/trickster/v2/pkg/config"
@@ -31,16 +33,23 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/config/mgmt"
 	"github.com/trickstercache/trickster/v2/pkg/errors"
 	tr "github.com/trickstercache/trickster/v2/pkg/observability/tracing/registry"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/providers/mtls"
 	ar "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/registry"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/router"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/router/lm"
+	tlsopts "github.com/trickstercache/trickster/v2/pkg/proxy/tls/options"
 	"github.com/trickstercache/trickster/v2/pkg/routing"
+	"github.com/trickstercache/trickster/v2/pkg/util/sets"
 )
 
 func Validate(c *config.Config) error {
 	if c == nil {
 		return errors.ErrInvalidOptions
 	}
+	if err := Main(c); err != nil {
+		return err
+	}
 	if c.MgmtConfig != nil {
 		if err := c.MgmtConfig.Validate(); err != nil {
 			return err
@@ -77,9 +86,20 @@ func Validate(c *config.Config) error {
 	if err := Backends(c); err != nil {
 		return err
 	}
+	if err := Authorization(c); err != nil {
+		return err
+	}
 	return Listeners(c)
 }
 
+func Main(c *config.Config) error {
+	if c == nil || c.Main == nil {
+		return nil
+	}
+	_, err := headers.ParseTrustedProxies(c.Main.TrustedProxies)
+	return err
+}
+
 func Rewriters(c *config.Config) error {
 	if c == nil || len(c.RequestRewriters) == 0 {
 		return nil
@@ -121,12 +141,29 @@ func NegativeCaches(c *config.Config) error {
 }
 
 func Authenticators(c *config.Config) error {
-	if c == nil || len(c.Authenticators) == 0 {
+	if c == nil {
+		return nil
+	}
+	if c.MgmtConfig != nil && c.MgmtConfig.AuthenticatorName != "" {
+		if _, ok := c.Authenticators[c.MgmtConfig.AuthenticatorName]; !ok {
+			return fmt.Errorf("%w: %s", mgmt.ErrInvalidAuthenticatorName,
+				c.MgmtConfig.AuthenticatorName)
+		}
+	}
+	if len(c.Authenticators) == 0 {
 		return nil
 	}
 	return c.Authenticators.Validate(ar.IsRegistered)
 }
 
+// Authorization validates that authorization policies reference defined backends.
+func Authorization(c *config.Config) error {
+	if c == nil || c.Authorization == nil {
+		return nil
+	}
+	return c.Authorization.Validate(sets.New(slices.Collect(maps.Keys(c.Backends))))
+}
+
 func Backends(c *config.Config) error {
 	if c == nil {
 		return errors.ErrNoValidBackends
@@ -166,9 +203,14 @@ func Listeners(c *config.Config) error {
 		if backend.ListenerName == mgmt.ListenerNameMgmt || backend.ListenerName == mgmt.ListenerNameMetrics {
 			return fmt.Errorf("backend %q cannot use reserved listener %q", backendName, backend.ListenerName)
 		}
-		if _, ok := c.Listeners[backend.ListenerName]; !ok {
+		lo, ok := c.Listeners[backend.ListenerName]
+		if !ok {
 			return fmt.Errorf("backend %q references undefined listener %q", backendName, backend.ListenerName)
 		}
+		if name := mtlsAuthenticator(c, backend); name != "" && (lo == nil || !lo.TLSClientAuth.Verifies()) {
+			return fmt.Errorf("backend %q authenticator %q on listener %q: %w", backendName,
+				name, backend.ListenerName, tlsopts.ErrClientAuthNotVerified)
+		}
 		mapped[backend.ListenerName]++
 		if backend.TLS != nil && backend.TLS.ServeTLS {
 			tlsMapped[backend.ListenerName] = true
@@ -196,6 +238,10 @@ func Listeners(c *config.Config) error {
 		if options.ListenPort < 0 || options.TLSListenPort < 0 {
 			return fmt.Errorf("listener %q has an invalid listen port", name)
 		}
+		if err := tlsopts.ValidateClientAuth(options.TLSClientAuth,
+			options.TLSClientCAPaths); err != nil {
+			return fmt.Errorf("listener %q: %w", name, err)
+		}
 
 		builtIn := name == listener.DefaultFrontendName ||
 			name == mgmt.ListenerNameMgmt || name == mgmt.ListenerNameMetrics
@@ -227,9 +273,37 @@ func Listeners(c *config.Config) error {
 			}
 		}
 	}
+	if c.MgmtConfig != nil && isMTLSAuthenticator(c, c.MgmtConfig.AuthenticatorName) {
+		if lo := c.Listeners[mgmt.ListenerNameMgmt]; lo == nil || !lo.TLSClientAuth.Verifies() {
+			return fmt.Errorf("mgmt authenticator %q on listener %q: %w",
+				c.MgmtConfig.AuthenticatorName, mgmt.ListenerNameMgmt,
+				tlsopts.ErrClientAuthNotVerified)
+		}
+	}
 	return nil
 }
 
+// mtlsAuthenticator returns the name of an mTLS Authenticator used by the
+// backend or any of its paths, or an empty string if there is none
+func mtlsAuthenticator(c *config.Config, backend *bo.Options) string {
+	if isMTLSAuthenticator(c, backend.AuthenticatorName) {
+		return backend.AuthenticatorName
+	}
+	for _, p := range backend.Paths {
+		if p != nil && isMTLSAuthenticator(c, p.AuthenticatorName) {
+			return p.AuthenticatorName
+		}
+	}
+	return ""
+}
+
+// isMTLSAuthenticator returns true if the named Authenticator is an mTLS
+// Authenticator
+func isMTLSAuthenticator(c *config.Config, name string) bool {
+	ao, ok := c.Authenticators[name]
+	return ok && ao != nil && ao.Provider == mtls.ID
+}
+
 func addWarning(c *config.Config, warning string) {
 	if slices.Contains(c.LoaderWarnings, warning) {
 		return
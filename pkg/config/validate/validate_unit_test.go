@@ -17,6 +17,7 @@
 package validate
 
 import (
+	stderrors "errors"
 	"strings"
 	"testing"
 
@@ -25,11 +26,14 @@ import (
 	rule "github.com/trickstercache/trickster/v2/pkg/backends/rule/options"
 	co "github.com/trickstercache/trickster/v2/pkg/cache/options"
 	"github.com/trickstercache/trickster/v2/pkg/config"
+	"github.com/trickstercache/trickster/v2/pkg/config/mgmt"
 	"github.com/trickstercache/trickster/v2/pkg/errors"
 	lo "github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
 	mo "github.com/trickstercache/trickster/v2/pkg/observability/metrics/options"
 	to "github.com/trickstercache/trickster/v2/pkg/observability/tracing/options"
 	auth "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	authz "github.com/trickstercache/trickster/v2/pkg/proxy/authorization/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	rwopts "github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter/options"
 	tlsopts "github.com/trickstercache/trickster/v2/pkg/proxy/tls/options"
 	tlstest "github.com/trickstercache/trickster/v2/pkg/testutil/tls"
@@ -89,6 +93,12 @@ func TestValidateRejectsInvalidSubsections(t *testing.T) {
 	if err := Validate(c); err == nil {
 		t.Fatal("expected invalid metrics listen port error")
 	}
+
+	c = config.NewConfig()
+	c.Main.TrustedProxies = []string{"10.0.0.0/8", "not-a-cidr"}
+	if err := Validate(c); !stderrors.Is(err, headers.ErrInvalidTrustedProxy) {
+		t.Fatalf("Validate() = %v, want ErrInvalidTrustedProxy", err)
+	}
 }
 
 func TestRewritersRulesAndAuthenticators(t *testing.T) {
@@ -131,6 +141,30 @@ func TestRewritersRulesAndAuthenticators(t *testing.T) {
 	if err := Authenticators(c); err == nil {
 		t.Fatal("expected invalid authenticator provider error")
 	}
+
+	c = config.NewConfig()
+	c.MgmtConfig.AuthenticatorName = "example"
+	if err := Authenticators(c); !stderrors.Is(err, mgmt.ErrInvalidAuthenticatorName) {
+		t.Fatalf("expected invalid mgmt authenticator name error, got %v", err)
+	}
+	c.Authenticators = auth.Lookup{
+		"example": {Provider: "basic"},
+	}
+	if err := Authenticators(c); err != nil {
+		t.Fatalf("Authenticators(valid mgmt) = %v", err)
+	}
+
+	c = config.NewConfig()
+	c.Authorization = &authz.Options{Policies: authz.Lookup{
+		"example": {Users: []string{"user1"}, Backends: []string{"default"}},
+	}}
+	if err := Authorization(c); err != nil {
+		t.Fatalf("Authorization(valid) = %v", err)
+	}
+	c.Authorization.Policies["example"].Backends = []string{"not-a-backend"}
+	if err := Authorization(c); err == nil {
+		t.Fatal("expected undefined authorization backend error")
+	}
 }
 
 func TestTracersRejectsInvalidProtocol(t *testing.T) {
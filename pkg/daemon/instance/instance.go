@@ -22,6 +22,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/cache"
 	"github.com/trickstercache/trickster/v2/pkg/config"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/listener"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/warming"
 )
 
 type ServerInstance struct {
@@ -30,4 +31,5 @@ type ServerInstance struct {
 	HealthChecker healthcheck.HealthChecker
 	Backends      backends.Backends
 	Listeners     *listener.Group
+	Warmers       warming.Lookup
 }
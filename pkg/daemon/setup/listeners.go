@@ -32,7 +32,10 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
 	"github.com/trickstercache/trickster/v2/pkg/observability/pprof"
 	"github.com/trickstercache/trickster/v2/pkg/observability/tracing"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/handler"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/audit"
 	ch "github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/config"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/failures"
 	ph "github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/purge"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/listener"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/router"
@@ -73,7 +76,13 @@ func applyListenerConfigs(conf, oldConf *config.Config,
 	managementRouter.RegisterRoute(conf.MgmtConfig.ReloadHandlerPath, nil, nil,
 		false, reloadHandler)
 	managementRouter.RegisterRoute(conf.MgmtConfig.PurgeByPathHandlerPath, nil, nil,
-		true, http.HandlerFunc(ph.PathHandler(conf.MgmtConfig.PurgeByPathHandlerPath, &backends)))
+		true, mgmtHandler(conf, audit.ActionPurgePath,
+			http.HandlerFunc(ph.PathHandler(conf.MgmtConfig.PurgeByPathHandlerPath, &backends))))
+	if conf.MgmtConfig.PurgeByTenantHandlerPath != "" {
+		managementRouter.RegisterRoute(conf.MgmtConfig.PurgeByTenantHandlerPath, nil, nil,
+			true, mgmtHandler(conf, audit.ActionPurgeTenant,
+				http.HandlerFunc(ph.TenantHandler(conf.MgmtConfig.PurgeByTenantHandlerPath, &backends))))
+	}
 	if listenerEnabledOn(conf.MgmtConfig.PprofListener, mgmt.ListenerNameMgmt) {
 		pprof.RegisterRoutes(mgmt.ListenerNameMgmt, managementRouter)
 	}
@@ -183,14 +192,39 @@ func listenerKey(listenerName string, tls bool) string {
 func listenerNeedsRestart(old, current desiredListener) bool {
 	return old.address != current.address || old.port != current.port || old.tls != current.tls ||
 		old.options.ConnectionsLimit != current.options.ConnectionsLimit ||
-		old.options.ReadHeaderTimeout != current.options.ReadHeaderTimeout
+		old.options.ReadHeaderTimeout != current.options.ReadHeaderTimeout ||
+		(current.tls && !old.options.TLSClientAuthEqual(current.options))
 }
 
 func registerConfigRoutes(conf *config.Config, r router.Router) {
 	r.RegisterRoute(conf.MgmtConfig.ConfigHandlerPath, nil, nil,
-		false, http.HandlerFunc(ch.HandlerFunc(conf)))
+		false, mgmtHandler(conf, audit.ActionConfigDump,
+			http.HandlerFunc(ch.HandlerFunc(conf))))
 	r.RegisterRoute(ch.SanitizedHandlerPath(conf.MgmtConfig.ConfigHandlerPath), nil, nil,
-		false, http.HandlerFunc(ch.SanitizedHandlerFunc(conf)))
+		false, mgmtHandler(conf, audit.ActionConfigDump,
+			http.HandlerFunc(ch.SanitizedHandlerFunc(conf))))
+}
+
+// mgmtHandler wraps a management API handler with the audit log and, when
+// configured, the management authenticator. If the configured authenticator is
+// unavailable, the handler fails closed by responding with a 500.
+func mgmtHandler(conf *config.Config, action string, h http.Handler) http.Handler {
+	if conf.MgmtConfig != nil && conf.MgmtConfig.AuthenticatorName != "" {
+		name := conf.MgmtConfig.AuthenticatorName
+		if ao, ok := conf.Authenticators[name]; ok &&
+			ao != nil && ao.Authenticator != nil {
+			h = handler.Middleware(ao.Authenticator, h)
+		} else {
+			logger.Error("mgmt authenticator is unavailable, denying mgmt requests",
+				logging.Pairs{"authenticatorName": name, "action": action})
+			h = http.HandlerFunc(failures.HandleInternalServerError)
+		}
+	}
+	var fh string
+	if conf.MgmtConfig != nil {
+		fh = conf.MgmtConfig.ForwardedHeaders
+	}
+	return audit.Handler(action, fh, h)
 }
 
 func updateListenerCertificates(conf *config.Config, desired desiredListener, lg *listener.Group) {
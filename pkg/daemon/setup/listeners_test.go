@@ -17,6 +17,7 @@
 package setup
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net"
@@ -24,12 +25,18 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/trickstercache/trickster/v2/pkg/config"
 	listenerconfig "github.com/trickstercache/trickster/v2/pkg/config/listener"
 	"github.com/trickstercache/trickster/v2/pkg/config/mgmt"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	authoptions "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/providers/basic"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/audit"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/listener"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/router"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/router/lm"
@@ -346,3 +353,73 @@ func tlsTestConfig(t *testing.T, keyPath, certPath string, port int) *config.Con
 	o.TLSListenPort = port
 	return conf
 }
+
+func TestMgmtHandlerAuthenticator(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetAuditLogger(logging.StreamAuditLogger(buf))
+	t.Cleanup(func() { logger.SetAuditLogger(nil) })
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// without a mgmt authenticator, requests are served and audited
+	conf := config.NewConfig()
+	w := httptest.NewRecorder()
+	mgmtHandler(conf, audit.ActionConfigDump, next).ServeHTTP(w,
+		httptest.NewRequest(http.MethodGet, "/trickster/config", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+
+	conf.Authenticators = authoptions.Lookup{
+		"mgmt": &authoptions.Options{
+			Name:     "mgmt",
+			Provider: basic.ID,
+			Users:    map[string]string{"admin": "secret"},
+		},
+	}
+	conf.MgmtConfig.AuthenticatorName = "mgmt"
+	if err := buildAuthenticators(conf); err != nil {
+		t.Fatal(err)
+	}
+	h := mgmtHandler(conf, audit.ActionReload, next)
+
+	buf.Reset()
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/trickster/config/reload", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected %d got %d", http.StatusUnauthorized, w.Code)
+	}
+	if !strings.Contains(buf.String(), `"outcome":"failure"`) {
+		t.Errorf("expected an audited failure, got %s", buf.String())
+	}
+
+	buf.Reset()
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/trickster/config/reload", nil)
+	r.SetBasicAuth("admin", "secret")
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+	if !strings.Contains(buf.String(), `"user":"admin"`) {
+		t.Errorf("expected an audited user, got %s", buf.String())
+	}
+
+	// an unavailable mgmt authenticator fails closed
+	for _, ao := range []*authoptions.Options{nil, {Name: "mgmt", Provider: basic.ID}} {
+		conf.Authenticators = authoptions.Lookup{"mgmt": ao}
+		w = httptest.NewRecorder()
+		mgmtHandler(conf, audit.ActionReload, next).ServeHTTP(w, r)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+		}
+	}
+	conf.Authenticators = nil
+	w = httptest.NewRecorder()
+	mgmtHandler(conf, audit.ActionReload, next).ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected %d got %d", http.StatusInternalServerError, w.Code)
+	}
+}
@@ -46,12 +46,15 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
 	tr "github.com/trickstercache/trickster/v2/pkg/observability/tracing/registry"
 	ar "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/registry"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/audit"
 	pnh "github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/ping"
 	ph "github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/purge"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/reload"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/listener"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/router"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/router/lm"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/warming"
 	"github.com/trickstercache/trickster/v2/pkg/routing"
 	"github.com/trickstercache/trickster/v2/pkg/util/safego"
 )
@@ -147,6 +150,8 @@ func ApplyConfig(si *instance.ServerInstance, newConf *config.Config,
 		newConf.Main.ServerName, _ = os.Hostname()
 	}
 	appinfo.SetServer(newConf.Main.ServerName)
+	trustedProxies, _ := headers.ParseTrustedProxies(newConf.Main.TrustedProxies)
+	headers.SetTrustedProxies(trustedProxies)
 
 	if newConf.MgmtConfig == nil {
 		newConf.MgmtConfig = mgmt.New()
@@ -186,7 +191,8 @@ func ApplyConfig(si *instance.ServerInstance, newConf *config.Config,
 	}
 
 	caches := applyCachingConfig(si, newConf)
-	rh := reload.HandlerFunc(hupFunc)
+	rh := mgmtHandler(newConf, audit.ActionReload,
+		reload.HandlerFunc(hupFunc, func() *config.Config { return si.Config }))
 	err = routing.RegisterProxyRoutesForListeners(newConf, clients, listenerRouters, mr, caches, tracers, false)
 	if err != nil {
 		handleStartupIssue("route registration failed",
@@ -200,7 +206,8 @@ func ApplyConfig(si *instance.ServerInstance, newConf *config.Config,
 	for _, r := range listenerRouters {
 		r.RegisterRoute(newConf.MgmtConfig.PurgeByKeyHandlerPath, nil,
 			[]string{http.MethodDelete}, true,
-			http.HandlerFunc(ph.KeyHandler(newConf.MgmtConfig.PurgeByKeyHandlerPath, clients)))
+			mgmtHandler(newConf, audit.ActionPurgeKey,
+				http.HandlerFunc(ph.KeyHandler(newConf.MgmtConfig.PurgeByKeyHandlerPath, clients))))
 	}
 
 	if si.Backends != nil {
@@ -220,6 +227,10 @@ func ApplyConfig(si *instance.ServerInstance, newConf *config.Config,
 		return err
 	}
 	alb.StartALBPools(clients, si.HealthChecker.Statuses())
+	if si.Warmers != nil {
+		si.Warmers.Stop()
+	}
+	si.Warmers = warming.StartAll(clients)
 	routing.RegisterDefaultBackendRoutesForListeners(listenerRouters, newConf, clients, tracers)
 	routing.RegisterHealthHandler(mr, newConf.MgmtConfig.HealthHandlerPath, si.HealthChecker, clients)
 	applyListenerConfigs(newConf, si.Config, listenerRouters, rh, mr, tracers, clients, errorFunc, lg)
@@ -256,31 +267,72 @@ func applyLoggingConfig(c, o *config.Config) {
 	if c.MgmtConfig == nil {
 		c.MgmtConfig = mgmt.New()
 	}
+	applyAccessLogConfig(c, o)
+	applyAuditLogConfig(c, o)
 	if isReload {
 		if c.Logging.LogFile == o.Logging.LogFile &&
-			c.Logging.LogLevel == o.Logging.LogLevel {
-			// no changes in logging config,
-			// so we keep the old logger intact
-			return
-		}
-		if c.Logging.LogFile != o.Logging.LogFile {
-			if o.Logging.LogFile != "" {
-				// if we're changing from file1 -> console or file1 -> file2, close file1 handle
-				// the extra 1s allows HTTP listeners to close first and finish their log writes
-				go delayedLogCloser(oldLogger, time.Duration(c.MgmtConfig.ReloadDrainTimeout)+(1*time.Millisecond))
+			c.Logging.Format == o.Logging.Format &&
+			c.Logging.Rotation.Equal(o.Logging.Rotation) {
+			// the log output is unchanged, so we keep the old logger intact,
+			// updating only the log level if it has changed
+			if c.Logging.LogLevel != o.Logging.LogLevel {
+				oldLogger.SetLogLevel(level.Level(c.Logging.LogLevel))
 			}
-			initLogger(c)
 			return
 		}
-		if c.Logging.LogLevel != o.Logging.LogLevel {
-			// the only change is the log level, so update it and return the original logger
-			oldLogger.SetLogLevel(level.Level(c.Logging.LogLevel))
-			return
+		if o.Logging.LogFile != "" {
+			// if we're changing from file1 -> console or file1 -> file2, close file1 handle
+			// the extra 1s allows HTTP listeners to close first and finish their log writes
+			go delayedLogCloser(oldLogger, time.Duration(c.MgmtConfig.ReloadDrainTimeout)+(1*time.Millisecond))
 		}
+		initLogger(c)
+		return
 	}
 	initLogger(c)
 }
 
+// applyAccessLogConfig installs the configured access logger, retaining the
+// existing one when its configuration is unchanged
+func applyAccessLogConfig(c, o *config.Config) {
+	oldAccessLogger := logger.AccessLogger()
+	if oldAccessLogger != nil && o != nil && c != o && o.Logging != nil &&
+		c.Logging.AccessLog.Equal(o.Logging.AccessLog) {
+		return
+	}
+	if c.Logging.AccessLog == nil {
+		logger.SetAccessLogger(nil)
+	} else {
+		logger.SetAccessLogger(logging.NewAccessLogger(c.Logging.AccessLog,
+			c.Main.InstanceID))
+	}
+	if oldAccessLogger != nil {
+		// allow in-flight requests to finish their access log writes
+		time.AfterFunc(time.Duration(c.MgmtConfig.ReloadDrainTimeout)+
+			(1*time.Millisecond), oldAccessLogger.Close)
+	}
+}
+
+// applyAuditLogConfig installs the configured audit logger, retaining the
+// existing one when its configuration is unchanged
+func applyAuditLogConfig(c, o *config.Config) {
+	oldAuditLogger := logger.AuditLogger()
+	if oldAuditLogger != nil && o != nil && c != o && o.Logging != nil &&
+		c.Logging.AuditLog.Equal(o.Logging.AuditLog) {
+		return
+	}
+	if c.Logging.AuditLog == nil {
+		logger.SetAuditLogger(nil)
+	} else {
+		logger.SetAuditLogger(logging.NewAuditLogger(c.Logging.AuditLog,
+			c.Main.InstanceID))
+	}
+	if oldAuditLogger != nil {
+		// allow in-flight management requests to finish their audit log writes
+		time.AfterFunc(time.Duration(c.MgmtConfig.ReloadDrainTimeout)+
+			(1*time.Millisecond), oldAuditLogger.Close)
+	}
+}
+
 func applyCachingConfig(si *instance.ServerInstance,
 	newConf *config.Config,
 ) cache.Lookup {
@@ -32,6 +32,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/level"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	lo "github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/providers/basic"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/listener"
@@ -517,6 +518,88 @@ func TestApplyLoggingConfigFileChange(t *testing.T) {
 	time.Sleep(20 * time.Millisecond)
 }
 
+func TestApplyLoggingConfigAccessLog(t *testing.T) {
+	dir := t.TempDir()
+	t.Cleanup(func() {
+		logger.AccessLogger().Close()
+		logger.SetAccessLogger(nil)
+		logger.SetLogger(logging.NoopLogger())
+	})
+	old := config.NewConfig()
+	applyLoggingConfig(old, nil)
+	if logger.AccessLogger() != nil {
+		t.Error("expected no access logger")
+	}
+
+	nc := config.NewConfig()
+	nc.Logging.AccessLog = lo.NewAccessLog()
+	nc.Logging.AccessLog.File = filepath.Join(dir, "access.log")
+	applyLoggingConfig(nc, old)
+	before := logger.AccessLogger()
+	if before == nil {
+		t.Fatal("expected an access logger")
+	}
+
+	nc2 := config.NewConfig()
+	nc2.Logging.AccessLog = nc.Logging.AccessLog.Clone()
+	applyLoggingConfig(nc2, nc)
+	if logger.AccessLogger() != before {
+		t.Error("an unchanged access log config should retain the access logger")
+	}
+
+	nc3 := config.NewConfig()
+	nc3.Logging.AccessLog = nc.Logging.AccessLog.Clone()
+	nc3.Logging.AccessLog.Format = lo.FormatJSON
+	nc3.MgmtConfig.ReloadDrainTimeout = 0
+	applyLoggingConfig(nc3, nc2)
+	if logger.AccessLogger() == before {
+		t.Error("a changed access log config should install a new access logger")
+	}
+	// let the delayed closer for the old access logger run before TempDir cleanup
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestApplyLoggingConfigAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	t.Cleanup(func() {
+		logger.AuditLogger().Close()
+		logger.SetAuditLogger(nil)
+		logger.SetLogger(logging.NoopLogger())
+	})
+	old := config.NewConfig()
+	applyLoggingConfig(old, nil)
+	if logger.AuditLogger() != nil {
+		t.Error("expected no audit logger")
+	}
+
+	nc := config.NewConfig()
+	nc.Logging.AuditLog = lo.NewAuditLog()
+	nc.Logging.AuditLog.File = filepath.Join(dir, "audit.log")
+	applyLoggingConfig(nc, old)
+	before := logger.AuditLogger()
+	if before == nil {
+		t.Fatal("expected an audit logger")
+	}
+
+	nc2 := config.NewConfig()
+	nc2.Logging.AuditLog = nc.Logging.AuditLog.Clone()
+	applyLoggingConfig(nc2, nc)
+	if logger.AuditLogger() != before {
+		t.Error("an unchanged audit log config should retain the audit logger")
+	}
+
+	nc3 := config.NewConfig()
+	nc3.Logging.AuditLog = nc.Logging.AuditLog.Clone()
+	nc3.Logging.AuditLog.File = filepath.Join(dir, "audit2.log")
+	nc3.MgmtConfig.ReloadDrainTimeout = 0
+	applyLoggingConfig(nc3, nc2)
+	if logger.AuditLogger() == before {
+		t.Error("a changed audit log config should install a new audit logger")
+	}
+	// let the delayed closer for the old audit logger run before TempDir cleanup
+	time.Sleep(20 * time.Millisecond)
+}
+
 func TestApplyCachingConfigNilArgs(t *testing.T) {
 	if got := applyCachingConfig(nil, config.NewConfig()); got != nil {
 		t.Errorf("got = %v, want nil", got)
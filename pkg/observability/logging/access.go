@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
+)
+
+// AccessLogger writes one line for each client-facing or upstream request to
+// a log that is separate from the application log
+type AccessLogger struct {
+	writer     io.Writer
+	closer     io.Closer
+	format     string
+	sampleRate float64
+	fields     map[string]struct{}
+	mtx        sync.Mutex
+	now        func() time.Time
+}
+
+// NewAccessLogger returns an AccessLogger for the provided access log
+// configuration. When instanceID is greater than 0, it is inserted into the
+// file name.
+func NewAccessLogger(o *options.AccessLogOptions, instanceID int) *AccessLogger {
+	return StreamAccessLogger(newWriter(o.File, instanceID, o.Rotation), o)
+}
+
+// StreamAccessLogger returns an AccessLogger that writes to the provided
+// writer, using the format, sampling and fields of the provided options
+func StreamAccessLogger(w io.Writer, o *options.AccessLogOptions) *AccessLogger {
+	a := &AccessLogger{
+		writer:     w,
+		format:     o.Format,
+		sampleRate: o.SampleRate,
+		now:        time.Now,
+	}
+	if c, ok := w.(io.Closer); ok && c != nil {
+		a.closer = c
+	}
+	if len(o.Fields) > 0 {
+		a.fields = make(map[string]struct{}, len(o.Fields))
+		for _, f := range o.Fields {
+			a.fields[f] = struct{}{}
+		}
+	}
+	return a
+}
+
+// Log writes an access log entry of the provided kind (client or upstream),
+// subject to the sample rate and field list
+func (a *AccessLogger) Log(kind string, detail Pairs) {
+	if a == nil || a.writer == nil ||
+		(a.sampleRate < 1 && rand.Float64() >= a.sampleRate) {
+		return
+	}
+	if a.fields != nil {
+		filtered := make(Pairs, len(a.fields))
+		for k, v := range detail {
+			if _, ok := a.fields[k]; ok {
+				filtered[k] = v
+			}
+		}
+		detail = filtered
+	}
+	header := []field{
+		{"time", a.now().UTC().Format(time.RFC3339Nano)},
+		{"kind", kind},
+	}
+	logLine := appendLine(nil, a.format, header, detail)
+	a.mtx.Lock()
+	a.writer.Write(logLine)
+	a.mtx.Unlock()
+}
+
+// Close closes the AccessLogger's underlying file, if any
+func (a *AccessLogger) Close() {
+	if a != nil && a.closer != nil {
+		a.closer.Close()
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testAccessLogger(buf *bytes.Buffer, o *options.AccessLogOptions) *AccessLogger {
+	a := StreamAccessLogger(buf, o)
+	a.now = func() time.Time { return time.Time{} }
+	return a
+}
+
+func TestAccessLoggerLog(t *testing.T) {
+	t.Parallel()
+
+	detail := Pairs{"code": 200, "clientIP": "127.0.0.1", "uri": "/api/v1/query?query=up"}
+
+	buf := &bytes.Buffer{}
+	testAccessLogger(buf, options.NewAccessLog()).Log(options.AccessKindClient, detail)
+	require.Equal(t, "time=0001-01-01T00:00:00Z kind=client clientIP=127.0.0.1 code=200 uri=/api/v1/query?query=up\n",
+		buf.String())
+
+	buf.Reset()
+	o := options.NewAccessLog()
+	o.Format = options.FormatJSON
+	o.Fields = []string{"code", "size"}
+	testAccessLogger(buf, o).Log(options.AccessKindUpstream, detail)
+	require.Equal(t, `{"time":"0001-01-01T00:00:00Z","kind":"upstream","code":200}`+"\n",
+		buf.String())
+}
+
+func TestAccessLoggerSampling(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	o := options.NewAccessLog()
+	o.SampleRate = 0.5
+	a := testAccessLogger(buf, o)
+	const n = 1000
+	for range n {
+		a.Log(options.AccessKindClient, nil)
+	}
+	lines := bytes.Count(buf.Bytes(), []byte(newline))
+	require.Greater(t, lines, n/4)
+	require.Less(t, lines, n*3/4)
+}
+
+func TestNewAccessLogger(t *testing.T) {
+	t.Parallel()
+
+	var a *AccessLogger
+	a.Log(options.AccessKindClient, nil)
+	a.Close()
+
+	dir := t.TempDir()
+	o := options.NewAccessLog()
+	o.File = filepath.Join(dir, "access.log")
+	a = NewAccessLogger(o, 2)
+	a.Log(options.AccessKindClient, Pairs{"code": 200})
+	a.Close()
+	b, err := os.ReadFile(filepath.Join(dir, "access.2.log"))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "kind=client code=200")
+}
+
+func TestAuditLogger(t *testing.T) {
+	t.Parallel()
+
+	var a *AuditLogger
+	a.Log("reload", nil)
+	a.Close()
+
+	buf := &bytes.Buffer{}
+	a = StreamAuditLogger(buf)
+	a.now = func() time.Time { return time.Time{} }
+	a.Log("purge_key", Pairs{"backend": "prom1", "code": 200})
+	require.Equal(t, `{"time":"0001-01-01T00:00:00Z","action":"purge_key","backend":"prom1","code":200}`+"\n",
+		buf.String())
+
+	dir := t.TempDir()
+	o := options.NewAuditLog()
+	o.File = filepath.Join(dir, "audit.log")
+	a = NewAuditLogger(o, 0)
+	a.Log("reload", nil)
+	a.Close()
+	b, err := os.ReadFile(o.File)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"action":"reload"`)
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
+)
+
+// AuditLogger writes one JSON line for each management API action to a log
+// that is separate from the application log
+type AuditLogger struct {
+	writer io.Writer
+	closer io.Closer
+	mtx    sync.Mutex
+	now    func() time.Time
+}
+
+// NewAuditLogger returns an AuditLogger for the provided audit log
+// configuration. When instanceID is greater than 0, it is inserted into the
+// file name.
+func NewAuditLogger(o *options.AuditLogOptions, instanceID int) *AuditLogger {
+	return StreamAuditLogger(newWriter(o.File, instanceID, o.Rotation))
+}
+
+// StreamAuditLogger returns an AuditLogger that writes to the provided writer
+func StreamAuditLogger(w io.Writer) *AuditLogger {
+	a := &AuditLogger{
+		writer: w,
+		now:    time.Now,
+	}
+	if c, ok := w.(io.Closer); ok && c != nil {
+		a.closer = c
+	}
+	return a
+}
+
+// Log writes an audit log entry for the provided action
+func (a *AuditLogger) Log(action string, detail Pairs) {
+	if a == nil || a.writer == nil {
+		return
+	}
+	header := []field{
+		{"time", a.now().UTC().Format(time.RFC3339Nano)},
+		{"action", action},
+	}
+	logLine := appendLine(nil, options.FormatJSON, header, detail)
+	a.mtx.Lock()
+	a.writer.Write(logLine)
+	a.mtx.Unlock()
+}
+
+// Close closes the AuditLogger's underlying file, if any
+func (a *AuditLogger) Close() {
+	if a != nil && a.closer != nil {
+		a.closer.Close()
+	}
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
+	tstr "github.com/trickstercache/trickster/v2/pkg/util/strings"
+)
+
+type field struct {
+	key string
+	val any
+}
+
+type item struct {
+	key string
+	val string
+}
+
+func (i *item) Bytes() []byte {
+	return append([]byte(i.key), append([]byte(equal), []byte(i.val)...)...)
+}
+
+const (
+	space   = " "
+	equal   = "="
+	newline = "\n"
+)
+
+// appendLine appends a newline-terminated log line in the provided format,
+// starting with the header fields in order, followed by the detail fields
+// sorted by key
+func appendLine(b []byte, format string, header []field, detail Pairs) []byte {
+	fields := make([]field, 0, len(detail))
+	for k, v := range detail {
+		fields = append(fields, field{k, v})
+	}
+	slices.SortFunc(fields, func(a, b field) int {
+		return cmp.Compare(a.key, b.key)
+	})
+	if format == options.FormatJSON {
+		b = appendJSON(b, header, fields)
+	} else {
+		b = appendLogfmt(b, header, fields)
+	}
+	return append(b, newline...)
+}
+
+func appendLogfmt(b []byte, fieldLists ...[]field) []byte {
+	var n int
+	for _, fields := range fieldLists {
+		for _, f := range fields {
+			if n > 0 {
+				b = append(b, space...)
+			}
+			i := item{f.key, logfmtValue(f.val)}
+			b = append(b, i.Bytes()...)
+			n++
+		}
+	}
+	return b
+}
+
+func logfmtValue(v any) string {
+	switch t := v.(type) {
+	case string:
+		return quoteAsNeeded(t)
+	case fmt.Stringer:
+		return quoteAsNeeded(t.String())
+	case error:
+		return quoteAsNeeded(t.Error())
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func quoteAsNeeded(input string) string {
+	if !strings.Contains(input, " ") {
+		return input
+	}
+	return `"` + tstr.EscapeQuotes(input) + `"`
+}
+
+func appendJSON(b []byte, fieldLists ...[]field) []byte {
+	b = append(b, '{')
+	var n int
+	for _, fields := range fieldLists {
+		for _, f := range fields {
+			if n > 0 {
+				b = append(b, ',')
+			}
+			k, _ := json.Marshal(f.key)
+			b = append(b, k...)
+			b = append(b, ':')
+			b = append(b, jsonValue(f.val)...)
+			n++
+		}
+	}
+	return append(b, '}')
+}
+
+func jsonValue(v any) []byte {
+	switch t := v.(type) {
+	case fmt.Stringer:
+		v = t.String()
+	case error:
+		v = t.Error()
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		out, _ = json.Marshal(fmt.Sprintf("%v", v))
+	}
+	return out
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+)
+
+var accessLogger atomic.Pointer[logging.AccessLogger]
+
+// AccessLogger returns the package-level AccessLogger, which is nil when
+// access logging is disabled
+func AccessLogger() *logging.AccessLogger {
+	return accessLogger.Load()
+}
+
+// SetAccessLogger sets the package-level AccessLogger. Set to nil to disable
+// access logging
+func SetAccessLogger(a *logging.AccessLogger) {
+	accessLogger.Store(a)
+}
+
+// Access writes an entry to the package-level AccessLogger, if one is set,
+// and returns true if it is set
+func Access(kind string, detail logging.Pairs) bool {
+	a := accessLogger.Load()
+	if a == nil {
+		return false
+	}
+	a.Log(kind, detail)
+	return true
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+)
+
+var auditLogger atomic.Pointer[logging.AuditLogger]
+
+// AuditLogger returns the package-level AuditLogger, which is nil when audit
+// logging is disabled
+func AuditLogger() *logging.AuditLogger {
+	return auditLogger.Load()
+}
+
+// SetAuditLogger sets the package-level AuditLogger. Set to nil to disable
+// audit logging
+func SetAuditLogger(a *logging.AuditLogger) {
+	auditLogger.Store(a)
+}
+
+// Audit writes an entry to the package-level AuditLogger, if one is set, and
+// returns true if it is set
+func Audit(action string, detail logging.Pairs) bool {
+	a := auditLogger.Load()
+	if a == nil {
+		return false
+	}
+	a.Log(action, detail)
+	return true
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/level"
+	lo "github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
 
 	"github.com/stretchr/testify/require"
 )
@@ -116,3 +117,29 @@ func TestPackageLoggerAsync(t *testing.T) {
 	}
 	require.Contains(t, buf.String(), "async package log")
 }
+
+func TestPackageAccessLogger(t *testing.T) {
+	t.Cleanup(func() { SetAccessLogger(nil) })
+
+	require.Nil(t, AccessLogger())
+	require.False(t, Access(lo.AccessKindClient, nil))
+
+	buf := &bytes.Buffer{}
+	SetAccessLogger(logging.StreamAccessLogger(buf, lo.NewAccessLog()))
+	require.NotNil(t, AccessLogger())
+	require.True(t, Access(lo.AccessKindClient, logging.Pairs{"code": 200}))
+	require.Contains(t, buf.String(), "kind=client code=200")
+}
+
+func TestPackageAuditLogger(t *testing.T) {
+	t.Cleanup(func() { SetAuditLogger(nil) })
+
+	require.Nil(t, AuditLogger())
+	require.False(t, Audit("reload", nil))
+
+	buf := &bytes.Buffer{}
+	SetAuditLogger(logging.StreamAuditLogger(buf))
+	require.NotNil(t, AuditLogger())
+	require.True(t, Audit("reload", logging.Pairs{"outcome": "reloaded"}))
+	require.Contains(t, buf.String(), `"action":"reload","outcome":"reloaded"`)
+}
@@ -17,12 +17,9 @@
 package logging
 
 import (
-	"cmp"
-	"fmt"
 	"io"
 	"os"
 	"runtime"
-	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,9 +27,6 @@ import (
 
 	"github.com/trickstercache/trickster/v2/pkg/config"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/level"
-	tstr "github.com/trickstercache/trickster/v2/pkg/util/strings"
-
-	lumberjack "gopkg.in/natefinch/lumberjack.v2"
 )
 
 var (
@@ -88,22 +82,9 @@ func New(conf *config.Config) Logger {
 		now: time.Now,
 	}
 	l.logFunc = l.logAsyncronous
-	if conf.Logging.LogFile == "" {
-		l.writer = os.Stdout
-	} else {
-		logFile := conf.Logging.LogFile
-		if conf.Main.InstanceID > 0 {
-			logFile = strings.Replace(logFile, ".log",
-				"."+strconv.Itoa(conf.Main.InstanceID)+".log", 1)
-		}
-		l.writer = &lumberjack.Logger{
-			Filename:   logFile,
-			MaxSize:    256,  // megabytes
-			MaxBackups: 80,   // 256 megs @ 80 backups is 20GB of Logs
-			MaxAge:     7,    // days
-			Compress:   true, // Compress Rolled Backups
-		}
-	}
+	l.writer = newWriter(conf.Logging.LogFile, conf.Main.InstanceID,
+		conf.Logging.Rotation)
+	l.format = conf.Logging.Format
 	if c, ok := l.writer.(io.Closer); ok && c != nil {
 		l.closer = c
 	}
@@ -154,6 +135,7 @@ type logger struct {
 	onceRanEntries sync.Map
 	logFunc        logFunc
 	now            func() time.Time
+	format         string
 }
 
 func (l *logger) Write(b []byte) (int, error) {
@@ -324,21 +306,6 @@ func (l *logger) logAsyncronous(logLevel level.Level, event string, detail Pairs
 	go l.logWithCaller(logLevel, event, detail, getCaller(1))
 }
 
-type item struct {
-	key string
-	val string
-}
-
-func (i *item) Bytes() []byte {
-	return append([]byte(i.key), append([]byte(equal), []byte(i.val)...)...)
-}
-
-const (
-	space   = " "
-	equal   = "="
-	newline = "\n"
-)
-
 // getCaller returns the first path in the call stack from /pkg not in
 func getCaller(skip int) string {
 	for s := skip; s < skip+20; s++ {
@@ -370,66 +337,26 @@ func (l *logger) logWithCaller(logLevel level.Level, event string, detail Pairs,
 		return
 	}
 	ts := l.now()
-	ld := len(detail)
 	if strings.HasPrefix(event, space) || strings.HasSuffix(event, space) {
 		event = strings.TrimSpace(event)
 	}
 
-	logLine := []byte(
-		"time=" + ts.UTC().Format(time.RFC3339Nano) + space +
-			"app=trickster" + space +
-			"level=" + string(logLevel) + space +
-			"event=" + quoteAsNeeded(event),
-	)
-
+	header := []field{
+		{"time", ts.UTC().Format(time.RFC3339Nano)},
+		{"app", "trickster"},
+		{"level", string(logLevel)},
+		{"event", event},
+	}
 	// Add caller field if available
 	if caller != "" {
-		logLine = append(logLine, []byte(space+"caller="+caller)...)
-	}
-
-	if ld > 0 {
-		logLine = append(logLine, []byte(space)...)
-		keyPairs := make([]item, ld)
-		var i int
-		for k, v := range detail {
-			var s string
-			var ok bool
-			if s, ok = v.(string); ok {
-				s = quoteAsNeeded(s)
-			} else if stringer, ok := v.(fmt.Stringer); ok {
-				s = quoteAsNeeded(stringer.String())
-			} else if err, ok := v.(error); ok {
-				s = quoteAsNeeded(err.Error())
-			} else {
-				s = fmt.Sprintf("%v", v)
-			}
-			keyPairs[i] = item{k, s}
-			i++
-		}
-		slices.SortFunc(keyPairs, func(a, b item) int {
-			return cmp.Compare(a.key, b.key)
-		})
-		i = 0
-		for _, v := range keyPairs {
-			logLine = append(logLine, v.Bytes()...)
-			i++
-			if i < ld {
-				logLine = append(logLine, []byte(space)...)
-			}
-		}
+		header = append(header, field{"caller", caller})
 	}
+	logLine := appendLine(nil, l.format, header, detail)
 	l.mtx.Lock()
-	l.writer.Write(append(logLine, []byte(newline)...))
+	l.writer.Write(logLine)
 	l.mtx.Unlock()
 }
 
-func quoteAsNeeded(input string) string {
-	if !strings.Contains(input, " ") {
-		return input
-	}
-	return `"` + tstr.EscapeQuotes(input) + `"`
-}
-
 func (l *logger) Level() level.Level {
 	return l.level
 }
@@ -17,6 +17,8 @@
 package logging
 
 import (
+	"bytes"
+	"errors"
 	"net/http/httptest"
 	"os"
 	"testing"
@@ -87,6 +89,26 @@ func TestNewLogger_LogFile(t *testing.T) {
 	require.Equal(t, `time=0001-01-01T00:00:00Z app=trickster level=info event=testEntry testKey="test Val" testKey2=testValue2 testKey3=testValue3`+"\n", string(b))
 }
 
+func TestNewLoggerJSON(t *testing.T) {
+	conf := config.NewConfig()
+	conf.Logging.Format = options.FormatJSON
+	log := New(conf)
+	buf := &bytes.Buffer{}
+	l := log.(*logger)
+	l.writer = buf
+	l.now = func() time.Time {
+		return time.Time{}
+	}
+	l.logWithCaller(level.Warn, "test event", Pairs{
+		"count": 3,
+		"error": errors.New("some error"),
+		"ok":    true,
+	}, "pkg/example/file.go:10")
+	require.Equal(t, `{"time":"0001-01-01T00:00:00Z","app":"trickster","level":"warn",`+
+		`"event":"test event","caller":"pkg/example/file.go:10","count":3,`+
+		`"error":"some error","ok":true}`+"\n", buf.String())
+}
+
 func TestNewLoggerDebug_LogFile(t *testing.T) {
 	fileName := t.TempDir() + "/out.debug.log"
 	// it should create a logger that outputs to a log file ("out.test.log")
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Access log entry kinds
+const (
+	// AccessKindClient is the kind of access log entries for requests from
+	// clients to Trickster
+	AccessKindClient = "client"
+	// AccessKindUpstream is the kind of access log entries for requests from
+	// Trickster to an origin
+	AccessKindUpstream = "upstream"
+)
+
+// AccessLogFields is the list of fields that may be included in access log
+// entries. The time and kind fields are always included.
+var AccessLogFields = []string{
+	"backendName", "backendProvider", "clientIP", "code", "durationMS",
+	"handlerName", "method", "path", "result", "size", "uri", "userAgent",
+}
+
+var (
+	ErrInvalidSampleRate     = errors.New("access_log sample_rate must be greater than 0 and at most 1")
+	ErrInvalidAccessLogField = errors.New("invalid access_log field")
+)
+
+// AccessLogOptions defines a log of client-facing and upstream requests that
+// is separate from the application log
+type AccessLogOptions struct {
+	// File provides the filepath to the access log. Set as empty string to
+	// log to console
+	File string `yaml:"file,omitempty"`
+	// Format is the format of access log lines: 'logfmt' (the default) or 'json'
+	Format string `yaml:"format,omitempty"`
+	// SampleRate is the fraction of requests that are logged, from 0 to 1
+	SampleRate float64 `yaml:"sample_rate,omitempty"`
+	// Fields is the list of fields included in each entry. Empty includes all
+	Fields []string `yaml:"fields,omitempty"`
+	// Rotation configures the rotation of File
+	Rotation *RotationOptions `yaml:"rotation,omitempty"`
+}
+
+// NewAccessLog returns a new AccessLogOptions with the default values
+func NewAccessLog() *AccessLogOptions {
+	return &AccessLogOptions{
+		Format:     DefaultLogFormat,
+		SampleRate: DefaultAccessLogSampleRate,
+		Rotation:   NewRotation(),
+	}
+}
+
+// Clone returns a copy of the AccessLogOptions
+func (o *AccessLogOptions) Clone() *AccessLogOptions {
+	if o == nil {
+		return nil
+	}
+	c := *o
+	c.Fields = slices.Clone(o.Fields)
+	c.Rotation = o.Rotation.Clone()
+	return &c
+}
+
+// Equal returns true if the AccessLogOptions are equal
+func (o *AccessLogOptions) Equal(o2 *AccessLogOptions) bool {
+	if o == nil || o2 == nil {
+		return o == o2
+	}
+	return o.File == o2.File && o.Format == o2.Format &&
+		o.SampleRate == o2.SampleRate && slices.Equal(o.Fields, o2.Fields) &&
+		o.Rotation.Equal(o2.Rotation)
+}
+
+// Initialize prepares the AccessLogOptions for use
+func (o *AccessLogOptions) Initialize() {
+	if o == nil {
+		return
+	}
+	if o.Format == "" {
+		o.Format = DefaultLogFormat
+	}
+	if o.Rotation == nil {
+		o.Rotation = NewRotation()
+	}
+}
+
+// Validate validates the AccessLogOptions
+func (o *AccessLogOptions) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if !validFormat(o.Format) {
+		return false, ErrInvalidLogFormat
+	}
+	if o.SampleRate <= 0 || o.SampleRate > 1 {
+		return false, ErrInvalidSampleRate
+	}
+	for _, f := range o.Fields {
+		if !slices.Contains(AccessLogFields, f) {
+			return false, fmt.Errorf("%w: %s", ErrInvalidAccessLogField, f)
+		}
+	}
+	return o.Rotation.Validate()
+}
+
+// UnmarshalYAML applies defaults before decoding an access_log configuration
+// block.
+func (o *AccessLogOptions) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions AccessLogOptions
+	lo := loadOptions(*(NewAccessLog()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = AccessLogOptions(lo)
+	return nil
+}
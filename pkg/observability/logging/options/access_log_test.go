@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestAccessLogValidate(t *testing.T) {
+	t.Parallel()
+
+	var o *AccessLogOptions
+	ok, err := o.Validate()
+	require.True(t, ok)
+	require.NoError(t, err)
+
+	o = NewAccessLog()
+	o.Fields = []string{"clientIP", "code"}
+	ok, err = o.Validate()
+	require.True(t, ok)
+	require.NoError(t, err)
+
+	tests := []struct {
+		mutate   func(*AccessLogOptions)
+		expected error
+	}{
+		{func(o *AccessLogOptions) { o.Format = "xml" }, ErrInvalidLogFormat},
+		{func(o *AccessLogOptions) { o.SampleRate = 0 }, ErrInvalidSampleRate},
+		{func(o *AccessLogOptions) { o.SampleRate = 1.5 }, ErrInvalidSampleRate},
+		{func(o *AccessLogOptions) { o.Fields = []string{"bogus"} }, ErrInvalidAccessLogField},
+		{func(o *AccessLogOptions) { o.Rotation.MaxSizeMB = -1 }, ErrInvalidMaxSizeMB},
+	}
+	for _, test := range tests {
+		o := NewAccessLog()
+		test.mutate(o)
+		ok, err := o.Validate()
+		require.False(t, ok)
+		require.True(t, errors.Is(err, test.expected))
+	}
+}
+
+func TestAccessLogCloneEqual(t *testing.T) {
+	t.Parallel()
+
+	var o *AccessLogOptions
+	require.Nil(t, o.Clone())
+	require.True(t, o.Equal(nil))
+
+	o = NewAccessLog()
+	o.Fields = []string{"code"}
+	c := o.Clone()
+	require.True(t, o.Equal(c))
+	require.NotSame(t, o.Rotation, c.Rotation)
+	c.Fields[0] = "size"
+	require.Equal(t, "code", o.Fields[0])
+	require.False(t, o.Equal(c))
+	require.False(t, o.Equal(nil))
+}
+
+func TestAccessLogUnmarshalYAML(t *testing.T) {
+	t.Parallel()
+
+	o := &AccessLogOptions{}
+	require.NoError(t, yaml.Unmarshal([]byte("file: /tmp/access.log\nformat: json\n"), o))
+	require.Equal(t, "/tmp/access.log", o.File)
+	require.Equal(t, FormatJSON, o.Format)
+	require.Equal(t, DefaultAccessLogSampleRate, o.SampleRate)
+	require.True(t, o.Rotation.Equal(NewRotation()))
+
+	o = &AccessLogOptions{}
+	o.Initialize()
+	require.Equal(t, DefaultLogFormat, o.Format)
+	require.NotNil(t, o.Rotation)
+}
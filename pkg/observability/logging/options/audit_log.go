@@ -0,0 +1,65 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+// AuditLogOptions defines a log of management API actions, like cache purges,
+// configuration reloads and configuration dumps, written as JSON lines
+type AuditLogOptions struct {
+	// File provides the filepath to the audit log. Set as empty string to
+	// log to console
+	File string `yaml:"file,omitempty"`
+	// Rotation configures the rotation of File
+	Rotation *RotationOptions `yaml:"rotation,omitempty"`
+}
+
+// NewAuditLog returns a new AuditLogOptions with the default values
+func NewAuditLog() *AuditLogOptions {
+	return &AuditLogOptions{Rotation: NewRotation()}
+}
+
+// Clone returns a copy of the AuditLogOptions
+func (o *AuditLogOptions) Clone() *AuditLogOptions {
+	if o == nil {
+		return nil
+	}
+	c := *o
+	c.Rotation = o.Rotation.Clone()
+	return &c
+}
+
+// Equal returns true if the AuditLogOptions are equal
+func (o *AuditLogOptions) Equal(o2 *AuditLogOptions) bool {
+	if o == nil || o2 == nil {
+		return o == o2
+	}
+	return o.File == o2.File && o.Rotation.Equal(o2.Rotation)
+}
+
+// Initialize prepares the AuditLogOptions for use
+func (o *AuditLogOptions) Initialize() {
+	if o != nil && o.Rotation == nil {
+		o.Rotation = NewRotation()
+	}
+}
+
+// Validate validates the AuditLogOptions
+func (o *AuditLogOptions) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	return o.Rotation.Validate()
+}
@@ -22,4 +22,18 @@ const (
 	DefaultLogFile = ""
 	// DefaultLogLevel is the default level for logging
 	DefaultLogLevel = "INFO"
+	// DefaultLogFormat is the default format for log lines
+	DefaultLogFormat = FormatLogfmt
+	// DefaultMaxSizeMB is the default size in megabytes at which a log file is
+	// rotated
+	DefaultMaxSizeMB = 256
+	// DefaultMaxBackups is the default number of rotated log files to retain
+	DefaultMaxBackups = 80
+	// DefaultMaxAgeDays is the default number of days to retain rotated log files
+	DefaultMaxAgeDays = 7
+	// DefaultCompress is the default for whether rotated log files are compressed
+	DefaultCompress = true
+	// DefaultAccessLogSampleRate is the default fraction of requests written to
+	// the access log
+	DefaultAccessLogSampleRate = 1.0
 )
@@ -24,39 +24,92 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
 )
 
+// Log line formats
+const (
+	// FormatLogfmt formats log lines as space-separated key=value pairs
+	FormatLogfmt = "logfmt"
+	// FormatJSON formats log lines as JSON objects
+	FormatJSON = "json"
+)
+
 // Options is a collection of Logging options
 type Options struct {
 	// LogFile provides the filepath to the instances's logfile. Set as empty string to Log to Console
 	LogFile string `yaml:"log_file,omitempty"`
 	// LogLevel provides the most granular level (e.g., DEBUG, INFO, ERROR) to log
 	LogLevel string `yaml:"log_level,omitempty"`
+	// Format is the format of log lines: 'logfmt' (the default) or 'json'
+	Format string `yaml:"format,omitempty"`
+	// Rotation configures the rotation of LogFile
+	Rotation *RotationOptions `yaml:"rotation,omitempty"`
+	// AccessLog configures a separate log of client-facing and upstream
+	// requests. Omit to disable
+	AccessLog *AccessLogOptions `yaml:"access_log,omitempty"`
+	// AuditLog configures a log of management API actions. Omit to disable
+	AuditLog *AuditLogOptions `yaml:"audit_log,omitempty"`
 }
 
 var _ types.ConfigOptions[Options] = &Options{}
 
-var ErrInvalidLogLevel = errors.New("invalid log level")
+var (
+	ErrInvalidLogLevel  = errors.New("invalid log level")
+	ErrInvalidLogFormat = errors.New("log format must be 'logfmt' or 'json'")
+)
 
 // New returns a new Options with default values
 func New() *Options {
-	return &Options{LogLevel: DefaultLogLevel, LogFile: DefaultLogFile}
+	return &Options{
+		LogLevel: DefaultLogLevel,
+		LogFile:  DefaultLogFile,
+		Format:   DefaultLogFormat,
+		Rotation: NewRotation(),
+	}
 }
 
 // Clone returns a clone of the Options
 func (o *Options) Clone() *Options {
-	return pointers.Clone(o)
+	c := pointers.Clone(o)
+	if c != nil {
+		c.Rotation = o.Rotation.Clone()
+		c.AccessLog = o.AccessLog.Clone()
+		c.AuditLog = o.AuditLog.Clone()
+	}
+	return c
 }
 
 func (o *Options) Initialize(_ string) error {
 	if o.LogLevel == "" {
 		o.LogLevel = DefaultLogLevel
 	}
+	if o.Format == "" {
+		o.Format = DefaultLogFormat
+	}
+	if o.Rotation == nil {
+		o.Rotation = NewRotation()
+	}
+	o.AccessLog.Initialize()
+	o.AuditLog.Initialize()
 	return nil
 }
 
 func (o *Options) Validate() (bool, error) {
 	switch strings.ToLower(o.LogLevel) {
 	case "error", "warn", "fatal", "info", "debug":
-		return true, nil
+	default:
+		return false, ErrInvalidLogLevel
+	}
+	if o.Format != "" && !validFormat(o.Format) {
+		return false, ErrInvalidLogFormat
+	}
+	if ok, err := o.Rotation.Validate(); !ok {
+		return false, err
 	}
-	return false, ErrInvalidLogLevel
+	if ok, err := o.AccessLog.Validate(); !ok {
+		return false, err
+	}
+	return o.AuditLog.Validate()
+}
+
+func validFormat(format string) bool {
+	return format == FormatLogfmt || format == FormatJSON
 }
@@ -55,6 +55,9 @@ func TestInitialize(t *testing.T) {
 	require.NoError(t, o.Initialize(""))
 	require.Equal(t, DefaultLogLevel, o.LogLevel)
 
+	require.Equal(t, DefaultLogFormat, o.Format)
+	require.Equal(t, NewRotation(), o.Rotation)
+
 	o = &Options{LogLevel: "warn", LogFile: "/var/log/trickster.log"}
 	require.NoError(t, o.Initialize(""))
 	require.Equal(t, "warn", o.LogLevel)
@@ -87,3 +90,52 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateNested(t *testing.T) {
+	t.Parallel()
+
+	o := New()
+	o.Format = "xml"
+	ok, err := o.Validate()
+	require.False(t, ok)
+	require.True(t, errors.Is(err, ErrInvalidLogFormat))
+
+	o = New()
+	o.Rotation.MaxSizeMB = 0
+	ok, err = o.Validate()
+	require.False(t, ok)
+	require.True(t, errors.Is(err, ErrInvalidMaxSizeMB))
+
+	o = New()
+	o.AuditLog = &AuditLogOptions{}
+	require.NoError(t, o.Initialize(""))
+	require.Equal(t, NewRotation(), o.AuditLog.Rotation)
+	o.AuditLog.Rotation.MaxAgeDays = -1
+	ok, err = o.Validate()
+	require.False(t, ok)
+	require.True(t, errors.Is(err, ErrInvalidMaxAgeDays))
+
+	o = New()
+	o.AccessLog = &AccessLogOptions{}
+	require.NoError(t, o.Initialize(""))
+	ok, err = o.Validate()
+	require.False(t, ok)
+	require.True(t, errors.Is(err, ErrInvalidSampleRate))
+}
+
+func TestCloneNested(t *testing.T) {
+	t.Parallel()
+
+	o := New()
+	o.AccessLog = NewAccessLog()
+	o.AuditLog = NewAuditLog()
+	c := o.Clone()
+	require.NotSame(t, o.Rotation, c.Rotation)
+	require.NotSame(t, o.AccessLog, c.AccessLog)
+	require.True(t, o.AccessLog.Equal(c.AccessLog))
+	require.NotSame(t, o.AuditLog.Rotation, c.AuditLog.Rotation)
+	require.True(t, o.AuditLog.Equal(c.AuditLog))
+	c.AuditLog.File = "/tmp/audit.log"
+	require.False(t, o.AuditLog.Equal(c.AuditLog))
+	require.False(t, o.AuditLog.Equal(nil))
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+var (
+	ErrInvalidMaxSizeMB  = errors.New("rotation max_size_mb must be greater than 0")
+	ErrInvalidMaxBackups = errors.New("rotation max_backups must not be negative")
+	ErrInvalidMaxAgeDays = errors.New("rotation max_age_days must not be negative")
+)
+
+// RotationOptions defines the size and age-based rotation of a log file
+type RotationOptions struct {
+	// MaxSizeMB is the size in megabytes at which the log file is rotated
+	MaxSizeMB int `yaml:"max_size_mb,omitempty"`
+	// MaxBackups is the number of rotated log files to retain. 0 retains all
+	MaxBackups int `yaml:"max_backups,omitempty"`
+	// MaxAgeDays is the number of days to retain rotated log files. 0 retains
+	// them regardless of age
+	MaxAgeDays int `yaml:"max_age_days,omitempty"`
+	// Compress indicates whether rotated log files are gzip-compressed
+	Compress bool `yaml:"compress"`
+}
+
+// NewRotation returns a new RotationOptions with the default values
+func NewRotation() *RotationOptions {
+	return &RotationOptions{
+		MaxSizeMB:  DefaultMaxSizeMB,
+		MaxBackups: DefaultMaxBackups,
+		MaxAgeDays: DefaultMaxAgeDays,
+		Compress:   DefaultCompress,
+	}
+}
+
+// Clone returns a copy of the RotationOptions
+func (o *RotationOptions) Clone() *RotationOptions {
+	if o == nil {
+		return nil
+	}
+	return pointers.Clone(o)
+}
+
+// Equal returns true if the RotationOptions are equal
+func (o *RotationOptions) Equal(o2 *RotationOptions) bool {
+	if o == nil || o2 == nil {
+		return o == o2
+	}
+	return *o == *o2
+}
+
+// Validate validates the RotationOptions
+func (o *RotationOptions) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.MaxSizeMB <= 0 {
+		return false, ErrInvalidMaxSizeMB
+	}
+	if o.MaxBackups < 0 {
+		return false, ErrInvalidMaxBackups
+	}
+	if o.MaxAgeDays < 0 {
+		return false, ErrInvalidMaxAgeDays
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a rotation configuration
+// block.
+func (o *RotationOptions) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions RotationOptions
+	lo := loadOptions(*(NewRotation()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = RotationOptions(lo)
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+func TestRotationValidate(t *testing.T) {
+	t.Parallel()
+
+	var o *RotationOptions
+	ok, err := o.Validate()
+	require.True(t, ok)
+	require.NoError(t, err)
+
+	o = NewRotation()
+	ok, err = o.Validate()
+	require.True(t, ok)
+	require.NoError(t, err)
+
+	tests := []struct {
+		mutate   func(*RotationOptions)
+		expected error
+	}{
+		{func(o *RotationOptions) { o.MaxSizeMB = 0 }, ErrInvalidMaxSizeMB},
+		{func(o *RotationOptions) { o.MaxBackups = -1 }, ErrInvalidMaxBackups},
+		{func(o *RotationOptions) { o.MaxAgeDays = -1 }, ErrInvalidMaxAgeDays},
+	}
+	for _, test := range tests {
+		o := NewRotation()
+		test.mutate(o)
+		ok, err := o.Validate()
+		require.False(t, ok)
+		require.True(t, errors.Is(err, test.expected))
+	}
+}
+
+func TestRotationEqual(t *testing.T) {
+	t.Parallel()
+
+	var o *RotationOptions
+	require.True(t, o.Equal(nil))
+	require.False(t, o.Equal(NewRotation()))
+	o = NewRotation()
+	c := o.Clone()
+	require.NotSame(t, o, c)
+	require.True(t, o.Equal(c))
+	c.Compress = false
+	require.False(t, o.Equal(c))
+}
+
+func TestRotationUnmarshalYAML(t *testing.T) {
+	t.Parallel()
+
+	o := &RotationOptions{}
+	require.NoError(t, yaml.Unmarshal([]byte("max_size_mb: 64\n"), o))
+	require.Equal(t, 64, o.MaxSizeMB)
+	require.Equal(t, DefaultMaxBackups, o.MaxBackups)
+	require.Equal(t, DefaultMaxAgeDays, o.MaxAgeDays)
+	require.True(t, o.Compress)
+
+	require.NoError(t, yaml.Unmarshal([]byte("compress: false\n"), o))
+	require.False(t, o.Compress)
+}
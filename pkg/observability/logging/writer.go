@@ -0,0 +1,53 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logging
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
+
+	lumberjack "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// newWriter returns a writer to the provided log file, rotated per the
+// provided options, or to the console if the file is empty. When instanceID
+// is greater than 0, it is inserted into the file name.
+func newWriter(logFile string, instanceID int,
+	ro *options.RotationOptions,
+) io.Writer {
+	if logFile == "" {
+		return os.Stdout
+	}
+	if instanceID > 0 {
+		logFile = strings.Replace(logFile, ".log",
+			"."+strconv.Itoa(instanceID)+".log", 1)
+	}
+	if ro == nil {
+		ro = options.NewRotation()
+	}
+	return &lumberjack.Logger{
+		Filename:   logFile,
+		MaxSize:    ro.MaxSizeMB,
+		MaxBackups: ro.MaxBackups,
+		MaxAge:     ro.MaxAgeDays,
+		Compress:   ro.Compress,
+	}
+}
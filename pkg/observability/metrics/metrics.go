@@ -36,6 +36,8 @@ const (
 	albSubsystem      = "alb"
 	healthSubsystem   = "healthcheck"
 	sqlSubsystem      = "sql"
+	warmingSubsystem  = "warming"
+	ruleSubsystem     = "rule"
 )
 
 // Default histogram buckets used by trickster
@@ -240,6 +242,29 @@ var (
 		[]string{"cache_name", "provider"},
 	)
 
+	// TenantCacheRequests counts requests to tenant-partitioned backends by
+	// tenant and cache lookup status
+	TenantCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: cacheSubsystem,
+			Name:      "tenant_requests_total",
+			Help:      "Count of requests to tenant-partitioned backends, by backend, tenant and cache status.",
+		},
+		[]string{"backend_name", "tenant", "cache_status"},
+	)
+
+	// TenantCacheWrites counts cache writes for tenant-partitioned backends by tenant
+	TenantCacheWrites = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: cacheSubsystem,
+			Name:      "tenant_writes_total",
+			Help:      "Count of cache writes for tenant-partitioned backends, by backend and tenant.",
+		},
+		[]string{"backend_name", "tenant"},
+	)
+
 	// CacheMaxObjects is a Gauge for the Trickster cache's Max Object Threshold for triggering an eviction exercise
 	CacheMaxObjects = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -333,6 +358,247 @@ var (
 		[]string{"backend_name"},
 	)
 
+	// ProxyQueryCostRejections is a counter for requests rejected due to
+	// exceeding a query_cost limit
+	ProxyQueryCostRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "query_cost_rejections_total",
+			Help:      "Trickster total number of queries rejected due to exceeding a query_cost limit, by limit.",
+		},
+		[]string{"backend_name", "limit"},
+	)
+
+	// ProxyRateLimited is a counter for requests rejected by a rate limit budget
+	ProxyRateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "rate_limited_total",
+			Help:      "Trickster total number of requests rejected due to exceeding a rate limit.",
+		},
+		[]string{"backend_name", "budget"},
+	)
+
+	// ProxyUpstreamQueueDepth is a gauge of requests waiting in a backend's
+	// upstream queue
+	ProxyUpstreamQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_queue_depth",
+			Help:      "Trickster number of requests waiting in the upstream queue.",
+		},
+		[]string{"backend_name"},
+	)
+
+	// ProxyUpstreamQueueWait is a histogram of the time requests waited in a
+	// backend's upstream queue before being sent upstream
+	ProxyUpstreamQueueWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_queue_wait_seconds",
+			Help:      "Time in seconds requests waited in the upstream queue.",
+			Buckets:   defaultBuckets,
+		},
+		[]string{"backend_name", "class"},
+	)
+
+	// ProxyUpstreamQueueShed is a counter of requests shed by a backend's
+	// upstream queue
+	ProxyUpstreamQueueShed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_queue_shed_total",
+			Help:      "Trickster total number of requests shed by the upstream queue.",
+		},
+		[]string{"backend_name", "class", "reason"},
+	)
+
+	// ProxyCircuitBreakerState is a gauge of the state of a backend's
+	// circuit breaker
+	ProxyCircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "circuit_breaker_state",
+			Help:      "Trickster circuit breaker state: 0 is closed, 1 is half-open and 2 is open.",
+		},
+		[]string{"backend_name"},
+	)
+
+	// ProxyCircuitBreakerTransitions is a counter of circuit breaker state
+	// changes
+	ProxyCircuitBreakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "circuit_breaker_transitions_total",
+			Help:      "Trickster total number of circuit breaker state changes, by new state.",
+		},
+		[]string{"backend_name", "state"},
+	)
+
+	// ProxyCircuitBreakerRejections is a counter of upstream requests
+	// rejected by an open circuit breaker
+	ProxyCircuitBreakerRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "circuit_breaker_rejections_total",
+			Help:      "Trickster total number of upstream requests rejected by a circuit breaker.",
+		},
+		[]string{"backend_name"},
+	)
+
+	// ProxyUpstreamRetries is a counter of retried upstream requests
+	ProxyUpstreamRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_retries_total",
+			Help:      "Trickster total number of retried upstream requests, by reason.",
+		},
+		[]string{"backend_name", "reason"},
+	)
+
+	// ProxyUpstreamHedges is a counter of hedged upstream requests
+	ProxyUpstreamHedges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "upstream_hedged_requests_total",
+			Help:      "Trickster total number of hedged upstream requests, by whether they won.",
+		},
+		[]string{"backend_name", "outcome"},
+	)
+
+	// ProxyShadowRequests is a counter of requests considered for mirroring
+	// to a shadow backend
+	ProxyShadowRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "shadow_requests_total",
+			Help:      "Trickster total number of sampled requests mirrored to a shadow backend, by outcome.",
+		},
+		[]string{"backend_name", "shadow_backend", "outcome"},
+	)
+
+	// ProxyShadowComparisons is a counter of comparisons between primary and
+	// shadow responses
+	ProxyShadowComparisons = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "shadow_comparisons_total",
+			Help:      "Trickster total number of primary and shadow response comparisons, by result.",
+		},
+		[]string{"backend_name", "shadow_backend", "result"},
+	)
+
+	// ProxyShadowMismatches is a counter of differences found between primary
+	// and shadow responses
+	ProxyShadowMismatches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "shadow_mismatches_total",
+			Help:      "Trickster total number of primary and shadow response mismatches, by reason.",
+		},
+		[]string{"backend_name", "shadow_backend", "reason"},
+	)
+
+	// ProxyCacheVerifications is a counter of verifications of cached
+	// timeseries against the origin
+	ProxyCacheVerifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "cache_verifications_total",
+			Help:      "Trickster total number of cached timeseries verifications against the origin, by result.",
+		},
+		[]string{"backend_name", "result"},
+	)
+
+	// ProxyCacheVerificationDivergences is a counter of divergences found
+	// between cached timeseries and the origin
+	ProxyCacheVerificationDivergences = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: proxySubsystem,
+			Name:      "cache_verification_divergences_total",
+			Help:      "Trickster total number of divergences between cached timeseries and the origin, by kind.",
+		},
+		[]string{"backend_name", "kind"},
+	)
+
+	// RuleCaseMatches counts requests by the expression rule case they matched.
+	// The case label is "default" for requests that matched no case.
+	RuleCaseMatches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: ruleSubsystem,
+			Name:      "case_matches_total",
+			Help:      "Count of requests evaluated by expression rules, by rule and matched case.",
+		},
+		[]string{"rule_name", "case"},
+	)
+
+	// WarmingRequests counts background cache warming requests issued per
+	// backend. The status label is success or error.
+	WarmingRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: warmingSubsystem,
+			Name:      "requests_total",
+			Help:      "Count of background cache warming requests, by backend and status.",
+		},
+		[]string{"backend_name", "status"},
+	)
+
+	// WarmingTrackedQueries is the number of distinct queries currently
+	// tracked as warming candidates per backend.
+	WarmingTrackedQueries = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Subsystem: warmingSubsystem,
+			Name:      "tracked_queries",
+			Help:      "Number of distinct timeseries queries tracked for cache warming, by backend.",
+		},
+		[]string{"backend_name"},
+	)
+
+	// WarmingClientRequests counts client requests for tracked queries by
+	// whether the query was warmed since its previous client request. Comparing
+	// the cache_status distribution of warmed="true" versus warmed="false"
+	// shows the hit-rate improvement provided by warming.
+	WarmingClientRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: warmingSubsystem,
+			Name:      "client_requests_total",
+			Help:      "Count of client requests for warming-tracked queries, by backend, warmed state and cache status.",
+		},
+		[]string{"backend_name", "warmed", "cache_status"},
+	)
+
+	// WarmingDeferrals counts eligible warming candidates that were not
+	// issued in a cycle because the upstream QPS budget or max_queries was
+	// exhausted.
+	WarmingDeferrals = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Subsystem: warmingSubsystem,
+			Name:      "deferrals_total",
+			Help:      "Count of eligible cache warming candidates deferred due to budget limits, by backend.",
+		},
+		[]string{"backend_name"},
+	)
+
 	// SQLQueryAnalysis counts SQL analyzer classifications using bounded mode,
 	// dialect, and reason labels. Parse failures and OPC fallback are represented
 	// by the invalid_sql reason and object cache mode respectively.
@@ -588,6 +854,8 @@ func init() {
 	prometheus.MustRegister(CacheBytes)
 	prometheus.MustRegister(CacheMaxObjects)
 	prometheus.MustRegister(CacheMaxBytes)
+	prometheus.MustRegister(TenantCacheRequests)
+	prometheus.MustRegister(TenantCacheWrites)
 	prometheus.MustRegister(BuildInfo)
 	prometheus.MustRegister(LastReloadSuccessful)
 	prometheus.MustRegister(LastReloadSuccessfulTimestamp)
@@ -596,8 +864,28 @@ func init() {
 	prometheus.MustRegister(ReloadFailuresTotal)
 	prometheus.MustRegister(ReloadDurationSeconds)
 	prometheus.MustRegister(ProxyQueryRangeRejections)
+	prometheus.MustRegister(ProxyQueryCostRejections)
+	prometheus.MustRegister(ProxyRateLimited)
+	prometheus.MustRegister(ProxyUpstreamQueueDepth)
+	prometheus.MustRegister(ProxyUpstreamQueueWait)
+	prometheus.MustRegister(ProxyUpstreamQueueShed)
+	prometheus.MustRegister(ProxyCircuitBreakerState)
+	prometheus.MustRegister(ProxyCircuitBreakerTransitions)
+	prometheus.MustRegister(ProxyCircuitBreakerRejections)
+	prometheus.MustRegister(ProxyUpstreamRetries)
+	prometheus.MustRegister(ProxyUpstreamHedges)
+	prometheus.MustRegister(ProxyShadowRequests)
+	prometheus.MustRegister(ProxyShadowComparisons)
+	prometheus.MustRegister(ProxyShadowMismatches)
+	prometheus.MustRegister(ProxyCacheVerifications)
+	prometheus.MustRegister(ProxyCacheVerificationDivergences)
+	prometheus.MustRegister(RuleCaseMatches)
 	prometheus.MustRegister(SQLQueryAnalysis)
 	prometheus.MustRegister(SQLQueryRewriteFailures)
+	prometheus.MustRegister(WarmingRequests)
+	prometheus.MustRegister(WarmingTrackedQueries)
+	prometheus.MustRegister(WarmingClientRequests)
+	prometheus.MustRegister(WarmingDeferrals)
 }
 
 // Handler returns the http handler for the listener
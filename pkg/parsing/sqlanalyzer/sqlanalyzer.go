@@ -115,6 +115,14 @@ type DialectAnalyzer interface {
 	Analyze(statement string, now time.Time) Analysis
 }
 
+// ParameterizedAnalyzer is optionally implemented by a DialectAnalyzer whose
+// dialect supports server-side query parameters. params maps parameter names
+// to their request-provided values, which the analyzer may resolve into the
+// plan's bounds.
+type ParameterizedAnalyzer interface {
+	AnalyzeWithParameters(statement string, params map[string]string, now time.Time) Analysis
+}
+
 // Bound records a cadence-normalized time boundary without discarding its
 // comparator semantics.
 type Bound struct {
@@ -147,6 +155,10 @@ type QueryPlan struct {
 	// it, and no shared code interprets it.
 	OutputFormat byte
 	Renderer     ExtentRenderer
+	// Parameters are the request-provided query parameter values that the
+	// rendered query still references. Parameters resolved into the bounds
+	// are omitted. The values vary the cache key.
+	Parameters map[string]string
 }
 
 // ErrMissingRenderer indicates that a plan cannot produce an origin query.
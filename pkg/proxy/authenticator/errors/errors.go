@@ -24,4 +24,10 @@ var (
 	ErrInvalidName              = errors.New("invalid authenticator name")
 	ErrInvalidProvider          = errors.New("invalid authenticator provider name")
 	ErrInvalidUsersFile         = errors.New("users does not exist or is not readable")
+	ErrInvalidUsersFileFormat   = errors.New("invalid users file format")
+	ErrInvalidToken             = errors.New("invalid token")
+	ErrInvalidKeys              = errors.New("invalid verification keys")
+	ErrNoVerificationKeys       = errors.New("no verification keys configured")
+	ErrMissingUsernameClaim     = errors.New("token is missing the username claim")
+	ErrUsersNotSupported        = errors.New("authenticator provider does not support users lists")
 )
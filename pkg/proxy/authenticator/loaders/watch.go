@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loaders
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FileWatcher detects modifications to a credentials file by comparing its
+// modification time and size, checking no more than once per interval. It
+// does not start any goroutines, so callers check it inline (e.g., while
+// authenticating a request) and it needs no cleanup when discarded.
+type FileWatcher struct {
+	path     string
+	interval time.Duration
+	next     atomic.Int64
+	mtx      sync.Mutex
+	modTime  time.Time
+	size     int64
+}
+
+// NewFileWatcher returns a FileWatcher for path that checks for changes at
+// most once per interval. The file's current state is the baseline.
+func NewFileWatcher(path string, interval time.Duration) *FileWatcher {
+	w := &FileWatcher{path: path, interval: interval}
+	if fi, err := os.Stat(path); err == nil {
+		w.modTime, w.size = fi.ModTime(), fi.Size()
+	}
+	w.next.Store(time.Now().Add(interval).UnixNano())
+	return w
+}
+
+// Path returns the path of the watched file
+func (w *FileWatcher) Path() string {
+	return w.path
+}
+
+// Changed returns true if the file has been modified since the baseline or
+// the last time Changed returned true. It returns false without checking the
+// file when the interval since the last check has not elapsed, or when the
+// file is missing (e.g., mid-rotation), so the previous contents stay in use.
+func (w *FileWatcher) Changed(now time.Time) bool {
+	next := w.next.Load()
+	if now.UnixNano() < next ||
+		!w.next.CompareAndSwap(next, now.Add(w.interval).UnixNano()) {
+		return false
+	}
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return false
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if fi.ModTime().Equal(w.modTime) && fi.Size() == w.size {
+		return false
+	}
+	w.modTime, w.size = fi.ModTime(), fi.Size()
+	return true
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package loaders
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	if err := os.WriteFile(path, []byte("alice,secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w := NewFileWatcher(path, time.Minute)
+	if w.Path() != path {
+		t.Errorf("expected %s got %s", path, w.Path())
+	}
+	now := time.Now()
+	if w.Changed(now.Add(2 * time.Minute)) {
+		t.Error("expected no change")
+	}
+	if err := os.WriteFile(path, []byte("alice,secret\nbob,hash\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// the interval has not elapsed since the last check
+	if w.Changed(now.Add(2*time.Minute + time.Second)) {
+		t.Error("expected check to be rate limited")
+	}
+	if !w.Changed(now.Add(4 * time.Minute)) {
+		t.Error("expected change")
+	}
+	if w.Changed(now.Add(6 * time.Minute)) {
+		t.Error("expected change to be reported only once")
+	}
+	// a missing file is not reported as a change
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if w.Changed(now.Add(8 * time.Minute)) {
+		t.Error("expected missing file to not be a change")
+	}
+}
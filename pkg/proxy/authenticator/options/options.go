@@ -33,6 +33,10 @@ import (
 
 var restrictedNames = sets.New([]string{"", "none"})
 
+// sensitiveProviderData is the list of provider-specific config fields that
+// may hold credentials and are redacted by CloneYAMLSafe
+var sensitiveProviderData = []string{"keys"}
+
 type Options struct {
 	Name            string                      `yaml:"-"` // populated from the Lookup key
 	Provider        types.Provider              `yaml:"provider"`
@@ -72,9 +76,30 @@ func (o *Options) CloneYAMLSafe() *Options {
 	for i := range userNames {
 		out.Users[fmt.Sprintf("user%d", i+1)] = "*****"
 	}
+	for _, k := range sensitiveProviderData {
+		if _, ok := out.ProviderData[k]; ok {
+			out.ProviderData[k] = "*****"
+		}
+	}
 	return out
 }
 
+// DecodeProviderData decodes the provider-specific config into v, which
+// should be a pointer to a yaml-tagged struct
+func (o *Options) DecodeProviderData(v any) error {
+	if len(o.ProviderData) == 0 {
+		return nil
+	}
+	b, err := yaml.Marshal(o.ProviderData)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("invalid %s authenticator config: %w", o.Provider, err)
+	}
+	return nil
+}
+
 func (o *Options) Initialize() error {
 	return nil
 }
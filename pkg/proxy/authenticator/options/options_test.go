@@ -39,3 +39,20 @@ func TestCloneYAMLSafe(t *testing.T) {
 		t.Fatalf("CloneYAMLSafe mutated original users: %#v", o.Users)
 	}
 }
+
+func TestCloneYAMLSafeProviderData(t *testing.T) {
+	o := &Options{ProviderData: map[string]any{
+		"issuer": "https://issuer.example.com",
+		"keys":   []any{map[string]any{"secret": "shh"}},
+	}}
+	got := o.CloneYAMLSafe()
+	if got.ProviderData["keys"] != "*****" {
+		t.Fatalf("expected redacted keys, got %#v", got.ProviderData["keys"])
+	}
+	if got.ProviderData["issuer"] != "https://issuer.example.com" {
+		t.Fatalf("unexpected issuer: %#v", got.ProviderData["issuer"])
+	}
+	if _, ok := o.ProviderData["keys"].([]any); !ok {
+		t.Fatalf("CloneYAMLSafe mutated original provider data: %#v", o.ProviderData)
+	}
+}
@@ -0,0 +1,378 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package apikey provides an Authenticator that validates API Keys, provided
+// in a request header or query parameter, against a store of hashed keys
+package apikey
+
+import (
+	"crypto/sha256"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ct "github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/errors"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/cred"
+	ae "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/errors"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/loaders"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+)
+
+const ID types.Provider = "apikey"
+
+const optionsField = "options"
+
+const (
+	// DefaultHeader is the request header read for the API Key when header is
+	// not configured
+	DefaultHeader = "X-API-Key"
+	// DefaultReloadInterval is how often the users file is checked for changes
+	// when reloadInterval is not configured
+	DefaultReloadInterval = 10 * time.Second
+)
+
+// Config is the provider-specific configuration for the API Key
+// Authenticator, which is read from the 'config' section of the Authenticator
+// Options
+type Config struct {
+	// Header is the request header that provides the API Key
+	Header string `yaml:"header"`
+	// QueryParam is the optional URL query parameter that provides the API Key
+	QueryParam string `yaml:"queryParam"`
+	// ReloadInterval is the minimum time between checks of the users file for
+	// changes. A negative value disables reloading.
+	ReloadInterval *time.Duration `yaml:"reloadInterval"`
+}
+
+// keyStore is an immutable set of principals and their hashed secrets. Keys
+// have the form <principal>.<secret>, so a key is only ever compared against
+// the hash of the principal it names. Keys that have been verified are
+// memoized by digest, so the (intentionally slow) hash comparison only occurs
+// on a key's first use.
+type keyStore struct {
+	users    types.CredentialsManifest
+	verified sync.Map // map[[sha256.Size]byte]string
+}
+
+type Authenticator struct {
+	store            atomic.Pointer[keyStore]
+	mtx              sync.Mutex // serializes changes to the store
+	embedded         types.CredentialsManifest
+	usersFile        string
+	usersFileFormat  types.CredentialsFileFormat
+	watcher          *loaders.FileWatcher
+	reloadInterval   time.Duration
+	header           string
+	queryParam       string
+	extractCredsFunc types.ExtractCredsFunc
+	setCredsFunc     types.SetCredentialsFunc
+	proxyPreserve    bool
+	observeOnly      bool
+}
+
+func RegistryEntry() types.RegistryEntry {
+	return types.RegistryEntry{Provider: ID, New: New}
+}
+
+func New(data map[string]any) (types.Authenticator, error) {
+	return NewPtr(data)
+}
+
+func NewPtr(data map[string]any) (*Authenticator, error) {
+	var opts *options.Options
+	if data != nil {
+		if v, ok := data[optionsField]; ok && v != nil {
+			opts, _ = v.(*options.Options)
+		}
+	}
+	if opts == nil {
+		return nil, errors.ErrInvalidOptions
+	}
+	cfg := &Config{}
+	if err := opts.DecodeProviderData(cfg); err != nil {
+		return nil, err
+	}
+	a := &Authenticator{
+		header:        cfg.Header,
+		queryParam:    cfg.QueryParam,
+		embedded:      esLookup(opts.Users).ToCredentialsManifest(),
+		proxyPreserve: opts.ProxyPreserve,
+		observeOnly:   opts.ObserveOnly,
+	}
+	if a.header == "" {
+		a.header = DefaultHeader
+	}
+	a.store.Store(newKeyStore(a.embedded))
+	if opts.UsersFile != "" {
+		if !types.IsValidCredentialsFileFormat(opts.UsersFileFormat) {
+			return nil, ae.ErrInvalidUsersFileFormat
+		}
+		if err := a.LoadUsers(opts.UsersFile, opts.UsersFileFormat, true); err != nil {
+			return nil, err
+		}
+		a.reloadInterval = DefaultReloadInterval
+		if cfg.ReloadInterval != nil {
+			a.reloadInterval = *cfg.ReloadInterval
+		}
+		if a.reloadInterval >= 0 {
+			a.watcher = loaders.NewFileWatcher(opts.UsersFile, a.reloadInterval)
+		}
+	}
+	return a, nil
+}
+
+func newKeyStore(users types.CredentialsManifest) *keyStore {
+	if users == nil {
+		users = make(types.CredentialsManifest)
+	}
+	return &keyStore{users: users}
+}
+
+// principal returns the name of the principal that key belongs to. A key that
+// does not name a known principal is rejected without any hash comparison.
+func (ks *keyStore) principal(key string) (string, bool) {
+	digest := sha256.Sum256([]byte(key))
+	if v, ok := ks.verified.Load(digest); ok {
+		return v.(string), true
+	}
+	i := strings.LastIndexByte(key, '.')
+	if i <= 0 || i == len(key)-1 {
+		return "", false
+	}
+	name, secret := key[:i], key[i+1:]
+	hash, ok := ks.users[name]
+	if !ok || cred.VerifyPassword(hash, secret) != nil {
+		return "", false
+	}
+	ks.verified.Store(digest, name)
+	return name, true
+}
+
+func failedResult() *types.AuthResult {
+	return &types.AuthResult{Status: types.AuthFailed}
+}
+
+// Authenticate checks the request's API Key against the key store
+func (a *Authenticator) Authenticate(r *http.Request) (*types.AuthResult, error) {
+	a.checkReload()
+	_, key, err := a.ExtractCredentials(r)
+	if err != nil && !a.observeOnly {
+		return failedResult(), err
+	}
+	if key == "" {
+		if a.observeOnly {
+			return &types.AuthResult{Status: types.AuthObserved}, nil
+		}
+		return failedResult(), ae.ErrInvalidCredentials
+	}
+	u, ok := a.store.Load().principal(key)
+	if a.observeOnly {
+		return &types.AuthResult{Username: u, Status: types.AuthObserved}, nil
+	}
+	if !ok {
+		return failedResult(), ae.ErrInvalidCredentials
+	}
+	return &types.AuthResult{Username: u, Status: types.AuthSuccess}, nil
+}
+
+// checkReload reloads the users file when the watcher detects a change. If
+// the file cannot be loaded, the existing keys remain in use.
+func (a *Authenticator) checkReload() {
+	if a.watcher == nil || !a.watcher.Changed(time.Now()) {
+		return
+	}
+	err := a.LoadUsers(a.usersFile, a.usersFileFormat, true)
+	if err != nil {
+		logger.Warn("api key users file reload failed",
+			logging.Pairs{"usersFile": a.usersFile, "detail": err.Error()})
+		return
+	}
+	logger.Info("api key users file reloaded",
+		logging.Pairs{"usersFile": a.usersFile, "principals": len(a.store.Load().users)})
+}
+
+// Clone clones a new Authenticator (i) from a
+func (a *Authenticator) Clone() types.Authenticator {
+	return a.ClonePtr()
+}
+
+// ClonePtr returns a new, independent clone of the Authenticator. The clone
+// shares the current key store (which is never modified in place) and watches
+// the users file independently.
+func (a *Authenticator) ClonePtr() *Authenticator {
+	out := &Authenticator{
+		embedded:         maps.Clone(a.embedded),
+		usersFile:        a.usersFile,
+		usersFileFormat:  a.usersFileFormat,
+		reloadInterval:   a.reloadInterval,
+		header:           a.header,
+		queryParam:       a.queryParam,
+		extractCredsFunc: a.extractCredsFunc,
+		setCredsFunc:     a.setCredsFunc,
+		proxyPreserve:    a.proxyPreserve,
+		observeOnly:      a.observeOnly,
+	}
+	out.store.Store(a.store.Load())
+	if a.watcher != nil {
+		out.watcher = loaders.NewFileWatcher(a.usersFile, a.reloadInterval)
+	}
+	return out
+}
+
+func (a *Authenticator) ProxyPreserve() bool {
+	return a.proxyPreserve
+}
+
+func (a *Authenticator) Sanitize(r *http.Request) {
+	if a.proxyPreserve {
+		return
+	}
+	a.RemoveCredentials(r)
+}
+
+// RemoveCredentials implements types.CredentialRemover by stripping the API
+// Key header and query parameter from r
+func (a *Authenticator) RemoveCredentials(r *http.Request) {
+	r.Header.Del(a.header)
+	if a.queryParam != "" {
+		q := r.URL.Query()
+		if q.Has(a.queryParam) {
+			q.Del(a.queryParam)
+			r.URL.RawQuery = q.Encode()
+		}
+	}
+}
+
+// ExtractCredentials returns the API Key from the request as the credential.
+// The principal is not known until the key is verified, so the username is
+// always empty.
+func (a *Authenticator) ExtractCredentials(r *http.Request) (
+	string, string, error,
+) {
+	if a.extractCredsFunc != nil {
+		return a.extractCredsFunc(r)
+	}
+	if k := r.Header.Get(a.header); k != "" {
+		return "", k, nil
+	}
+	if a.queryParam != "" {
+		if k := r.URL.Query().Get(a.queryParam); k != "" {
+			return "", k, nil
+		}
+	}
+	return "", "", ae.ErrInvalidCredentials
+}
+
+func (a *Authenticator) SetExtractCredentialsFunc(f types.ExtractCredsFunc) {
+	a.extractCredsFunc = f
+}
+
+// SetCredentials replaces the request's API Key with the provided credential,
+// using the query parameter if the request provided its key there
+func (a *Authenticator) SetCredentials(
+	r *http.Request, user, credential string,
+) error {
+	if a.setCredsFunc != nil {
+		return a.setCredsFunc(r, user, credential)
+	}
+	if a.queryParam != "" && r.Header.Get(a.header) == "" {
+		q := r.URL.Query()
+		if q.Has(a.queryParam) {
+			q.Set(a.queryParam, credential)
+			r.URL.RawQuery = q.Encode()
+			return nil
+		}
+	}
+	r.Header.Set(a.header, credential)
+	return nil
+}
+
+func (a *Authenticator) SetSetCredentialsFunc(f types.SetCredentialsFunc) {
+	a.setCredsFunc = f
+}
+
+func (a *Authenticator) SetObserveOnly(t bool) {
+	a.observeOnly = t
+}
+
+func (a *Authenticator) IsObserveOnly() bool {
+	return a.observeOnly
+}
+
+// LoadUsers loads the principals and hashed keys from the users file. When
+// replace is true, the loaded principals replace those previously loaded from
+// any file, and embedded principals are retained. Either way, the key store
+// is swapped atomically, so in-flight requests are unaffected.
+func (a *Authenticator) LoadUsers(
+	path string, ff types.CredentialsFileFormat, replace bool,
+) error {
+	users, err := loaders.LoadData(path, ff)
+	if err != nil {
+		return err
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	var next types.CredentialsManifest
+	if replace {
+		next = maps.Clone(a.embedded)
+		if next == nil {
+			next = make(types.CredentialsManifest, len(users))
+		}
+	} else {
+		next = maps.Clone(a.store.Load().users)
+	}
+	maps.Copy(next, users)
+	a.usersFile, a.usersFileFormat = path, ff
+	a.store.Store(newKeyStore(next))
+	return nil
+}
+
+// AddUser adds a principal and its hashed key to the key store
+func (a *Authenticator) AddUser(username, hash string) error {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	next := maps.Clone(a.store.Load().users)
+	next[username] = hash
+	a.store.Store(newKeyStore(next))
+	return nil
+}
+
+// RemoveUser removes a principal from the key store
+func (a *Authenticator) RemoveUser(username string) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	next := maps.Clone(a.store.Load().users)
+	delete(next, username)
+	a.store.Store(newKeyStore(next))
+}
+
+type esLookup ct.EnvStringMap
+
+func (l esLookup) ToCredentialsManifest() types.CredentialsManifest {
+	if len(l) == 0 {
+		return nil
+	}
+	out := make(types.CredentialsManifest, len(l))
+	maps.Copy(out, l)
+	return out
+}
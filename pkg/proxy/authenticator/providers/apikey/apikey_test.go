@@ -0,0 +1,208 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apikey
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ct "github.com/trickstercache/trickster/v2/pkg/config/types"
+	pkgerrors "github.com/trickstercache/trickster/v2/pkg/errors"
+	ae "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/errors"
+	authopt "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func bcryptHash(key string) string {
+	h, _ := bcrypt.GenerateFromPassword([]byte(key), bcrypt.MinCost)
+	return string(h)
+}
+
+func writeUsers(t *testing.T, path string, users map[string]string) {
+	t.Helper()
+	var b []byte
+	for u, k := range users {
+		b = fmt.Appendf(b, "%s:%s\n", u, bcryptHash(k))
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestAuthenticator(t *testing.T, o *authopt.Options) *Authenticator {
+	t.Helper()
+	a, err := NewPtr(map[string]any{"options": o})
+	if err != nil {
+		t.Fatalf("NewPtr: %v", err)
+	}
+	return a
+}
+
+func keyRequest(header, key string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/?q=up", nil)
+	if key != "" {
+		r.Header.Set(header, key)
+	}
+	return r
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(nil); !errors.Is(err, pkgerrors.ErrInvalidOptions) {
+		t.Errorf("expected %v got %v", pkgerrors.ErrInvalidOptions, err)
+	}
+	o := authopt.New()
+	o.UsersFile = filepath.Join(t.TempDir(), "keys.htpasswd")
+	if _, err := New(map[string]any{"options": o}); !errors.Is(err, ae.ErrInvalidUsersFileFormat) {
+		t.Errorf("expected %v got %v", ae.ErrInvalidUsersFileFormat, err)
+	}
+	o.UsersFileFormat = types.HTPasswd
+	if _, err := New(map[string]any{"options": o}); err == nil {
+		t.Error("expected error for missing users file")
+	}
+	o.ProviderData = map[string]any{"reloadInterval": "not-a-duration"}
+	if _, err := New(map[string]any{"options": o}); err == nil {
+		t.Error("expected error for invalid config")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	o := authopt.New()
+	o.Users = ct.EnvStringMap{"svc-a": bcryptHash("key-a")}
+	o.ProviderData = map[string]any{"header": "X-Token", "queryParam": "api_key"}
+	a := newTestAuthenticator(t, o)
+
+	res, err := a.Authenticate(keyRequest("X-Token", "svc-a.key-a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != types.AuthSuccess || res.Username != "svc-a" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	// the second use of the key is served from the verified cache
+	res, err = a.Authenticate(keyRequest("X-Token", "svc-a.key-a"))
+	if err != nil || res.Username != "svc-a" {
+		t.Errorf("unexpected result %+v %v", res, err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?api_key=svc-a.key-a&q=up", nil)
+	res, err = a.Authenticate(r)
+	if err != nil || res.Username != "svc-a" {
+		t.Errorf("unexpected result %+v %v", res, err)
+	}
+	a.Sanitize(r)
+	if r.URL.RawQuery != "q=up" {
+		t.Errorf("expected api_key to be removed, got %s", r.URL.RawQuery)
+	}
+
+	// a wrong secret, a secret for another principal, an unknown principal
+	// and a key without a principal are all rejected
+	for _, key := range []string{"svc-a.wrong", "svc-b.key-a", "key-a", "svc-a.", ".key-a"} {
+		res, err = a.Authenticate(keyRequest("X-Token", key))
+		if !errors.Is(err, ae.ErrInvalidCredentials) || res.Status != types.AuthFailed {
+			t.Errorf("%s: unexpected result %+v %v", key, res, err)
+		}
+	}
+	res, err = a.Authenticate(keyRequest(DefaultHeader, "svc-a.key-a"))
+	if !errors.Is(err, ae.ErrInvalidCredentials) || res.Status != types.AuthFailed {
+		t.Errorf("unexpected result %+v %v", res, err)
+	}
+
+	a.SetObserveOnly(true)
+	res, err = a.Authenticate(keyRequest("X-Token", "wrong"))
+	if err != nil || res.Status != types.AuthObserved || res.Username != "" {
+		t.Errorf("unexpected result %+v %v", res, err)
+	}
+}
+
+func TestUsersFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.htpasswd")
+	writeUsers(t, path, map[string]string{"svc-a": "key-a"})
+	o := authopt.New()
+	o.UsersFile = path
+	o.UsersFileFormat = types.HTPasswd
+	o.Users = ct.EnvStringMap{"svc-embedded": bcryptHash("key-e")}
+	o.ProviderData = map[string]any{"reloadInterval": "0s"}
+	a := newTestAuthenticator(t, o)
+
+	for _, key := range []string{"svc-a.key-a", "svc-embedded.key-e"} {
+		if res, err := a.Authenticate(keyRequest(DefaultHeader, key)); err != nil ||
+			res.Status != types.AuthSuccess {
+			t.Fatalf("expected %s to authenticate: %+v %v", key, res, err)
+		}
+	}
+
+	// rotate key-a to key-a2; ensure a distinct mtime on coarse filesystems
+	writeUsers(t, path, map[string]string{"svc-a": "key-a2"})
+	future := time.Now().Add(time.Second)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Authenticate(keyRequest(DefaultHeader, "svc-a.key-a")); err == nil {
+		t.Error("expected rotated key to be rejected")
+	}
+	for _, key := range []string{"svc-a.key-a2", "svc-embedded.key-e"} {
+		if res, err := a.Authenticate(keyRequest(DefaultHeader, key)); err != nil ||
+			res.Status != types.AuthSuccess {
+			t.Errorf("expected %s to authenticate: %+v %v", key, res, err)
+		}
+	}
+
+	// a failed reload keeps the existing keys
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := a.Authenticate(keyRequest(DefaultHeader, "svc-a.key-a2")); err != nil ||
+		res.Status != types.AuthSuccess {
+		t.Errorf("expected key-a2 to authenticate: %+v %v", res, err)
+	}
+}
+
+func TestUsersAndClone(t *testing.T) {
+	a := newTestAuthenticator(t, authopt.New())
+	if err := a.AddUser("svc-b", bcryptHash("key-b")); err != nil {
+		t.Fatal(err)
+	}
+	c := a.ClonePtr()
+	a.RemoveUser("svc-b")
+	if _, err := a.Authenticate(keyRequest(DefaultHeader, "svc-b.key-b")); err == nil {
+		t.Error("expected removed key to be rejected")
+	}
+	if res, err := c.Authenticate(keyRequest(DefaultHeader, "svc-b.key-b")); err != nil ||
+		res.Username != "svc-b" {
+		t.Errorf("expected clone to retain key: %+v %v", res, err)
+	}
+
+	r := keyRequest(DefaultHeader, "")
+	if err := c.SetCredentials(r, "", "key-x"); err != nil {
+		t.Fatal(err)
+	}
+	if r.Header.Get(DefaultHeader) != "key-x" {
+		t.Errorf("expected key-x got %s", r.Header.Get(DefaultHeader))
+	}
+	c.Sanitize(r)
+	if r.Header.Get(DefaultHeader) != "" {
+		t.Error("expected key header to be stripped")
+	}
+}
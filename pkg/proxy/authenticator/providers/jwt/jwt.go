@@ -0,0 +1,362 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package jwt provides an Authenticator that validates JWT Bearer Tokens
+// against a JWKS file or statically-configured verification keys
+package jwt
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/trickstercache/trickster/v2/pkg/errors"
+	ae "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/errors"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+)
+
+const ID types.Provider = "jwt"
+
+const (
+	optionsField = "options"
+	bearerPrefix = "Bearer "
+
+	// DefaultUsernameClaim is the claim used as the AuthResult Username when
+	// usernameClaim is not configured
+	DefaultUsernameClaim = "sub"
+	// DefaultGroupsClaim is the claim used as the AuthResult Groups when
+	// groupsClaim is not configured
+	DefaultGroupsClaim = "groups"
+)
+
+// DefaultAlgorithms is the list of permitted signing algorithms used when
+// algorithms is not configured
+var DefaultAlgorithms = []string{"RS256", "ES256", "HS256"}
+
+// Config is the provider-specific configuration for the JWT Authenticator,
+// which is read from the 'config' section of the Authenticator Options
+type Config struct {
+	// JWKSFile is the path to a JSON Web Key Set file of verification keys
+	JWKSFile string `yaml:"jwksFile"`
+	// Keys is an optional list of statically-configured verification keys
+	Keys []KeyConfig `yaml:"keys"`
+	// Algorithms is the list of permitted signing algorithms
+	Algorithms []string `yaml:"algorithms"`
+	// Issuer, when set, must match the token's iss claim
+	Issuer string `yaml:"issuer"`
+	// Audience, when set, must be present in the token's aud claim
+	Audience string `yaml:"audience"`
+	// ClockSkew is the leeway permitted when validating exp, nbf and iat
+	ClockSkew time.Duration `yaml:"clockSkew"`
+	// UsernameClaim is the claim (dot-delimited for nested claims) whose value
+	// is used as the AuthResult Username
+	UsernameClaim string `yaml:"usernameClaim"`
+	// GroupsClaim is the claim (dot-delimited for nested claims) whose string
+	// or list-of-strings value is used as the AuthResult Groups
+	GroupsClaim string `yaml:"groupsClaim"`
+	// Realm is the realm value provided in the WWW-Authenticate header.
+	// If not set, the realm will default to the Authenticator name
+	Realm string `yaml:"realm"`
+}
+
+// KeyConfig represents a statically-configured verification key
+type KeyConfig struct {
+	// ID is the optional Key ID, matched against the token's kid header
+	ID string `yaml:"kid"`
+	// Algorithm optionally restricts the key to a single signing algorithm
+	Algorithm string `yaml:"alg"`
+	// Secret is the shared secret for HMAC-signed tokens. ${ENV_NAME}
+	// references are expanded.
+	Secret string `yaml:"secret"`
+	// PEM is an inline PEM-encoded public key or certificate
+	PEM string `yaml:"pem"`
+	// File is the path to a PEM-encoded public key or certificate
+	File string `yaml:"file"`
+}
+
+type Authenticator struct {
+	keys             keySet
+	parser           *gojwt.Parser
+	usernameClaim    []string
+	groupsClaim      []string
+	extractCredsFunc types.ExtractCredsFunc
+	setCredsFunc     types.SetCredentialsFunc
+	realm            string
+	proxyPreserve    bool
+	observeOnly      bool
+}
+
+func RegistryEntry() types.RegistryEntry {
+	return types.RegistryEntry{Provider: ID, New: New}
+}
+
+func New(data map[string]any) (types.Authenticator, error) {
+	return NewPtr(data)
+}
+
+func NewPtr(data map[string]any) (*Authenticator, error) {
+	var opts *options.Options
+	if data != nil {
+		if v, ok := data[optionsField]; ok && v != nil {
+			opts, _ = v.(*options.Options)
+		}
+	}
+	if opts == nil {
+		return nil, errors.ErrInvalidOptions
+	}
+	if opts.UsersFile != "" || len(opts.Users) > 0 {
+		return nil, ae.ErrUsersNotSupported
+	}
+	cfg, err := parseConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	ks, err := loadKeys(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a := &Authenticator{
+		keys:          ks,
+		usernameClaim: strings.Split(cfg.UsernameClaim, "."),
+		groupsClaim:   strings.Split(cfg.GroupsClaim, "."),
+		realm:         cfg.Realm,
+		proxyPreserve: opts.ProxyPreserve,
+		observeOnly:   opts.ObserveOnly,
+	}
+	if a.realm == "" {
+		a.realm = opts.Name
+	}
+	po := []gojwt.ParserOption{
+		gojwt.WithValidMethods(cfg.Algorithms),
+		gojwt.WithLeeway(cfg.ClockSkew),
+		gojwt.WithExpirationRequired(),
+	}
+	if cfg.Issuer != "" {
+		po = append(po, gojwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		po = append(po, gojwt.WithAudience(cfg.Audience))
+	}
+	a.parser = gojwt.NewParser(po...)
+	return a, nil
+}
+
+// parseConfig decodes the provider-specific config into a Config
+func parseConfig(opts *options.Options) (*Config, error) {
+	cfg := &Config{}
+	if err := opts.DecodeProviderData(cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = DefaultAlgorithms
+	}
+	for _, alg := range cfg.Algorithms {
+		if gojwt.GetSigningMethod(alg) == nil {
+			return nil, fmt.Errorf("invalid jwt authenticator algorithm: %s", alg)
+		}
+	}
+	if cfg.ClockSkew < 0 {
+		cfg.ClockSkew = 0
+	}
+	if cfg.UsernameClaim == "" {
+		cfg.UsernameClaim = DefaultUsernameClaim
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = DefaultGroupsClaim
+	}
+	return cfg, nil
+}
+
+func (a *Authenticator) failedResult(err error) *types.AuthResult {
+	v := fmt.Sprintf(`Bearer realm="%s"`, a.realm)
+	if err != nil && err != ae.ErrInvalidCredentials {
+		v += `, error="invalid_token"`
+	}
+	return &types.AuthResult{
+		Status:          types.AuthFailed,
+		ResponseHeaders: map[string]string{headers.NameWWWAuthenticate: v},
+	}
+}
+
+// Authenticate validates the request's Bearer Token
+func (a *Authenticator) Authenticate(r *http.Request) (*types.AuthResult, error) {
+	_, token, err := a.ExtractCredentials(r)
+	if err != nil {
+		if a.observeOnly {
+			return &types.AuthResult{Status: types.AuthObserved}, nil
+		}
+		return a.failedResult(err), err
+	}
+	if a.observeOnly {
+		claims := gojwt.MapClaims{}
+		_, _, err := a.parser.ParseUnverified(token, claims)
+		if err != nil {
+			return &types.AuthResult{Status: types.AuthObserved}, nil
+		}
+		u, _ := a.username(claims)
+		return &types.AuthResult{Username: u, Status: types.AuthObserved,
+			Claims: claims}, nil
+	}
+	claims := gojwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, a.keys.keyFunc); err != nil {
+		return a.failedResult(ae.ErrInvalidToken),
+			fmt.Errorf("%w: %w", ae.ErrInvalidToken, err)
+	}
+	u, err := a.username(claims)
+	if err != nil {
+		return a.failedResult(err), err
+	}
+	return &types.AuthResult{Username: u, Status: types.AuthSuccess,
+		Groups: a.groups(claims), Claims: claims}, nil
+}
+
+// claim returns the value of the claim at the provided (nested) path
+func claim(claims gojwt.MapClaims, path []string) (any, bool) {
+	var v any = map[string]any(claims)
+	for _, part := range path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// groups returns the string values of the configured groups claim
+func (a *Authenticator) groups(claims gojwt.MapClaims) []string {
+	v, ok := claim(claims, a.groupsClaim)
+	if !ok {
+		return nil
+	}
+	switch t := v.(type) {
+	case string:
+		return strings.Fields(t)
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, g := range t {
+			if s, ok := g.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// username returns the value of the configured username claim
+func (a *Authenticator) username(claims gojwt.MapClaims) (string, error) {
+	v, ok := claim(claims, a.usernameClaim)
+	if !ok {
+		return "", ae.ErrMissingUsernameClaim
+	}
+	switch t := v.(type) {
+	case string:
+		if t != "" {
+			return t, nil
+		}
+	case float64, bool:
+		return fmt.Sprint(t), nil
+	}
+	return "", ae.ErrMissingUsernameClaim
+}
+
+// Clone clones a new Authenticator (i) from a
+func (a *Authenticator) Clone() types.Authenticator {
+	return a.ClonePtr()
+}
+
+// ClonePtr returns a new clone of the Authenticator. Verification keys are
+// immutable once loaded and are shared with the clone.
+func (a *Authenticator) ClonePtr() *Authenticator {
+	out := *a
+	return &out
+}
+
+func (a *Authenticator) ProxyPreserve() bool {
+	return a.proxyPreserve
+}
+
+func (a *Authenticator) Sanitize(r *http.Request) {
+	if a.proxyPreserve {
+		return
+	}
+	r.Header.Del(headers.NameAuthorization)
+}
+
+// ExtractCredentials returns the Bearer Token from the request as the
+// credential. The username is not known until the token is validated, so it
+// is always empty.
+func (a *Authenticator) ExtractCredentials(r *http.Request) (
+	string, string, error,
+) {
+	if a.extractCredsFunc != nil {
+		return a.extractCredsFunc(r)
+	}
+	h := r.Header.Get(headers.NameAuthorization)
+	if len(h) <= len(bearerPrefix) ||
+		!strings.EqualFold(h[:len(bearerPrefix)], bearerPrefix) {
+		return "", "", ae.ErrInvalidCredentials
+	}
+	return "", strings.TrimSpace(h[len(bearerPrefix):]), nil
+}
+
+func (a *Authenticator) SetExtractCredentialsFunc(f types.ExtractCredsFunc) {
+	a.extractCredsFunc = f
+}
+
+// SetCredentials replaces the request's Authorization header with the
+// provided credential as a Bearer Token
+func (a *Authenticator) SetCredentials(
+	r *http.Request, user, credential string,
+) error {
+	if a.setCredsFunc != nil {
+		return a.setCredsFunc(r, user, credential)
+	}
+	r.Header.Set(headers.NameAuthorization, bearerPrefix+credential)
+	return nil
+}
+
+func (a *Authenticator) SetSetCredentialsFunc(f types.SetCredentialsFunc) {
+	a.setCredsFunc = f
+}
+
+func (a *Authenticator) SetObserveOnly(t bool) {
+	a.observeOnly = t
+}
+
+func (a *Authenticator) IsObserveOnly() bool {
+	return a.observeOnly
+}
+
+// LoadUsers is not supported by the JWT Authenticator
+func (a *Authenticator) LoadUsers(string, types.CredentialsFileFormat, bool) error {
+	return ae.ErrUsersNotSupported
+}
+
+// AddUser is not supported by the JWT Authenticator
+func (a *Authenticator) AddUser(string, string) error {
+	return ae.ErrUsersNotSupported
+}
+
+// RemoveUser is a no-op for the JWT Authenticator
+func (a *Authenticator) RemoveUser(string) {}
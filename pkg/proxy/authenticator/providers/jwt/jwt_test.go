@@ -0,0 +1,301 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	ae "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/errors"
+	authopt "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+)
+
+const testSecret = "test-shared-secret"
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJWKS(t *testing.T, rk *rsa.PrivateKey, ek *ecdsa.PrivateKey) string {
+	t.Helper()
+	ecb, err := ek.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := (ek.Curve.Params().BitSize + 7) / 8
+	doc := map[string]any{"keys": []map[string]any{
+		{"kty": "RSA", "kid": "rsa1", "alg": "RS256", "use": "sig",
+			"n": b64(rk.N.Bytes()), "e": b64(big.NewInt(int64(rk.E)).Bytes())},
+		{"kty": "EC", "kid": "ec1", "crv": "P-256",
+			"x": b64(ecb[1 : 1+size]), "y": b64(ecb[1+size:])},
+		{"kty": "RSA", "kid": "enc1", "use": "enc", "n": "", "e": ""},
+	}}
+	b, _ := json.Marshal(doc)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func sign(t *testing.T, m gojwt.SigningMethod, kid string, key any,
+	claims gojwt.MapClaims,
+) string {
+	t.Helper()
+	tk := gojwt.NewWithClaims(m, claims)
+	if kid != "" {
+		tk.Header["kid"] = kid
+	}
+	s, err := tk.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newTestAuthenticator(t *testing.T, cfg map[string]any) *Authenticator {
+	t.Helper()
+	o := authopt.New()
+	o.Name = "test-jwt"
+	o.Provider = ID
+	o.ProviderData = cfg
+	a, err := NewPtr(map[string]any{"options": o})
+	if err != nil {
+		t.Fatalf("NewPtr: %v", err)
+	}
+	return a
+}
+
+func bearerRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if token != "" {
+		r.Header.Set(headers.NameAuthorization, "Bearer "+token)
+	}
+	return r
+}
+
+func TestAuthenticate(t *testing.T) {
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newTestAuthenticator(t, map[string]any{
+		"jwksFile":      writeJWKS(t, rk, ek),
+		"keys":          []any{map[string]any{"kid": "hs1", "alg": "HS256", "secret": testSecret}},
+		"issuer":        "https://issuer.example.com",
+		"audience":      "trickster",
+		"clockSkew":     "30s",
+		"usernameClaim": "user.name",
+	})
+	now := time.Now()
+	valid := func() gojwt.MapClaims {
+		return gojwt.MapClaims{
+			"iss":    "https://issuer.example.com",
+			"aud":    []string{"other", "trickster"},
+			"exp":    now.Add(time.Minute).Unix(),
+			"user":   map[string]any{"name": "alice"},
+			"groups": []string{"readers", "admins"},
+		}
+	}
+	with := func(k string, v any) gojwt.MapClaims {
+		c := valid()
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		status types.AuthResultStatus
+		err    error
+	}{
+		{"rs256", sign(t, gojwt.SigningMethodRS256, "rsa1", rk, valid()), types.AuthSuccess, nil},
+		{"es256", sign(t, gojwt.SigningMethodES256, "ec1", ek, valid()), types.AuthSuccess, nil},
+		{"es256 no kid", sign(t, gojwt.SigningMethodES256, "", ek, valid()), types.AuthSuccess, nil},
+		{"hs256", sign(t, gojwt.SigningMethodHS256, "hs1", []byte(testSecret), valid()), types.AuthSuccess, nil},
+		{"hs256 wrong secret", sign(t, gojwt.SigningMethodHS256, "hs1", []byte("nope"), valid()), types.AuthFailed, ae.ErrInvalidToken},
+		{"wrong kid", sign(t, gojwt.SigningMethodRS256, "ec1", rk, valid()), types.AuthFailed, ae.ErrInvalidToken},
+		{"alg not permitted", sign(t, gojwt.SigningMethodRS512, "rsa1", rk, valid()), types.AuthFailed, ae.ErrInvalidToken},
+		{"wrong issuer", sign(t, gojwt.SigningMethodRS256, "rsa1", rk, with("iss", "https://evil.example.com")), types.AuthFailed, ae.ErrInvalidToken},
+		{"wrong audience", sign(t, gojwt.SigningMethodRS256, "rsa1", rk, with("aud", "other")), types.AuthFailed, ae.ErrInvalidToken},
+		{"expired", sign(t, gojwt.SigningMethodRS256, "rsa1", rk, with("exp", now.Add(-time.Minute).Unix())), types.AuthFailed, ae.ErrInvalidToken},
+		{"expired within skew", sign(t, gojwt.SigningMethodRS256, "rsa1", rk, with("exp", now.Add(-10*time.Second).Unix())), types.AuthSuccess, nil},
+		{"missing exp", sign(t, gojwt.SigningMethodRS256, "rsa1", rk, with("exp", nil)), types.AuthFailed, ae.ErrInvalidToken},
+		{"missing username", sign(t, gojwt.SigningMethodRS256, "rsa1", rk, with("user", nil)), types.AuthFailed, ae.ErrMissingUsernameClaim},
+		{"malformed", "not.a.token", types.AuthFailed, ae.ErrInvalidToken},
+		{"missing token", "", types.AuthFailed, ae.ErrInvalidCredentials},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, err := a.Authenticate(bearerRequest(test.token))
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v got %v", test.err, err)
+			}
+			if res == nil || res.Status != test.status {
+				t.Fatalf("expected status %d got %+v", test.status, res)
+			}
+			if test.status == types.AuthSuccess {
+				if res.Username != "alice" {
+					t.Errorf("expected username alice got %s", res.Username)
+				}
+				if len(res.Groups) != 2 || res.Groups[0] != "readers" {
+					t.Errorf("expected groups in result, got %v", res.Groups)
+				}
+				if res.Claims["iss"] != "https://issuer.example.com" {
+					t.Errorf("expected claims in result, got %v", res.Claims)
+				}
+				return
+			}
+			h := res.ResponseHeaders[headers.NameWWWAuthenticate]
+			if !strings.HasPrefix(h, `Bearer realm="test-jwt"`) {
+				t.Errorf("unexpected %s header: %s", headers.NameWWWAuthenticate, h)
+			}
+			if test.token != "" && !strings.Contains(h, `error="invalid_token"`) {
+				t.Errorf("expected invalid_token error in header: %s", h)
+			}
+		})
+	}
+}
+
+func TestStaticPEMKey(t *testing.T) {
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&ek.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, p, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, kc := range []map[string]any{{"pem": string(p)}, {"file": path}} {
+		a := newTestAuthenticator(t, map[string]any{"keys": []any{kc}})
+		tk := sign(t, gojwt.SigningMethodES256, "", ek, gojwt.MapClaims{
+			"sub": "bob", "exp": time.Now().Add(time.Minute).Unix()})
+		res, err := a.Authenticate(bearerRequest(tk))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Username != "bob" {
+			t.Errorf("expected bob got %s", res.Username)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Error("expected error for nil options")
+	}
+	o := authopt.New()
+	if _, err := New(map[string]any{"options": o}); !errors.Is(err, ae.ErrNoVerificationKeys) {
+		t.Errorf("expected %v got %v", ae.ErrNoVerificationKeys, err)
+	}
+	o.ProviderData = map[string]any{"algorithms": []any{"XX999"},
+		"keys": []any{map[string]any{"secret": testSecret}}}
+	if _, err := New(map[string]any{"options": o}); err == nil {
+		t.Error("expected error for invalid algorithm")
+	}
+	o.ProviderData = map[string]any{"keys": []any{map[string]any{"pem": "junk"}}}
+	if _, err := New(map[string]any{"options": o}); !errors.Is(err, ae.ErrInvalidKeys) {
+		t.Errorf("expected %v got %v", ae.ErrInvalidKeys, err)
+	}
+	o.ProviderData = map[string]any{"jwksFile": "/path/does/not/exist.json"}
+	if _, err := New(map[string]any{"options": o}); !errors.Is(err, ae.ErrInvalidKeys) {
+		t.Errorf("expected %v got %v", ae.ErrInvalidKeys, err)
+	}
+	o.ProviderData = map[string]any{"keys": []any{map[string]any{"secret": testSecret}}}
+	o.Users = map[string]string{"user1": "pass"}
+	if _, err := New(map[string]any{"options": o}); !errors.Is(err, ae.ErrUsersNotSupported) {
+		t.Errorf("expected %v got %v", ae.ErrUsersNotSupported, err)
+	}
+}
+
+func TestObserveOnly(t *testing.T) {
+	a := newTestAuthenticator(t, map[string]any{
+		"keys": []any{map[string]any{"secret": testSecret}}})
+	a.SetObserveOnly(true)
+	if !a.IsObserveOnly() {
+		t.Fatal("expected observe only")
+	}
+	tk := sign(t, gojwt.SigningMethodHS256, "", []byte("unverified"),
+		gojwt.MapClaims{"sub": "carol"})
+	res, err := a.Authenticate(bearerRequest(tk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != types.AuthObserved || res.Username != "carol" {
+		t.Errorf("unexpected result %+v", res)
+	}
+	res, err = a.Authenticate(bearerRequest(""))
+	if err != nil || res.Status != types.AuthObserved {
+		t.Errorf("unexpected result %+v %v", res, err)
+	}
+}
+
+func TestCredentialsAndSanitize(t *testing.T) {
+	a := newTestAuthenticator(t, map[string]any{
+		"keys": []any{map[string]any{"secret": testSecret}}})
+	r := bearerRequest("")
+	if err := a.SetCredentials(r, "ignored", "tok123"); err != nil {
+		t.Fatal(err)
+	}
+	if _, c, err := a.ExtractCredentials(r); err != nil || c != "tok123" {
+		t.Errorf("expected tok123 got %s %v", c, err)
+	}
+	c := a.ClonePtr()
+	c.proxyPreserve = true
+	c.Sanitize(r)
+	if r.Header.Get(headers.NameAuthorization) == "" {
+		t.Error("expected header to be preserved")
+	}
+	a.Sanitize(r)
+	if r.Header.Get(headers.NameAuthorization) != "" {
+		t.Error("expected header to be stripped")
+	}
+	if err := a.AddUser("u", "p"); !errors.Is(err, ae.ErrUsersNotSupported) {
+		t.Errorf("expected %v got %v", ae.ErrUsersNotSupported, err)
+	}
+}
@@ -0,0 +1,228 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	ae "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/errors"
+)
+
+// verificationKey is a loaded key along with its optional kid and alg
+// restrictions
+type verificationKey struct {
+	id  string
+	alg string
+	key gojwt.VerificationKey
+}
+
+type keySet []verificationKey
+
+// jwk is the subset of RFC 7517 JSON Web Key fields used for verification
+type jwk struct {
+	KeyType   string `json:"kty"`
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	K         string `json:"k"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+// loadKeys loads the JWKS file and any static keys from the Config
+func loadKeys(cfg *Config) (keySet, error) {
+	var ks keySet
+	if cfg.JWKSFile != "" {
+		b, err := os.ReadFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ae.ErrInvalidKeys, err)
+		}
+		ks, err = parseJWKS(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, kc := range cfg.Keys {
+		k, err := kc.load()
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %d: %w", ae.ErrInvalidKeys, i, err)
+		}
+		ks = append(ks, verificationKey{id: kc.ID, alg: kc.Algorithm, key: k})
+	}
+	if len(ks) == 0 {
+		return nil, ae.ErrNoVerificationKeys
+	}
+	return ks, nil
+}
+
+// parseJWKS parses a JSON Web Key Set document. Keys that are not usable for
+// signature verification are skipped.
+func parseJWKS(b []byte) (keySet, error) {
+	var set jwks
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("%w: %w", ae.ErrInvalidKeys, err)
+	}
+	ks := make(keySet, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: kid %q: %w", ae.ErrInvalidKeys, k.ID, err)
+		}
+		ks = append(ks, verificationKey{id: k.ID, alg: k.Algorithm, key: key})
+	}
+	return ks, nil
+}
+
+func (k jwk) publicKey() (gojwt.VerificationKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, fmt.Errorf("invalid EC point size")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(curve, point)
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		return secret, nil
+	}
+	return nil, fmt.Errorf("unsupported key type: %s", k.KeyType)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// load returns the verification key for a statically-configured key
+func (kc KeyConfig) load() (gojwt.VerificationKey, error) {
+	if kc.Secret != "" {
+		return []byte(os.ExpandEnv(kc.Secret)), nil
+	}
+	b := []byte(kc.PEM)
+	if kc.File != "" {
+		var err error
+		if b, err = os.ReadFile(kc.File); err != nil {
+			return nil, err
+		}
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("one of secret, pem or file is required")
+	}
+	return parsePEMPublicKey(b)
+}
+
+func parsePEMPublicKey(b []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// keyFunc returns the candidate verification keys for the token, matching on
+// the kid header (when present) and the key's algorithm restriction
+func (ks keySet) keyFunc(t *gojwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	alg := t.Method.Alg()
+	out := gojwt.VerificationKeySet{}
+	for _, k := range ks {
+		if kid != "" && k.id != "" && k.id != kid {
+			continue
+		}
+		if k.alg != "" && k.alg != alg {
+			continue
+		}
+		out.Keys = append(out.Keys, k.key)
+	}
+	if len(out.Keys) == 0 {
+		return nil, ae.ErrNoVerificationKeys
+	}
+	return out, nil
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mtls provides an Authenticator that identifies users by the
+// verified client certificate presented on a TLS listener
+package mtls
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/trickstercache/trickster/v2/pkg/errors"
+	ae "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/errors"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/util/sets"
+)
+
+const ID types.Provider = "mtls"
+
+const optionsField = "options"
+
+// UsernameSource identifies the client certificate field used as the username
+type UsernameSource string
+
+const (
+	// SourceCN uses the certificate's Subject Common Name (default)
+	SourceCN UsernameSource = "cn"
+	// SourceDNS uses the certificate's first DNS Subject Alternative Name
+	SourceDNS UsernameSource = "dns"
+	// SourceEmail uses the certificate's first Email Subject Alternative Name
+	SourceEmail UsernameSource = "email"
+	// SourceURI uses the certificate's first URI Subject Alternative Name
+	SourceURI UsernameSource = "uri"
+	// SourceSPIFFE uses the certificate's SPIFFE ID (a spiffe:// URI SAN)
+	SourceSPIFFE UsernameSource = "spiffe"
+)
+
+const spiffeScheme = "spiffe"
+
+// Config is the provider-specific configuration for the mTLS Authenticator,
+// which is read from the 'config' section of the Authenticator Options
+type Config struct {
+	// UsernameFrom is the client certificate field used as the username
+	UsernameFrom UsernameSource `yaml:"usernameFrom"`
+	// Allow is an optional list of permitted usernames. An entry ending in
+	// '*' permits any username with that prefix.
+	Allow []string `yaml:"allow"`
+}
+
+type Authenticator struct {
+	source           UsernameSource
+	allowExact       sets.Set[string]
+	allowPrefixes    []string
+	extractCredsFunc types.ExtractCredsFunc
+	setCredsFunc     types.SetCredentialsFunc
+	observeOnly      bool
+}
+
+func RegistryEntry() types.RegistryEntry {
+	return types.RegistryEntry{Provider: ID, New: New}
+}
+
+func New(data map[string]any) (types.Authenticator, error) {
+	return NewPtr(data)
+}
+
+func NewPtr(data map[string]any) (*Authenticator, error) {
+	var opts *options.Options
+	if data != nil {
+		if v, ok := data[optionsField]; ok && v != nil {
+			opts, _ = v.(*options.Options)
+		}
+	}
+	if opts == nil {
+		return nil, errors.ErrInvalidOptions
+	}
+	if opts.UsersFile != "" || len(opts.Users) > 0 {
+		return nil, ae.ErrUsersNotSupported
+	}
+	cfg := &Config{}
+	if err := opts.DecodeProviderData(cfg); err != nil {
+		return nil, err
+	}
+	a := &Authenticator{source: cfg.UsernameFrom, observeOnly: opts.ObserveOnly}
+	switch a.source {
+	case "":
+		a.source = SourceCN
+	case SourceCN, SourceDNS, SourceEmail, SourceURI, SourceSPIFFE:
+	default:
+		return nil, fmt.Errorf("invalid mtls authenticator usernameFrom: %s",
+			cfg.UsernameFrom)
+	}
+	if len(cfg.Allow) > 0 {
+		a.allowExact = sets.NewStringSet()
+		for _, v := range cfg.Allow {
+			if p, ok := strings.CutSuffix(v, "*"); ok {
+				a.allowPrefixes = append(a.allowPrefixes, p)
+				continue
+			}
+			a.allowExact.Set(v)
+		}
+	}
+	return a, nil
+}
+
+func failedResult() *types.AuthResult {
+	return &types.AuthResult{Status: types.AuthFailed}
+}
+
+// Authenticate checks the request's verified client certificate
+func (a *Authenticator) Authenticate(r *http.Request) (*types.AuthResult, error) {
+	if a.observeOnly {
+		u, _, _ := a.ExtractCredentials(r)
+		return &types.AuthResult{Username: u, Status: types.AuthObserved}, nil
+	}
+	// the certificate chain must have been verified by the listener (per its
+	// tls_client_auth and tls_client_ca_paths), not merely presented
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 ||
+		len(r.TLS.VerifiedChains[0]) == 0 {
+		return failedResult(), ae.ErrInvalidCredentials
+	}
+	u := a.username(r.TLS.VerifiedChains[0][0])
+	if u == "" || !a.allowed(u) {
+		return failedResult(), ae.ErrInvalidCredentials
+	}
+	return &types.AuthResult{Username: u, Status: types.AuthSuccess}, nil
+}
+
+// username returns the configured identity field from the certificate
+func (a *Authenticator) username(cert *x509.Certificate) string {
+	switch a.source {
+	case SourceDNS:
+		if len(cert.DNSNames) > 0 {
+			return cert.DNSNames[0]
+		}
+	case SourceEmail:
+		if len(cert.EmailAddresses) > 0 {
+			return cert.EmailAddresses[0]
+		}
+	case SourceURI:
+		if len(cert.URIs) > 0 {
+			return cert.URIs[0].String()
+		}
+	case SourceSPIFFE:
+		// a SPIFFE X.509-SVID has exactly one URI SAN
+		if len(cert.URIs) == 1 && cert.URIs[0].Scheme == spiffeScheme &&
+			cert.URIs[0].Host != "" {
+			return cert.URIs[0].String()
+		}
+	default:
+		return cert.Subject.CommonName
+	}
+	return ""
+}
+
+func (a *Authenticator) allowed(username string) bool {
+	if a.allowExact == nil {
+		return true
+	}
+	if a.allowExact.Contains(username) {
+		return true
+	}
+	for _, p := range a.allowPrefixes {
+		if strings.HasPrefix(username, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone clones a new Authenticator (i) from a
+func (a *Authenticator) Clone() types.Authenticator {
+	return a.ClonePtr()
+}
+
+// ClonePtr returns a new, independent clone of the Authenticator
+func (a *Authenticator) ClonePtr() *Authenticator {
+	out := *a
+	if a.allowExact != nil {
+		out.allowExact = a.allowExact.Clone()
+	}
+	out.allowPrefixes = append([]string(nil), a.allowPrefixes...)
+	return &out
+}
+
+// ProxyPreserve always returns true, since client certificates are not
+// forwarded in request headers
+func (a *Authenticator) ProxyPreserve() bool {
+	return true
+}
+
+// Sanitize is a no-op, since client certificates are not forwarded in request
+// headers
+func (a *Authenticator) Sanitize(*http.Request) {}
+
+// ExtractCredentials returns the username from the request's client
+// certificate, which may not have been verified. There is no credential.
+func (a *Authenticator) ExtractCredentials(r *http.Request) (
+	string, string, error,
+) {
+	if a.extractCredsFunc != nil {
+		return a.extractCredsFunc(r)
+	}
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return "", "", ae.ErrInvalidCredentials
+	}
+	return a.username(r.TLS.PeerCertificates[0]), "", nil
+}
+
+func (a *Authenticator) SetExtractCredentialsFunc(f types.ExtractCredsFunc) {
+	a.extractCredsFunc = f
+}
+
+// SetCredentials sets Basic Auth credentials on the request, so the user
+// router can map certificate identities to upstream credentials
+func (a *Authenticator) SetCredentials(
+	r *http.Request, user, credential string,
+) error {
+	if a.setCredsFunc != nil {
+		return a.setCredsFunc(r, user, credential)
+	}
+	r.Header.Del(headers.NameAuthorization)
+	r.SetBasicAuth(user, credential)
+	return nil
+}
+
+func (a *Authenticator) SetSetCredentialsFunc(f types.SetCredentialsFunc) {
+	a.setCredsFunc = f
+}
+
+func (a *Authenticator) SetObserveOnly(t bool) {
+	a.observeOnly = t
+}
+
+func (a *Authenticator) IsObserveOnly() bool {
+	return a.observeOnly
+}
+
+// LoadUsers is not supported by the mTLS Authenticator
+func (a *Authenticator) LoadUsers(string, types.CredentialsFileFormat, bool) error {
+	return ae.ErrUsersNotSupported
+}
+
+// AddUser is not supported by the mTLS Authenticator
+func (a *Authenticator) AddUser(string, string) error {
+	return ae.ErrUsersNotSupported
+}
+
+// RemoveUser is a no-op for the mTLS Authenticator
+func (a *Authenticator) RemoveUser(string) {}
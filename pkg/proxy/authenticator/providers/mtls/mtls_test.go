@@ -0,0 +1,237 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ae "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/errors"
+	authopt "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	tlsopts "github.com/trickstercache/trickster/v2/pkg/proxy/tls/options"
+)
+
+func newTestAuthenticator(t *testing.T, cfg map[string]any) *Authenticator {
+	t.Helper()
+	o := authopt.New()
+	o.ProviderData = cfg
+	a, err := NewPtr(map[string]any{"options": o})
+	if err != nil {
+		t.Fatalf("NewPtr: %v", err)
+	}
+	return a
+}
+
+func testCert() *x509.Certificate {
+	return &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "alice"},
+		DNSNames:       []string{"alice.example.com", "alt.example.com"},
+		EmailAddresses: []string{"alice@example.com"},
+		URIs:           []*url.URL{{Scheme: "spiffe", Host: "example.org", Path: "/ns/prod/sa/alice"}},
+	}
+}
+
+func verifiedRequest(cert *x509.Certificate) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "https://trickster/", nil)
+	r.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+	return r
+}
+
+func TestUsernameFrom(t *testing.T) {
+	tests := []struct {
+		source   UsernameSource
+		expected string
+	}{
+		{"", "alice"},
+		{SourceCN, "alice"},
+		{SourceDNS, "alice.example.com"},
+		{SourceEmail, "alice@example.com"},
+		{SourceURI, "spiffe://example.org/ns/prod/sa/alice"},
+		{SourceSPIFFE, "spiffe://example.org/ns/prod/sa/alice"},
+	}
+	for _, test := range tests {
+		t.Run(string(test.source), func(t *testing.T) {
+			a := newTestAuthenticator(t, map[string]any{"usernameFrom": string(test.source)})
+			res, err := a.Authenticate(verifiedRequest(testCert()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Status != types.AuthSuccess || res.Username != test.expected {
+				t.Errorf("expected %s got %+v", test.expected, res)
+			}
+		})
+	}
+}
+
+func TestAuthenticateFailures(t *testing.T) {
+	a := newTestAuthenticator(t, map[string]any{"usernameFrom": "spiffe"})
+	cert := testCert()
+	cert.URIs = append(cert.URIs, &url.URL{Scheme: "https", Host: "example.org"})
+	if _, err := a.Authenticate(verifiedRequest(cert)); !errors.Is(err, ae.ErrInvalidCredentials) {
+		t.Errorf("expected error for multiple URI SANs, got %v", err)
+	}
+
+	// presented, but unverified, certificates are rejected
+	r := verifiedRequest(testCert())
+	r.TLS.VerifiedChains = nil
+	res, err := a.Authenticate(r)
+	if !errors.Is(err, ae.ErrInvalidCredentials) || res.Status != types.AuthFailed {
+		t.Errorf("unexpected result %+v %v", res, err)
+	}
+	if _, err := a.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil)); err == nil {
+		t.Error("expected error for plaintext request")
+	}
+
+	// observe only reads the unverified certificate
+	a.SetObserveOnly(true)
+	res, err = a.Authenticate(r)
+	if err != nil || res.Status != types.AuthObserved ||
+		res.Username != "spiffe://example.org/ns/prod/sa/alice" {
+		t.Errorf("unexpected result %+v %v", res, err)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	a := newTestAuthenticator(t, map[string]any{
+		"usernameFrom": "spiffe",
+		"allow":        []any{"spiffe://example.org/ns/prod/*", "spiffe://other.org/svc"},
+	})
+	if _, err := a.Authenticate(verifiedRequest(testCert())); err != nil {
+		t.Errorf("expected prefix allow, got %v", err)
+	}
+	cert := testCert()
+	cert.URIs[0] = &url.URL{Scheme: "spiffe", Host: "other.org", Path: "/svc"}
+	if _, err := a.Authenticate(verifiedRequest(cert)); err != nil {
+		t.Errorf("expected exact allow, got %v", err)
+	}
+	cert.URIs[0] = &url.URL{Scheme: "spiffe", Host: "example.org", Path: "/ns/dev/sa/alice"}
+	if _, err := a.Authenticate(verifiedRequest(cert)); !errors.Is(err, ae.ErrInvalidCredentials) {
+		t.Errorf("expected disallowed error, got %v", err)
+	}
+	c := a.ClonePtr()
+	if !c.allowed("spiffe://other.org/svc") || c.allowed("spiffe://other.org/x") {
+		t.Error("unexpected clone allowlist")
+	}
+}
+
+func TestNew(t *testing.T) {
+	o := authopt.New()
+	o.ProviderData = map[string]any{"usernameFrom": "serial"}
+	if _, err := New(map[string]any{"options": o}); err == nil {
+		t.Error("expected error for invalid usernameFrom")
+	}
+	o.ProviderData = nil
+	o.Users = map[string]string{"u": "p"}
+	if _, err := New(map[string]any{"options": o}); !errors.Is(err, ae.ErrUsersNotSupported) {
+		t.Errorf("expected %v got %v", ae.ErrUsersNotSupported, err)
+	}
+}
+
+func writePEM(t *testing.T, path, typ string, b []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: b}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestListenerClientAuth verifies the authenticator with a client certificate
+// that was verified by a listener using tls_client_auth: verify_if_given
+func TestListenerClientAuth(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test-ca"},
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour),
+		IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+	caPath := filepath.Join(t.TempDir(), "client-ca.pem")
+	writePEM(t, caPath, "CERTIFICATE", caDER)
+
+	clientKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clientTmpl := testCert()
+	clientTmpl.SerialNumber = big.NewInt(2)
+	clientTmpl.NotBefore, clientTmpl.NotAfter = caTmpl.NotBefore, caTmpl.NotAfter
+	clientTmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTmpl, caCert, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := newTestAuthenticator(t, map[string]any{"usernameFrom": "spiffe"})
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		res, err := a.Authenticate(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(res.Username))
+	}))
+	ts.TLS = &tls.Config{}
+	if err := tlsopts.ApplyClientAuth(ts.TLS, tlsopts.ClientAuthVerifyIfGiven,
+		[]string{caPath}); err != nil {
+		t.Fatal(err)
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := ts.Client()
+	tr := client.Transport.(*http.Transport)
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without a client cert, got %d", resp.StatusCode)
+	}
+
+	tr.TLSClientConfig.Certificates = []tls.Certificate{{
+		Certificate: [][]byte{clientDER}, PrivateKey: clientKey,
+	}}
+	tr.CloseIdleConnections()
+	resp, err = client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 with a client cert, got %d", resp.StatusCode)
+	}
+}
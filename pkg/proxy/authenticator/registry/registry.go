@@ -21,7 +21,10 @@ import (
 
 	clickhouse "github.com/trickstercache/trickster/v2/pkg/backends/clickhouse/authenticator"
 	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/providers/apikey"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/providers/basic"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/providers/jwt"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/providers/mtls"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
 )
 
@@ -31,6 +34,9 @@ var ErrUnsupportedAuthenticator = errors.New("unsupported authenticator")
 var registry = []types.RegistryEntry{
 	basic.RegistryEntry(),      //  BasicAuth
 	clickhouse.RegistryEntry(), // ClickHouse Auth (Basic + url params)
+	jwt.RegistryEntry(),        // JWT Bearer Tokens
+	apikey.RegistryEntry(),     // API Keys
+	mtls.RegistryEntry(),       // TLS Client Certificates
 }
 
 var registryByName = compileSupportedByName()
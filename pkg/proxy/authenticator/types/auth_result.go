@@ -17,11 +17,14 @@
 package types
 
 type AuthResult struct {
-	Status          AuthResultStatus
-	StatusDetail    string
-	Username        string
+	Status       AuthResultStatus
+	StatusDetail string
+	Username     string
+	// Groups holds any group memberships provided by the Authenticator
+	Groups          []string
 	ResponseHeaders map[string]string
-	// Claims, etc. would go here in the future as needed
+	// Claims holds the verified token claims for token-based Authenticators
+	Claims map[string]any
 }
 
 type AuthResultStatus int
@@ -59,6 +59,14 @@ type Authenticator interface {
 	Sanitize(*http.Request)
 }
 
+// CredentialRemover is implemented by Authenticators that read credentials
+// from request locations other than the standard credential headers
+type CredentialRemover interface {
+	// RemoveCredentials strips the Authenticator's credentials from r,
+	// regardless of ProxyPreserve
+	RemoveCredentials(*http.Request)
+}
+
 type Lookup map[string]Authenticator
 
 // Provider is a defined type for the Authenticator Provider's name
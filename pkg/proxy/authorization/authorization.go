@@ -0,0 +1,156 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package authorization enforces Authorization Policies on requests that have
+// been authenticated
+package authorization
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/prometheus/promql"
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authorization/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/failures"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/params"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+)
+
+// Middleware returns a handler that passes the request to the next handler
+// when an Authorization Policy grants it, else responds with 403 Forbidden.
+// Backends that are not governed by any Policy are not wrapped.
+func Middleware(o *options.Options, backendName, provider string,
+	next http.Handler,
+) http.Handler {
+	if !o.Governs(backendName) {
+		return next
+	}
+	policies := o.SortedPolicies()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var username string
+		var groups []string
+		if rsc := request.GetResources(r); rsc != nil && rsc.AuthResult != nil &&
+			rsc.AuthResult.Status == types.AuthSuccess {
+			username = rsc.AuthResult.Username
+			groups = append(groups, rsc.AuthResult.Groups...)
+			groups = append(groups, o.GroupsOf(username)...)
+		}
+		if ok, reason := Authorize(policies, backendName, provider, username,
+			groups, r); !ok {
+			logger.Debug("authorization denied", logging.Pairs{
+				"backendName": backendName, "username": username,
+				"path": r.URL.Path, "reason": reason,
+			})
+			failures.HandleForbidden(w, reason)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Authorize returns true if any of the policies grants the user access to the
+// request; otherwise it returns false with the reason for the denial
+func Authorize(policies []*options.Policy, backendName, provider,
+	username string, groups []string, r *http.Request,
+) (bool, string) {
+	if username == "" {
+		return false, fmt.Sprintf("authentication is required for backend %q",
+			backendName)
+	}
+	var reason string
+	var metrics []string
+	var metricsResolved, metricsOK bool
+	for _, p := range policies {
+		if !p.AppliesTo(backendName) || !p.Grants(username, groups) {
+			continue
+		}
+		if !p.PermitsPath(r.URL.Path) {
+			if reason == "" {
+				reason = fmt.Sprintf("path %q is not permitted", r.URL.Path)
+			}
+			continue
+		}
+		if !p.PermitsMethod(r.Method) {
+			if reason == "" {
+				reason = fmt.Sprintf("method %s is not permitted", r.Method)
+			}
+			continue
+		}
+		if p.RestrictsMetrics() {
+			// metric restrictions can only be checked for Prometheus
+			// backends, so deny rather than pass through unchecked
+			if provider != providers.Prometheus {
+				if reason == "" {
+					reason = fmt.Sprintf("metric restrictions cannot be "+
+						"checked for provider %q", provider)
+				}
+				continue
+			}
+			if !metricsResolved {
+				metrics, metricsOK = requestMetricNames(r)
+				metricsResolved = true
+			}
+			if !metricsOK {
+				if reason == "" {
+					reason = "the metric names in the query could not be determined"
+				}
+				continue
+			}
+			if name, ok := firstUnpermittedMetric(p, metrics); !ok {
+				if reason == "" {
+					reason = fmt.Sprintf("metric %q is not permitted", name)
+				}
+				continue
+			}
+		}
+		return true, ""
+	}
+	if reason == "" {
+		reason = fmt.Sprintf("user %q is not granted access to backend %q",
+			username, backendName)
+	}
+	return false, reason
+}
+
+func firstUnpermittedMetric(p *options.Policy, metrics []string) (string, bool) {
+	for _, m := range metrics {
+		if !p.PermitsMetric(m) {
+			return m, false
+		}
+	}
+	return "", true
+}
+
+// requestMetricNames returns the metric names referenced by the request's
+// query and match[] parameters. ok is false if any name cannot be determined.
+func requestMetricNames(r *http.Request) ([]string, bool) {
+	v, _, _ := params.GetRequestValues(r)
+	var out []string
+	for _, k := range []string{"query", "match[]"} {
+		for _, q := range v[k] {
+			names, ok := promql.MetricNames(q)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, names...)
+		}
+	}
+	return out, true
+}
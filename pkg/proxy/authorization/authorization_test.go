@@ -0,0 +1,211 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package authorization
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+	at "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authorization/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+)
+
+func testOptions(t *testing.T) *options.Options {
+	t.Helper()
+	o := &options.Options{
+		Groups: map[string][]string{"sre": {"alice"}},
+		Policies: options.Lookup{
+			"sre": {Groups: []string{"sre"}, Backends: []string{"prom1", "prom2"}},
+			"dev": {
+				Users:    []string{"bob"},
+				Groups:   []string{"devs"},
+				Backends: []string{"prom1"},
+				Paths:    []string{"/api/v1/*"},
+				Methods:  []string{http.MethodGet, http.MethodPost},
+				Metrics:  []string{"up", "node_.*"},
+			},
+		},
+	}
+	if err := o.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestMiddleware(t *testing.T) {
+	o := testOptions(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := Middleware(o, "prom1", providers.Prometheus, next)
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		auth       *at.AuthResult
+		expCode    int
+		expMessage string
+	}{
+		{
+			name:       "anonymous",
+			url:        "/api/v1/query?query=up",
+			expCode:    http.StatusForbidden,
+			expMessage: `authentication is required for backend "prom1"`,
+		},
+		{
+			name:       "observed only",
+			url:        "/api/v1/query?query=up",
+			auth:       &at.AuthResult{Username: "bob", Status: at.AuthObserved},
+			expCode:    http.StatusForbidden,
+			expMessage: `authentication is required for backend "prom1"`,
+		},
+		{
+			name:    "static group",
+			url:     "/anything?query=go_goroutines",
+			auth:    &at.AuthResult{Username: "alice", Status: at.AuthSuccess},
+			expCode: http.StatusOK,
+		},
+		{
+			name:    "authenticator group",
+			url:     "/api/v1/query?query=" + url.QueryEscape("sum(rate(node_cpu[5m]))"),
+			auth:    &at.AuthResult{Username: "carol", Groups: []string{"devs"}, Status: at.AuthSuccess},
+			expCode: http.StatusOK,
+		},
+		{
+			name:       "ungranted user",
+			url:        "/api/v1/query?query=up",
+			auth:       &at.AuthResult{Username: "mallory", Status: at.AuthSuccess},
+			expCode:    http.StatusForbidden,
+			expMessage: `user "mallory" is not granted access to backend "prom1"`,
+		},
+		{
+			name:       "path",
+			url:        "/federate",
+			auth:       &at.AuthResult{Username: "bob", Status: at.AuthSuccess},
+			expCode:    http.StatusForbidden,
+			expMessage: `path "/federate" is not permitted`,
+		},
+		{
+			name:       "method",
+			method:     http.MethodDelete,
+			url:        "/api/v1/series",
+			auth:       &at.AuthResult{Username: "bob", Status: at.AuthSuccess},
+			expCode:    http.StatusForbidden,
+			expMessage: "method DELETE is not permitted",
+		},
+		{
+			name:       "metric",
+			url:        "/api/v1/query?query=" + url.QueryEscape("up + go_goroutines"),
+			auth:       &at.AuthResult{Username: "bob", Status: at.AuthSuccess},
+			expCode:    http.StatusForbidden,
+			expMessage: `metric "go_goroutines" is not permitted`,
+		},
+		{
+			name:       "undeterminable metric",
+			url:        "/api/v1/query?query=" + url.QueryEscape(`{job="x"}`),
+			auth:       &at.AuthResult{Username: "bob", Status: at.AuthSuccess},
+			expCode:    http.StatusForbidden,
+			expMessage: "the metric names in the query could not be determined",
+		},
+		{
+			name:       "series match",
+			url:        "/api/v1/series?match[]=up&match[]=process_cpu",
+			auth:       &at.AuthResult{Username: "bob", Status: at.AuthSuccess},
+			expCode:    http.StatusForbidden,
+			expMessage: `metric "process_cpu" is not permitted`,
+		},
+		{
+			name:    "post form",
+			method:  http.MethodPost,
+			url:     "/api/v1/query",
+			body:    "query=node_load1",
+			auth:    &at.AuthResult{Username: "bob", Status: at.AuthSuccess},
+			expCode: http.StatusOK,
+		},
+		{
+			name:       "post form metric",
+			method:     http.MethodPost,
+			url:        "/api/v1/query",
+			body:       "query=go_goroutines",
+			auth:       &at.AuthResult{Username: "bob", Status: at.AuthSuccess},
+			expCode:    http.StatusForbidden,
+			expMessage: `metric "go_goroutines" is not permitted`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "http://example.com"+test.url,
+				strings.NewReader(test.body))
+			if test.body != "" {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			r = request.SetResources(r, &request.Resources{AuthResult: test.auth})
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != test.expCode {
+				t.Errorf("expected %d got %d", test.expCode, w.Code)
+			}
+			if test.expMessage != "" && w.Body.String() != "Forbidden: "+test.expMessage {
+				t.Errorf("expected message %q got %q", test.expMessage, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestMiddlewareUngoverned(t *testing.T) {
+	o := testOptions(t)
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := Middleware(o, "prom3", providers.Prometheus, next)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected %d got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestAuthorizeNonPrometheus(t *testing.T) {
+	o := testOptions(t)
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/api/v1/x?query=foo", nil)
+	ok, reason := Authorize(o.SortedPolicies(), "prom1", providers.ReverseProxyCache,
+		"bob", nil, r)
+	if ok {
+		t.Error("expected metric-restricted policy to deny non-prometheus backends")
+	}
+	if !strings.Contains(reason, "cannot be checked") {
+		t.Errorf("unexpected reason: %s", reason)
+	}
+	// alice's policy does not restrict metrics, so it is unaffected
+	ok, reason = Authorize(o.SortedPolicies(), "prom1", providers.ReverseProxyCache,
+		"alice", []string{"sre"}, r)
+	if !ok {
+		t.Errorf("expected unrestricted policy to permit request: %s", reason)
+	}
+}
@@ -0,0 +1,314 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options provides configuration for Authorization Policies
+package options
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/trickstercache/trickster/v2/pkg/util/sets"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Wildcard matches any backend, or any authenticated user
+const Wildcard = "*"
+
+var (
+	ErrNoPolicySubjects = errors.New("authorization policy must define users or groups")
+	ErrNoPolicyBackends = errors.New("authorization policy must define backends")
+)
+
+// Options is the top-level Authorization configuration
+type Options struct {
+	// Groups maps group names to their member usernames, supplementing any
+	// groups provided by the Authenticator (e.g., a JWT groups claim)
+	Groups map[string][]string `yaml:"groups,omitempty"`
+	// Policies is a map of Authorization Policies keyed by name
+	Policies Lookup `yaml:"policies,omitempty"`
+
+	memberships map[string][]string // username -> group names
+	governed    sets.Set[string]
+	wildcard    bool
+}
+
+// Policy grants its users and groups access to the requests matching its
+// backends, paths, methods and metrics
+type Policy struct {
+	Name string `yaml:"-"`
+	// Users is the list of usernames granted by this policy. '*' grants any
+	// successfully-authenticated user.
+	Users []string `yaml:"users,omitempty"`
+	// Groups is the list of group names granted by this policy
+	Groups []string `yaml:"groups,omitempty"`
+	// Backends is the list of backend names this policy applies to, or '*'
+	Backends []string `yaml:"backends,omitempty"`
+	// Paths is an optional list of path patterns, where '*' matches any
+	// sequence of characters (including '/'). When empty, all paths match.
+	Paths []string `yaml:"paths,omitempty"`
+	// Methods is an optional list of HTTP methods. When empty, all methods match.
+	Methods []string `yaml:"methods,omitempty"`
+	// Metrics is an optional list of regular expressions, which every metric
+	// name referenced in a Prometheus query must fully match
+	Metrics []string `yaml:"metrics,omitempty"`
+
+	users           sets.Set[string]
+	groups          sets.Set[string]
+	backends        sets.Set[string]
+	methods         sets.Set[string]
+	pathPatterns    []*regexp.Regexp
+	metricsPatterns []*regexp.Regexp
+}
+
+// Lookup is a map of Policies keyed by Policy Name
+type Lookup map[string]*Policy
+
+// New returns a new Authorization Options with default values
+func New() *Options {
+	return &Options{}
+}
+
+// Clone returns a copy of the Options, sharing any compiled state
+func (o *Options) Clone() *Options {
+	out := &Options{
+		Groups:      make(map[string][]string, len(o.Groups)),
+		memberships: o.memberships,
+		wildcard:    o.wildcard,
+	}
+	if o.governed != nil {
+		out.governed = o.governed.Clone()
+	}
+	for k, v := range o.Groups {
+		out.Groups[k] = slices.Clone(v)
+	}
+	if o.Policies != nil {
+		out.Policies = make(Lookup, len(o.Policies))
+		for k, p := range o.Policies {
+			out.Policies[k] = p.Clone()
+		}
+	}
+	return out
+}
+
+// Clone returns a copy of the Policy, sharing any compiled state
+func (p *Policy) Clone() *Policy {
+	return &Policy{
+		Name:            p.Name,
+		Users:           slices.Clone(p.Users),
+		Groups:          slices.Clone(p.Groups),
+		Backends:        slices.Clone(p.Backends),
+		Paths:           slices.Clone(p.Paths),
+		Methods:         slices.Clone(p.Methods),
+		Metrics:         slices.Clone(p.Metrics),
+		users:           p.users,
+		groups:          p.groups,
+		backends:        p.backends,
+		methods:         p.methods,
+		pathPatterns:    p.pathPatterns,
+		metricsPatterns: p.metricsPatterns,
+	}
+}
+
+// Initialize compiles the Policies' patterns and indexes group memberships
+func (o *Options) Initialize() error {
+	o.memberships = make(map[string][]string)
+	for _, g := range slices.Sorted(maps.Keys(o.Groups)) {
+		for _, u := range o.Groups[g] {
+			o.memberships[u] = append(o.memberships[u], g)
+		}
+	}
+	o.governed = sets.NewStringSet()
+	o.wildcard = false
+	for k, p := range o.Policies {
+		if p == nil {
+			return fmt.Errorf("authorization policy %q is empty", k)
+		}
+		p.Name = k
+		if err := p.Initialize(); err != nil {
+			return fmt.Errorf("authorization policy %q: %w", k, err)
+		}
+		o.governed.Merge(p.backends)
+		o.wildcard = o.wildcard || p.backends.Contains(Wildcard)
+	}
+	return nil
+}
+
+// Initialize compiles the Policy's patterns
+func (p *Policy) Initialize() error {
+	if len(p.Users) == 0 && len(p.Groups) == 0 {
+		return ErrNoPolicySubjects
+	}
+	if len(p.Backends) == 0 {
+		return ErrNoPolicyBackends
+	}
+	p.users = sets.New(p.Users)
+	p.groups = sets.New(p.Groups)
+	p.backends = sets.New(p.Backends)
+	p.methods = sets.NewStringSet()
+	for _, m := range p.Methods {
+		p.methods.Set(strings.ToUpper(m))
+	}
+	p.pathPatterns = make([]*regexp.Regexp, len(p.Paths))
+	for i, path := range p.Paths {
+		p.pathPatterns[i] = compileGlob(path)
+	}
+	p.metricsPatterns = make([]*regexp.Regexp, len(p.Metrics))
+	for i, m := range p.Metrics {
+		re, err := regexp.Compile("^(?:" + m + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid metrics pattern %q: %w", m, err)
+		}
+		p.metricsPatterns[i] = re
+	}
+	return nil
+}
+
+// compileGlob compiles a path pattern where '*' matches any characters
+func compileGlob(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, Wildcard)
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// Validate ensures each Policy's backends are defined
+func (o *Options) Validate(backendNames sets.Set[string]) error {
+	for _, k := range slices.Sorted(maps.Keys(o.Policies)) {
+		for _, b := range o.Policies[k].Backends {
+			if b != Wildcard && !backendNames.Contains(b) {
+				return fmt.Errorf("authorization policy %q references undefined backend %q", k, b)
+			}
+		}
+	}
+	return nil
+}
+
+// Governs returns true if any Policy applies to the backend, in which case
+// all requests to the backend must be granted by a Policy
+func (o *Options) Governs(backendName string) bool {
+	return o != nil && (o.wildcard || o.governed.Contains(backendName))
+}
+
+// GroupsOf returns the configured groups having username as a member
+func (o *Options) GroupsOf(username string) []string {
+	return o.memberships[username]
+}
+
+// SortedPolicies returns the Policies ordered by name
+func (o *Options) SortedPolicies() []*Policy {
+	out := make([]*Policy, 0, len(o.Policies))
+	for _, k := range slices.Sorted(maps.Keys(o.Policies)) {
+		out = append(out, o.Policies[k])
+	}
+	return out
+}
+
+// AppliesTo returns true if the Policy covers the backend
+func (p *Policy) AppliesTo(backendName string) bool {
+	return p.backends.Contains(Wildcard) || p.backends.Contains(backendName)
+}
+
+// Grants returns true if the Policy grants the user (or one of the groups).
+// Anonymous users, with an empty username, are never granted.
+func (p *Policy) Grants(username string, groups []string) bool {
+	if username == "" {
+		return false
+	}
+	if p.users.Contains(Wildcard) || p.users.Contains(username) {
+		return true
+	}
+	for _, g := range groups {
+		if p.groups.Contains(g) {
+			return true
+		}
+	}
+	return false
+}
+
+// PermitsPath returns true if the path matches the Policy's path patterns.
+// The path is cleaned before matching so that variants like '//' or '/./'
+// cannot be used to evade a pattern.
+func (p *Policy) PermitsPath(urlPath string) bool {
+	if len(p.pathPatterns) == 0 {
+		return true
+	}
+	urlPath = cleanPath(urlPath)
+	for _, re := range p.pathPatterns {
+		if re.MatchString(urlPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanPath returns the rooted, cleaned form of urlPath, preserving any
+// trailing slash
+func cleanPath(urlPath string) string {
+	cleaned := path.Clean("/" + urlPath)
+	if cleaned != "/" && strings.HasSuffix(urlPath, "/") {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// PermitsMethod returns true if the method is in the Policy's methods
+func (p *Policy) PermitsMethod(method string) bool {
+	if len(p.methods) == 0 {
+		return true
+	}
+	if method == http.MethodHead && p.methods.Contains(http.MethodGet) {
+		return true
+	}
+	return p.methods.Contains(method)
+}
+
+// RestrictsMetrics returns true if the Policy has metrics patterns
+func (p *Policy) RestrictsMetrics() bool {
+	return len(p.metricsPatterns) > 0
+}
+
+// PermitsMetric returns true if the metric name matches a metrics pattern
+func (p *Policy) PermitsMetric(name string) bool {
+	if len(p.metricsPatterns) == 0 {
+		return true
+	}
+	for _, re := range p.metricsPatterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML decodes an authorization configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/trickstercache/trickster/v2/pkg/util/sets"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const testYAML = `
+groups:
+  admins:
+    - alice
+  devs:
+    - alice
+    - bob
+policies:
+  admin:
+    groups: [admins]
+    backends: ['*']
+  dev-read:
+    users: [carol]
+    groups: [devs]
+    backends: [prom1]
+    paths: [/api/v1/query*, /api/v1/series]
+    methods: [get, post]
+    metrics: ['up', 'node_.*']
+`
+
+func testOptions(t *testing.T) *Options {
+	t.Helper()
+	o := New()
+	if err := yaml.Unmarshal([]byte(testYAML), o); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestInitialize(t *testing.T) {
+	o := testOptions(t)
+	if !o.Governs("prom1") || !o.Governs("other") {
+		t.Error("expected wildcard policy to govern all backends")
+	}
+	if got := o.GroupsOf("alice"); len(got) != 2 || got[0] != "admins" || got[1] != "devs" {
+		t.Errorf("unexpected groups for alice: %v", got)
+	}
+	if got := o.GroupsOf("nobody"); len(got) != 0 {
+		t.Errorf("unexpected groups for nobody: %v", got)
+	}
+	ps := o.SortedPolicies()
+	if len(ps) != 2 || ps[0].Name != "admin" || ps[1].Name != "dev-read" {
+		t.Fatalf("unexpected policies: %v", ps)
+	}
+
+	o.Policies["admin"].Backends = []string{"prom2"}
+	if err := o.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if !o.Governs("prom1") || !o.Governs("prom2") || o.Governs("other") {
+		t.Error("unexpected governed backends")
+	}
+	var nilOpts *Options
+	if nilOpts.Governs("prom1") {
+		t.Error("expected nil options to govern nothing")
+	}
+}
+
+func TestInitializeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *Policy
+		err    error
+	}{
+		{"no subjects", &Policy{Backends: []string{"a"}}, ErrNoPolicySubjects},
+		{"no backends", &Policy{Users: []string{"a"}}, ErrNoPolicyBackends},
+		{"bad metrics", &Policy{Users: []string{"a"}, Backends: []string{"a"},
+			Metrics: []string{"("}}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := &Options{Policies: Lookup{"p": test.policy}}
+			err := o.Initialize()
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if test.err != nil && !errors.Is(err, test.err) {
+				t.Errorf("expected %v got %v", test.err, err)
+			}
+		})
+	}
+	o := &Options{Policies: Lookup{"p": nil}}
+	if err := o.Initialize(); err == nil {
+		t.Error("expected error for empty policy")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o := testOptions(t)
+	if err := o.Validate(sets.New([]string{"prom1"})); err != nil {
+		t.Error(err)
+	}
+	if err := o.Validate(sets.New([]string{"prom2"})); err == nil {
+		t.Error("expected error for undefined backend")
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := testOptions(t).Policies["dev-read"]
+	if !p.AppliesTo("prom1") || p.AppliesTo("prom2") {
+		t.Error("unexpected AppliesTo result")
+	}
+	if !p.Grants("carol", nil) || !p.Grants("dave", []string{"devs"}) ||
+		p.Grants("dave", []string{"admins"}) || p.Grants("", []string{"devs"}) {
+		t.Error("unexpected Grants result")
+	}
+	for path, want := range map[string]bool{
+		"/api/v1/query":           true,
+		"/api/v1/query_range":     true,
+		"/api/v1/series":          true,
+		"/api/v1/series/extra":    false,
+		"/api/v1/labels":          false,
+		"/prefix/api/v1/series":   false,
+		"//api/v1/labels":         false,
+		"/api/v1/./labels":        false,
+		"/api/v1/query/../labels": false,
+		"/x/../api/v1/series":     true,
+		"api/v1/query":            true,
+	} {
+		if got := p.PermitsPath(path); got != want {
+			t.Errorf("PermitsPath(%q): expected %t got %t", path, want, got)
+		}
+	}
+	for method, want := range map[string]bool{
+		http.MethodGet:    true,
+		http.MethodHead:   true,
+		http.MethodPost:   true,
+		http.MethodDelete: false,
+	} {
+		if got := p.PermitsMethod(method); got != want {
+			t.Errorf("PermitsMethod(%q): expected %t got %t", method, want, got)
+		}
+	}
+	if !p.RestrictsMetrics() {
+		t.Error("expected metrics restriction")
+	}
+	for name, want := range map[string]bool{
+		"up":             true,
+		"node_cpu":       true,
+		"upstream":       false,
+		"go_goroutines":  false,
+		"xnode_cpu_secs": false,
+	} {
+		if got := p.PermitsMetric(name); got != want {
+			t.Errorf("PermitsMetric(%q): expected %t got %t", name, want, got)
+		}
+	}
+
+	p = &Policy{Users: []string{Wildcard}, Backends: []string{"a"}}
+	if err := p.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if !p.Grants("anyone", nil) || !p.PermitsPath("/x") ||
+		!p.PermitsMethod(http.MethodPut) || p.RestrictsMetrics() ||
+		!p.PermitsMetric("x") {
+		t.Error("expected unrestricted policy")
+	}
+}
+
+func TestClone(t *testing.T) {
+	o := testOptions(t)
+	c := o.Clone()
+	c.Groups["admins"][0] = "mallory"
+	c.Policies["dev-read"].Backends[0] = "prom9"
+	if o.Groups["admins"][0] != "alice" ||
+		o.Policies["dev-read"].Backends[0] != "prom1" {
+		t.Error("clone mutated original")
+	}
+	if !c.Governs("prom1") || !c.Policies["dev-read"].PermitsMetric("up") {
+		t.Error("expected clone to retain compiled state")
+	}
+}
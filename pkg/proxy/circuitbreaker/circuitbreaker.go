@@ -0,0 +1,246 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package circuitbreaker provides a circuit breaker driven by the error rate
+// and latency of upstream requests
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	// StateClosed permits all requests
+	StateClosed State = iota
+	// StateHalfOpen permits a limited number of probe requests
+	StateHalfOpen
+	// StateOpen rejects all requests
+	StateOpen
+)
+
+var stateNames = map[State]string{
+	StateClosed:   "closed",
+	StateHalfOpen: "half_open",
+	StateOpen:     "open",
+}
+
+func (s State) String() string {
+	return stateNames[s]
+}
+
+// Outcome is the result of a request permitted by a circuit breaker
+type Outcome int
+
+const (
+	// OutcomeSuccess indicates the request succeeded
+	OutcomeSuccess Outcome = iota
+	// OutcomeFailure indicates the request failed
+	OutcomeFailure
+	// OutcomeIgnored indicates the request's result should not be counted,
+	// such as when the client canceled it
+	OutcomeIgnored
+)
+
+// numBuckets is the number of buckets in the rolling window
+const numBuckets = 10
+
+var now = time.Now
+
+// Config defines the thresholds of a Breaker
+type Config struct {
+	// Window is the duration of the rolling window of request outcomes
+	Window time.Duration
+	// MinRequests is the minimum number of requests in the window before
+	// the circuit can open
+	MinRequests int
+	// FailureRate is the rate of failed requests in the window that opens
+	// the circuit
+	FailureRate float64
+	// SlowCallDuration is the latency at which a request is slow. Zero
+	// disables slow call detection
+	SlowCallDuration time.Duration
+	// SlowCallRate is the rate of slow requests in the window that opens
+	// the circuit
+	SlowCallRate float64
+	// OpenDuration is how long the circuit stays open before half-opening
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of probe requests permitted while
+	// half-open, all of which must succeed to close the circuit
+	HalfOpenProbes int
+}
+
+// Breaker is a circuit breaker
+type Breaker struct {
+	cfg           Config
+	onStateChange func(State)
+
+	mtx       sync.Mutex
+	state     State
+	openUntil time.Time
+	buckets   [numBuckets]bucket
+	probes    int
+	successes int
+}
+
+type bucket struct {
+	start    time.Time
+	total    int
+	failures int
+	slow     int
+}
+
+// New returns a new, closed Breaker. onStateChange, when not nil, is called
+// with the new state whenever it changes
+func New(cfg Config, onStateChange func(State)) *Breaker {
+	return &Breaker{cfg: cfg, onStateChange: onStateChange}
+}
+
+// State returns the current state of the Breaker
+func (b *Breaker) State() State {
+	if b == nil {
+		return StateClosed
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.checkOpenExpired(now())
+	return b.state
+}
+
+// IsOpen returns true if the Breaker is rejecting all requests
+func (b *Breaker) IsOpen() bool {
+	return b.State() == StateOpen
+}
+
+// Allow returns true if a request is permitted. When permitted, the
+// returned function must be called with the request's outcome and latency
+func (b *Breaker) Allow() (func(Outcome, time.Duration), bool) {
+	if b == nil {
+		return func(Outcome, time.Duration) {}, true
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.checkOpenExpired(now())
+	switch b.state {
+	case StateOpen:
+		return nil, false
+	case StateHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			return nil, false
+		}
+		b.probes++
+		return b.doneFunc(true), true
+	}
+	return b.doneFunc(false), true
+}
+
+func (b *Breaker) doneFunc(probe bool) func(Outcome, time.Duration) {
+	var once sync.Once
+	return func(o Outcome, latency time.Duration) {
+		once.Do(func() { b.record(probe, o, latency) })
+	}
+}
+
+func (b *Breaker) record(probe bool, o Outcome, latency time.Duration) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	t := now()
+	failed := o == OutcomeFailure
+	slow := b.cfg.SlowCallDuration > 0 && latency >= b.cfg.SlowCallDuration
+	if probe {
+		if b.state != StateHalfOpen {
+			return
+		}
+		switch {
+		case o == OutcomeIgnored:
+			// free the probe slot for another request
+			b.probes--
+		case failed || slow:
+			b.open(t)
+		default:
+			b.successes++
+			if b.successes >= b.cfg.HalfOpenProbes {
+				b.setState(StateClosed)
+				b.buckets = [numBuckets]bucket{}
+			}
+		}
+		return
+	}
+	if b.state != StateClosed || o == OutcomeIgnored {
+		return
+	}
+	bk := b.bucket(t)
+	bk.total++
+	if failed {
+		bk.failures++
+	}
+	if slow {
+		bk.slow++
+	}
+	var total, failures, slows int
+	for i := range b.buckets {
+		if t.Sub(b.buckets[i].start) < b.cfg.Window {
+			total += b.buckets[i].total
+			failures += b.buckets[i].failures
+			slows += b.buckets[i].slow
+		}
+	}
+	if total < b.cfg.MinRequests || total == 0 {
+		return
+	}
+	if float64(failures)/float64(total) >= b.cfg.FailureRate ||
+		(b.cfg.SlowCallDuration > 0 &&
+			float64(slows)/float64(total) >= b.cfg.SlowCallRate) {
+		b.open(t)
+	}
+}
+
+// bucket returns the window bucket for t, resetting it if it is stale
+func (b *Breaker) bucket(t time.Time) *bucket {
+	width := max(b.cfg.Window/numBuckets, time.Millisecond)
+	start := t.Truncate(width)
+	bk := &b.buckets[(start.UnixNano()/int64(width))%numBuckets]
+	if !bk.start.Equal(start) {
+		*bk = bucket{start: start}
+	}
+	return bk
+}
+
+func (b *Breaker) open(t time.Time) {
+	b.openUntil = t.Add(b.cfg.OpenDuration)
+	b.setState(StateOpen)
+}
+
+func (b *Breaker) checkOpenExpired(t time.Time) {
+	if b.state == StateOpen && !t.Before(b.openUntil) {
+		b.probes = 0
+		b.successes = 0
+		b.setState(StateHalfOpen)
+	}
+}
+
+func (b *Breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+	b.state = s
+	if b.onStateChange != nil {
+		b.onStateChange(s)
+	}
+}
@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+)
+
+func testConfig() Config {
+	return Config{
+		Window:           10 * time.Second,
+		MinRequests:      4,
+		FailureRate:      0.5,
+		SlowCallDuration: time.Second,
+		SlowCallRate:     0.75,
+		OpenDuration:     30 * time.Second,
+		HalfOpenProbes:   2,
+	}
+}
+
+func setNow(t *testing.T, ts *time.Time) {
+	t.Helper()
+	orig := now
+	now = func() time.Time { return *ts }
+	t.Cleanup(func() { now = orig })
+}
+
+func request(t *testing.T, b *Breaker, o Outcome, latency time.Duration) {
+	t.Helper()
+	done, ok := b.Allow()
+	if !ok {
+		t.Fatal("expected request to be allowed")
+	}
+	done(o, latency)
+}
+
+func TestBreakerLifecycle(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	setNow(t, &ts)
+
+	var states []State
+	b := New(testConfig(), func(s State) { states = append(states, s) })
+
+	// below min_requests, failures do not open the circuit
+	request(t, b, OutcomeFailure, 0)
+	request(t, b, OutcomeFailure, 0)
+	request(t, b, OutcomeSuccess, 0)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+	request(t, b, OutcomeSuccess, 0)
+	if b.State() != StateOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+	if _, ok := b.Allow(); ok {
+		t.Error("expected request to be rejected while open")
+	}
+
+	ts = ts.Add(30 * time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half_open, got %s", b.State())
+	}
+	done1, ok1 := b.Allow()
+	done2, ok2 := b.Allow()
+	if !ok1 || !ok2 {
+		t.Fatal("expected probes to be allowed")
+	}
+	if _, ok := b.Allow(); ok {
+		t.Error("expected requests beyond the probes to be rejected")
+	}
+	done1(OutcomeSuccess, 0)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half_open, got %s", b.State())
+	}
+	done2(OutcomeSuccess, 0)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+
+	expected := []State{StateOpen, StateHalfOpen, StateClosed}
+	if len(states) != len(expected) {
+		t.Fatalf("expected transitions %v, got %v", expected, states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Fatalf("expected transitions %v, got %v", expected, states)
+		}
+	}
+}
+
+func TestBreakerProbeFailure(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	setNow(t, &ts)
+
+	b := New(testConfig(), nil)
+	for range 4 {
+		request(t, b, OutcomeFailure, 0)
+	}
+	ts = ts.Add(30 * time.Second)
+
+	// an ignored probe frees its slot
+	request(t, b, OutcomeIgnored, 0)
+	request(t, b, OutcomeSuccess, 0)
+	request(t, b, OutcomeSuccess, 2*time.Second) // slow probes fail
+	if b.State() != StateOpen {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+}
+
+func TestBreakerSlowCalls(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	setNow(t, &ts)
+
+	b := New(testConfig(), nil)
+	for range 3 {
+		request(t, b, OutcomeSuccess, 2*time.Second)
+	}
+	request(t, b, OutcomeSuccess, 0)
+	if !b.IsOpen() {
+		t.Fatalf("expected open, got %s", b.State())
+	}
+}
+
+func TestBreakerWindow(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	setNow(t, &ts)
+
+	b := New(testConfig(), nil)
+	request(t, b, OutcomeFailure, 0)
+	request(t, b, OutcomeFailure, 0)
+	// earlier failures age out of the window
+	ts = ts.Add(11 * time.Second)
+	request(t, b, OutcomeFailure, 0)
+	request(t, b, OutcomeSuccess, 0)
+	request(t, b, OutcomeSuccess, 0)
+	request(t, b, OutcomeSuccess, 0)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+	request(t, b, OutcomeIgnored, 0)
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, got %s", b.State())
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	done, ok := b.Allow()
+	if !ok {
+		t.Fatal("expected nil breaker to allow")
+	}
+	done(OutcomeFailure, 0)
+	if b.IsOpen() {
+		t.Error("expected nil breaker to be closed")
+	}
+}
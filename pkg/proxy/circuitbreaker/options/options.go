@@ -0,0 +1,199 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for upstream circuit breakers
+package options
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	cb "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// DefaultWindow is the default duration of the rolling window
+	DefaultWindow = 10 * time.Second
+	// DefaultMinRequests is the default minimum number of requests in the
+	// window before the circuit can open
+	DefaultMinRequests = 20
+	// DefaultFailureRateThreshold is the default rate of failed requests
+	// that opens the circuit
+	DefaultFailureRateThreshold = 0.5
+	// DefaultSlowCallRateThreshold is the default rate of slow requests that
+	// opens the circuit
+	DefaultSlowCallRateThreshold = 0.5
+	// DefaultOpenDuration is the default time the circuit stays open
+	DefaultOpenDuration = 30 * time.Second
+	// DefaultHalfOpenProbes is the default number of probe requests
+	// permitted while half-open
+	DefaultHalfOpenProbes = 3
+)
+
+var (
+	ErrInvalidWindow         = errors.New("circuit_breaker window must be greater than 0")
+	ErrInvalidMinRequests    = errors.New("circuit_breaker min_requests must be greater than 0")
+	ErrInvalidFailureRate    = errors.New("circuit_breaker failure_rate_threshold must be greater than 0 and at most 1")
+	ErrInvalidSlowCallRate   = errors.New("circuit_breaker slow_call_rate_threshold must be greater than 0 and at most 1")
+	ErrInvalidSlowCall       = errors.New("circuit_breaker slow_call_duration must not be negative")
+	ErrInvalidOpenDuration   = errors.New("circuit_breaker open_duration must be greater than 0")
+	ErrInvalidHalfOpenProbes = errors.New("circuit_breaker half_open_probes must be greater than 0")
+)
+
+// Options defines a circuit breaker for a Backend's upstream requests
+type Options struct {
+	// Window is the duration of the rolling window of upstream request
+	// outcomes. The default is 10s
+	Window timeconv.Duration `yaml:"window,omitempty"`
+	// MinRequests is the minimum number of requests in the window before the
+	// circuit can open. The default is 20
+	MinRequests int `yaml:"min_requests,omitempty"`
+	// FailureRateThreshold is the rate of failed requests (transport errors
+	// and 5xx responses) in the window that opens the circuit. The default
+	// is 0.5
+	FailureRateThreshold float64 `yaml:"failure_rate_threshold,omitempty"`
+	// SlowCallDuration is the latency at which an upstream request is slow.
+	// Slow call detection is disabled when unset
+	SlowCallDuration timeconv.Duration `yaml:"slow_call_duration,omitempty"`
+	// SlowCallRateThreshold is the rate of slow requests in the window that
+	// opens the circuit. The default is 0.5
+	SlowCallRateThreshold float64 `yaml:"slow_call_rate_threshold,omitempty"`
+	// OpenDuration is how long the circuit stays open before permitting
+	// probe requests. The default is 30s
+	OpenDuration timeconv.Duration `yaml:"open_duration,omitempty"`
+	// HalfOpenProbes is the number of probe requests permitted while
+	// half-open, all of which must succeed to close the circuit. The
+	// default is 3
+	HalfOpenProbes int `yaml:"half_open_probes,omitempty"`
+
+	backendName string
+	breaker     *sharedBreaker
+}
+
+// sharedBreaker holds the Breaker for an Options and is shared by its clones
+type sharedBreaker struct {
+	once sync.Once
+	b    *cb.Breaker
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{
+		Window:                timeconv.Duration(DefaultWindow),
+		MinRequests:           DefaultMinRequests,
+		FailureRateThreshold:  DefaultFailureRateThreshold,
+		SlowCallRateThreshold: DefaultSlowCallRateThreshold,
+		OpenDuration:          timeconv.Duration(DefaultOpenDuration),
+		HalfOpenProbes:        DefaultHalfOpenProbes,
+		breaker:               &sharedBreaker{},
+	}
+}
+
+// Clone returns a copy of the Options that shares its circuit breaker
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	return pointers.Clone(o)
+}
+
+// Initialize associates the Options with the named Backend for metrics
+func (o *Options) Initialize(backendName string) error {
+	if o == nil {
+		return nil
+	}
+	o.backendName = backendName
+	if o.breaker == nil {
+		o.breaker = &sharedBreaker{}
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.Window <= 0 {
+		return false, ErrInvalidWindow
+	}
+	if o.MinRequests <= 0 {
+		return false, ErrInvalidMinRequests
+	}
+	if o.FailureRateThreshold <= 0 || o.FailureRateThreshold > 1 {
+		return false, ErrInvalidFailureRate
+	}
+	if o.SlowCallDuration < 0 {
+		return false, ErrInvalidSlowCall
+	}
+	if o.SlowCallRateThreshold <= 0 || o.SlowCallRateThreshold > 1 {
+		return false, ErrInvalidSlowCallRate
+	}
+	if o.OpenDuration <= 0 {
+		return false, ErrInvalidOpenDuration
+	}
+	if o.HalfOpenProbes <= 0 {
+		return false, ErrInvalidHalfOpenProbes
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a circuit_breaker configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
+
+// Breaker returns the circuit breaker for the Options, or nil if the Options
+// have not been initialized
+func (o *Options) Breaker() *cb.Breaker {
+	if o == nil || o.breaker == nil {
+		return nil
+	}
+	sb := o.breaker
+	sb.once.Do(func() {
+		name := o.backendName
+		gauge := metrics.ProxyCircuitBreakerState.WithLabelValues(name)
+		gauge.Set(float64(cb.StateClosed))
+		sb.b = cb.New(cb.Config{
+			Window:           time.Duration(o.Window),
+			MinRequests:      o.MinRequests,
+			FailureRate:      o.FailureRateThreshold,
+			SlowCallDuration: time.Duration(o.SlowCallDuration),
+			SlowCallRate:     o.SlowCallRateThreshold,
+			OpenDuration:     time.Duration(o.OpenDuration),
+			HalfOpenProbes:   o.HalfOpenProbes,
+		}, func(s cb.State) {
+			gauge.Set(float64(s))
+			metrics.ProxyCircuitBreakerTransitions.WithLabelValues(name, s.String()).Inc()
+		})
+	})
+	return sb.b
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	cb "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Options)
+		wantErr error
+	}{
+		{name: "defaults", mutate: func(*Options) {}},
+		{name: "window", mutate: func(o *Options) { o.Window = 0 },
+			wantErr: ErrInvalidWindow},
+		{name: "min requests", mutate: func(o *Options) { o.MinRequests = 0 },
+			wantErr: ErrInvalidMinRequests},
+		{name: "failure rate", mutate: func(o *Options) { o.FailureRateThreshold = 1.5 },
+			wantErr: ErrInvalidFailureRate},
+		{name: "slow call", mutate: func(o *Options) { o.SlowCallDuration = -1 },
+			wantErr: ErrInvalidSlowCall},
+		{name: "slow call rate", mutate: func(o *Options) { o.SlowCallRateThreshold = 0 },
+			wantErr: ErrInvalidSlowCallRate},
+		{name: "open duration", mutate: func(o *Options) { o.OpenDuration = 0 },
+			wantErr: ErrInvalidOpenDuration},
+		{name: "half open probes", mutate: func(o *Options) { o.HalfOpenProbes = 0 },
+			wantErr: ErrInvalidHalfOpenProbes},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := New()
+			tc.mutate(o)
+			ok, err := o.Validate()
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if ok != (tc.wantErr == nil) {
+				t.Errorf("unexpected validation result %t", ok)
+			}
+		})
+	}
+}
+
+func TestOptionsYAML(t *testing.T) {
+	o := &Options{}
+	if err := yaml.Unmarshal([]byte("min_requests: 5\nslow_call_duration: 2s\n"), o); err != nil {
+		t.Fatal(err)
+	}
+	if o.MinRequests != 5 {
+		t.Errorf("expected min_requests 5, got %d", o.MinRequests)
+	}
+	if time.Duration(o.SlowCallDuration) != 2*time.Second {
+		t.Errorf("expected slow_call_duration 2s, got %s", time.Duration(o.SlowCallDuration))
+	}
+	if time.Duration(o.OpenDuration) != DefaultOpenDuration {
+		t.Errorf("expected default open_duration, got %s", time.Duration(o.OpenDuration))
+	}
+	if o.HalfOpenProbes != DefaultHalfOpenProbes {
+		t.Errorf("expected default half_open_probes, got %d", o.HalfOpenProbes)
+	}
+}
+
+func TestBreakerSharedByClones(t *testing.T) {
+	o := New()
+	if err := o.Initialize("test"); err != nil {
+		t.Fatal(err)
+	}
+	b := o.Breaker()
+	if b == nil || b != o.Clone().Breaker() {
+		t.Fatal("expected clones to share the breaker")
+	}
+	if b.State() != cb.StateClosed {
+		t.Errorf("expected closed, got %s", b.State())
+	}
+	if (&Options{}).Breaker() != nil {
+		t.Error("expected nil breaker for uninitialized options")
+	}
+}
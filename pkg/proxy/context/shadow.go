@@ -0,0 +1,36 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+)
+
+// WithShadowFlag returns a copy of the provided context that also includes a
+// bit indicating the request is mirrored to a shadow backend
+func WithShadowFlag(ctx context.Context, isShadow bool) context.Context {
+	return context.WithValue(ctx, shadowKey, isShadow)
+}
+
+// ShadowFlag returns true if the request is mirrored to a shadow backend
+func ShadowFlag(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	b, _ := ctx.Value(shadowKey).(bool)
+	return b
+}
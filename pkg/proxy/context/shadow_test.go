@@ -0,0 +1,33 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package context
+
+import (
+	"context"
+	"testing"
+)
+
+func TestShadow(t *testing.T) {
+	ctx := context.Background()
+	if ShadowFlag(ctx) {
+		t.Error("expected false")
+	}
+	ctx = WithShadowFlag(ctx, true)
+	if !ShadowFlag(ctx) {
+		t.Error("expected true")
+	}
+}
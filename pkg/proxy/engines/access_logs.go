@@ -19,21 +19,27 @@ package engines
 import (
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	lo "github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
 )
 
+// logUpstreamRequest writes the upstream request to the access log when one is
+// configured, and otherwise to the application log at the debug level
 func logUpstreamRequest(backendName, backendProvider, handlerName, method,
 	path, userAgent string, responseCode, size int, requestDuration float64,
 ) {
-	logger.Debug("upstream request",
-		logging.Pairs{
-			"backendName":     backendName,
-			"backendProvider": backendProvider,
-			"handlerName":     handlerName,
-			"method":          method,
-			"uri":             path,
-			"userAgent":       userAgent,
-			"code":            responseCode,
-			"size":            size,
-			"durationMS":      int(requestDuration * 1000),
-		})
+	pairs := logging.Pairs{
+		"backendName":     backendName,
+		"backendProvider": backendProvider,
+		"handlerName":     handlerName,
+		"method":          method,
+		"uri":             path,
+		"userAgent":       userAgent,
+		"code":            responseCode,
+		"size":            size,
+		"durationMS":      int(requestDuration * 1000),
+	}
+	if logger.Access(lo.AccessKindUpstream, pairs) {
+		return
+	}
+	logger.Debug("upstream request", pairs)
 }
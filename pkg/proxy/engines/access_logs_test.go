@@ -17,7 +17,9 @@
 package engines
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/trickstercache/trickster/v2/pkg/config"
@@ -44,3 +46,16 @@ func TestLogUpstreamRequest(t *testing.T) {
 	logger.SetLogger(logging.ConsoleLogger(level.Error))
 	l.Close()
 }
+
+func TestLogUpstreamRequestAccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetAccessLogger(logging.StreamAccessLogger(buf, tlo.NewAccessLog()))
+	defer logger.SetAccessLogger(nil)
+	logUpstreamRequest("testBackend", "testType", "testHandler", "GET",
+		"/testPath", "testUserAgent", 200, 10, 1.0)
+	out := buf.String()
+	if !strings.Contains(out, "kind=upstream backendName=testBackend") ||
+		!strings.Contains(out, "durationMS=1000") {
+		t.Errorf("unexpected access log entry: %s", out)
+	}
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/trickstercache/trickster/v2/pkg/cache"
 	"github.com/trickstercache/trickster/v2/pkg/cache/status"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
 	tspan "github.com/trickstercache/trickster/v2/pkg/observability/tracing/span"
 	tc "github.com/trickstercache/trickster/v2/pkg/proxy/context"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
@@ -321,6 +322,11 @@ func WriteCache(ctx context.Context, c cache.Cache, key string, d *HTTPDocument,
 		}
 		return err
 	}
+	if opts != nil {
+		if label, ok := opts.Tenancy.MetricsLabel(rsc.Tenant); ok {
+			metrics.TenantCacheWrites.WithLabelValues(opts.Name, label).Inc()
+		}
+	}
 	if span != nil {
 		span.AddEvent(
 			"Cache Write",
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	cb "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker"
+)
+
+// checkCircuitBreaker asks the backend's circuit breaker, if one is
+// configured, to permit an upstream request. It returns a function that
+// records the request's outcome, or a 503 Service Unavailable response if
+// the circuit is open
+func checkCircuitBreaker(r *http.Request,
+	o *bo.Options,
+) (func(cb.Outcome, time.Duration), *http.Response) {
+	done, ok := o.CircuitBreaker.Breaker().Allow()
+	if ok {
+		return done, nil
+	}
+	metrics.ProxyCircuitBreakerRejections.WithLabelValues(o.Name).Inc()
+	logger.Debug("upstream request rejected by open circuit breaker",
+		logging.Pairs{"backendName": o.Name, "url": r.URL.String()})
+	return nil, &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Request:    r, Header: make(http.Header),
+	}
+}
+
+// upstreamOutcome classifies an upstream response for the circuit breaker.
+// Transport errors and 5xx responses are failures
+func upstreamOutcome(resp *http.Response, err error) cb.Outcome {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return cb.OutcomeIgnored
+	case err != nil, resp == nil, resp.StatusCode >= http.StatusInternalServerError:
+		return cb.OutcomeFailure
+	}
+	return cb.OutcomeSuccess
+}
+
+// serveStaleOnError returns true if cached content should be served when the
+// origin is unavailable, which is when serve_stale_on_error is set or when
+// the backend's circuit breaker is not closed
+func serveStaleOnError(o *bo.Options) bool {
+	return o.ServeStaleOnError || o.CircuitBreaker.Breaker().State() != cb.StateClosed
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/config"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	cb "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker"
+	cbo "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker/options"
+	tc "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	tu "github.com/trickstercache/trickster/v2/pkg/testutil"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestCircuitBreaker(t *testing.T, name string) *cbo.Options {
+	t.Helper()
+	o := cbo.New()
+	o.MinRequests = 2
+	if err := o.Initialize(name); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestPrepareFetchReader_CircuitBreaker(t *testing.T) {
+	logger.SetLogger(testLogger)
+	conf, err := config.Load([]string{
+		"-origin-url", "http://example.com/",
+		"-provider", "test", "-log-level", "debug",
+	})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	o := conf.Backends["default"]
+	o.Name = "breaker-test"
+	o.CircuitBreaker = newTestCircuitBreaker(t, o.Name)
+	rt := &mockRoundTripper{
+		resp: &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(bytes.NewReader(nil)),
+		},
+	}
+	o.HTTPClient = &http.Client{Transport: rt}
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest("GET", "http://example.com/", nil)
+		return r.WithContext(tc.WithResources(r.Context(),
+			request.NewResources(o, nil, nil, nil, nil, tu.NewTestTracer())))
+	}
+
+	for range 2 {
+		_, resp, _ := PrepareFetchReader(newRequest())
+		if resp == nil || resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("expected 500, got: %+v", resp)
+		}
+	}
+	if !o.CircuitBreaker.Breaker().IsOpen() {
+		t.Fatal("expected the circuit to be open")
+	}
+	if !serveStaleOnError(o) {
+		t.Error("expected stale content to be served while the circuit is open")
+	}
+	_, resp, _ := PrepareFetchReader(newRequest())
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 Service Unavailable, got: %+v", resp)
+	}
+	if len(rt.reqs) != 2 {
+		t.Errorf("expected 2 upstream requests, got %d", len(rt.reqs))
+	}
+	if v := testutil.ToFloat64(metrics.ProxyCircuitBreakerRejections.WithLabelValues(o.Name)); v != 1 {
+		t.Errorf("expected 1 rejection, got %f", v)
+	}
+	if v := testutil.ToFloat64(metrics.ProxyCircuitBreakerState.WithLabelValues(o.Name)); v != float64(cb.StateOpen) {
+		t.Errorf("expected open state gauge, got %f", v)
+	}
+}
+
+func TestObjectProxyCacheCircuitOpenServesStale(t *testing.T) {
+	// objects are retained past their freshness lifetime when they can be
+	// revalidated
+	hdr := map[string]string{
+		headers.NameCacheControl: headers.ValueMaxAge + "=1",
+		headers.NameETag:         "test-etag",
+	}
+	ts, _, r, rsc, err := setupTestHarnessOPC("", "test", http.StatusOK, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeTestHarness(ts, r)
+
+	rsc.PathConfig.ResponseHeaders = hdr
+	rsc.BackendOptions.RevalidationFactor = 2
+	rsc.BackendOptions.CircuitBreaker = newTestCircuitBreaker(t, "opc-breaker-test")
+
+	_, e := testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+
+	time.Sleep(1010 * time.Millisecond)
+
+	// open the circuit
+	b := rsc.BackendOptions.CircuitBreaker.Breaker()
+	for !b.IsOpen() {
+		done, _ := b.Allow()
+		done(cb.OutcomeFailure, 0)
+	}
+
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "stale"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
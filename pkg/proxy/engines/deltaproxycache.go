@@ -44,6 +44,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/failures"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/warming"
 	"github.com/trickstercache/trickster/v2/pkg/timeseries"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -135,7 +136,8 @@ func fetchFastForward(
 
 // finalizeDPCResponse writes metrics, logs, and the HTTP response for a DPC request.
 // If wireBody is non-nil, it is written directly (skipping marshal).
-// Otherwise rts is marshaled to the wire format.
+// Otherwise rts is marshaled to the wire format. The wire body is checked
+// against the query_cost max_response_bytes limit before anything is written.
 func finalizeDPCResponse(
 	w http.ResponseWriter, r *http.Request, rsc *request.Resources,
 	rts timeseries.Timeseries, rh http.Header, sc int,
@@ -164,23 +166,39 @@ func finalizeDPCResponse(
 	// so as to not map conflict with cacheData on WriteCache
 	logDeltaRoutine(dpStatus)
 	recordDPCResult(r, cacheStatus, sc, r.URL.Path, ffStatus, elapsed, missRanges, failed, rh)
+	if cacheStatus != status.LookupStatusPurge {
+		observeForWarming(r, rsc, cacheStatus, key, modeler)
+		if co, ok := rsc.BackendClient.(backends.CachedQueryObserver); ok {
+			co.ObserveCachedQuery(r, key, rsc.TimeRangeQuery)
+		}
+	}
 
-	rsc.TS = rts
-	Respond(w, 0, rh, nil) // body and code are nil so this only sets appropriate headers; no writes
 	if rsc.TSTransformer != nil {
 		rsc.TSTransformer(rts)
 	}
-	if rsc.IsMergeMember { // don't bother marshaling this dataset if it's just going to be merged internally
+	// merge members are only marshaled when needed to enforce the size limit,
+	// since their datasets are otherwise merged internally
+	if wireBody == nil && (!rsc.IsMergeMember || limitsQueryBytes(rsc)) {
+		var buf bytes.Buffer
+		modeler.WireMarshalWriter(rts, rlo, sc, &buf)
+		wireBody = buf.Bytes()
+	}
+	if cw := limitQueryBytes(r, rsc, rsc.TimeRangeQuery, len(wireBody)); cw != nil {
+		if rsc.IsMergeMember {
+			rsc.Response = &http.Response{StatusCode: cw.StatusCode()}
+		}
+		Respond(w, cw.StatusCode(), cw.Header(), bytes.NewReader(cw.Body()))
+		return
+	}
+	rsc.TS = rts
+	Respond(w, 0, rh, nil) // body and code are nil so this only sets appropriate headers; no writes
+	if rsc.IsMergeMember {
 		if rsc.Response == nil {
 			rsc.Response = &http.Response{StatusCode: sc}
 		}
 		return
 	}
-	if wireBody != nil {
-		w.Write(wireBody)
-	} else {
-		modeler.WireMarshalWriter(rts, rlo, sc, w)
-	}
+	w.Write(wireBody)
 }
 
 // DeltaProxyCache is used for Time Series Acceleration, but not for normal HTTP Object Caching
@@ -195,7 +213,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 		rsc.TSUnmarshaler = modeler.WireUnmarshaler
 	}
 	o := rsc.BackendOptions
-	if o == nil {
+	if o == nil || bypassesCache(r) {
 		DoProxy(w, r, true)
 		return
 	}
@@ -221,7 +239,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 			if trq != nil && trq.OriginalBody != nil {
 				request.SetBody(r, trq.OriginalBody)
 			}
-			DoProxy(w, r, true)
+			proxyQuery(w, r)
 			return
 		}
 		if canOPC {
@@ -235,14 +253,14 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 		if trq != nil && trq.OriginalBody != nil {
 			request.SetBody(r, trq.OriginalBody)
 		}
-		DoProxy(w, r, true)
+		proxyQuery(w, r)
 		return
 	}
 	if o.ProxyOnly {
 		if trq.OriginalBody != nil {
 			request.SetBody(r, trq.OriginalBody)
 		}
-		DoProxy(w, r, true)
+		proxyQuery(w, r)
 		return
 	}
 	var cacheStatus status.LookupStatus
@@ -270,7 +288,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 			if trq.OriginalBody != nil {
 				request.SetBody(r, trq.OriginalBody)
 			}
-			DoProxy(w, r, true)
+			proxyQuery(w, r)
 			return
 		}
 	}
@@ -414,15 +432,23 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 				mts, _, mresp, failedExts, severeFault = fetchExtents(missRanges, frsc,
 					fetchHeaders, client, pr, modeler.WireUnmarshalerReader, span)
 				if len(failedExts) > 0 && severeFault {
-					// mresp.Body is only set inside fetchExtents's non-200
-					// branch; when every shard fails at the transport level
-					// (e.g. dial refused) mresp.Body remains nil and
-					// io.ReadAll(nil) panics on the first Read.
-					var body []byte
-					if mresp != nil && mresp.Body != nil {
-						body, _ = io.ReadAll(mresp.Body)
+					if !serveStaleOnError(o) || cacheStatus != status.LookupStatusPartialHit {
+						// mresp.Body is only set inside fetchExtents's non-200
+						// branch; when every shard fails at the transport level
+						// (e.g. dial refused) mresp.Body remains nil and
+						// io.ReadAll(nil) panics on the first Read.
+						var body []byte
+						if mresp != nil && mresp.Body != nil {
+							body, _ = io.ReadAll(mresp.Body)
+						}
+						return buildErrorResult(mresp.StatusCode, mresp.Header.Clone(), body, failedExts), nil
 					}
-					return buildErrorResult(mresp.StatusCode, mresp.Header.Clone(), body, failedExts), nil
+					// the origin is unavailable, but part of the requested range is cached,
+					// so serve what is cached rather than failing the whole request
+					logger.Warn("origin unavailable, serving stale timeseries",
+						logging.Pairs{"backendName": o.Name, "cacheKey": key,
+							"failedRanges": failedExts.String()})
+					cacheStatus = status.LookupStatusStale
 				}
 				doc.Headers = fetchHeaders
 				// Merge the new delta timeseries into the cached timeseries
@@ -435,7 +461,8 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 
 			// this handles the tolerance part of backfill tolerance, by adding new tolerable ranges to
 			// the timeseries's volatile list, and removing those that no longer tolerate backfill
-			if bt > 0 && cacheStatus != status.LookupStatusHit {
+			if bt > 0 && cacheStatus != status.LookupStatusHit &&
+				cacheStatus != status.LookupStatusStale {
 				var shouldCompress bool
 				ve := cts.VolatileExtents()
 				// first, remove those that are now too old to tolerate backfill.
@@ -469,13 +496,19 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 			var rts timeseries.Timeseries
 			if cacheStatus != status.LookupStatusKeyMiss {
 				rts = cts.CroppedClone(trq.Extent)
+				if cacheStatus == status.LookupStatusHit {
+					maybeVerifyCacheHit(pr, o, key, trq, client, modeler, rts)
+				}
 			} else {
 				rts = cts.Clone()
 			}
+			if cw := limitQuerySeries(r, rsc, trq, rts); cw != nil {
+				return buildErrorResult(cw.StatusCode(), cw.Header(), cw.Body(), nil), nil
+			}
 
 			// Crop the Cache Object down to the Sample Size or Age Retention Policy and the
 			// Backfill Tolerance before storing to cache
-			if cacheStatus != status.LookupStatusHit {
+			if cacheStatus != status.LookupStatusHit && cacheStatus != status.LookupStatusStale {
 				switch o.TimeseriesEvictionMethod {
 				case evictionmethods.EvictionMethodLRU:
 					cts.CropToSize(o.TimeseriesRetentionFactor, now, trq.Extent)
@@ -502,8 +535,17 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 
 			uncachedValueCount := rts.ValueCount() - cts.ValueCount()
 
-			ffStatus := fetchFastForward(ctx, r, o, cc, cache, client, rsc,
-				rlo, trq, normalizedNow, modeler, rts)
+			// the origin is known to be unavailable, so don't wait on fast forward
+			ffStatus := statusOff
+			if cacheStatus != status.LookupStatusStale {
+				ffStatus = fetchFastForward(ctx, r, o, cc, cache, client, rsc,
+					rlo, trq, normalizedNow, modeler, rts)
+			}
+
+			h := doc.SafeHeaderClone()
+			if serveStaleOnError(o) && len(failedExts) > 0 {
+				markPartial(rts, h, failedExts)
+			}
 
 			// marshal the response timeseries to wire format
 			rts.SetExtents(nil) // so they are not included in the client response json
@@ -513,7 +555,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 			return &dpcResult{
 				wireBody:           buf.Bytes(),
 				rts:                rts,
-				headers:            doc.SafeHeaderClone(),
+				headers:            h,
 				statusCode:         doc.StatusCode,
 				elapsed:            float64(elapsed.Seconds()),
 				ffStatus:           ffStatus,
@@ -537,7 +579,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 			if trq.OriginalBody != nil {
 				request.SetBody(r, trq.OriginalBody)
 			}
-			DoProxy(w, r, true)
+			proxyQuery(w, r)
 			return
 		}
 		if result.cacheStatus == status.LookupStatusProxyError {
@@ -549,7 +591,7 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 		}
 
 		cacheStatus = result.cacheStatus
-		if !isExecutor {
+		if !isExecutor && cacheStatus != status.LookupStatusStale {
 			if status.IsSuccessful(cacheStatus) {
 				cacheStatus = status.LookupStatusProxyHit
 			} else {
@@ -598,14 +640,21 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 		return
 	}
 	rts = cts.Clone()
+	if cw := limitQuerySeries(r, rsc, trq, rts); cw != nil {
+		Respond(w, cw.StatusCode(), cw.Header(), bytes.NewReader(cw.Body()))
+		return
+	}
 
 	tspan.SetAttributes(rsc.Tracer, span, attribute.String("cache.status", cacheStatus.String()))
 
 	ffStatus := fetchFastForward(ctx, r, o, cc, cache, client, rsc,
 		rlo, trq, normalizedNow, modeler, rts)
 
-	rts.SetExtents(nil) // so they are not included in the client response json
 	rh := doc.SafeHeaderClone()
+	if serveStaleOnError(o) && len(failedExts) > 0 {
+		markPartial(rts, rh, failedExts)
+	}
+	rts.SetExtents(nil) // so they are not included in the client response json
 	sc := doc.StatusCode
 
 	finalizeDPCResponse(w, r, rsc, rts, rh, sc,
@@ -613,6 +662,23 @@ func DeltaProxyCacheRequest(w http.ResponseWriter, r *http.Request, modeler *tim
 		key, o, rlo, modeler, nil)
 }
 
+// observeForWarming tracks the request with the backend's cache Warmer, if any
+func observeForWarming(r *http.Request, rsc *request.Resources,
+	cacheStatus status.LookupStatus, key string, modeler *timeseries.Modeler,
+) {
+	if rsc.BackendOptions == nil {
+		return
+	}
+	wr := warming.Get(rsc.BackendOptions.Name)
+	if wr == nil {
+		return
+	}
+	wr.Observe(r, rsc.TimeRangeQuery, key, cacheStatus,
+		func(w http.ResponseWriter, r *http.Request) {
+			DeltaProxyCacheRequest(w, r, modeler)
+		})
+}
+
 func logDeltaRoutine(p logging.Pairs) {
 	logger.Debug("delta routine completed", p)
 }
@@ -751,6 +751,82 @@ func TestDeltaProxyCacheRequestPartialHitWithFailedExtents(t *testing.T) {
 	t.Logf("Result Header: %s", resultHdr)
 }
 
+func TestDeltaProxyCacheRequestServeStaleOnError(t *testing.T) {
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	if err != nil {
+		t.Error(err)
+	}
+	defer closeTestHarness(ts, r)
+
+	client := rsc.BackendClient.(*TestClient)
+	o := rsc.BackendOptions
+	rsc.CacheConfig.Provider = "test"
+
+	client.RangeCacheKey = "test-range-key-stale"
+	client.InstantCacheKey = "test-instant-key-stale"
+
+	o.FastForwardDisable = true
+	o.ServeStaleOnError = true
+
+	step := time.Duration(300) * time.Second
+	now := time.Now()
+	end := now.Add(-time.Duration(12) * time.Hour)
+
+	extr := timeseries.Extent{Start: end.Add(-time.Duration(18) * time.Hour), End: end}
+
+	u := r.URL
+	u.Path = "/prometheus/api/v1/query_range"
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency, client.RangeCacheKey, client.InstantCacheKey)
+
+	client.QueryRangeHandler(w, r)
+	resp := w.Result()
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "kmiss"})
+	if err != nil {
+		t.Error(err)
+	}
+	if v := resp.Header.Get(headers.NameTricksterPartial); v != "" {
+		t.Errorf("expected no partial header, got %s", v)
+	}
+
+	time.Sleep(time.Millisecond * 10)
+
+	// extend the upper range with a query whose upstream fetch fails, so the
+	// response can only be served from the cached extents
+	phitStart := normalizeTime(extr.End.Add(step), step)
+	extr.End = extr.End.Add(time.Duration(1) * time.Hour)
+	extentToFetch := timeseries.Extent{Start: phitStart, End: normalizeTime(extr.End, step)}
+	expectedFailed := "[" + timeseries.ExtentList{extentToFetch}.String() + "]"
+
+	u.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s&rk=%s&ik=%s", int(step.Seconds()),
+		extr.Start.Unix(), extr.End.Unix(), queryReturnsBadGateway, client.RangeCacheKey, client.InstantCacheKey)
+	r.URL = u
+
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	resp = w.Result()
+
+	err = testStatusCodeMatch(resp.StatusCode, http.StatusOK)
+	if err != nil {
+		t.Error(err)
+	}
+	err = testResultHeaderPartMatch(resp.Header, map[string]string{"status": "stale",
+		"failed": expectedFailed})
+	if err != nil {
+		t.Error(err)
+	}
+	if v := resp.Header.Get(headers.NameTricksterPartial); v != "true" {
+		t.Errorf("expected %s got %s", "true", v)
+	}
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(string(bodyBytes), `"values":[[`) {
+		t.Errorf("expected cached values in body: %s", string(bodyBytes))
+	}
+}
+
 func TestDeltayProxyCacheRequestDeltaFetchError(t *testing.T) {
 	ts, w, r, rsc, err := setupTestHarnessDPC()
 	if err != nil {
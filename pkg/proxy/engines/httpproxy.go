@@ -40,7 +40,9 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/methods"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/params"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/ratelimit"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/tenancy"
 	"github.com/trickstercache/trickster/v2/pkg/timeseries"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -195,6 +197,27 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 
 	pc := rsc.PathConfig
 
+	if rl, key := request.RateLimit(r, rsc); rl != nil {
+		if ok, wait := rl.AllowUpstream(key); !ok {
+			metrics.ProxyRateLimited.WithLabelValues(o.Name, "upstream").Inc()
+			logger.Debug("upstream request rejected due to rate limit",
+				logging.Pairs{
+					"backendName": o.Name,
+					"url":         r.URL.String(),
+					"key":         key,
+					"retryAfter":  wait.String(),
+				})
+			setHTTPStatusSpanAttributes(rsc.Tracer, http.StatusTooManyRequests, span)
+			resp := &http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Request:    r, Header: make(http.Header),
+			}
+			resp.Header.Set(headers.NameRetryAfter,
+				strconv.Itoa(ratelimit.RetryAfterSeconds(wait)))
+			return nil, resp, 0
+		}
+	}
+
 	var rc io.ReadCloser
 
 	headers.AddForwardingHeaders(r, o.ForwardedHeaders)
@@ -262,7 +285,11 @@ func PrepareFetchReader(r *http.Request) (io.ReadCloser, *http.Response, int64)
 		}
 	}
 
-	resp, err := o.HTTPClient.Do(r)
+	resp, rejected, err := doUpstream(r, o, rsc.TimeRangeQuery != nil)
+	if rejected != nil {
+		setHTTPStatusSpanAttributes(rsc.Tracer, rejected.StatusCode, span, doSpan)
+		return nil, rejected, 0
+	}
 	if err != nil {
 		if rsc == nil || !rsc.Cancelable || !errors.Is(err, context.Canceled) {
 			logger.Error("error downloading url",
@@ -412,5 +439,14 @@ func recordResults(
 			metrics.ProxyRequestDuration.WithLabelValues(lvs...).Observe(elapsed)
 		}
 	}
+	if o.Tenancy != nil && len(o.Tenancy.MetricsTenants) > 0 {
+		tenant := rsc.Tenant
+		if tenant == "" {
+			tenant = tenancy.Tenant(o.Tenancy, r)
+		}
+		if label, ok := o.Tenancy.MetricsLabel(tenant); ok {
+			metrics.TenantCacheRequests.WithLabelValues(o.Name, label, s).Inc()
+		}
+	}
 	headers.SetResultsHeader(header, engine, s, ffStatus, extents, failed)
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/proxy/forwarding"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	po "github.com/trickstercache/trickster/v2/pkg/proxy/paths/options"
+	rlo "github.com/trickstercache/trickster/v2/pkg/proxy/ratelimit/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
 	tu "github.com/trickstercache/trickster/v2/pkg/testutil"
 )
@@ -608,6 +609,53 @@ func TestPrepareFetchReader_GetBody(t *testing.T) {
 	}
 }
 
+func TestPrepareFetchReader_UpstreamRateLimit(t *testing.T) {
+	logger.SetLogger(testLogger)
+	conf, err := config.Load([]string{
+		"-origin-url", "http://example.com/",
+		"-provider", "test", "-log-level", "debug",
+	})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	o := conf.Backends["default"]
+	o.RateLimit = rlo.New()
+	o.RateLimit.Upstream = &rlo.Budget{Rate: 0.5, Burst: 1}
+	if err := o.RateLimit.Initialize(""); err != nil {
+		t.Fatal(err)
+	}
+	rt := &mockRoundTripper{
+		resp: &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(bytes.NewReader([]byte("ok"))),
+		},
+	}
+	o.HTTPClient = &http.Client{Transport: rt}
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest("GET", "http://example.com/", nil)
+		return r.WithContext(tc.WithResources(r.Context(),
+			request.NewResources(o, nil, nil, nil, nil, tu.NewTestTracer())))
+	}
+
+	_, resp, _ := PrepareFetchReader(newRequest())
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got: %+v", resp)
+	}
+	_, resp, _ = PrepareFetchReader(newRequest())
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 Too Many Requests, got: %+v", resp)
+	}
+	if v := resp.Header.Get(headers.NameRetryAfter); v != "2" {
+		t.Errorf("expected Retry-After of 2, got %q", v)
+	}
+	if len(rt.reqs) != 1 {
+		t.Errorf("expected 1 upstream request, got %d", len(rt.reqs))
+	}
+}
+
 func TestHTTP2GOAWAYRetry(t *testing.T) {
 	logger.SetLogger(testLogger)
 	conf, err := config.Load([]string{
@@ -30,6 +30,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/methods"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/params"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/tenancy"
 	proxyurls "github.com/trickstercache/trickster/v2/pkg/proxy/urls"
 	"github.com/trickstercache/trickster/v2/pkg/util/sets"
 )
@@ -48,10 +49,11 @@ func ComposeCacheKey(name, prefix, engine, suffix string) string {
 func (pr *proxyRequest) DeriveCacheKey(extra string) string {
 	pc := pr.rsc.PathConfig
 	upstreamKeyPart := pr.upstreamURLRewriteCacheKey()
+	tenantKeyPart := pr.tenantCacheKeyPart()
 
 	if pc == nil {
 		return md5.Checksum(pr.URL.Path + upstreamKeyPart +
-			pr.corsCacheKeyPart(pr.Request) + extra)
+			pr.corsCacheKeyPart(pr.Request) + extra + tenantKeyPart)
 	}
 
 	var qp url.Values
@@ -83,7 +85,7 @@ func (pr *proxyRequest) DeriveCacheKey(extra string) string {
 
 	if pc.KeyHasher != nil {
 		key := pc.KeyHasher(r.URL.Path, qp, r.Header, b, trq, extra)
-		keyPart := upstreamKeyPart + pr.corsCacheKeyPart(r)
+		keyPart := upstreamKeyPart + pr.corsCacheKeyPart(r) + tenantKeyPart
 		if keyPart != "" {
 			return md5.Checksum(key + keyPart)
 		}
@@ -194,7 +196,7 @@ func (pr *proxyRequest) DeriveCacheKey(extra string) string {
 	slices.Sort(vals)
 	return md5.Checksum(pr.URL.Path + "." + strings.Join(vals, "") +
 		upstreamKeyPart +
-		pr.corsCacheKeyPart(r) + extra)
+		pr.corsCacheKeyPart(r) + extra + tenantKeyPart)
 }
 
 func (pr *proxyRequest) upstreamURLRewriteCacheKey() string {
@@ -206,6 +208,19 @@ func (pr *proxyRequest) upstreamURLRewriteCacheKey() string {
 	return proxyurls.UpstreamURLRewriteCacheKey(pr.Request, base)
 }
 
+// tenantCacheKeyPart returns the cache key component partitioning the request
+// by tenant, and records the tenant to the request Resources for metrics
+func (pr *proxyRequest) tenantCacheKeyPart() string {
+	if pr == nil || pr.rsc == nil || pr.rsc.BackendOptions == nil ||
+		pr.rsc.BackendOptions.Tenancy == nil {
+		return ""
+	}
+	o := pr.rsc.BackendOptions
+	tenant := tenancy.Tenant(o.Tenancy, pr.Request)
+	pr.rsc.Tenant = tenant
+	return tenancy.KeyPart(pr.rsc.CacheClient, o.Name, o.CacheKeyPrefix, tenant)
+}
+
 func (pr *proxyRequest) corsCacheKeyPart(r *http.Request) string {
 	if pr == nil || pr.rsc == nil || pr.rsc.FrontendCORS == nil ||
 		!pr.rsc.FrontendCORS.PreservesOrigin() || r == nil {
@@ -37,6 +37,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 	po "github.com/trickstercache/trickster/v2/pkg/proxy/paths/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	tno "github.com/trickstercache/trickster/v2/pkg/proxy/tenancy/options"
 	proxyurls "github.com/trickstercache/trickster/v2/pkg/proxy/urls"
 	tu "github.com/trickstercache/trickster/v2/pkg/testutil"
 	"github.com/trickstercache/trickster/v2/pkg/timeseries"
@@ -383,6 +384,38 @@ func TestDeriveCacheKeyVariesByCORSOrigin(t *testing.T) {
 	}
 }
 
+func TestDeriveCacheKeyVariesByTenant(t *testing.T) {
+	makeKey := func(o *tno.Options, tenant string, customHasher bool) (string, string) {
+		t.Helper()
+		pc := po.New()
+		if customHasher {
+			pc.KeyHasher = exampleKeyHasher
+		}
+		rsc := request.NewResources(&bo.Options{Name: "tenant-test", Tenancy: o},
+			pc, nil, nil, nil, nil)
+		r := httptest.NewRequest(http.MethodGet, "http://trickster.example.com/data", nil)
+		r.Header.Set(tno.DefaultHeader, tenant)
+		r = request.SetResources(r, rsc)
+		return newProxyRequest(r, nil).DeriveCacheKey(""), rsc.Tenant
+	}
+
+	for _, customHasher := range []bool{false, true} {
+		a, tenantA := makeKey(tno.New(), "a", customHasher)
+		b, _ := makeKey(tno.New(), "b", customHasher)
+		if a == b {
+			t.Errorf("expected tenants to have distinct keys (customHasher=%t)", customHasher)
+		}
+		if tenantA != "a" {
+			t.Errorf("expected resources tenant %q got %q", "a", tenantA)
+		}
+		c, _ := makeKey(nil, "a", customHasher)
+		d, _ := makeKey(nil, "b", customHasher)
+		if c != d {
+			t.Errorf("expected shared keys without tenancy (customHasher=%t)", customHasher)
+		}
+	}
+}
+
 // TestDeriveCacheKey_MultiValueParams is a comprehensive test for multi-value
 // query parameter handling in cache key derivation.
 // Regression tests for https://github.com/trickstercache/trickster/issues/858
@@ -125,6 +125,14 @@ func handleCachePartialHit(pr *proxyRequest) error {
 func confirmTrueCacheHit(pr *proxyRequest) (bool, error) {
 	pr.cachingPolicy.Merge(pr.cacheDocument.CachingPolicy)
 
+	// while the origin's circuit breaker is open, serve the expired object
+	// rather than a request that would be rejected
+	if !pr.checkCacheFreshness() && pr.cacheStatus == status.LookupStatusHit &&
+		pr.rsc.BackendOptions.CircuitBreaker.Breaker().IsOpen() {
+		pr.cacheStatus = status.LookupStatusStale
+		return true, nil
+	}
+
 	if (!pr.checkCacheFreshness()) && (pr.cachingPolicy.CanRevalidate) {
 		return false, handleCacheRevalidation(pr)
 	}
@@ -413,7 +421,7 @@ func cacheResponseHandler(s status.LookupStatus) func(*proxyRequest) error {
 func fetchViaObjectProxyCache(w io.Writer, r *http.Request) (*http.Response, status.LookupStatus) {
 	rsc := request.GetResources(r)
 	o := rsc.BackendOptions
-	if o == nil || o.ProxyOnly {
+	if o == nil || o.ProxyOnly || bypassesCache(r) {
 		return nil, status.LookupStatusProxyOnly
 	}
 
@@ -153,6 +153,26 @@ func TestObjectProxyCacheRequest(t *testing.T) {
 	}
 }
 
+func TestObjectProxyCacheShadowBypass(t *testing.T) {
+	hdrs := map[string]string{"Cache-Control": "max-age=60"}
+	ts, _, r, _, err := setupTestHarnessOPC("", "test", http.StatusOK, hdrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeTestHarness(ts, r)
+
+	// mirrored requests are proxied without reading or writing the cache
+	sr := r.WithContext(tc.WithShadowFlag(r.Context(), true))
+	_, e := testFetchOPC(sr, http.StatusOK, "test", map[string]string{"status": "proxy-only"})
+	for _, err = range e {
+		t.Error(err)
+	}
+	_, e = testFetchOPC(r, http.StatusOK, "test", map[string]string{"status": "kmiss"})
+	for _, err = range e {
+		t.Error(err)
+	}
+}
+
 func TestObjectProxyCacheCORSOnMissAndHit(t *testing.T) {
 	hdrs := map[string]string{
 		headers.NameCacheControl:           "max-age=60",
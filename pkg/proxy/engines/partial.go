@@ -0,0 +1,40 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+)
+
+// markPartial flags a timeseries response as missing the failed ranges. The
+// X-Trickster-Partial header is set for all providers, and for DataSets the
+// flag is also added to the Warnings, which providers like Prometheus include
+// in the response body.
+func markPartial(ts timeseries.Timeseries, h http.Header,
+	failed timeseries.ExtentList) {
+	h.Set(headers.NameTricksterPartial, "true")
+	if ds, ok := ts.(*dataset.DataSet); ok {
+		ds.Warnings = append(ds.Warnings, fmt.Sprintf(
+			"trickster: partial response; origin unavailable for range(s) %s",
+			failed.String()))
+	}
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+)
+
+func TestMarkPartial(t *testing.T) {
+	failed := timeseries.ExtentList{{Start: time.Unix(60, 0), End: time.Unix(120, 0)}}
+	ds := &dataset.DataSet{Warnings: []string{"existing"}}
+	h := http.Header{}
+	markPartial(ds, h, failed)
+	if v := h.Get(headers.NameTricksterPartial); v != "true" {
+		t.Errorf("expected %s got %s", "true", v)
+	}
+	if len(ds.Warnings) != 2 {
+		t.Fatalf("expected %d got %d", 2, len(ds.Warnings))
+	}
+	if !strings.Contains(ds.Warnings[1], failed.String()) {
+		t.Errorf("expected warning to include failed ranges, got %s", ds.Warnings[1])
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/trickstercache/trickster/v2/pkg/proxy/querycost"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/response/capture"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+)
+
+// limitQuerySeries enforces the query_cost max_series limit on rts, clamping
+// it to the limit when configured to. It returns the captured error response
+// if the query was rejected.
+func limitQuerySeries(r *http.Request, rsc *request.Resources,
+	trq *timeseries.TimeRangeQuery, rts timeseries.Timeseries,
+) *capture.CaptureResponseWriter {
+	qc := rsc.BackendOptions.QueryCost
+	if qc == nil || qc.MaxSeries <= 0 || rts == nil {
+		return nil
+	}
+	n := rts.SeriesCount()
+	if n <= qc.MaxSeries {
+		return nil
+	}
+	if ds, ok := rts.(*dataset.DataSet); ok && qc.Clamps() {
+		ds.LimitSeries(qc.MaxSeries)
+		ds.Warnings = append(ds.Warnings, querycost.ClampWarning(querycost.LimitSeries,
+			int64(n), int64(qc.MaxSeries)))
+		querycost.LogClamp(r, rsc, trq, querycost.LimitSeries, int64(n),
+			int64(qc.MaxSeries))
+		return nil
+	}
+	cw := capture.NewCaptureResponseWriter()
+	querycost.Reject(cw, r, rsc, trq, querycost.LimitSeries, int64(n),
+		int64(qc.MaxSeries))
+	return cw
+}
+
+// limitsQueryBytes reports whether the query_cost max_response_bytes limit is
+// enabled for the request's backend.
+func limitsQueryBytes(rsc *request.Resources) bool {
+	qc := rsc.BackendOptions.QueryCost
+	return qc != nil && qc.MaxResponseBytes > 0
+}
+
+// limitQueryBytes enforces the query_cost max_response_bytes limit on a
+// response body of n bytes. It returns the captured error response if the
+// query was rejected.
+func limitQueryBytes(r *http.Request, rsc *request.Resources,
+	trq *timeseries.TimeRangeQuery, n int,
+) *capture.CaptureResponseWriter {
+	if !limitsQueryBytes(rsc) {
+		return nil
+	}
+	qc := rsc.BackendOptions.QueryCost
+	if n <= qc.MaxResponseBytes {
+		return nil
+	}
+	cw := capture.NewCaptureResponseWriter()
+	querycost.Reject(cw, r, rsc, trq, querycost.LimitBytes, int64(n),
+		int64(qc.MaxResponseBytes))
+	return cw
+}
+
+// proxyQuery proxies r to the origin without caching, enforcing the
+// query_cost max_response_bytes limit on the origin's response body. When
+// the limit is enabled, the response is buffered, up to one byte past the
+// limit, so it can be rejected before anything is written to w.
+func proxyQuery(w http.ResponseWriter, r *http.Request) {
+	rsc := request.GetResources(r)
+	if !limitsQueryBytes(rsc) {
+		DoProxy(w, r, true)
+		return
+	}
+	pw := capture.NewCaptureResponseWriterWithLimit(rsc.BackendOptions.QueryCost.MaxResponseBytes + 1)
+	DoProxy(pw, r, true)
+	if cw := limitQueryBytes(r, rsc, rsc.TimeRangeQuery, len(pw.Body())); cw != nil {
+		pw = cw
+	}
+	Respond(w, pw.StatusCode(), pw.Header(), bytes.NewReader(pw.Body()))
+}
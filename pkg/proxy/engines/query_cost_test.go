@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	qco "github.com/trickstercache/trickster/v2/pkg/proxy/querycost/options"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+)
+
+func TestDeltaProxyCacheRequestQueryCost(t *testing.T) {
+	const query = "some_query_here{latency_ms=0,range_latency_ms=0,series_count=3}"
+	tests := []struct {
+		name     string
+		qc       *qco.Options
+		code     int
+		contains string
+		limit    string
+		noCache  bool
+		proxy    bool
+	}{
+		{
+			name:     "series rejected",
+			qc:       &qco.Options{MaxSeries: 2, Action: qco.ActionReject},
+			code:     http.StatusUnprocessableEntity,
+			contains: "query would return 3 series, exceeding the allowed limit of 2",
+			limit:    "series",
+		},
+		{
+			name: "series clamped",
+			qc:   &qco.Options{MaxSeries: 2, Action: qco.ActionClamp},
+			code: http.StatusOK,
+		},
+		{
+			name:     "bytes rejected",
+			qc:       &qco.Options{MaxResponseBytes: 100, Action: qco.ActionReject},
+			code:     http.StatusUnprocessableEntity,
+			contains: "exceeding the allowed limit of 100",
+			limit:    "bytes",
+		},
+		{
+			name:     "bytes rejected without caching",
+			qc:       &qco.Options{MaxResponseBytes: 100, Action: qco.ActionReject},
+			code:     http.StatusUnprocessableEntity,
+			contains: "exceeding the allowed limit of 100",
+			limit:    "bytes",
+			noCache:  true,
+		},
+		{
+			name:     "bytes rejected when proxy only",
+			qc:       &qco.Options{MaxResponseBytes: 100, Action: qco.ActionReject},
+			code:     http.StatusUnprocessableEntity,
+			contains: "exceeding the allowed limit of 100",
+			limit:    "bytes",
+			proxy:    true,
+		},
+		{
+			name: "within limits",
+			qc:   &qco.Options{MaxSeries: 3, MaxResponseBytes: 1 << 20, Action: qco.ActionReject},
+			code: http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ts, w, r, rsc, err := setupTestHarnessDPC()
+			require.NoError(t, err)
+			defer closeTestHarness(ts, r)
+
+			client := rsc.BackendClient.(*TestClient)
+			o := rsc.BackendOptions
+			o.FastForwardDisable = true
+			o.QueryCost = test.qc
+			o.ProxyOnly = test.proxy
+
+			step := 300 * time.Second
+			end := time.Now().Add(-12 * time.Hour)
+			extr := timeseries.Extent{Start: end.Add(-6 * time.Hour), End: end}
+			r.URL.Path = "/prometheus/api/v1/query_range"
+			r.URL.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+				int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), query)
+			if test.noCache {
+				r.Header.Set(headers.NameCacheControl, headers.ValueNoCache)
+			}
+
+			var rejections float64
+			if test.limit != "" {
+				rejections = testutil.ToFloat64(
+					metrics.ProxyQueryCostRejections.WithLabelValues(o.Name, test.limit))
+			}
+
+			client.QueryRangeHandler(w, r)
+			resp := w.Result()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.Equal(t, test.code, resp.StatusCode, string(body))
+			require.Contains(t, string(body), test.contains)
+
+			if test.limit != "" {
+				require.Equal(t, rejections+1, testutil.ToFloat64(
+					metrics.ProxyQueryCostRejections.WithLabelValues(o.Name, test.limit)))
+			}
+			if test.qc.Clamps() {
+				require.Equal(t, 2, strings.Count(string(body), `"series_id"`))
+			}
+		})
+	}
+}
+
+func TestLimitQueryBytes(t *testing.T) {
+	_, _, r, rsc, err := setupTestHarnessDPC()
+	require.NoError(t, err)
+	defer closeTestHarness(nil, r)
+	require.Nil(t, limitQueryBytes(r, rsc, nil, 1<<30))
+	rsc.BackendOptions.QueryCost = &qco.Options{MaxResponseBytes: 10}
+	require.Nil(t, limitQueryBytes(r, rsc, nil, 10))
+	cw := limitQueryBytes(httptest.NewRequest(http.MethodGet, "/", nil), rsc, nil, 11)
+	require.NotNil(t, cw)
+	require.Equal(t, http.StatusUnprocessableEntity, cw.StatusCode())
+}
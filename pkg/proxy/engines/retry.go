@@ -0,0 +1,241 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	cb "github.com/trickstercache/trickster/v2/pkg/proxy/circuitbreaker"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/retry"
+	rto "github.com/trickstercache/trickster/v2/pkg/proxy/retry/options"
+)
+
+// doUpstream sends an upstream request to the backend's origin. When the
+// backend has retry options and the request is read-only, failed attempts
+// are retried with jittered backoff and each attempt may be hedged. Every
+// attempt, including retries and hedges, is separately admitted by the
+// backend's circuit breaker and upstream queue; rejected is non-nil when the
+// request was not admitted
+func doUpstream(r *http.Request, o *bo.Options,
+	isQuery bool,
+) (resp, rejected *http.Response, err error) {
+	ro := o.Retry
+	if ro == nil || !ro.IsReadOnly(r, isQuery) || !canReplay(r) {
+		return sendAttempt(r.Context(), r, o)
+	}
+	for attempt := 1; ; attempt++ {
+		resp, rejected, err = hedgedDo(r, o)
+		if rejected != nil {
+			return nil, rejected, nil
+		}
+		reason := retryReason(r, ro, resp, err)
+		if reason == "" || attempt >= ro.MaxAttempts {
+			return resp, nil, err
+		}
+		discardResponse(resp)
+		metrics.ProxyUpstreamRetries.WithLabelValues(o.Name, reason).Inc()
+		wait := retry.Backoff(attempt, time.Duration(ro.BackoffBase),
+			time.Duration(ro.BackoffMax))
+		logger.Debug("retrying upstream request",
+			logging.Pairs{
+				"backendName": o.Name,
+				"url":         r.URL.String(),
+				"attempt":     attempt,
+				"reason":      reason,
+				"backoff":     wait.String(),
+			})
+		t := time.NewTimer(wait)
+		select {
+		case <-r.Context().Done():
+			t.Stop()
+			return nil, nil, r.Context().Err()
+		case <-t.C:
+		}
+	}
+}
+
+// retryReason returns the reason an upstream attempt should be retried, or
+// an empty string if it should not be
+func retryReason(r *http.Request, ro *rto.Options, resp *http.Response,
+	err error,
+) string {
+	switch {
+	case r.Context().Err() != nil:
+		return ""
+	case err != nil:
+		return "error"
+	case resp != nil && ro.RetryStatus(resp.StatusCode):
+		return "status"
+	}
+	return ""
+}
+
+// canReplay returns true if the request body, if any, can be sent again
+func canReplay(r *http.Request) bool {
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// sendAttempt sends a copy of the request with the provided context and a
+// fresh body, once it is admitted by the backend's circuit breaker and
+// upstream queue. The attempt's own outcome and latency are reported to the
+// circuit breaker, and its queue slot is held until its response body is read
+func sendAttempt(ctx context.Context, r *http.Request,
+	o *bo.Options,
+) (resp, rejected *http.Response, err error) {
+	req := r.Clone(ctx)
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Body = body
+	}
+	done, rejected := checkCircuitBreaker(req, o)
+	if rejected != nil {
+		return nil, rejected, nil
+	}
+	release, shed := acquireUpstreamSlot(req, o)
+	if shed != nil {
+		done(cb.OutcomeIgnored, 0)
+		return nil, shed, nil
+	}
+	start := time.Now()
+	resp, err = o.HTTPClient.Do(req)
+	elapsed := time.Since(start)
+	holdUpstreamSlot(resp, err, release)
+	done(upstreamOutcome(resp, err), elapsed)
+	if err == nil {
+		o.Retry.Latency().Observe(elapsed)
+	}
+	return resp, nil, err
+}
+
+type attemptResult struct {
+	resp     *http.Response
+	rejected *http.Response
+	err      error
+	index    int
+}
+
+// hedgedDo sends an upstream attempt. If hedging is enabled and the attempt
+// has not responded within the hedge delay, duplicate requests are sent and
+// the first successful response is used; the others are canceled. Each hedge
+// takes its own upstream queue slot and circuit breaker admission, and a
+// hedge that is not admitted is abandoned in favor of the others in flight
+func hedgedDo(r *http.Request, o *bo.Options) (*http.Response, *http.Response, error) {
+	delay, ok := o.Retry.HedgeDelay()
+	if !ok {
+		return sendAttempt(r.Context(), r, o)
+	}
+	maxHedges := o.Retry.Hedge.MaxHedges
+	results := make(chan attemptResult, 1+maxHedges)
+	cancels := make([]context.CancelFunc, 0, 1+maxHedges)
+	send := func() {
+		ctx, cancel := context.WithCancel(r.Context())
+		i := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			resp, rejected, err := sendAttempt(ctx, r, o)
+			results <- attemptResult{resp: resp, rejected: rejected, err: err, index: i}
+		}()
+	}
+	send()
+	inflight := 1
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	var res attemptResult
+	for {
+		select {
+		case <-t.C:
+			if len(cancels) > maxHedges {
+				continue
+			}
+			logger.Debug("hedging upstream request",
+				logging.Pairs{
+					"backendName": o.Name,
+					"url":         r.URL.String(),
+					"delay":       delay.String(),
+				})
+			send()
+			inflight++
+			t.Reset(delay)
+			continue
+		case res = <-results:
+			inflight--
+		}
+		if (res.err != nil || res.rejected != nil) && inflight > 0 {
+			cancels[res.index]()
+			continue
+		}
+		break
+	}
+	for i, cancel := range cancels {
+		if i != res.index {
+			cancel()
+		}
+	}
+	if inflight > 0 {
+		go func(n int) {
+			for range n {
+				discardResponse((<-results).resp)
+			}
+		}(inflight)
+	}
+	if hedges := len(cancels) - 1; hedges > 0 {
+		var won int
+		if res.err == nil && res.rejected == nil && res.index > 0 {
+			won = 1
+		}
+		metrics.ProxyUpstreamHedges.WithLabelValues(o.Name, "won").Add(float64(won))
+		metrics.ProxyUpstreamHedges.WithLabelValues(o.Name, "lost").Add(float64(hedges - won))
+	}
+	if res.err != nil || res.rejected != nil {
+		cancels[res.index]()
+		return nil, res.rejected, res.err
+	}
+	res.resp.Body = &cancelOnClose{ReadCloser: res.resp.Body, cancel: cancels[res.index]}
+	return res.resp, nil, nil
+}
+
+// cancelOnClose cancels a hedged attempt's context once the winning
+// response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// discardResponse drains and closes an unused upstream response
+func discardResponse(resp *http.Response) {
+	if resp == nil || resp.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
@@ -0,0 +1,282 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/config"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	tc "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	qo "github.com/trickstercache/trickster/v2/pkg/proxy/queue/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	rto "github.com/trickstercache/trickster/v2/pkg/proxy/retry/options"
+	tu "github.com/trickstercache/trickster/v2/pkg/testutil"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// funcRoundTripper answers upstream requests with a function of the
+// attempt number, starting at 1, recording each request body
+type funcRoundTripper struct {
+	mtx    sync.Mutex
+	bodies []string
+	fn     func(attempt int, req *http.Request) (*http.Response, error)
+}
+
+func (f *funcRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body string
+	if req.Body != nil {
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+	}
+	f.mtx.Lock()
+	f.bodies = append(f.bodies, body)
+	n := len(f.bodies)
+	f.mtx.Unlock()
+	return f.fn(n, req)
+}
+
+func (f *funcRoundTripper) attempts() int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return len(f.bodies)
+}
+
+func statusResponse(code int) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+	}
+}
+
+func newRetryTestBackend(t *testing.T, name string,
+	rt http.RoundTripper,
+) *bo.Options {
+	t.Helper()
+	logger.SetLogger(testLogger)
+	conf, err := config.Load([]string{
+		"-origin-url", "http://example.com/",
+		"-provider", "test", "-log-level", "debug",
+	})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+	o := conf.Backends["default"]
+	o.Name = name
+	o.Retry = rto.New()
+	o.Retry.BackoffBase = timeconv.Duration(time.Millisecond)
+	o.Retry.BackoffMax = timeconv.Duration(5 * time.Millisecond)
+	if err := o.Retry.Initialize(name); err != nil {
+		t.Fatal(err)
+	}
+	o.HTTPClient = &http.Client{Transport: rt}
+	return o
+}
+
+func newRetryTestRequest(o *bo.Options, method, body string) *http.Request {
+	var r *http.Request
+	if body == "" {
+		r = httptest.NewRequest(method, "http://example.com/api/v1/query", nil)
+	} else {
+		r = httptest.NewRequest(method, "http://example.com/api/v1/query",
+			strings.NewReader(body))
+	}
+	return r.WithContext(tc.WithResources(r.Context(),
+		request.NewResources(o, nil, nil, nil, nil, tu.NewTestTracer())))
+}
+
+func TestPrepareFetchReader_RetryStatus(t *testing.T) {
+	rt := &funcRoundTripper{fn: func(n int, _ *http.Request) (*http.Response, error) {
+		if n == 1 {
+			return statusResponse(http.StatusServiceUnavailable), nil
+		}
+		return statusResponse(http.StatusOK), nil
+	}}
+	o := newRetryTestBackend(t, "retry-status-test", rt)
+	_, resp, _ := PrepareFetchReader(newRetryTestRequest(o, http.MethodGet, ""))
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got: %+v", resp)
+	}
+	if n := rt.attempts(); n != 2 {
+		t.Errorf("expected 2 upstream requests, got %d", n)
+	}
+	if v := testutil.ToFloat64(metrics.ProxyUpstreamRetries.WithLabelValues(o.Name, "status")); v != 1 {
+		t.Errorf("expected 1 status retry, got %f", v)
+	}
+}
+
+func TestPrepareFetchReader_RetryExhausted(t *testing.T) {
+	rt := &funcRoundTripper{fn: func(int, *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}}
+	o := newRetryTestBackend(t, "retry-exhausted-test", rt)
+	_, resp, _ := PrepareFetchReader(newRetryTestRequest(o, http.MethodGet, ""))
+	if resp == nil || resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502, got: %+v", resp)
+	}
+	if n := rt.attempts(); n != rto.DefaultMaxAttempts {
+		t.Errorf("expected %d upstream requests, got %d", rto.DefaultMaxAttempts, n)
+	}
+	if v := testutil.ToFloat64(metrics.ProxyUpstreamRetries.WithLabelValues(o.Name, "error")); v != 2 {
+		t.Errorf("expected 2 error retries, got %f", v)
+	}
+}
+
+func TestPrepareFetchReader_RetryPOST(t *testing.T) {
+	rt := &funcRoundTripper{fn: func(n int, _ *http.Request) (*http.Response, error) {
+		if n == 1 {
+			return nil, errors.New("connection reset")
+		}
+		return statusResponse(http.StatusOK), nil
+	}}
+	o := newRetryTestBackend(t, "retry-post-test", rt)
+
+	// writes are not retried
+	_, resp, _ := PrepareFetchReader(newRetryTestRequest(o, http.MethodPost, "up"))
+	if resp == nil || resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502, got: %+v", resp)
+	}
+	if n := rt.attempts(); n != 1 {
+		t.Fatalf("expected 1 upstream request, got %d", n)
+	}
+
+	// read-only queries are retried with their body
+	rt.bodies = nil
+	o.Retry.ReadOnlyPOSTPaths = []string{"/api/v1/query"}
+	_, resp, _ = PrepareFetchReader(newRetryTestRequest(o, http.MethodPost, "up"))
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got: %+v", resp)
+	}
+	if len(rt.bodies) != 2 || rt.bodies[0] != "up" || rt.bodies[1] != "up" {
+		t.Errorf("expected the body to be sent with each attempt, got %q", rt.bodies)
+	}
+}
+
+func TestPrepareFetchReader_Hedge(t *testing.T) {
+	canceled := make(chan struct{})
+	rt := &funcRoundTripper{fn: func(n int, req *http.Request) (*http.Response, error) {
+		if n == 1 {
+			select {
+			case <-req.Context().Done():
+				close(canceled)
+				return nil, req.Context().Err()
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return statusResponse(http.StatusOK), nil
+	}}
+	o := newRetryTestBackend(t, "hedge-test", rt)
+	o.Retry.Hedge = rto.NewHedgeOptions()
+	for range 100 {
+		o.Retry.Latency().Observe(time.Millisecond)
+	}
+
+	start := time.Now()
+	rc, resp, _ := PrepareFetchReader(newRetryTestRequest(o, http.MethodGet, ""))
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got: %+v", resp)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the hedged response, waited %s", elapsed)
+	}
+	if rc != nil {
+		rc.Close()
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("expected the original request to be canceled")
+	}
+	if v := testutil.ToFloat64(metrics.ProxyUpstreamHedges.WithLabelValues(o.Name, "won")); v != 1 {
+		t.Errorf("expected 1 winning hedge, got %f", v)
+	}
+	if v := testutil.ToFloat64(metrics.ProxyUpstreamRetries.WithLabelValues(o.Name, "error")); v != 0 {
+		t.Errorf("expected no retries, got %f", v)
+	}
+}
+
+func TestPrepareFetchReader_RetryCircuitBreaker(t *testing.T) {
+	rt := &funcRoundTripper{fn: func(int, *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}}
+	o := newRetryTestBackend(t, "retry-breaker-test", rt)
+	o.CircuitBreaker = newTestCircuitBreaker(t, o.Name)
+
+	// each attempt is reported to the breaker, which opens after the second
+	// failed attempt, so the final attempt is rejected rather than sent
+	_, resp, _ := PrepareFetchReader(newRetryTestRequest(o, http.MethodGet, ""))
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got: %+v", resp)
+	}
+	if n := rt.attempts(); n != 2 {
+		t.Errorf("expected 2 upstream requests, got %d", n)
+	}
+	if !o.CircuitBreaker.Breaker().IsOpen() {
+		t.Error("expected the circuit to be open")
+	}
+}
+
+func TestPrepareFetchReader_HedgeUpstreamQueue(t *testing.T) {
+	rt := &funcRoundTripper{fn: func(int, *http.Request) (*http.Response, error) {
+		time.Sleep(100 * time.Millisecond)
+		return statusResponse(http.StatusOK), nil
+	}}
+	o := newRetryTestBackend(t, "hedge-queue-test", rt)
+	o.Retry.Hedge = rto.NewHedgeOptions()
+	for range 100 {
+		o.Retry.Latency().Observe(time.Millisecond)
+	}
+	o.UpstreamQueue = qo.New()
+	o.UpstreamQueue.MaxConcurrent = 1
+	o.UpstreamQueue.MaxQueued = 0
+	if err := o.UpstreamQueue.Initialize(o.Name); err != nil {
+		t.Fatal(err)
+	}
+
+	// hedges need their own slot, so they are shed while the original
+	// request holds the only one, and the original response is used
+	rc, resp, _ := PrepareFetchReader(newRetryTestRequest(o, http.MethodGet, ""))
+	if resp == nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got: %+v", resp)
+	}
+	if rc != nil {
+		rc.Close()
+	}
+	if n := rt.attempts(); n != 1 {
+		t.Errorf("expected 1 upstream request, got %d", n)
+	}
+	if v := testutil.ToFloat64(metrics.ProxyUpstreamQueueShed.WithLabelValues(
+		o.Name, qo.DefaultClassName, "full")); v < 1 {
+		t.Errorf("expected shed hedges, got %f", v)
+	}
+	if v := testutil.ToFloat64(metrics.ProxyUpstreamHedges.WithLabelValues(o.Name, "won")); v != 0 {
+		t.Errorf("expected no winning hedges, got %f", v)
+	}
+}
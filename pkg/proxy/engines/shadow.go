@@ -0,0 +1,30 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"net/http"
+
+	tctx "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+)
+
+// bypassesCache returns true if the request must bypass the backend's cache.
+// Requests mirrored to a shadow backend bypass its cache, so the shadow
+// response reflects the shadow origin and does not populate the cache
+func bypassesCache(r *http.Request) bool {
+	return tctx.ShadowFlag(r.Context())
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/queue"
+)
+
+// acquireUpstreamSlot waits for a slot in the backend's upstream queue, if
+// one is configured. It returns a function that releases the slot, which is
+// nil when there is no queue, or a 503 Service Unavailable response if the
+// request was shed
+func acquireUpstreamSlot(r *http.Request, o *bo.Options) (func(), *http.Response) {
+	q := o.UpstreamQueue.Queue()
+	if q == nil {
+		return nil, nil
+	}
+	class, priority := o.UpstreamQueue.Classify(r)
+	start := time.Now()
+	release, err := q.Acquire(r.Context(), priority, time.Duration(o.UpstreamQueue.MaxWait))
+	wait := time.Since(start)
+	if err == nil {
+		metrics.ProxyUpstreamQueueWait.WithLabelValues(o.Name, class).Observe(wait.Seconds())
+		return release, nil
+	}
+	var reason string
+	switch {
+	case errors.Is(err, queue.ErrQueueFull):
+		reason = "full"
+	case errors.Is(err, queue.ErrQueueTimeout):
+		reason = "timeout"
+	}
+	if reason != "" {
+		metrics.ProxyUpstreamQueueShed.WithLabelValues(o.Name, class, reason).Inc()
+		logger.Debug("upstream request shed by upstream queue",
+			logging.Pairs{
+				"backendName": o.Name,
+				"url":         r.URL.String(),
+				"class":       class,
+				"wait":        wait.String(),
+				"detail":      err.Error(),
+			})
+	}
+	return nil, &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Request:    r, Header: make(http.Header),
+	}
+}
+
+// holdUpstreamSlot holds an upstream queue slot until the upstream response
+// body has been fully read or closed, so the slot covers the entire upstream
+// transfer. The slot is released immediately when there is no body.
+func holdUpstreamSlot(resp *http.Response, err error, release func()) {
+	if release == nil {
+		return
+	}
+	if err != nil || resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+		release()
+		return
+	}
+	resp.Body = &slotBody{ReadCloser: resp.Body, release: release}
+}
+
+// slotBody is a response body that releases an upstream queue slot on EOF or
+// Close, whichever happens first
+type slotBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *slotBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+func (b *slotBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/trickstercache/trickster/v2/pkg/config"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	tc "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	qo "github.com/trickstercache/trickster/v2/pkg/proxy/queue/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	tu "github.com/trickstercache/trickster/v2/pkg/testutil"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrepareFetchReader_UpstreamQueue(t *testing.T) {
+	logger.SetLogger(testLogger)
+	conf, err := config.Load([]string{
+		"-origin-url", "http://example.com/",
+		"-provider", "test", "-log-level", "debug",
+	})
+	if err != nil {
+		t.Fatalf("Could not load configuration: %s", err.Error())
+	}
+
+	o := conf.Backends["default"]
+	o.Name = "queue-test"
+	o.UpstreamQueue = qo.New()
+	o.UpstreamQueue.MaxConcurrent = 1
+	o.UpstreamQueue.MaxQueued = 0
+	if err := o.UpstreamQueue.Initialize(o.Name); err != nil {
+		t.Fatal(err)
+	}
+	rt := &mockRoundTripper{}
+	o.HTTPClient = &http.Client{Transport: rt}
+	newResponse := func() *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     make(http.Header),
+			Body:       io.NopCloser(bytes.NewReader([]byte("ok"))),
+		}
+	}
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest("GET", "http://example.com/", nil)
+		return r.WithContext(tc.WithResources(r.Context(),
+			request.NewResources(o, nil, nil, nil, nil, tu.NewTestTracer())))
+	}
+	expectStatus := func(code int) io.ReadCloser {
+		t.Helper()
+		rt.resp = newResponse()
+		reader, resp, _ := PrepareFetchReader(newRequest())
+		if resp == nil || resp.StatusCode != code {
+			t.Fatalf("expected %d, got: %+v", code, resp)
+		}
+		return reader
+	}
+
+	// the slot is held until the upstream response body is closed
+	reader := expectStatus(http.StatusOK)
+	expectStatus(http.StatusServiceUnavailable)
+	reader.Close()
+	reader = expectStatus(http.StatusOK)
+
+	// or has been fully read
+	if _, err := io.ReadAll(reader); err != nil {
+		t.Fatal(err)
+	}
+	reader = expectStatus(http.StatusOK)
+	reader.Close()
+
+	// and is released when the upstream request fails
+	rt.err = errors.New("upstream unavailable")
+	expectStatus(http.StatusBadGateway)
+	rt.err = nil
+	expectStatus(http.StatusOK).Close()
+
+	// occupy the only slot so the next request is shed
+	release, err := o.UpstreamQueue.Queue().Acquire(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	_, resp, _ := PrepareFetchReader(newRequest())
+	if resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 Service Unavailable, got: %+v", resp)
+	}
+	if v := testutil.ToFloat64(metrics.ProxyUpstreamQueueShed.WithLabelValues(
+		o.Name, qo.DefaultClassName, "full")); v != 2 {
+		t.Errorf("expected 2 shed requests, got %f", v)
+	}
+}
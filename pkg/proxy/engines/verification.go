@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"context"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends"
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/epoch"
+)
+
+// maybeVerifyCacheHit asynchronously fetches a sample of full cache hits from
+// the origin and compares the result to the cached timeseries, reporting any
+// divergences as metrics and logs. cached must not be modified by the caller
+// until this returns, as it is cloned here before the verification begins.
+func maybeVerifyCacheHit(pr *proxyRequest, o *bo.Options, key string,
+	trq *timeseries.TimeRangeQuery, client backends.TimeseriesBackend,
+	modeler *timeseries.Modeler, cached timeseries.Timeseries,
+) {
+	vo := o.CacheVerification
+	if vo == nil || !vo.Sample() {
+		return
+	}
+	cds, ok := cached.(*dataset.DataSet)
+	if !ok {
+		return
+	}
+	release, ok := vo.Acquire()
+	if !ok {
+		metrics.ProxyCacheVerifications.WithLabelValues(o.Name, "dropped").Inc()
+		return
+	}
+	cds = cds.Clone().(*dataset.DataSet)
+	vtrq := trq.Clone()
+	vpr := pr.Clone()
+	vpr.rsc = pr.rsc.Clone()
+	vpr.rsc.TimeRangeQuery = vtrq
+	// the verification outlives the client request
+	vpr.upstreamRequest = vpr.upstreamRequest.WithContext(
+		context.WithoutCancel(vpr.upstreamRequest.Context()))
+	goWithRecover("dpc.verifyCacheHit", func() {
+		defer release()
+		verifyCacheHit(vpr, o, key, vtrq, client, modeler, cds, vo.Tolerance)
+	})
+}
+
+// verifyCacheHit fetches trq from the origin and compares the result to the
+// cached DataSet
+func verifyCacheHit(pr *proxyRequest, o *bo.Options, key string,
+	trq *timeseries.TimeRangeQuery, client backends.TimeseriesBackend,
+	modeler *timeseries.Modeler, cached *dataset.DataSet, tolerance float64,
+) {
+	ots, _, _, failedExts, _ := fetchTimeseries(pr, trq, client, modeler)
+	ods, ok := ots.(*dataset.DataSet)
+	if len(failedExts) > 0 || !ok {
+		metrics.ProxyCacheVerifications.WithLabelValues(o.Name, "error").Inc()
+		pairs := logging.Pairs{"backendName": o.Name, "cacheKey": key}
+		if len(failedExts) > 0 {
+			pairs["failedRanges"] = failedExts.String()
+		}
+		logger.Debug("could not fetch timeseries for cache verification", pairs)
+		return
+	}
+	ods.CropToRange(trq.Extent)
+
+	d := dataset.Compare(ods, cached, tolerance)
+	if d.Equal() {
+		metrics.ProxyCacheVerifications.WithLabelValues(o.Name, "match").Inc()
+		return
+	}
+	metrics.ProxyCacheVerifications.WithLabelValues(o.Name, "divergent").Inc()
+	for kind, n := range map[string]int{
+		"missing_series": d.MissingSeries,
+		"extra_series":   d.ExtraSeries,
+		"changed_values": d.ChangedValues,
+		"extent_gaps":    d.MissingPoints,
+		"extra_points":   d.ExtraPoints,
+	} {
+		if n > 0 {
+			metrics.ProxyCacheVerificationDivergences.WithLabelValues(o.Name,
+				kind).Add(float64(n))
+		}
+	}
+	logger.Warn("cached timeseries diverges from origin",
+		logging.Pairs{
+			"backendName":   o.Name,
+			"cacheKey":      key,
+			"statement":     trq.Statement,
+			"extent":        trq.Extent.String(),
+			"originSeries":  d.ExpectedSeries,
+			"cachedSeries":  d.ActualSeries,
+			"missingSeries": d.MissingSeries,
+			"extraSeries":   d.ExtraSeries,
+			"changedValues": d.ChangedValues,
+			"missingPoints": d.MissingPoints,
+			"extraPoints":   d.ExtraPoints,
+			"gaps":          epochGaps(d.MissingEpochs, trq.Step).String(),
+		},
+	)
+}
+
+// epochGaps returns the sorted epochs as a list of Extents, merging epochs
+// that are within step of each other
+func epochGaps(epochs []epoch.Epoch, step time.Duration) timeseries.ExtentList {
+	el := make(timeseries.ExtentList, len(epochs))
+	for i, e := range epochs {
+		t := time.Unix(0, int64(e))
+		el[i] = timeseries.Extent{Start: t, End: t}
+	}
+	return el.Compress(step)
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package engines
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	vo "github.com/trickstercache/trickster/v2/pkg/proxy/verification/options"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/epoch"
+)
+
+func TestDeltaProxyCacheRequestVerification(t *testing.T) {
+	ts, w, r, rsc, err := setupTestHarnessDPC()
+	require.NoError(t, err)
+	defer closeTestHarness(ts, r)
+
+	client := rsc.BackendClient.(*TestClient)
+	o := rsc.BackendOptions
+	o.FastForwardDisable = true
+	o.CacheVerification = vo.New()
+	o.CacheVerification.Percent = 100
+
+	step := 300 * time.Second
+	end := time.Now().Add(-12 * time.Hour)
+	extr := timeseries.Extent{Start: end.Add(-18 * time.Hour), End: end}
+
+	r.URL.Path = "/prometheus/api/v1/query_range"
+	r.URL.RawQuery = fmt.Sprintf("step=%d&start=%d&end=%d&query=%s",
+		int(step.Seconds()), extr.Start.Unix(), extr.End.Unix(), queryReturnsOKNoLatency)
+
+	match := metrics.ProxyCacheVerifications.WithLabelValues(o.Name, "match")
+	base := testutil.ToFloat64(match)
+
+	client.QueryRangeHandler(w, r)
+	require.NoError(t, testResultHeaderPartMatch(w.Result().Header,
+		map[string]string{"status": "kmiss"}))
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, base, testutil.ToFloat64(match),
+		"expected cache misses to not be verified")
+
+	w = httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	require.NoError(t, testResultHeaderPartMatch(w.Result().Header,
+		map[string]string{"status": "hit"}))
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(match) == base+1
+	}, time.Second, 5*time.Millisecond)
+
+	// verify a cached DataSet that has diverged from the origin
+	trq := rsc.TimeRangeQuery.Clone()
+	body, err := testFetchBody(client, r)
+	require.NoError(t, err)
+	cts, err := client.testModeler().WireUnmarshaler(body, trq)
+	require.NoError(t, err)
+	cds := cts.(*dataset.DataSet)
+	s := cds.Results[0].SeriesList[0]
+	s.Points[0].Values = []any{"-1"}
+	s.Points = s.Points[:len(s.Points)-1]
+
+	divergent := metrics.ProxyCacheVerifications.WithLabelValues(o.Name, "divergent")
+	changed := metrics.ProxyCacheVerificationDivergences.WithLabelValues(o.Name, "changed_values")
+	gaps := metrics.ProxyCacheVerificationDivergences.WithLabelValues(o.Name, "extent_gaps")
+	bd, bc, bg := testutil.ToFloat64(divergent), testutil.ToFloat64(changed),
+		testutil.ToFloat64(gaps)
+
+	verifyCacheHit(newProxyRequest(r, httptest.NewRecorder()), o, "test-key", trq,
+		client, client.testModeler(), cds, o.CacheVerification.Tolerance)
+	require.Equal(t, bd+1, testutil.ToFloat64(divergent))
+	require.Equal(t, bc+1, testutil.ToFloat64(changed))
+	require.Equal(t, bg+1, testutil.ToFloat64(gaps))
+}
+
+func testFetchBody(client *TestClient, r *http.Request) ([]byte, error) {
+	w := httptest.NewRecorder()
+	client.QueryRangeHandler(w, r)
+	return io.ReadAll(w.Result().Body)
+}
+
+func TestEpochGaps(t *testing.T) {
+	step := time.Minute
+	s := epoch.Epoch(step)
+	el := epochGaps([]epoch.Epoch{s, 2 * s, 3 * s, 10 * s}, step)
+	require.Equal(t, timeseries.ExtentList{
+		{Start: time.Unix(60, 0), End: time.Unix(180, 0)},
+		{Start: time.Unix(600, 0), End: time.Unix(600, 0)},
+	}, el)
+	require.Empty(t, epochGaps(nil, step))
+}
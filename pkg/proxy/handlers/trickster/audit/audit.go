@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package audit records management API actions, like cache purges and
+// configuration reloads, to the audit log
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+)
+
+// Management API actions
+const (
+	ActionConfigDump  = "config_dump"
+	ActionReload      = "reload"
+	ActionPurgeKey    = "purge_key"
+	ActionPurgePath   = "purge_path"
+	ActionPurgeTenant = "purge_tenant"
+)
+
+// Outcomes of management API actions. A handler may set a more specific
+// outcome with Set
+const (
+	OutcomeSuccess     = "success"
+	OutcomeFailure     = "failure"
+	OutcomeReloaded    = "reloaded"
+	OutcomeNotReloaded = "not_reloaded"
+)
+
+type entryKey struct{}
+
+// Handler returns a handler that records each request served by next to the
+// audit log as the provided action, along with any details set by next using
+// Set. The client address is read from the forwardedHeaders class of
+// forwarding headers, for requests received from a trusted proxy. Requests
+// without Resources are given empty Resources, so that an
+// authenticator wrapped by Handler can record the authenticated user, even
+// when the audit log is disabled.
+func Handler(action, forwardedHeaders string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rsc := request.GetResources(r)
+		if rsc == nil {
+			rsc = &request.Resources{}
+			r = request.SetResources(r, rsc)
+		}
+		if logger.AuditLogger() == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		entry := make(logging.Pairs)
+		r = r.WithContext(context.WithValue(r.Context(), entryKey{}, entry))
+		sw := &statusWriter{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		entry["endpoint"] = r.URL.Path
+		entry["method"] = r.Method
+		entry["remoteAddr"] = r.RemoteAddr
+		// the forwarded client IP is only resolved from the forwarding
+		// headers of trusted proxies, so it is omitted for direct requests
+		remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remoteIP = r.RemoteAddr
+		}
+		if clientIP := headers.ClientIP(r, forwardedHeaders); clientIP != remoteIP {
+			entry["forwardedClientIP"] = clientIP
+		}
+		entry["code"] = sw.code
+		if rsc.AuthResult != nil && rsc.AuthResult.Username != "" {
+			// usernames from observe-only authenticators are not verified
+			if rsc.AuthResult.Status == types.AuthSuccess {
+				entry["user"] = rsc.AuthResult.Username
+			} else {
+				entry["unverifiedUser"] = rsc.AuthResult.Username
+			}
+		}
+		if _, ok := entry["outcome"]; !ok {
+			if sw.code < http.StatusBadRequest {
+				entry["outcome"] = OutcomeSuccess
+			} else {
+				entry["outcome"] = OutcomeFailure
+			}
+		}
+		logger.Audit(action, entry)
+	})
+}
+
+// Enabled returns true if the request is being audited
+func Enabled(r *http.Request) bool {
+	_, ok := r.Context().Value(entryKey{}).(logging.Pairs)
+	return ok
+}
+
+// Set adds a detail to the audit log entry for the request. It is a no-op when
+// the request is not being audited
+func Set(r *http.Request, key string, value any) {
+	if entry, ok := r.Context().Value(entryKey{}).(logging.Pairs); ok {
+		entry[key] = value
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	code int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
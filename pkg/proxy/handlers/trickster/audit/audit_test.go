@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetAuditLogger(logging.StreamAuditLogger(buf))
+	t.Cleanup(func() { logger.SetAuditLogger(nil) })
+
+	h := Handler(ActionPurgeKey, "none", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, Enabled(r))
+		// simulate an authenticator wrapped by the audit handler
+		request.GetResources(r).AuthResult = &types.AuthResult{
+			Status: types.AuthSuccess, Username: "alice",
+		}
+		Set(r, "backend", "prom1")
+		Set(r, "key", "abc")
+		w.WriteHeader(http.StatusOK)
+	}))
+	r := httptest.NewRequest(http.MethodDelete, "/trickster/purge/key/prom1/abc", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	entry := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, ActionPurgeKey, entry["action"])
+	require.Equal(t, "/trickster/purge/key/prom1/abc", entry["endpoint"])
+	require.Equal(t, http.MethodDelete, entry["method"])
+	require.Equal(t, "10.0.0.1:1234", entry["remoteAddr"])
+	require.NotContains(t, entry, "forwardedClientIP")
+	require.Equal(t, "alice", entry["user"])
+	require.Equal(t, "prom1", entry["backend"])
+	require.Equal(t, "abc", entry["key"])
+	require.Equal(t, float64(http.StatusOK), entry["code"])
+	require.Equal(t, OutcomeSuccess, entry["outcome"])
+	require.NotEmpty(t, entry["time"])
+}
+
+func TestHandlerOutcome(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetAuditLogger(logging.StreamAuditLogger(buf))
+	t.Cleanup(func() { logger.SetAuditLogger(nil) })
+
+	h := Handler(ActionReload, "x", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/trickster/config/reload", nil)
+	r.Header.Set("X-Forwarded-For", "10.0.0.2")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	// forwarding headers from untrusted peers are not logged
+	entry := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, OutcomeFailure, entry["outcome"])
+	require.Equal(t, "192.0.2.1:1234", entry["remoteAddr"])
+	require.NotContains(t, entry, "forwardedClientIP")
+	require.NotContains(t, entry, "user")
+
+	tp, err := headers.ParseTrustedProxies([]string{"192.0.2.0/24"})
+	require.NoError(t, err)
+	headers.SetTrustedProxies(tp)
+	t.Cleanup(func() { headers.SetTrustedProxies(nil) })
+	buf.Reset()
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	entry = map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "192.0.2.1:1234", entry["remoteAddr"])
+	require.Equal(t, "10.0.0.2", entry["forwardedClientIP"])
+
+	// forwarding headers of another class are not read
+	buf.Reset()
+	Handler(ActionReload, "standard", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	entry = map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.NotContains(t, entry, "forwardedClientIP")
+
+	buf.Reset()
+	h = Handler(ActionReload, "x", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Set(r, "outcome", OutcomeNotReloaded)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, OutcomeNotReloaded, entry["outcome"])
+}
+
+func TestHandlerUnverifiedUser(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetAuditLogger(logging.StreamAuditLogger(buf))
+	t.Cleanup(func() { logger.SetAuditLogger(nil) })
+
+	h := Handler(ActionPurgeKey, "none", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request.GetResources(r).AuthResult = &types.AuthResult{
+			Status: types.AuthObserved, Username: "mallory",
+		}
+	}))
+	h.ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodDelete, "/trickster/purge/key/prom1/abc", nil))
+
+	entry := map[string]any{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.NotContains(t, entry, "user")
+	require.Equal(t, "mallory", entry["unverifiedUser"])
+}
+
+func TestHandlerDisabled(t *testing.T) {
+	logger.SetAuditLogger(nil)
+	var served bool
+	h := Handler(ActionConfigDump, "none", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = true
+		require.False(t, Enabled(r))
+		require.NotNil(t, request.GetResources(r))
+		Set(r, "sanitized", true)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, "/trickster/config", nil))
+	require.True(t, served)
+}
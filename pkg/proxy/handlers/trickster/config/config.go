@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	"github.com/trickstercache/trickster/v2/pkg/config"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/audit"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 )
 
@@ -42,7 +43,8 @@ func SanitizedHandlerPath(configHandlerPath string) string {
 // SanitizedHandlerFunc responds to the HTTP request with a sanitized copy of the
 // running configuration.
 func SanitizedHandlerFunc(conf *config.Config) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		audit.Set(r, "sanitized", true)
 		w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
 		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
 		w.WriteHeader(http.StatusOK)
@@ -19,6 +19,7 @@ package failures
 import (
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
@@ -48,6 +49,16 @@ func HandleUnauthorized(w http.ResponseWriter, _ *http.Request) {
 	handleFailureWithMessage(w, http.StatusUnauthorized, "Unauthorized")
 }
 
+// HandleForbidden responds to an HTTP Request with a 403 Forbidden, including
+// the reason in the response body when provided
+func HandleForbidden(w http.ResponseWriter, reason string) {
+	message := "Forbidden"
+	if reason != "" {
+		message += ": " + reason
+	}
+	handleFailureWithMessage(w, http.StatusForbidden, message)
+}
+
 // HandleNotFound responds to an HTTP Request with a 404 Not Found
 func HandleNotFound(w http.ResponseWriter, _ *http.Request) {
 	handleFailureWithMessage(w, http.StatusNotFound, "Resource Not Found")
@@ -58,6 +69,16 @@ func HandlePayloadTooLarge(w http.ResponseWriter, _ *http.Request) {
 	handleFailureWithMessage(w, http.StatusRequestEntityTooLarge, PayloadTooLargeText)
 }
 
+// HandleTooManyRequests responds to an HTTP Request with a 429 Too Many
+// Requests, including a Retry-After header of the provided seconds
+func HandleTooManyRequests(w http.ResponseWriter, retryAfterSecs int) {
+	if w == nil {
+		return
+	}
+	w.Header().Set(headers.NameRetryAfter, strconv.Itoa(retryAfterSecs))
+	handleFailureWithMessage(w, http.StatusTooManyRequests, "Too Many Requests")
+}
+
 // handleFailureWithMessage responds to an HTTP Request with the provided status code and message
 func handleFailureWithMessage(w http.ResponseWriter, statusCode int, message string) {
 	if w == nil {
@@ -64,6 +64,23 @@ func TestHandleUnauthorized(t *testing.T) {
 	}
 }
 
+func TestHandleForbidden(t *testing.T) {
+	HandleForbidden(nil, "")
+	w := httptest.NewRecorder()
+	HandleForbidden(w, "")
+	if w.Result().StatusCode != 403 {
+		t.Errorf("expected %d got %d", 403, w.Result().StatusCode)
+	}
+	if body := w.Body.String(); body != "Forbidden" {
+		t.Errorf("expected body %q got %q", "Forbidden", body)
+	}
+	w = httptest.NewRecorder()
+	HandleForbidden(w, "not allowed")
+	if body := w.Body.String(); body != "Forbidden: not allowed" {
+		t.Errorf("expected body %q got %q", "Forbidden: not allowed", body)
+	}
+}
+
 func TestHandleNotFound(t *testing.T) {
 	HandleNotFound(nil, nil)
 	w := httptest.NewRecorder()
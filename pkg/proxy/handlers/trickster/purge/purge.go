@@ -21,14 +21,18 @@ import (
 	"html"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/trickstercache/trickster/v2/pkg/backends"
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
 	"github.com/trickstercache/trickster/v2/pkg/cache"
 	"github.com/trickstercache/trickster/v2/pkg/checksum/md5"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
 	proxyengines "github.com/trickstercache/trickster/v2/pkg/proxy/engines"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/audit"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/tenancy"
 )
 
 // writeValidationError writes a standardized validation error response
@@ -80,6 +84,8 @@ func KeyHandler(pathPrefix string,
 		}
 		backendName := parts[0]
 		purgeKey := parts[1]
+		audit.Set(req, "backend", backendName)
+		audit.Set(req, "key", purgeKey)
 		backend := from.Get(backendName)
 		if !validateBackend(w, backend, backendName) {
 			return
@@ -126,6 +132,8 @@ func PathHandler(pathPrefix string,
 		}
 		logger.Debug("purging cache item",
 			logging.Pairs{"backend": backendName, "path": purgePath})
+		audit.Set(req, "backend", backendName)
+		audit.Set(req, "path", purgePath)
 		backend := from.Get(backendName)
 		if !validateBackend(w, backend, backendName) {
 			return
@@ -136,9 +144,19 @@ func PathHandler(pathPrefix string,
 		}
 
 		cfg := backend.Configuration()
+		// for tenant-partitioned backends, the entries of the tenant provided
+		// in the query string are purged
+		var tenantKeyPart string
+		if cfg.Tenancy != nil {
+			tenant := req.URL.Query().Get("tenant")
+			tenantKeyPart = tenancy.KeyPart(cache, cfg.Name, cfg.CacheKeyPrefix,
+				tenant)
+			audit.Set(req, "tenant", tenant)
+		}
 		for _, engine := range engines {
 			for _, method := range methods {
-				suffix := md5.Checksum(fmt.Sprintf("%s.method.%s.", purgePath, method))
+				suffix := md5.Checksum(fmt.Sprintf("%s.method.%s.%s", purgePath, method,
+					tenantKeyPart))
 				cache.Remove(proxyengines.ComposeCacheKey(cfg.Name, cfg.CacheKeyPrefix, engine, suffix))
 			}
 		}
@@ -146,3 +164,52 @@ func PathHandler(pathPrefix string,
 		writePurgeResult(w, backendName, purgePath)
 	}
 }
+
+// TenantHandler purges all of a tenant's objects from a tenant-partitioned
+// backend's cache.
+func TenantHandler(pathPrefix string,
+	from *backends.Backends,
+) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		vals := strings.Replace(req.URL.Path, pathPrefix, "", 1)
+		parts := strings.SplitN(vals, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			writeValidationError(w, "Usage: "+pathPrefix+"{backend}/{tenant}\n")
+			return
+		}
+		backendName := parts[0]
+		tenant := parts[1]
+		audit.Set(req, "backend", backendName)
+		audit.Set(req, "tenant", tenant)
+		backend := from.Get(backendName)
+		if !validateBackend(w, backend, backendName) {
+			return
+		}
+		cache := backend.Cache()
+		if !validateCache(w, cache, backendName) {
+			return
+		}
+		cfg := backend.Configuration()
+		if cfg.Tenancy == nil {
+			writeValidationError(w, "Backend "+html.EscapeString(backendName)+
+				" doesn't have tenancy configured.")
+			return
+		}
+		logger.Debug("purging tenant cache items",
+			logging.Pairs{"backend": backendName, "tenant": tenant})
+		ttl := time.Duration(cfg.MaxTTL)
+		if ttl <= 0 {
+			ttl = bo.DefaultMaxTTL
+		}
+		if err := tenancy.Purge(cache, cfg.Name, cfg.CacheKeyPrefix, tenant,
+			ttl); err != nil {
+			logger.Error("tenant cache purge failed",
+				logging.Pairs{"backend": backendName, "tenant": tenant,
+					"detail": err.Error()})
+			w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writePurgeResult(w, backendName, "tenant "+tenant)
+	}
+}
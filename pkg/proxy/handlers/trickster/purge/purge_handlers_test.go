@@ -17,12 +17,17 @@
 package purge
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/trickstercache/trickster/v2/pkg/backends"
 	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/audit"
 )
 
 func TestKeyHandler(t *testing.T) {
@@ -135,3 +140,27 @@ func TestPathHandlerValidation(t *testing.T) {
 		}
 	})
 }
+
+func TestKeyHandlerAudit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetAuditLogger(logging.StreamAuditLogger(buf))
+	t.Cleanup(func() { logger.SetAuditLogger(nil) })
+
+	const pathPrefix = "/trickster/purge/key/"
+	bes := backends.Backends{
+		"backend-a": &fakeBackend{
+			cfg:   &bo.Options{Name: "backend-a"},
+			cache: newMemCache(),
+		},
+	}
+	h := audit.Handler(audit.ActionPurgeKey, "none", http.HandlerFunc(KeyHandler(pathPrefix, bes)))
+	h.ServeHTTP(httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodDelete, pathPrefix+"backend-a/object-key", nil))
+	out := buf.String()
+	for _, s := range []string{`"action":"purge_key"`, `"backend":"backend-a"`,
+		`"key":"object-key"`, `"outcome":"success"`} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %s in audit entry %s", s, out)
+		}
+	}
+}
@@ -30,7 +30,10 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/cache/options"
 	"github.com/trickstercache/trickster/v2/pkg/cache/status"
 	"github.com/trickstercache/trickster/v2/pkg/checksum/md5"
+	proxyengines "github.com/trickstercache/trickster/v2/pkg/proxy/engines"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/tenancy"
+	tno "github.com/trickstercache/trickster/v2/pkg/proxy/tenancy/options"
 )
 
 // memCache is a minimal in-memory cache.Cache used to assert key-format parity
@@ -220,3 +223,69 @@ func TestPathHandler_KeyFormatMatchesEngines(t *testing.T) {
 		}
 	}
 }
+
+// TestPathHandler_TenantKeyFormatMatchesEngines asserts a path purge with a
+// tenant query parameter removes only that tenant's entries.
+func TestPathHandler_TenantKeyFormatMatchesEngines(t *testing.T) {
+	const purgePath = "/api/v1/query"
+	c := newMemCache()
+	cfg := &bo.Options{Name: "tenant-path", CacheKeyPrefix: "pfx", Tenancy: tno.New()}
+	bes := backends.Backends{"tenant-path": &fakeBackend{cfg: cfg, cache: c}}
+
+	keyFor := func(tenant string) string {
+		return proxyengines.ComposeCacheKey(cfg.Name, cfg.CacheKeyPrefix, "dpc",
+			md5.Checksum(fmt.Sprintf("%s.method.%s.%s", purgePath, http.MethodGet,
+				tenancy.KeyPart(c, cfg.Name, cfg.CacheKeyPrefix, tenant))))
+	}
+	ka, kb := keyFor("a"), keyFor("b")
+	c.Store(ka, []byte("v"), time.Minute)
+	c.Store(kb, []byte("v"), time.Minute)
+
+	const pathPrefix = "/trickster/purge/path/"
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, pathPrefix+"tenant-path"+purgePath+"?tenant=a", nil)
+	PathHandler(pathPrefix, &bes)(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	if _, _, err := c.Retrieve(ka); err == nil {
+		t.Errorf("tenant a key %q should have been purged", ka)
+	}
+	if _, _, err := c.Retrieve(kb); err != nil {
+		t.Errorf("tenant b key %q should still be present, got err=%v", kb, err)
+	}
+}
+
+func TestTenantHandler(t *testing.T) {
+	const pathPrefix = "/trickster/purge/tenant/"
+	c := newMemCache()
+	cfg := &bo.Options{Name: "tenant-all", CacheKeyPrefix: "pfx", Tenancy: tno.New()}
+	bes := backends.Backends{
+		"tenant-all": &fakeBackend{cfg: cfg, cache: c},
+		"untenanted": &fakeBackend{cfg: &bo.Options{Name: "untenanted"}, cache: c},
+	}
+	h := TenantHandler(pathPrefix, &bes)
+
+	a := tenancy.KeyPart(c, cfg.Name, cfg.CacheKeyPrefix, "a")
+	b := tenancy.KeyPart(c, cfg.Name, cfg.CacheKeyPrefix, "b")
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, pathPrefix+"tenant-all/a", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d body=%s", w.Code, w.Body.String())
+	}
+	if tenancy.KeyPart(c, cfg.Name, cfg.CacheKeyPrefix, "a") == a {
+		t.Error("expected tenant a's entries to be purged")
+	}
+	if tenancy.KeyPart(c, cfg.Name, cfg.CacheKeyPrefix, "b") != b {
+		t.Error("expected tenant b's entries to remain")
+	}
+
+	for _, path := range []string{"tenant-all", "tenant-all/", "missing/a", "untenanted/a"} {
+		w = httptest.NewRecorder()
+		h(w, httptest.NewRequest(http.MethodGet, pathPrefix+path, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+}
@@ -19,14 +19,35 @@ package reload
 import (
 	"net/http"
 
+	"github.com/trickstercache/trickster/v2/pkg/checksum/sha1"
+	"github.com/trickstercache/trickster/v2/pkg/config"
 	"github.com/trickstercache/trickster/v2/pkg/config/reload"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/audit"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 )
 
-// HandlerFunc will reload the running configuration if it has changed
-func HandlerFunc(f reload.Reloader) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
-		didReload, _ := f("handler")
+// HandlerFunc will reload the running configuration if it has changed. When
+// the request is audited, running provides the running configuration, whose
+// hash before and after the reload is recorded to the audit log.
+func HandlerFunc(f reload.Reloader, running func() *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		auditing := audit.Enabled(r) && running != nil
+		if auditing {
+			audit.Set(r, "previousConfigHash", configHash(running()))
+		}
+		didReload, err := f("handler")
+		if auditing {
+			switch {
+			case err != nil:
+				audit.Set(r, "outcome", audit.OutcomeFailure)
+				audit.Set(r, "error", err.Error())
+			case didReload:
+				audit.Set(r, "outcome", audit.OutcomeReloaded)
+			default:
+				audit.Set(r, "outcome", audit.OutcomeNotReloaded)
+			}
+			audit.Set(r, "configHash", configHash(running()))
+		}
 		w.Header().Set(headers.NameContentType, headers.ValueTextPlain)
 		w.Header().Set(headers.NameCacheControl, headers.ValueNoCache)
 		w.WriteHeader(http.StatusOK)
@@ -37,3 +58,10 @@ func HandlerFunc(f reload.Reloader) http.HandlerFunc {
 		}
 	}
 }
+
+func configHash(c *config.Config) string {
+	if c == nil {
+		return ""
+	}
+	return sha1.Checksum(c.String())
+}
@@ -17,6 +17,9 @@
 package reload
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -28,6 +31,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/level"
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/audit"
 )
 
 func TestReloadHandleFunc(t *testing.T) {
@@ -54,7 +58,7 @@ func TestReloadHandleFunc(t *testing.T) {
 	w := httptest.NewRecorder()
 	r, _ := http.NewRequest("GET", "/", nil)
 
-	f := HandlerFunc(emptyFunc)
+	f := HandlerFunc(emptyFunc, nil)
 	f(w, r)
 	os.Remove(testFile)
 	time.Sleep(time.Millisecond * 500)
@@ -72,7 +76,7 @@ func TestReloadHandleFuncNotReloaded(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	r, _ := http.NewRequest("GET", "/", nil)
-	HandlerFunc(noReload)(w, r)
+	HandlerFunc(noReload, nil)(w, r)
 
 	if w.Code != http.StatusOK {
 		t.Fatalf("status = %d, want 200", w.Code)
@@ -81,3 +85,53 @@ func TestReloadHandleFuncNotReloaded(t *testing.T) {
 		t.Fatalf("body = %q, want %q", body, reload.ConfigNotReloadedText)
 	}
 }
+
+func TestReloadHandleFuncAudit(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetAuditLogger(logging.StreamAuditLogger(buf))
+	t.Cleanup(func() { logger.SetAuditLogger(nil) })
+
+	running := config.NewConfig()
+	next := config.NewConfig()
+	next.Main.ServerName = "reloaded"
+	var reloader reload.Reloader = func(string) (bool, error) {
+		running = next
+		return true, nil
+	}
+	h := audit.Handler(audit.ActionReload, "none",
+		HandlerFunc(reloader, func() *config.Config { return running }))
+	prevHash := configHash(running)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	entry := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["outcome"] != audit.OutcomeReloaded {
+		t.Errorf("outcome = %v, want %s", entry["outcome"], audit.OutcomeReloaded)
+	}
+	if entry["previousConfigHash"] != prevHash {
+		t.Errorf("previousConfigHash = %v, want %s", entry["previousConfigHash"], prevHash)
+	}
+	if entry["configHash"] != configHash(next) || prevHash == configHash(next) {
+		t.Errorf("configHash = %v, want %s", entry["configHash"], configHash(next))
+	}
+
+	buf.Reset()
+	reloader = func(string) (bool, error) {
+		return false, errors.New("invalid config")
+	}
+	h = audit.Handler(audit.ActionReload, "none",
+		HandlerFunc(reloader, func() *config.Config { return running }))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	entry = map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["outcome"] != audit.OutcomeFailure || entry["error"] != "invalid config" {
+		t.Errorf("unexpected entry %v", entry)
+	}
+	if entry["previousConfigHash"] != entry["configHash"] {
+		t.Errorf("expected unchanged config hash, got %v", entry)
+	}
+}
@@ -17,9 +17,13 @@
 package headers
 
 import (
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"strings"
+	"sync/atomic"
 
 	"github.com/trickstercache/trickster/v2/pkg/appinfo"
 )
@@ -241,6 +245,103 @@ func HopsFromRequest(r *http.Request) *Hop {
 	return hop
 }
 
+// ErrInvalidTrustedProxy is returned when a trusted proxy is not a valid CIDR
+// or IP address
+var ErrInvalidTrustedProxy = errors.New("invalid trusted proxy")
+
+// trustedProxies holds the networks of the proxies whose forwarding headers
+// are honored by ClientIP
+var trustedProxies atomic.Pointer[[]netip.Prefix]
+
+// ParseTrustedProxies parses a list of CIDRs or IP addresses into prefixes
+func ParseTrustedProxies(cidrs []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(cidrs))
+	for _, s := range cidrs {
+		if p, err := netip.ParsePrefix(s); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidTrustedProxy, s)
+		}
+		ip = ip.Unmap()
+		out = append(out, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return out, nil
+}
+
+// SetTrustedProxies sets the networks of the proxies whose forwarding headers
+// are honored by ClientIP
+func SetTrustedProxies(prefixes []netip.Prefix) {
+	trustedProxies.Store(&prefixes)
+}
+
+func isTrustedProxy(ip netip.Addr) bool {
+	p := trustedProxies.Load()
+	if p == nil {
+		return false
+	}
+	ip = ip.Unmap()
+	for _, prefix := range *p {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that originated the request.
+// Forwarding headers are only honored when the request was received from a
+// trusted proxy: when headerType is standard, x or both, the hops of the
+// matching forwarding header are walked from the right, and the first address
+// that is not a trusted proxy is returned. Otherwise, the address is taken from
+// the request's RemoteAddr.
+func ClientIP(r *http.Request, headerType string) string {
+	if r == nil {
+		return ""
+	}
+	remote := r.RemoteAddr
+	if ip, _, err := net.SplitHostPort(remote); err == nil {
+		remote = ip
+	}
+	ip, err := netip.ParseAddr(remote)
+	if err != nil || !isTrustedProxy(ip) {
+		return remote
+	}
+	var hops Hops
+	switch headerType {
+	case "standard":
+		hops = parseForwardHeaders(r.Header)
+	case "x":
+		hops = parseXForwardHeaders(r.Header)
+	case "both":
+		hops = HopsFromHeader(r.Header)
+	}
+	for i := len(hops) - 1; i >= 0 && isTrustedProxy(ip); i-- {
+		next, ok := hopAddr(hops[i].RemoteAddr)
+		if !ok {
+			// an unidentifiable hop ends the chain, so the last proxy that
+			// could be verified is the best known client address
+			break
+		}
+		ip = next
+	}
+	return ip.Unmap().String()
+}
+
+func hopAddr(addr string) (netip.Addr, bool) {
+	addr = strings.Trim(addr, `"`)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip, err := netip.ParseAddr(strings.Trim(addr, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip, true
+}
+
 // HopsFromHeader extracts a hop from the header
 func HopsFromHeader(h http.Header) Hops {
 	if _, ok := h[NameForwarded]; ok {
@@ -252,7 +353,7 @@ func HopsFromHeader(h http.Header) Hops {
 }
 
 func parseForwardHeaders(h http.Header) Hops {
-	fh := h.Get(NameForwarded)
+	fh := strings.Join(h.Values(NameForwarded), ",")
 	if fh != "" {
 		fwds := strings.Split(strings.ReplaceAll(fh, " ", ""), ",")
 		var k int
@@ -313,7 +414,7 @@ func formatForwardedAddress(input string) string {
 }
 
 func parseXForwardHeaders(h http.Header) Hops {
-	xff := h.Get(NameXForwardedFor)
+	xff := strings.Join(h.Values(NameXForwardedFor), ",")
 	if xff != "" {
 		fwds := strings.Split(strings.ReplaceAll(xff, " ", ""), ",")
 		hops := make(Hops, len(fwds))
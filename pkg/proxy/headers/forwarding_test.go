@@ -17,6 +17,7 @@
 package headers
 
 import (
+	"errors"
 	"net/http"
 	"testing"
 )
@@ -203,3 +204,86 @@ func TestStripMergeHeaders(t *testing.T) {
 		t.Error("expected Location Header to remain present")
 	}
 }
+
+func TestClientIP(t *testing.T) {
+	newRequest := func(h http.Header) *http.Request {
+		r, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		r.RemoteAddr = "192.168.1.10:51234"
+		r.Header = h
+		return r
+	}
+	fwd := http.Header{NameForwarded: {`for=10.0.0.1;proto=https, for=10.0.0.2`}}
+	xff := http.Header{NameXForwardedFor: {"10.0.0.3, 10.0.0.4"}}
+	xffLines := http.Header{NameXForwardedFor: {"10.0.0.3", "10.0.0.4"}}
+	xffTrusted := http.Header{NameXForwardedFor: {"10.0.0.3, 172.16.0.1, 172.16.0.2"}}
+	xffAllTrusted := http.Header{NameXForwardedFor: {"172.16.0.1, 172.16.0.2"}}
+	xffInvalid := http.Header{NameXForwardedFor: {"10.0.0.3, unknown, 172.16.0.1"}}
+	fwd6 := http.Header{NameForwarded: {`for="[2001:db8::1]:4711"`}}
+	tests := []struct {
+		name       string
+		headerType string
+		header     http.Header
+		expected   string
+	}{
+		{"none ignores headers", "none", fwd, "192.168.1.10"},
+		{"standard", "standard", fwd, "10.0.0.2"},
+		{"standard ignores x", "standard", xff, "192.168.1.10"},
+		{"standard v6", "standard", fwd6, "2001:db8::1"},
+		{"x", "x", xff, "10.0.0.4"},
+		{"x multiple lines", "x", xffLines, "10.0.0.4"},
+		{"x skips trusted", "x", xffTrusted, "10.0.0.3"},
+		{"x all trusted", "x", xffAllTrusted, "172.16.0.1"},
+		{"x invalid hop", "x", xffInvalid, "172.16.0.1"},
+		{"x ignores standard", "x", fwd, "192.168.1.10"},
+		{"both standard", "both", fwd, "10.0.0.2"},
+		{"both x", "both", xff, "10.0.0.4"},
+		{"no headers", "standard", http.Header{}, "192.168.1.10"},
+	}
+	prefixes, err := ParseTrustedProxies([]string{"192.168.0.0/16", "172.16.0.0/12"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetTrustedProxies(prefixes)
+	defer SetTrustedProxies(nil)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ClientIP(newRequest(test.header), test.headerType); got != test.expected {
+				t.Errorf("expected %s got %s", test.expected, got)
+			}
+		})
+	}
+	if got := ClientIP(nil, "standard"); got != "" {
+		t.Errorf("expected empty string got %s", got)
+	}
+
+	// headers from untrusted peers are ignored
+	r := newRequest(xff)
+	r.RemoteAddr = "203.0.113.5:51234"
+	if got := ClientIP(r, "x"); got != "203.0.113.5" {
+		t.Errorf("expected remote address got %s", got)
+	}
+	// and all headers are ignored when there are no trusted proxies
+	SetTrustedProxies(nil)
+	if got := ClientIP(newRequest(xff), "x"); got != "192.168.1.10" {
+		t.Errorf("expected remote address got %s", got)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	p, err := ParseTrustedProxies([]string{"10.1.2.3/8", "192.168.1.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"10.0.0.0/8", "192.168.1.1/32", "2001:db8::/32"}
+	if len(p) != len(expected) {
+		t.Fatalf("expected %d prefixes got %d", len(expected), len(p))
+	}
+	for i, v := range expected {
+		if p[i].String() != v {
+			t.Errorf("expected %s got %s", v, p[i])
+		}
+	}
+	if _, err := ParseTrustedProxies([]string{"not-an-ip"}); !errors.Is(err, ErrInvalidTrustedProxy) {
+		t.Errorf("expected %v got %v", ErrInvalidTrustedProxy, err)
+	}
+}
@@ -33,6 +33,8 @@ const (
 	ValueApplicationCSV = "application/csv"
 	// ValueApplicationJSON represents the HTTP Header Value of "application/json"
 	ValueApplicationJSON = "application/json"
+	// ValueApplicationOctetStream represents the HTTP Header Value of "application/octet-stream"
+	ValueApplicationOctetStream = "application/octet-stream"
 	// ValueApplicationYAML represents the HTTP Header Value of "application/yaml"
 	ValueApplicationYAML = "application/yaml"
 	// ValueApplicationFlux represents the HTTP Header Value of "application/vnd.flux"
@@ -96,6 +98,10 @@ const (
 	NameContentRange = "Content-Range"
 	// NameTricksterResult represents the HTTP Header Name of "X-Trickster-Result"
 	NameTricksterResult = "X-Trickster-Result"
+	// NameTricksterPartial represents the HTTP Header Name of "X-Trickster-Partial"
+	NameTricksterPartial = "X-Trickster-Partial"
+	// NameTricksterEffectiveStep represents the HTTP Header Name of "X-Trickster-Effective-Step"
+	NameTricksterEffectiveStep = "X-Trickster-Effective-Step"
 	// NameAcceptEncoding represents the HTTP Header Name of "Accept-Encoding"
 	NameAcceptEncoding = "Accept-Encoding"
 	// NameAcceptLanguage represents the HTTP Header Name of "Accept-Language"
@@ -106,8 +112,14 @@ const (
 	NameUserAgent = "User-Agent"
 	// NameSetCookie represents the HTTP Header Name of "Set-Cookie"
 	NameSetCookie = "Set-Cookie"
+	// NameCookie represents the HTTP Header Name of "Cookie"
+	NameCookie = "Cookie"
+	// NameXAPIKey represents the HTTP Header Name of "X-API-Key"
+	NameXAPIKey = "X-API-Key"
 	// NameRange represents the HTTP Header Name of "Range"
 	NameRange = "Range"
+	// NameRetryAfter represents the HTTP Header Name of "Retry-After"
+	NameRetryAfter = "Retry-After"
 	// NameTransferEncoding represents the HTTP Header Name of "Transfer-Encoding"
 	NameTransferEncoding = "Transfer-Encoding"
 	// NameIfModifiedSince represents the HTTP Header Name of "If-Modified-Since"
@@ -24,6 +24,19 @@ import (
 
 var sensitiveCredentials = sets.New([]string{NameAuthorization})
 
+// credentialHeaders are the headers that carry client credentials
+var credentialHeaders = []string{
+	NameAuthorization, NameProxyAuthorization, NameCookie, NameXAPIKey,
+}
+
+// StripCredentials removes the headers that carry client credentials, like
+// Authorization and Cookie, from the provided Header map
+func StripCredentials(h http.Header) {
+	for _, hn := range credentialHeaders {
+		h.Del(hn)
+	}
+}
+
 // HideAuthorizationCredentials replaces any sensitive HTTP header values with 5
 // asterisks sensitive headers are defined in the sensitiveCredentials map
 func HideAuthorizationCredentials[m ~map[K]V, K ~string, V ~string](headers m) {
@@ -55,4 +68,5 @@ var allowList = []string{
 	NameCacheControl, NameConnection, NameContentLength, NameContentType,
 	NameDate, NameHost, NameIfModifiedSince, NameIfNoneMatch, NameRange,
 	NameUserAgent, NameVia, NameXForwardedFor, NameTricksterResult,
+	NameTricksterPartial, NameTricksterEffectiveStep,
 }
@@ -16,7 +16,10 @@
 
 package headers
 
-import "testing"
+import (
+	"net/http"
+	"testing"
+)
 
 func TestHideAuthorizationCredentials(t *testing.T) {
 	hdrs := map[string]string{NameAuthorization: "Basic SomeHash"}
@@ -25,3 +28,15 @@ func TestHideAuthorizationCredentials(t *testing.T) {
 		t.Errorf("expected '*****' got '%s'", hdrs[NameAuthorization])
 	}
 }
+
+func TestStripCredentials(t *testing.T) {
+	h := http.Header{}
+	h.Set(NameAuthorization, "Basic SomeHash")
+	h.Set(NameCookie, "session=1")
+	h.Set(NameXAPIKey, "key")
+	h.Set(NameAccept, "*/*")
+	StripCredentials(h)
+	if len(h) != 1 || h.Get(NameAccept) != "*/*" {
+		t.Errorf("expected only %s to remain, got %v", NameAccept, h)
+	}
+}
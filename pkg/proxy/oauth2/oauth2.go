@@ -0,0 +1,83 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package oauth2 authorizes upstream requests with bearer tokens obtained via
+// the OAuth2 Client Credentials grant
+package oauth2
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/proxy/oauth2/options"
+
+	xoauth2 "golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// TokenRequestTimeout is the maximum duration of a token endpoint request
+const TokenRequestTimeout = 10 * time.Second
+
+// NewTokenSource returns a TokenSource that fetches tokens from the token
+// endpoint using base, caches them, and refreshes them once they are within
+// the configured refresh_before duration of expiring.
+func NewTokenSource(o *options.Options, base http.RoundTripper) xoauth2.TokenSource {
+	return xoauth2.ReuseTokenSourceWithExpiry(nil, &clientCredentialsSource{
+		opts:   o,
+		client: &http.Client{Transport: base, Timeout: TokenRequestTimeout},
+	}, time.Duration(o.RefreshBefore))
+}
+
+// NewRoundTripper returns a RoundTripper that sets a bearer token from the
+// token endpoint on each request before passing it to next
+func NewRoundTripper(o *options.Options, next http.RoundTripper) http.RoundTripper {
+	return &xoauth2.Transport{Source: NewTokenSource(o, next), Base: next}
+}
+
+type clientCredentialsSource struct {
+	opts   *options.Options
+	client *http.Client
+}
+
+// Token requests a new token from the token endpoint. When a client secret
+// file is configured, it is read for each request to pick up rotated secrets.
+func (s *clientCredentialsSource) Token() (*xoauth2.Token, error) {
+	secret := s.opts.ClientSecret
+	if s.opts.ClientSecretFile != "" {
+		b, err := os.ReadFile(s.opts.ClientSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		secret = strings.TrimSpace(string(b))
+	}
+	cfg := &clientcredentials.Config{
+		ClientID:     s.opts.ClientID,
+		ClientSecret: secret,
+		TokenURL:     s.opts.TokenURL,
+		Scopes:       s.opts.Scopes,
+	}
+	if len(s.opts.EndpointParams) > 0 {
+		cfg.EndpointParams = make(map[string][]string, len(s.opts.EndpointParams))
+		for k, v := range s.opts.EndpointParams {
+			cfg.EndpointParams.Set(k, v)
+		}
+	}
+	ctx := context.WithValue(context.Background(), xoauth2.HTTPClient, s.client)
+	return cfg.Token(ctx)
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package oauth2
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/oauth2/options"
+)
+
+type tokenServer struct {
+	*httptest.Server
+	requests  atomic.Int32
+	expiresIn int
+	secret    atomic.Value
+}
+
+func newTokenServer(t *testing.T, expiresIn int) *tokenServer {
+	t.Helper()
+	ts := &tokenServer{expiresIn: expiresIn}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := ts.requests.Add(1)
+		_, secret, _ := r.BasicAuth()
+		ts.secret.Store(secret)
+		if err := r.ParseForm(); err != nil ||
+			r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d-%s","token_type":"Bearer","expires_in":%d}`,
+			n, r.PostForm.Get("audience"), ts.expiresIn)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func newOrigin(t *testing.T) (*httptest.Server, *atomic.Value) {
+	t.Helper()
+	var auth atomic.Value
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(s.Close)
+	return s, &auth
+}
+
+func testOptions(tokenURL string) *options.Options {
+	o := options.New()
+	o.TokenURL = tokenURL
+	o.ClientID = "trickster"
+	o.ClientSecret = "secret"
+	return o
+}
+
+func TestRoundTripperInjectsCachedToken(t *testing.T) {
+	ts := newTokenServer(t, 3600)
+	origin, auth := newOrigin(t)
+	o := testOptions(ts.URL)
+	o.EndpointParams = map[string]string{"audience": "prometheus"}
+	client := &http.Client{Transport: NewRoundTripper(o, http.DefaultTransport)}
+	for range 3 {
+		resp, err := client.Get(origin.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if got := auth.Load(); got != "Bearer token-1-prometheus" {
+		t.Fatalf("Authorization = %v, want %q", got, "Bearer token-1-prometheus")
+	}
+	if n := ts.requests.Load(); n != 1 {
+		t.Fatalf("token requests = %d, want 1", n)
+	}
+}
+
+func TestRoundTripperRefreshesBeforeExpiry(t *testing.T) {
+	// tokens expire in 30s, which is within the 1m default refresh window,
+	// so every request fetches a new token
+	ts := newTokenServer(t, 30)
+	origin, auth := newOrigin(t)
+	client := &http.Client{Transport: NewRoundTripper(testOptions(ts.URL), http.DefaultTransport)}
+	for range 2 {
+		resp, err := client.Get(origin.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if n := ts.requests.Load(); n != 2 {
+		t.Fatalf("token requests = %d, want 2", n)
+	}
+	if got := auth.Load(); got != "Bearer token-2-" {
+		t.Fatalf("Authorization = %v, want %q", got, "Bearer token-2-")
+	}
+
+	// with a smaller refresh window, the token is reused
+	ts = newTokenServer(t, 30)
+	o := testOptions(ts.URL)
+	o.RefreshBefore = timeconv.Duration(time.Second)
+	client = &http.Client{Transport: NewRoundTripper(o, http.DefaultTransport)}
+	for range 2 {
+		resp, err := client.Get(origin.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if n := ts.requests.Load(); n != 1 {
+		t.Fatalf("token requests = %d, want 1", n)
+	}
+}
+
+func TestTokenSourceReadsSecretFile(t *testing.T) {
+	ts := newTokenServer(t, 0)
+	f := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(f, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	o := testOptions(ts.URL)
+	o.ClientSecret = ""
+	o.ClientSecretFile = f
+	src := &clientCredentialsSource{opts: o, client: http.DefaultClient}
+	if _, err := src.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ts.secret.Load(); got != "first" {
+		t.Fatalf("client secret = %v, want %q", got, "first")
+	}
+	if err := os.WriteFile(f, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Token(); err != nil {
+		t.Fatal(err)
+	}
+	if got := ts.secret.Load(); got != "second" {
+		t.Fatalf("client secret = %v, want %q", got, "second")
+	}
+
+	o.ClientSecretFile = filepath.Join(t.TempDir(), "missing")
+	if _, err := src.Token(); err == nil {
+		t.Fatal("expected error for missing secret file")
+	}
+}
+
+func TestRoundTripperTokenError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+	origin, auth := newOrigin(t)
+	client := &http.Client{Transport: NewRoundTripper(testOptions(ts.URL), http.DefaultTransport)}
+	if _, err := client.Get(origin.URL); err == nil {
+		t.Fatal("expected error when the token endpoint rejects the client")
+	}
+	if auth.Load() != nil {
+		t.Fatal("request should not reach the origin without a token")
+	}
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for OAuth2 client credentials used
+// to authorize upstream requests
+package options
+
+import (
+	"errors"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// DefaultRefreshBefore is the default time before a token's expiry that it
+// is refreshed
+const DefaultRefreshBefore = time.Minute
+
+const redacted = "*****"
+
+var (
+	ErrMissingTokenURL     = errors.New("oauth2 token_url is required")
+	ErrInvalidTokenURL     = errors.New("oauth2 token_url is invalid")
+	ErrMissingClientID     = errors.New("oauth2 client_id is required")
+	ErrMultipleSecrets     = errors.New("oauth2 client_secret and client_secret_file are mutually exclusive")
+	ErrInvalidRefreshDelta = errors.New("oauth2 refresh_before must not be negative")
+)
+
+// Options defines an OAuth2 Client Credentials grant used to obtain bearer
+// tokens for upstream requests
+type Options struct {
+	// TokenURL is the token endpoint of the OAuth2 provider
+	TokenURL string `yaml:"token_url,omitempty"`
+	// ClientID is the OAuth2 client ID
+	ClientID string `yaml:"client_id,omitempty"`
+	// ClientSecret is the OAuth2 client secret. Environment variable
+	// references (e.g., ${CLIENT_SECRET}) are expanded
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	// ClientSecretFile is the path to a file containing the client secret,
+	// which is read for each token request so that rotated secrets are used
+	ClientSecretFile string `yaml:"client_secret_file,omitempty"`
+	// Scopes is the optional list of scopes to request
+	Scopes []string `yaml:"scopes,omitempty"`
+	// EndpointParams are optional additional parameters for token requests
+	// (e.g., audience)
+	EndpointParams map[string]string `yaml:"endpoint_params,omitempty"`
+	// RefreshBefore is how long before a token's expiry that it is refreshed.
+	// The default is 1m
+	RefreshBefore timeconv.Duration `yaml:"refresh_before,omitempty"`
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{RefreshBefore: timeconv.Duration(DefaultRefreshBefore)}
+}
+
+// Clone returns an exact copy of the Options
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	out := pointers.Clone(o)
+	out.Scopes = slices.Clone(o.Scopes)
+	out.EndpointParams = maps.Clone(o.EndpointParams)
+	return out
+}
+
+// CloneYAMLSafe returns a copy of the Options with the client secret redacted
+func (o *Options) CloneYAMLSafe() *Options {
+	out := o.Clone()
+	if out != nil && out.ClientSecret != "" {
+		out.ClientSecret = redacted
+	}
+	return out
+}
+
+// Initialize expands environment variable references in the client secret
+func (o *Options) Initialize(_ string) error {
+	if o == nil {
+		return nil
+	}
+	o.ClientSecret = os.ExpandEnv(o.ClientSecret)
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.TokenURL == "" {
+		return false, ErrMissingTokenURL
+	}
+	if u, err := url.Parse(o.TokenURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return false, ErrInvalidTokenURL
+	}
+	if o.ClientID == "" {
+		return false, ErrMissingClientID
+	}
+	if o.ClientSecret != "" && o.ClientSecretFile != "" {
+		return false, ErrMultipleSecrets
+	}
+	if o.RefreshBefore < 0 {
+		return false, ErrInvalidRefreshDelta
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding an oauth2 configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
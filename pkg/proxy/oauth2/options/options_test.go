@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	valid := func() *Options {
+		o := New()
+		o.TokenURL = "https://idp.example.com/oauth2/token"
+		o.ClientID = "trickster"
+		o.ClientSecret = "secret"
+		return o
+	}
+	tests := []struct {
+		name    string
+		mutate  func(*Options)
+		wantErr error
+	}{
+		{name: "valid", mutate: func(*Options) {}},
+		{name: "secret file", mutate: func(o *Options) {
+			o.ClientSecret = ""
+			o.ClientSecretFile = "/etc/trickster/secret"
+		}},
+		{name: "missing token url", mutate: func(o *Options) { o.TokenURL = "" },
+			wantErr: ErrMissingTokenURL},
+		{name: "relative token url", mutate: func(o *Options) { o.TokenURL = "/oauth2/token" },
+			wantErr: ErrInvalidTokenURL},
+		{name: "missing client id", mutate: func(o *Options) { o.ClientID = "" },
+			wantErr: ErrMissingClientID},
+		{name: "multiple secrets", mutate: func(o *Options) { o.ClientSecretFile = "/tmp/secret" },
+			wantErr: ErrMultipleSecrets},
+		{name: "negative refresh", mutate: func(o *Options) {
+			o.RefreshBefore = timeconv.Duration(-time.Second)
+		}, wantErr: ErrInvalidRefreshDelta},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := valid()
+			tc.mutate(o)
+			_, err := o.Validate()
+			if err != tc.wantErr {
+				t.Fatalf("Validate() error = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+	var o *Options
+	if ok, err := o.Validate(); !ok || err != nil {
+		t.Fatalf("nil Options should validate, got %v, %v", ok, err)
+	}
+}
+
+func TestOptionsInitialize(t *testing.T) {
+	t.Setenv("TRICKSTER_TEST_OAUTH2_SECRET", "from-env")
+	o := &Options{ClientSecret: "${TRICKSTER_TEST_OAUTH2_SECRET}"}
+	if err := o.Initialize(""); err != nil {
+		t.Fatal(err)
+	}
+	if o.ClientSecret != "from-env" {
+		t.Fatalf("ClientSecret = %q, want %q", o.ClientSecret, "from-env")
+	}
+}
+
+func TestOptionsCloneYAMLSafe(t *testing.T) {
+	o := &Options{
+		ClientSecret:   "secret",
+		Scopes:         []string{"read"},
+		EndpointParams: map[string]string{"audience": "prometheus"},
+	}
+	c := o.CloneYAMLSafe()
+	if c.ClientSecret != redacted {
+		t.Fatalf("ClientSecret = %q, want %q", c.ClientSecret, redacted)
+	}
+	c.Scopes[0] = "write"
+	c.EndpointParams["audience"] = "other"
+	if o.ClientSecret != "secret" || o.Scopes[0] != "read" ||
+		o.EndpointParams["audience"] != "prometheus" {
+		t.Fatal("clone must not modify the original Options")
+	}
+	if (*Options)(nil).CloneYAMLSafe() != nil {
+		t.Fatal("CloneYAMLSafe of nil Options should be nil")
+	}
+}
+
+func TestOptionsUnmarshalYAML(t *testing.T) {
+	var o Options
+	if err := yaml.Unmarshal([]byte("token_url: https://idp.example.com/token\nclient_id: x\n"), &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.RefreshBefore != timeconv.Duration(DefaultRefreshBefore) {
+		t.Fatalf("RefreshBefore = %v, want %v", o.RefreshBefore, DefaultRefreshBefore)
+	}
+	if err := yaml.Unmarshal([]byte("refresh_before: 30s\n"), &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.RefreshBefore != timeconv.Duration(30*time.Second) {
+		t.Fatalf("RefreshBefore = %v, want 30s", o.RefreshBefore)
+	}
+}
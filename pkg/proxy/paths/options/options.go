@@ -31,6 +31,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/proxy/forwarding"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/methods"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/paths/matching"
+	rlo "github.com/trickstercache/trickster/v2/pkg/proxy/ratelimit/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request/rewriter"
 	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
 	strutil "github.com/trickstercache/trickster/v2/pkg/util/strings"
@@ -64,6 +65,8 @@ type Options struct {
 	ResponseHeaders types.EnvStringMap `yaml:"response_headers,omitempty"`
 	// CORS overrides the backend CORS response-header policy for this path
 	CORS *corso.Options `yaml:"cors,omitempty"`
+	// RateLimit overrides the backend rate limits for this path
+	RateLimit *rlo.Options `yaml:"rate_limit,omitempty"`
 	// ResponseCode sets a custom response code to be sent to downstream clients for this path.
 	ResponseCode int `yaml:"response_code,omitempty"`
 	// ResponseBody sets a custom response body to be sent to the donstream client for this path.
@@ -132,6 +135,9 @@ func (o *Options) Clone() *Options {
 	if o.CORS != nil {
 		out.CORS = o.CORS.Clone()
 	}
+	if o.RateLimit != nil {
+		out.RateLimit = o.RateLimit.Clone()
+	}
 	out.Methods = slices.Clone(o.Methods)
 	out.CacheKeyParams = slices.Clone(o.CacheKeyParams)
 	out.CacheKeyHeaders = slices.Clone(o.CacheKeyHeaders)
@@ -187,6 +193,11 @@ func (o *Options) Initialize(_ string) error {
 			return err
 		}
 	}
+	if o.RateLimit != nil {
+		if err := o.RateLimit.Initialize(""); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -224,6 +235,11 @@ func (o *Options) Validate() (bool, error) {
 			return false, err
 		}
 	}
+	if o.RateLimit != nil {
+		if _, err := o.RateLimit.Validate(); err != nil {
+			return false, err
+		}
+	}
 	return true, nil
 }
 
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/oauth2"
 
 	"github.com/prometheus/common/sigv4"
 )
@@ -113,6 +114,14 @@ func NewHTTPClient(o *bo.Options) (*http.Client, error) {
 		client.Transport = &idleClosingRoundTripper{RoundTripper: wrapped, inner: inner}
 	}
 
+	if o.OAuth2 != nil {
+		inner, _ := client.Transport.(*http.Transport)
+		client.Transport = &idleClosingRoundTripper{
+			RoundTripper: oauth2.NewRoundTripper(o.OAuth2, client.Transport),
+			inner:        inner,
+		}
+	}
+
 	return client, nil
 }
 
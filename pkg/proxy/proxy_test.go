@@ -30,6 +30,7 @@ import (
 
 	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
 	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	oauth2o "github.com/trickstercache/trickster/v2/pkg/proxy/oauth2/options"
 	tlstest "github.com/trickstercache/trickster/v2/pkg/testutil/tls"
 
 	"github.com/prometheus/common/sigv4"
@@ -266,3 +267,44 @@ func TestNewHTTPClient_SigV4WrapsIdleCloser(t *testing.T) {
 	}
 	ic.CloseIdleConnections()
 }
+
+func TestNewHTTPClient_OAuth2(t *testing.T) {
+	var tokenRequests int
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		tokenRequests++
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"access_token":"abc","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer idp.Close()
+	var auth string
+	origin := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer origin.Close()
+
+	o := bo.New()
+	o.OAuth2 = oauth2o.New()
+	o.OAuth2.TokenURL = idp.URL
+	o.OAuth2.ClientID = "trickster"
+	c, err := NewHTTPClient(o)
+	if err != nil {
+		t.Fatalf("NewHTTPClient: %v", err)
+	}
+	type idleCloser interface{ CloseIdleConnections() }
+	if _, ok := c.Transport.(idleCloser); !ok {
+		t.Fatalf("OAuth2 client Transport %T does not satisfy idleCloser", c.Transport)
+	}
+	for range 2 {
+		resp, err := c.Get(origin.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if auth != "Bearer abc" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer abc")
+	}
+	if tokenRequests != 1 {
+		t.Errorf("token requests = %d, want 1", tokenRequests)
+	}
+}
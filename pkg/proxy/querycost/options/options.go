@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for limiting the cost of timeseries
+// queries
+package options
+
+import (
+	"errors"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// ActionReject rejects queries that exceed a limit
+	ActionReject = "reject"
+	// ActionClamp reduces queries and responses that exceed a limit to fit it
+	ActionClamp = "clamp"
+	// DefaultAction is the default action for queries that exceed a limit
+	DefaultAction = ActionReject
+)
+
+var (
+	ErrInvalidMaxPoints        = errors.New("query_cost max_points must not be negative")
+	ErrInvalidMaxSeries        = errors.New("query_cost max_series must not be negative")
+	ErrInvalidMaxResponseBytes = errors.New("query_cost max_response_bytes must not be negative")
+	ErrInvalidAction           = errors.New("query_cost action must be 'reject' or 'clamp'")
+)
+
+// Options defines the limits on the estimated cost of a Backend's timeseries
+// queries. A limit of 0 is disabled
+type Options struct {
+	// MaxPoints is the maximum number of points per series that a query may
+	// request, as determined by its time range and step
+	MaxPoints int64 `yaml:"max_points,omitempty"`
+	// MaxSeries is the maximum number of series in a query response
+	MaxSeries int `yaml:"max_series,omitempty"`
+	// MaxResponseBytes is the maximum size of a query response body
+	MaxResponseBytes int `yaml:"max_response_bytes,omitempty"`
+	// Action is the action taken on queries that exceed MaxPoints or
+	// MaxSeries: 'reject' (the default) or 'clamp'. Queries that exceed
+	// MaxResponseBytes are always rejected
+	Action string `yaml:"action,omitempty"`
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{Action: DefaultAction}
+}
+
+// Clone returns a copy of the Options
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	return pointers.Clone(o)
+}
+
+// Initialize prepares the Options for use
+func (o *Options) Initialize(_ string) error {
+	if o == nil {
+		return nil
+	}
+	if o.Action == "" {
+		o.Action = DefaultAction
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.MaxPoints < 0 {
+		return false, ErrInvalidMaxPoints
+	}
+	if o.MaxSeries < 0 {
+		return false, ErrInvalidMaxSeries
+	}
+	if o.MaxResponseBytes < 0 {
+		return false, ErrInvalidMaxResponseBytes
+	}
+	if o.Action != ActionReject && o.Action != ActionClamp {
+		return false, ErrInvalidAction
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a query_cost configuration
+// block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
+
+// Clamps returns true if queries that exceed MaxPoints or MaxSeries are
+// clamped rather than rejected
+func (o *Options) Clamps() bool {
+	return o != nil && o.Action == ActionClamp
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Options)
+		wantErr error
+	}{
+		{name: "defaults", mutate: func(*Options) {}},
+		{name: "clamp", mutate: func(o *Options) { o.Action = ActionClamp }},
+		{name: "max points", mutate: func(o *Options) { o.MaxPoints = -1 },
+			wantErr: ErrInvalidMaxPoints},
+		{name: "max series", mutate: func(o *Options) { o.MaxSeries = -1 },
+			wantErr: ErrInvalidMaxSeries},
+		{name: "max response bytes", mutate: func(o *Options) { o.MaxResponseBytes = -1 },
+			wantErr: ErrInvalidMaxResponseBytes},
+		{name: "action", mutate: func(o *Options) { o.Action = "truncate" },
+			wantErr: ErrInvalidAction},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := New()
+			test.mutate(o)
+			_, err := o.Validate()
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("expected %v got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	o := &Options{}
+	err := yaml.Unmarshal([]byte(`
+max_points: 11000
+max_series: 500
+`), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.MaxPoints != 11000 || o.MaxSeries != 500 || o.MaxResponseBytes != 0 ||
+		o.Action != ActionReject || o.Clamps() {
+		t.Errorf("unexpected options %+v", o)
+	}
+}
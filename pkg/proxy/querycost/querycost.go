@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package querycost enforces limits on the estimated cost of timeseries
+// queries
+package querycost
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+)
+
+// Query cost limits
+const (
+	LimitPoints = "points"
+	LimitSeries = "series"
+	LimitBytes  = "bytes"
+)
+
+// Points returns the number of points per series requested by trq, or 0 if
+// trq has no step
+func Points(trq *timeseries.TimeRangeQuery) int64 {
+	if trq == nil || trq.Step <= 0 || trq.Extent.End.Before(trq.Extent.Start) {
+		return 0
+	}
+	return int64(trq.Extent.End.Sub(trq.Extent.Start)/trq.Step) + 1
+}
+
+// standardSteps are the steps to which clamped query steps are raised, so that
+// clamped queries for similar time ranges share cache entries. Steps beyond
+// the last are raised to a multiple of it
+var standardSteps = []time.Duration{
+	time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second,
+	15 * time.Second, 30 * time.Second, time.Minute, 2 * time.Minute,
+	5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour,
+}
+
+// ClampStep returns the smallest standard step at which trq requests no more
+// than maxPoints points per series, and true, if trq requests more than
+// maxPoints points per series at its own step
+func ClampStep(trq *timeseries.TimeRangeQuery, maxPoints int64) (time.Duration, bool) {
+	if maxPoints <= 0 || Points(trq) <= maxPoints {
+		return 0, false
+	}
+	// a step greater than range/maxPoints yields at most maxPoints points
+	minStep := trq.Extent.End.Sub(trq.Extent.Start)/time.Duration(maxPoints) + 1
+	for _, step := range standardSteps {
+		if step >= minStep {
+			return step, true
+		}
+	}
+	last := standardSteps[len(standardSteps)-1]
+	return ((minStep + last - 1) / last) * last, true
+}
+
+// Reject counts and logs a query whose value for the limit exceeds max, and
+// writes an error response in the backend's native format
+func Reject(w http.ResponseWriter, r *http.Request, rsc *request.Resources,
+	trq *timeseries.TimeRangeQuery, limit string, value, max int64,
+) {
+	o := rsc.BackendOptions
+	metrics.ProxyQueryCostRejections.WithLabelValues(o.Name, limit).Inc()
+	logger.Warn("query rejected due to query_cost limit",
+		logPairs(r, rsc, trq, limit, value, max))
+	code, msg := rejection(limit, value, max)
+	backends.WriteError(w, rsc.BackendClient, code, msg)
+}
+
+// LogClamp logs a query whose value for the limit exceeded max, and was
+// clamped to it
+func LogClamp(r *http.Request, rsc *request.Resources,
+	trq *timeseries.TimeRangeQuery, limit string, value, max int64,
+) {
+	logger.Info("query clamped due to query_cost limit",
+		logPairs(r, rsc, trq, limit, value, max))
+}
+
+// ClampWarning returns the warning included in responses that were clamped
+// to the limit
+func ClampWarning(limit string, value, max int64) string {
+	return fmt.Sprintf("query would return %d %s, clamped to the allowed limit of %d",
+		value, limitUnit(limit), max)
+}
+
+func rejection(limit string, value, max int64) (int, string) {
+	if limit == LimitPoints {
+		return http.StatusBadRequest, fmt.Sprintf(
+			"query would return %d points per series, exceeding the allowed limit of %d",
+			value, max)
+	}
+	return http.StatusUnprocessableEntity, fmt.Sprintf(
+		"query would return %d %s, exceeding the allowed limit of %d",
+		value, limitUnit(limit), max)
+}
+
+func limitUnit(limit string) string {
+	if limit == LimitPoints {
+		return "points per series"
+	}
+	return limit
+}
+
+func logPairs(r *http.Request, rsc *request.Resources,
+	trq *timeseries.TimeRangeQuery, limit string, value, max int64,
+) logging.Pairs {
+	p := logging.Pairs{
+		"backendName": rsc.BackendOptions.Name,
+		"clientIP":    headers.ClientIP(r, rsc.BackendOptions.ForwardedHeaders),
+		"path":        r.URL.Path,
+		"limit":       limit,
+		"value":       value,
+		"max":         max,
+	}
+	if trq != nil {
+		p["statement"] = trq.Statement
+		p["start"] = trq.Extent.Start.String()
+		p["end"] = trq.Extent.End.String()
+	}
+	return p
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package querycost
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/trickstercache/trickster/v2/pkg/backends"
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+)
+
+func TestPoints(t *testing.T) {
+	end := time.Unix(3600, 0)
+	tests := []struct {
+		trq      *timeseries.TimeRangeQuery
+		expected int64
+	}{
+		{nil, 0},
+		{&timeseries.TimeRangeQuery{Extent: timeseries.Extent{End: end}}, 0},
+		{&timeseries.TimeRangeQuery{Extent: timeseries.Extent{Start: end, End: end},
+			Step: time.Minute}, 1},
+		{&timeseries.TimeRangeQuery{Extent: timeseries.Extent{Start: time.Unix(0, 0),
+			End: end}, Step: time.Minute}, 61},
+	}
+	for i, test := range tests {
+		if v := Points(test.trq); v != test.expected {
+			t.Errorf("(%d) expected %d got %d", i, test.expected, v)
+		}
+	}
+}
+
+func TestReject(t *testing.T) {
+	tb, _ := backends.NewTimeseriesBackend("test", nil, nil, nil, nil, nil)
+	rsc := request.NewResources(&bo.Options{Name: "test"}, nil, nil, nil, tb, nil)
+	c := metrics.ProxyQueryCostRejections.WithLabelValues("test", LimitSeries)
+	base := testutil.ToFloat64(c)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/query_range", nil)
+	Reject(w, r, rsc, nil, LimitSeries, 12, 10)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected %d got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	const expected = "query would return 12 series, exceeding the allowed limit of 10"
+	if !strings.Contains(w.Body.String(), expected) {
+		t.Errorf("expected %s got %s", expected, w.Body.String())
+	}
+	if v := testutil.ToFloat64(c); v != base+1 {
+		t.Errorf("expected %f got %f", base+1, v)
+	}
+
+	w = httptest.NewRecorder()
+	Reject(w, r, rsc, nil, LimitPoints, 12, 10)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected %d got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestClampWarning(t *testing.T) {
+	const expected = "query would return 12 points per series, clamped to the allowed limit of 10"
+	if v := ClampWarning(LimitPoints, 12, 10); v != expected {
+		t.Errorf("expected %s got %s", expected, v)
+	}
+}
+
+func TestClampStep(t *testing.T) {
+	end := time.Unix(30*86400, 0)
+	trq := func(d, step time.Duration) *timeseries.TimeRangeQuery {
+		return &timeseries.TimeRangeQuery{
+			Extent: timeseries.Extent{Start: end.Add(-d), End: end},
+			Step:   step,
+		}
+	}
+	tests := []struct {
+		trq       *timeseries.TimeRangeQuery
+		maxPoints int64
+		expected  time.Duration
+		ok        bool
+	}{
+		{trq(time.Hour, time.Second), 0, 0, false},
+		{trq(time.Hour, time.Second), 3601, 0, false},
+		{trq(time.Hour, time.Second), 3600, 2 * time.Second, true},
+		{trq(time.Hour, time.Second), 120, time.Minute, true},
+		{trq(time.Hour, time.Second), 61, time.Minute, true},
+		{trq(time.Hour, time.Second), 60, 2 * time.Minute, true},
+		{trq(30*24*time.Hour, 15*time.Second), 11000, 5 * time.Minute, true},
+		{trq(30*24*time.Hour, time.Minute), 10, 96 * time.Hour, true},
+	}
+	for i, test := range tests {
+		step, ok := ClampStep(test.trq, test.maxPoints)
+		if step != test.expected || ok != test.ok {
+			t.Errorf("(%d) expected %s %t got %s %t", i, test.expected, test.ok, step, ok)
+		}
+		if ok {
+			test.trq.Step = step
+			if p := Points(test.trq); p > test.maxPoints {
+				t.Errorf("(%d) expected at most %d points got %d", i, test.maxPoints, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for upstream request queueing
+package options
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/queue"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// DefaultMaxQueued is the default maximum number of waiting requests
+	DefaultMaxQueued = 100
+	// DefaultMaxWait is the default maximum time a request waits in the queue
+	DefaultMaxWait = 10 * time.Second
+	// DefaultClassName is the class of requests that match no configured class
+	DefaultClassName = "default"
+)
+
+var (
+	ErrInvalidMaxConcurrent = errors.New("upstream_queue max_concurrent must be greater than 0")
+	ErrInvalidMaxQueued     = errors.New("upstream_queue max_queued must not be negative")
+	ErrInvalidMaxWait       = errors.New("upstream_queue max_wait must not be negative")
+	ErrMissingClassName     = errors.New("upstream_queue class name is required")
+)
+
+// Options defines an upstream request queue that limits the number of
+// concurrent requests to a Backend's origin
+type Options struct {
+	// MaxConcurrent is the maximum number of concurrent upstream requests
+	MaxConcurrent int `yaml:"max_concurrent,omitempty"`
+	// MaxQueued is the maximum number of requests waiting for an upstream
+	// slot. Requests received when the queue is full are shed. The default is 100
+	MaxQueued int `yaml:"max_queued,omitempty"`
+	// MaxWait is the maximum time a request waits in the queue before it is
+	// shed. The default is 10s
+	MaxWait timeconv.Duration `yaml:"max_wait,omitempty"`
+	// Classes prioritize queued requests. Each request is assigned the first
+	// class it matches, or the default class with a priority of 0
+	Classes []*Class `yaml:"classes,omitempty"`
+
+	backendName string
+	queue       *sharedQueue
+}
+
+// Class defines a priority class of upstream requests
+type Class struct {
+	// Name is the name of the class, used in metrics
+	Name string `yaml:"name,omitempty"`
+	// Priority orders queued requests. Requests with higher values are
+	// served first
+	Priority int `yaml:"priority,omitempty"`
+	// Paths are the upstream URL path prefixes the class matches. When
+	// empty, the class matches any path
+	Paths []string `yaml:"paths,omitempty"`
+	// Headers are request header values the class matches. All of the
+	// headers must match
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// sharedQueue holds the Queue for an Options and is shared by its clones
+type sharedQueue struct {
+	once sync.Once
+	q    *queue.Queue
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{
+		MaxQueued: DefaultMaxQueued,
+		MaxWait:   timeconv.Duration(DefaultMaxWait),
+		queue:     &sharedQueue{},
+	}
+}
+
+// Clone returns a copy of the Options that shares its queue
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	out := pointers.Clone(o)
+	if o.Classes != nil {
+		out.Classes = make([]*Class, len(o.Classes))
+		for i, c := range o.Classes {
+			out.Classes[i] = c.Clone()
+		}
+	}
+	return out
+}
+
+// Clone returns an exact copy of the Class
+func (c *Class) Clone() *Class {
+	out := pointers.Clone(c)
+	out.Paths = slices.Clone(c.Paths)
+	out.Headers = maps.Clone(c.Headers)
+	return out
+}
+
+// Initialize associates the Options with the named Backend for metrics
+func (o *Options) Initialize(backendName string) error {
+	if o == nil {
+		return nil
+	}
+	o.backendName = backendName
+	if o.queue == nil {
+		o.queue = &sharedQueue{}
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.MaxConcurrent <= 0 {
+		return false, ErrInvalidMaxConcurrent
+	}
+	if o.MaxQueued < 0 {
+		return false, ErrInvalidMaxQueued
+	}
+	if o.MaxWait < 0 {
+		return false, ErrInvalidMaxWait
+	}
+	for i, c := range o.Classes {
+		if c == nil || c.Name == "" {
+			return false, fmt.Errorf("%w (class %d)", ErrMissingClassName, i)
+		}
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding an upstream_queue configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
+
+// Classify returns the name and priority of the class matching the request
+func (o *Options) Classify(r *http.Request) (string, int) {
+	for _, c := range o.Classes {
+		if c.Matches(r) {
+			return c.Name, c.Priority
+		}
+	}
+	return DefaultClassName, 0
+}
+
+// Matches returns true if the request matches the Class
+func (c *Class) Matches(r *http.Request) bool {
+	if len(c.Paths) > 0 && !slices.ContainsFunc(c.Paths, func(p string) bool {
+		return strings.HasPrefix(r.URL.Path, p)
+	}) {
+		return false
+	}
+	for k, v := range c.Headers {
+		if r.Header.Get(k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Queue returns the upstream Queue for the Options, or nil if the Options
+// have not been initialized
+func (o *Options) Queue() *queue.Queue {
+	if o == nil || o.queue == nil {
+		return nil
+	}
+	sq := o.queue
+	sq.once.Do(func() {
+		depth := metrics.ProxyUpstreamQueueDepth.WithLabelValues(o.backendName)
+		sq.q = queue.New(o.MaxConcurrent, o.MaxQueued,
+			func(n int) { depth.Set(float64(n)) })
+	})
+	return sq.q
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Options)
+		wantErr error
+	}{
+		{name: "valid", mutate: func(o *Options) {
+			o.Classes = []*Class{{Name: "alerting", Priority: 10}}
+		}},
+		{name: "missing max concurrent", mutate: func(o *Options) { o.MaxConcurrent = 0 },
+			wantErr: ErrInvalidMaxConcurrent},
+		{name: "negative max queued", mutate: func(o *Options) { o.MaxQueued = -1 },
+			wantErr: ErrInvalidMaxQueued},
+		{name: "negative max wait", mutate: func(o *Options) { o.MaxWait = -1 },
+			wantErr: ErrInvalidMaxWait},
+		{name: "unnamed class", mutate: func(o *Options) { o.Classes = []*Class{{Priority: 1}} },
+			wantErr: ErrMissingClassName},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := New()
+			o.MaxConcurrent = 4
+			tc.mutate(o)
+			ok, err := o.Validate()
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if ok != (tc.wantErr == nil) {
+				t.Errorf("unexpected validation result %t", ok)
+			}
+		})
+	}
+}
+
+func TestOptionsYAML(t *testing.T) {
+	const conf = `
+max_concurrent: 8
+classes:
+  - name: alerting
+    priority: 10
+    headers:
+      X-Query-Source: alerting
+  - name: dashboards
+    priority: 5
+    paths: [ /api/v1/query_range ]
+`
+	o := &Options{}
+	if err := yaml.Unmarshal([]byte(conf), o); err != nil {
+		t.Fatal(err)
+	}
+	if o.MaxQueued != DefaultMaxQueued {
+		t.Errorf("expected default max_queued %d, got %d", DefaultMaxQueued, o.MaxQueued)
+	}
+	if time.Duration(o.MaxWait) != DefaultMaxWait {
+		t.Errorf("expected default max_wait %s, got %s", DefaultMaxWait, time.Duration(o.MaxWait))
+	}
+
+	tests := []struct {
+		path, source     string
+		expectedClass    string
+		expectedPriority int
+	}{
+		{"/api/v1/query_range", "alerting", "alerting", 10},
+		{"/api/v1/query_range", "", "dashboards", 5},
+		{"/api/v1/query", "", DefaultClassName, 0},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "http://example.com"+test.path, nil)
+		if test.source != "" {
+			r.Header.Set("X-Query-Source", test.source)
+		}
+		class, priority := o.Classify(r)
+		if class != test.expectedClass || priority != test.expectedPriority {
+			t.Errorf("expected %s/%d, got %s/%d", test.expectedClass,
+				test.expectedPriority, class, priority)
+		}
+	}
+}
+
+func TestQueueSharedByClones(t *testing.T) {
+	o := New()
+	o.MaxConcurrent = 1
+	if err := o.Initialize("test"); err != nil {
+		t.Fatal(err)
+	}
+	c := o.Clone()
+	if o.Queue() == nil || o.Queue() != c.Queue() {
+		t.Error("expected clones to share the queue")
+	}
+	if (&Options{}).Queue() != nil {
+		t.Error("expected nil queue for uninitialized options")
+	}
+}
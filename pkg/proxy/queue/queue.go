@@ -0,0 +1,182 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package queue provides a priority queue that limits the number of
+// concurrent upstream requests
+package queue
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned when a request is shed because the queue is full
+	ErrQueueFull = errors.New("upstream queue is full")
+	// ErrQueueTimeout is returned when a request is shed because it waited
+	// longer than the maximum wait time
+	ErrQueueTimeout = errors.New("upstream queue wait time exceeded")
+)
+
+// Queue limits the number of concurrently active requests. Excess requests
+// wait in priority order, and in arrival order within the same priority
+type Queue struct {
+	limit     int
+	maxQueued int
+	onDepth   func(int)
+
+	mtx     sync.Mutex
+	active  int
+	seq     uint64
+	waiting waiters
+}
+
+// New returns a new Queue permitting limit concurrent requests and up to
+// maxQueued waiting requests. onDepth, when not nil, is called with the
+// number of waiting requests whenever it changes
+func New(limit, maxQueued int, onDepth func(int)) *Queue {
+	return &Queue{limit: limit, maxQueued: maxQueued, onDepth: onDepth}
+}
+
+// Acquire waits for an active slot with the provided priority, where higher
+// values are served first. It returns a function that must be called to
+// release the slot, or an error if the request was shed or ctx was canceled
+func (q *Queue) Acquire(ctx context.Context, priority int,
+	maxWait time.Duration,
+) (func(), error) {
+	q.mtx.Lock()
+	if q.active < q.limit && len(q.waiting) == 0 {
+		q.active++
+		q.mtx.Unlock()
+		return q.releaseFunc(), nil
+	}
+	if len(q.waiting) >= q.maxQueued {
+		q.mtx.Unlock()
+		return nil, ErrQueueFull
+	}
+	w := &waiter{priority: priority, seq: q.seq, ready: make(chan struct{})}
+	q.seq++
+	heap.Push(&q.waiting, w)
+	q.depthChanged()
+	q.mtx.Unlock()
+
+	var timeout <-chan time.Time
+	if maxWait > 0 {
+		t := time.NewTimer(maxWait)
+		defer t.Stop()
+		timeout = t.C
+	}
+	var err error
+	select {
+	case <-w.ready:
+		return q.releaseFunc(), nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if w.index < 0 {
+		// the slot was granted while giving up, so keep it
+		return q.releaseFunc(), nil
+	}
+	heap.Remove(&q.waiting, w.index)
+	q.depthChanged()
+	return nil, err
+}
+
+// Depth returns the number of waiting requests
+func (q *Queue) Depth() int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return len(q.waiting)
+}
+
+// Active returns the number of active requests
+func (q *Queue) Active() int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return q.active
+}
+
+func (q *Queue) releaseFunc() func() {
+	var once sync.Once
+	return func() { once.Do(q.release) }
+}
+
+// release hands the slot to the next waiting request, if any
+func (q *Queue) release() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if len(q.waiting) == 0 {
+		q.active--
+		return
+	}
+	w := heap.Pop(&q.waiting).(*waiter)
+	close(w.ready)
+	q.depthChanged()
+}
+
+func (q *Queue) depthChanged() {
+	if q.onDepth != nil {
+		q.onDepth(len(q.waiting))
+	}
+}
+
+type waiter struct {
+	priority int
+	seq      uint64
+	index    int
+	ready    chan struct{}
+}
+
+// waiters implements heap.Interface
+type waiters []*waiter
+
+func (w waiters) Len() int { return len(w) }
+
+func (w waiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority > w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+
+func (w waiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *waiters) Push(x any) {
+	wt := x.(*waiter)
+	wt.index = len(*w)
+	*w = append(*w, wt)
+}
+
+func (w *waiters) Pop() any {
+	old := *w
+	n := len(old)
+	wt := old[n-1]
+	old[n-1] = nil
+	wt.index = -1
+	*w = old[:n-1]
+	return wt
+}
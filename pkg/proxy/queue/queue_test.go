@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquireRelease(t *testing.T) {
+	var depth int
+	q := New(1, 1, func(n int) { depth = n })
+	release, err := q.Acquire(context.Background(), 0, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Active() != 1 {
+		t.Errorf("expected 1 active, got %d", q.Active())
+	}
+	release()
+	release() // releasing twice is a no-op
+	if q.Active() != 0 {
+		t.Errorf("expected 0 active, got %d", q.Active())
+	}
+	if depth != 0 {
+		t.Errorf("expected depth 0, got %d", depth)
+	}
+}
+
+func TestAcquirePriority(t *testing.T) {
+	q := New(1, 10, nil)
+	release, err := q.Acquire(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mtx sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i, p := range []int{0, 5, 1, 5} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rel, err := q.Acquire(context.Background(), p, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mtx.Lock()
+			order = append(order, i)
+			mtx.Unlock()
+			rel()
+		}()
+		// wait for the request to be queued so that arrival order is known
+		for q.Depth() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	release()
+	wg.Wait()
+	expected := []int{1, 3, 2, 0}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("expected order %v, got %v", expected, order)
+		}
+	}
+	if q.Active() != 0 {
+		t.Errorf("expected 0 active, got %d", q.Active())
+	}
+}
+
+func TestAcquireShed(t *testing.T) {
+	q := New(1, 1, nil)
+	release, err := q.Acquire(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := q.Acquire(context.Background(), 0, 50*time.Millisecond)
+		errs <- err
+	}()
+	for q.Depth() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := q.Acquire(context.Background(), 0, 0); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected %v, got %v", ErrQueueFull, err)
+	}
+	if err := <-errs; !errors.Is(err, ErrQueueTimeout) {
+		t.Errorf("expected %v, got %v", ErrQueueTimeout, err)
+	}
+	if q.Depth() != 0 {
+		t.Errorf("expected depth 0, got %d", q.Depth())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := q.Acquire(ctx, 0, time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
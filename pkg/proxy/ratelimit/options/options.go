@@ -0,0 +1,228 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for request rate limiting
+package options
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/ratelimit"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// KeyBy identifies how requests are grouped into rate limit buckets
+type KeyBy string
+
+const (
+	// KeyByIP keys rate limit buckets by the client IP address
+	KeyByIP KeyBy = "ip"
+	// KeyByUser keys rate limit buckets by the authenticated username
+	KeyByUser KeyBy = "user"
+	// KeyByHeader keys rate limit buckets by the value of a request header
+	KeyByHeader KeyBy = "header"
+)
+
+var (
+	ErrInvalidKeyBy     = errors.New("rate_limit key_by must be one of 'ip', 'user' or 'header'")
+	ErrMissingKeyHeader = errors.New("rate_limit key_header is required when key_by is 'header'")
+	ErrInvalidRate      = errors.New("rate_limit rate must be greater than 0")
+	ErrInvalidBurst     = errors.New("rate_limit burst must not be negative")
+)
+
+// Options defines the rate limits applied to requests for a backend or path
+type Options struct {
+	// KeyBy is how requests are grouped into buckets: 'ip' (default), 'user'
+	// or 'header'. Requests without a username or key header are keyed by
+	// client IP
+	KeyBy KeyBy `yaml:"key_by,omitempty"`
+	// KeyHeader is the request header whose value is the bucket key when
+	// KeyBy is 'header'
+	KeyHeader string `yaml:"key_header,omitempty"`
+	// Requests is the budget for all requests, including those served from
+	// cache
+	Requests *Budget `yaml:"requests,omitempty"`
+	// Upstream is the budget for requests that are fetched from the origin
+	Upstream *Budget `yaml:"upstream,omitempty"`
+
+	// limiters is allocated by New and Initialize; Options without it are
+	// not limited
+	limiters *limiters
+}
+
+// Budget defines a token bucket refill rate and size
+type Budget struct {
+	// Rate is the number of requests per second that are permitted
+	Rate float64 `yaml:"rate,omitempty"`
+	// Burst is the bucket size, which is the number of requests permitted
+	// at once. The default is Rate rounded up
+	Burst int `yaml:"burst,omitempty"`
+}
+
+// limiters holds the token buckets for an Options and is shared by its clones
+type limiters struct {
+	once     sync.Once
+	requests *ratelimit.Limiter
+	upstream *ratelimit.Limiter
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{KeyBy: KeyByIP, limiters: &limiters{}}
+}
+
+// Clone returns a copy of the Options that shares its token buckets
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	out := pointers.Clone(o)
+	out.Requests = pointers.Clone(o.Requests)
+	out.Upstream = pointers.Clone(o.Upstream)
+	return out
+}
+
+// Initialize sets default values for the Options
+func (o *Options) Initialize(_ string) error {
+	if o == nil {
+		return nil
+	}
+	if o.KeyBy == "" {
+		o.KeyBy = KeyByIP
+	}
+	o.KeyBy = KeyBy(strings.ToLower(string(o.KeyBy)))
+	for _, b := range []*Budget{o.Requests, o.Upstream} {
+		if b != nil && b.Burst == 0 {
+			b.Burst = max(int(math.Ceil(b.Rate)), 1)
+		}
+	}
+	if o.limiters == nil {
+		o.limiters = &limiters{}
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	switch KeyBy(strings.ToLower(string(o.KeyBy))) {
+	case "", KeyByIP, KeyByUser:
+	case KeyByHeader:
+		if o.KeyHeader == "" {
+			return false, ErrMissingKeyHeader
+		}
+	default:
+		return false, ErrInvalidKeyBy
+	}
+	for _, b := range []*Budget{o.Requests, o.Upstream} {
+		if b == nil {
+			continue
+		}
+		if b.Rate <= 0 {
+			return false, ErrInvalidRate
+		}
+		if b.Burst < 0 {
+			return false, ErrInvalidBurst
+		}
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a rate_limit configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
+
+// Select returns the path-level Options when set, and otherwise the
+// backend-level Options
+func Select(pathOpts, backendOpts *Options) *Options {
+	if pathOpts != nil {
+		return pathOpts
+	}
+	return backendOpts
+}
+
+// Key returns the rate limit bucket key for the request
+func (o *Options) Key(r *http.Request, username, forwardedHeaders string) string {
+	switch o.KeyBy {
+	case KeyByUser:
+		if username != "" {
+			return "user:" + username
+		}
+	case KeyByHeader:
+		if v := r.Header.Get(o.KeyHeader); v != "" {
+			return "header:" + v
+		}
+	}
+	return "ip:" + headers.ClientIP(r, forwardedHeaders)
+}
+
+// AllowRequest takes a token from the key's requests budget. When the budget
+// is exhausted, it returns false and the time until a token is available
+func (o *Options) AllowRequest(key string) (bool, time.Duration) {
+	if o == nil || o.Requests == nil || o.limiters == nil {
+		return true, 0
+	}
+	return o.getLimiters().requests.Allow(key)
+}
+
+// AllowUpstream takes a token from the key's upstream budget. When the budget
+// is exhausted, it returns false and the time until a token is available
+func (o *Options) AllowUpstream(key string) (bool, time.Duration) {
+	if o == nil || o.Upstream == nil || o.limiters == nil {
+		return true, 0
+	}
+	return o.getLimiters().upstream.Allow(key)
+}
+
+func (o *Options) getLimiters() *limiters {
+	l := o.limiters
+	l.once.Do(func() {
+		if o.Requests != nil {
+			l.requests = ratelimit.NewLimiter(o.Requests.Rate, burst(o.Requests))
+		}
+		if o.Upstream != nil {
+			l.upstream = ratelimit.NewLimiter(o.Upstream.Rate, burst(o.Upstream))
+		}
+	})
+	return l
+}
+
+func burst(b *Budget) int {
+	if b.Burst > 0 {
+		return b.Burst
+	}
+	return max(int(math.Ceil(b.Rate)), 1)
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Options)
+		wantErr error
+	}{
+		{name: "empty", mutate: func(*Options) {}},
+		{name: "valid", mutate: func(o *Options) {
+			o.KeyBy = KeyByUser
+			o.Requests = &Budget{Rate: 10, Burst: 20}
+			o.Upstream = &Budget{Rate: 1}
+		}},
+		{name: "header", mutate: func(o *Options) {
+			o.KeyBy = KeyByHeader
+			o.KeyHeader = "X-API-Key"
+		}},
+		{name: "missing key header", mutate: func(o *Options) { o.KeyBy = KeyByHeader },
+			wantErr: ErrMissingKeyHeader},
+		{name: "invalid key by", mutate: func(o *Options) { o.KeyBy = "cookie" },
+			wantErr: ErrInvalidKeyBy},
+		{name: "zero rate", mutate: func(o *Options) { o.Requests = &Budget{} },
+			wantErr: ErrInvalidRate},
+		{name: "negative burst", mutate: func(o *Options) {
+			o.Upstream = &Budget{Rate: 1, Burst: -1}
+		}, wantErr: ErrInvalidBurst},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := New()
+			tc.mutate(o)
+			ok, err := o.Validate()
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if ok != (tc.wantErr == nil) {
+				t.Errorf("unexpected validation result %t", ok)
+			}
+		})
+	}
+}
+
+func TestOptionsYAML(t *testing.T) {
+	const conf = `
+key_by: USER
+requests:
+  rate: 2.5
+upstream:
+  rate: 1
+  burst: 5
+`
+	o := &Options{}
+	if err := yaml.Unmarshal([]byte(conf), o); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Initialize(""); err != nil {
+		t.Fatal(err)
+	}
+	if o.KeyBy != KeyByUser {
+		t.Errorf("expected key_by %s, got %s", KeyByUser, o.KeyBy)
+	}
+	if o.Requests.Burst != 3 {
+		t.Errorf("expected default burst of 3, got %d", o.Requests.Burst)
+	}
+	if o.Upstream.Burst != 5 {
+		t.Errorf("expected burst of 5, got %d", o.Upstream.Burst)
+	}
+
+	o = &Options{}
+	if err := yaml.Unmarshal([]byte("requests:\n  rate: 1\n"), o); err != nil {
+		t.Fatal(err)
+	}
+	if o.KeyBy != KeyByIP {
+		t.Errorf("expected default key_by %s, got %s", KeyByIP, o.KeyBy)
+	}
+}
+
+func TestAllowSharedByClones(t *testing.T) {
+	o := New()
+	o.Requests = &Budget{Rate: 1, Burst: 1}
+	if err := o.Initialize(""); err != nil {
+		t.Fatal(err)
+	}
+	c := o.Clone()
+	if ok, _ := o.AllowRequest("k"); !ok {
+		t.Fatal("expected first request to be allowed")
+	}
+	if ok, wait := c.AllowRequest("k"); ok || wait <= 0 {
+		t.Errorf("expected clone to share the exhausted bucket, got %t %s", ok, wait)
+	}
+	// no upstream budget is configured
+	if ok, _ := c.AllowUpstream("k"); !ok {
+		t.Error("expected upstream request to be allowed")
+	}
+	var nilOpts *Options
+	if ok, _ := nilOpts.AllowRequest("k"); !ok {
+		t.Error("expected nil options to allow")
+	}
+}
+
+func TestSelect(t *testing.T) {
+	p, b := New(), New()
+	if Select(p, b) != p {
+		t.Error("expected path options")
+	}
+	if Select(nil, b) != b {
+		t.Error("expected backend options")
+	}
+}
+
+func TestKey(t *testing.T) {
+	r := httptest.NewRequest("GET", "http://example.com/", nil)
+	r.RemoteAddr = "192.0.2.1:4711"
+	r.Header.Set("X-API-Key", "abc")
+
+	tests := []struct {
+		keyBy    KeyBy
+		username string
+		expected string
+	}{
+		{KeyByIP, "alice", "ip:192.0.2.1"},
+		{KeyByUser, "alice", "user:alice"},
+		{KeyByUser, "", "ip:192.0.2.1"},
+		{KeyByHeader, "", "header:abc"},
+	}
+	for _, test := range tests {
+		o := &Options{KeyBy: test.keyBy, KeyHeader: "X-API-Key"}
+		if k := o.Key(r, test.username, "none"); k != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, k)
+		}
+	}
+	o := &Options{KeyBy: KeyByHeader, KeyHeader: "X-Tenant"}
+	if k := o.Key(r, "", "none"); k != "ip:192.0.2.1" {
+		t.Errorf("expected client ip fallback, got %s", k)
+	}
+}
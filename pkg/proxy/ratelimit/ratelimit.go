@@ -0,0 +1,131 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ratelimit provides keyed token bucket rate limiters
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often idle buckets are removed from a Limiter
+const pruneInterval = time.Minute
+
+// maxBuckets is the most keys a Limiter tracks. Once it is reached, new keys
+// share a single overflow bucket until idle buckets are pruned, so a client
+// that varies its key can't grow the Limiter without bound
+var maxBuckets = 100000
+
+var now = time.Now
+
+// Limiter is a set of token buckets, one per key, that share a refill rate
+// and burst size
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mtx       sync.Mutex
+	buckets   map[string]*bucket
+	overflow  *bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a new Limiter that refills each key's bucket at rate
+// tokens per second, up to burst tokens
+func NewLimiter(rate float64, burst int) *Limiter {
+	return &Limiter{
+		rate:      rate,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastPrune: now(),
+	}
+}
+
+// Allow takes a token from the key's bucket. When the bucket is empty, Allow
+// returns false and the duration until the next token is available
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	t := now()
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if t.Sub(l.lastPrune) >= pruneInterval {
+		l.prune(t)
+	}
+	b, ok := l.buckets[key]
+	if !ok && len(l.buckets) >= maxBuckets {
+		if l.overflow == nil {
+			l.overflow = &bucket{tokens: l.burst, last: t}
+		}
+		b, ok = l.overflow, true
+	}
+	if !ok {
+		b = &bucket{tokens: l.burst, last: t}
+		l.buckets[key] = b
+	} else {
+		b.tokens = l.refill(b, t)
+		b.last = t
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	if l.rate <= 0 {
+		return false, pruneInterval
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// Len returns the number of keys currently tracked by the Limiter
+func (l *Limiter) Len() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return len(l.buckets)
+}
+
+func (l *Limiter) refill(b *bucket, t time.Time) float64 {
+	elapsed := t.Sub(b.last).Seconds()
+	if elapsed <= 0 {
+		return b.tokens
+	}
+	return math.Min(l.burst, b.tokens+elapsed*l.rate)
+}
+
+// prune removes buckets that have refilled completely, since they are
+// indistinguishable from a new bucket
+func (l *Limiter) prune(t time.Time) {
+	for k, b := range l.buckets {
+		if l.refill(b, t) >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+	l.lastPrune = t
+}
+
+// RetryAfterSeconds returns the value of a Retry-After header for the wait
+// duration, rounded up to the nearest second
+func RetryAfterSeconds(wait time.Duration) int {
+	return max(int(math.Ceil(wait.Seconds())), 1)
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func setNow(t *testing.T, ts *time.Time) {
+	t.Helper()
+	orig := now
+	now = func() time.Time { return *ts }
+	t.Cleanup(func() { now = orig })
+}
+
+func TestLimiterAllow(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	setNow(t, &ts)
+
+	l := NewLimiter(2, 3)
+	for i := range 3 {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("expected request to be denied")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("expected wait of 500ms, got %s", wait)
+	}
+	// other keys have their own bucket
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("expected request for another key to be allowed")
+	}
+
+	ts = ts.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected request to be allowed after refill")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("expected request to be denied")
+	}
+}
+
+func TestLimiterPrune(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	setNow(t, &ts)
+
+	l := NewLimiter(1, 1)
+	l.Allow("a")
+	l.Allow("b")
+	if n := l.Len(); n != 2 {
+		t.Fatalf("expected 2 buckets, got %d", n)
+	}
+	ts = ts.Add(pruneInterval)
+	l.Allow("c")
+	if n := l.Len(); n != 1 {
+		t.Errorf("expected idle buckets to be pruned, got %d", n)
+	}
+}
+
+func TestLimiterMaxBuckets(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	setNow(t, &ts)
+	orig := maxBuckets
+	maxBuckets = 2
+	t.Cleanup(func() { maxBuckets = orig })
+
+	l := NewLimiter(1, 1)
+	l.Allow("a")
+	l.Allow("b")
+	// new keys past the cap share the overflow bucket
+	if ok, _ := l.Allow("c"); !ok {
+		t.Error("expected first overflow request to be allowed")
+	}
+	if ok, _ := l.Allow("d"); ok {
+		t.Error("expected overflow bucket to be exhausted")
+	}
+	if n := l.Len(); n != 2 {
+		t.Errorf("expected 2 buckets, got %d", n)
+	}
+	// once idle buckets are pruned, new keys get their own bucket again
+	ts = ts.Add(pruneInterval)
+	l.Allow("a")
+	if ok, _ := l.Allow("c"); !ok {
+		t.Error("expected request to be allowed after prune")
+	}
+	if n := l.Len(); n != 2 {
+		t.Errorf("expected 2 buckets, got %d", n)
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("expected nil limiter to allow")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	tests := []struct {
+		wait     time.Duration
+		expected int
+	}{
+		{0, 1},
+		{100 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+	}
+	for _, test := range tests {
+		if v := RetryAfterSeconds(test.wait); v != test.expected {
+			t.Errorf("expected %d for %s, got %d", test.expected, test.wait, v)
+		}
+	}
+}
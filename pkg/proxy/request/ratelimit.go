@@ -0,0 +1,47 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package request
+
+import (
+	"net/http"
+
+	auth "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	rlo "github.com/trickstercache/trickster/v2/pkg/proxy/ratelimit/options"
+)
+
+// RateLimit returns the rate limit options that apply to the request, and the
+// bucket key for the request. The options are nil when no limits apply
+func RateLimit(r *http.Request, rsc *Resources) (*rlo.Options, string) {
+	if rsc == nil || rsc.BackendOptions == nil {
+		return nil, ""
+	}
+	var pathOpts *rlo.Options
+	if rsc.PathConfig != nil {
+		pathOpts = rsc.PathConfig.RateLimit
+	}
+	o := rlo.Select(pathOpts, rsc.BackendOptions.RateLimit)
+	if o == nil {
+		return nil, ""
+	}
+	// only verified usernames key a bucket, so clients can't spread their
+	// requests across buckets by claiming arbitrary unverified usernames
+	var username string
+	if rsc.AuthResult != nil && rsc.AuthResult.Status == auth.AuthSuccess {
+		username = rsc.AuthResult.Username
+	}
+	return o, o.Key(r, username, rsc.BackendOptions.ForwardedHeaders)
+}
@@ -68,6 +68,9 @@ type Resources struct {
 	AuthResult     *auth.AuthResult
 	AlreadyEncoded bool
 	Cancelable     bool
+	// Tenant is the tenant identity used to partition the request's cache
+	// entries, when the backend has tenancy configured
+	Tenant string
 }
 
 // Clone returns an exact copy of the subject Resources collection
@@ -97,6 +100,7 @@ func (r *Resources) Clone() *Resources {
 		AuthResult:            r.AuthResult, // shallow copy of the auth result
 		AlreadyEncoded:        r.AlreadyEncoded,
 		Cancelable:            r.Cancelable,
+		Tenant:                r.Tenant,
 	}
 }
 
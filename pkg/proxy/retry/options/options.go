@@ -0,0 +1,277 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for retried and hedged upstream
+// requests
+package options
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/retry"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// DefaultMaxAttempts is the default number of attempts, including the
+	// first, made for an upstream request
+	DefaultMaxAttempts = 3
+	// DefaultBackoffBase is the default delay before the first retry
+	DefaultBackoffBase = 50 * time.Millisecond
+	// DefaultBackoffMax is the default maximum delay between retries
+	DefaultBackoffMax = time.Second
+	// DefaultHedgePercentile is the default upstream latency percentile
+	// after which a hedged request is sent
+	DefaultHedgePercentile = 0.95
+	// DefaultMaxHedges is the default number of hedged requests sent per
+	// upstream attempt
+	DefaultMaxHedges = 1
+)
+
+// DefaultStatusCodes is the default list of upstream response status codes
+// that are retried
+var DefaultStatusCodes = []int{
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+var (
+	ErrInvalidMaxAttempts = errors.New("retry max_attempts must be greater than 0")
+	ErrInvalidStatusCode  = errors.New("retry status_codes must be between 100 and 599")
+	ErrInvalidBackoff     = errors.New("retry backoff_base and backoff_max must be greater than 0")
+	ErrInvalidPercentile  = errors.New("retry hedge percentile must be greater than 0 and less than 1")
+	ErrInvalidMaxHedges   = errors.New("retry hedge max_hedges must be greater than 0")
+	ErrInvalidMinDelay    = errors.New("retry hedge min_delay must not be negative")
+)
+
+// Options defines retries and hedging for a Backend's idempotent upstream
+// requests
+type Options struct {
+	// MaxAttempts is the number of attempts, including the first, made for
+	// an upstream request. The default is 3
+	MaxAttempts int `yaml:"max_attempts,omitempty"`
+	// StatusCodes is the list of upstream response status codes that are
+	// retried. Connection errors are always retried. The default is
+	// 502, 503 and 504
+	StatusCodes []int `yaml:"status_codes,omitempty"`
+	// BackoffBase is the delay before the first retry, which doubles with
+	// each subsequent retry. The default is 50ms
+	BackoffBase timeconv.Duration `yaml:"backoff_base,omitempty"`
+	// BackoffMax is the maximum delay between retries. The default is 1s
+	BackoffMax timeconv.Duration `yaml:"backoff_max,omitempty"`
+	// ReadOnlyPOSTPaths is a list of path prefixes whose POST requests are
+	// read-only queries that are safe to retry. POST requests parsed as
+	// time series queries are always considered read-only
+	ReadOnlyPOSTPaths []string `yaml:"read_only_post_paths,omitempty"`
+	// Hedge configures hedged requests. Hedging is disabled when unset
+	Hedge *HedgeOptions `yaml:"hedge,omitempty"`
+
+	statusCodes map[int]struct{}
+	latency     *sharedTracker
+}
+
+// HedgeOptions defines hedged upstream requests, which are duplicates sent
+// when the original has not responded within a latency percentile of
+// recent upstream requests. The first response is used
+type HedgeOptions struct {
+	// Percentile is the upstream latency percentile after which a hedged
+	// request is sent. The default is 0.95
+	Percentile float64 `yaml:"percentile,omitempty"`
+	// MinDelay is the minimum delay before a hedged request is sent
+	MinDelay timeconv.Duration `yaml:"min_delay,omitempty"`
+	// MaxHedges is the number of hedged requests that may be sent for each
+	// upstream attempt. The default is 1
+	MaxHedges int `yaml:"max_hedges,omitempty"`
+}
+
+// sharedTracker holds the LatencyTracker for an Options and is shared by
+// its clones
+type sharedTracker struct {
+	once sync.Once
+	t    *retry.LatencyTracker
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{
+		MaxAttempts: DefaultMaxAttempts,
+		StatusCodes: slices.Clone(DefaultStatusCodes),
+		BackoffBase: timeconv.Duration(DefaultBackoffBase),
+		BackoffMax:  timeconv.Duration(DefaultBackoffMax),
+		latency:     &sharedTracker{},
+	}
+}
+
+// NewHedgeOptions returns a new HedgeOptions with the default values
+func NewHedgeOptions() *HedgeOptions {
+	return &HedgeOptions{
+		Percentile: DefaultHedgePercentile,
+		MaxHedges:  DefaultMaxHedges,
+	}
+}
+
+// Clone returns a copy of the Options that shares its latency tracker
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	out := pointers.Clone(o)
+	out.StatusCodes = slices.Clone(o.StatusCodes)
+	out.ReadOnlyPOSTPaths = slices.Clone(o.ReadOnlyPOSTPaths)
+	out.Hedge = pointers.Clone(o.Hedge)
+	return out
+}
+
+// Initialize prepares the Options for use
+func (o *Options) Initialize(_ string) error {
+	if o == nil {
+		return nil
+	}
+	o.statusCodes = make(map[int]struct{}, len(o.StatusCodes))
+	for _, c := range o.StatusCodes {
+		o.statusCodes[c] = struct{}{}
+	}
+	if o.latency == nil {
+		o.latency = &sharedTracker{}
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.MaxAttempts <= 0 {
+		return false, ErrInvalidMaxAttempts
+	}
+	for _, c := range o.StatusCodes {
+		if c < 100 || c > 599 {
+			return false, ErrInvalidStatusCode
+		}
+	}
+	if o.BackoffBase <= 0 || o.BackoffMax <= 0 {
+		return false, ErrInvalidBackoff
+	}
+	if h := o.Hedge; h != nil {
+		if h.Percentile <= 0 || h.Percentile >= 1 {
+			return false, ErrInvalidPercentile
+		}
+		if h.MaxHedges <= 0 {
+			return false, ErrInvalidMaxHedges
+		}
+		if h.MinDelay < 0 {
+			return false, ErrInvalidMinDelay
+		}
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a retry configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
+
+// UnmarshalYAML applies defaults before decoding a hedge configuration block.
+func (o *HedgeOptions) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions HedgeOptions
+	lo := loadOptions(*(NewHedgeOptions()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = HedgeOptions(lo)
+	return nil
+}
+
+// IsReadOnly returns true if the request is safe to retry or hedge. GET,
+// HEAD and OPTIONS requests are read-only, as are POST requests that are
+// time series queries or that match a ReadOnlyPOSTPaths prefix
+func (o *Options) IsReadOnly(r *http.Request, isQuery bool) bool {
+	if o == nil {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		if isQuery {
+			return true
+		}
+		for _, p := range o.ReadOnlyPOSTPaths {
+			if strings.HasPrefix(r.URL.Path, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RetryStatus returns true if an upstream response with the status code
+// should be retried
+func (o *Options) RetryStatus(code int) bool {
+	if o == nil {
+		return false
+	}
+	if o.statusCodes == nil {
+		return slices.Contains(o.StatusCodes, code)
+	}
+	_, ok := o.statusCodes[code]
+	return ok
+}
+
+// Latency returns the tracker of recent upstream latencies for the Options
+func (o *Options) Latency() *retry.LatencyTracker {
+	if o == nil || o.latency == nil {
+		return nil
+	}
+	st := o.latency
+	st.once.Do(func() {
+		st.t = retry.NewLatencyTracker(retry.DefaultSampleSize)
+	})
+	return st.t
+}
+
+// HedgeDelay returns the delay after which a hedged request should be sent,
+// or false if hedging is disabled or too few upstream latencies have been
+// observed
+func (o *Options) HedgeDelay() (time.Duration, bool) {
+	if o == nil || o.Hedge == nil {
+		return 0, false
+	}
+	d, ok := o.Latency().Percentile(o.Hedge.Percentile)
+	if !ok {
+		return 0, false
+	}
+	return max(d, time.Duration(o.Hedge.MinDelay)), true
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Options)
+		wantErr error
+	}{
+		{name: "defaults", mutate: func(*Options) {}},
+		{name: "max attempts", mutate: func(o *Options) { o.MaxAttempts = 0 },
+			wantErr: ErrInvalidMaxAttempts},
+		{name: "status code", mutate: func(o *Options) { o.StatusCodes = []int{1000} },
+			wantErr: ErrInvalidStatusCode},
+		{name: "backoff", mutate: func(o *Options) { o.BackoffMax = 0 },
+			wantErr: ErrInvalidBackoff},
+		{name: "hedge defaults", mutate: func(o *Options) { o.Hedge = NewHedgeOptions() }},
+		{name: "percentile", mutate: func(o *Options) {
+			o.Hedge = NewHedgeOptions()
+			o.Hedge.Percentile = 1
+		}, wantErr: ErrInvalidPercentile},
+		{name: "max hedges", mutate: func(o *Options) {
+			o.Hedge = NewHedgeOptions()
+			o.Hedge.MaxHedges = 0
+		}, wantErr: ErrInvalidMaxHedges},
+		{name: "min delay", mutate: func(o *Options) {
+			o.Hedge = NewHedgeOptions()
+			o.Hedge.MinDelay = -1
+		}, wantErr: ErrInvalidMinDelay},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := New()
+			test.mutate(o)
+			_, err := o.Validate()
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("expected %v got %v", test.wantErr, err)
+			}
+		})
+	}
+	var o *Options
+	if ok, err := o.Validate(); !ok || err != nil {
+		t.Error("expected nil options to be valid")
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	o := &Options{}
+	err := yaml.Unmarshal([]byte(`
+max_attempts: 5
+status_codes: [500, 503]
+hedge:
+  min_delay: 20ms
+`), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.MaxAttempts != 5 || len(o.StatusCodes) != 2 {
+		t.Errorf("unexpected options %+v", o)
+	}
+	if time.Duration(o.BackoffBase) != DefaultBackoffBase {
+		t.Errorf("expected default backoff_base, got %s", time.Duration(o.BackoffBase))
+	}
+	if o.Hedge == nil || o.Hedge.Percentile != DefaultHedgePercentile ||
+		o.Hedge.MaxHedges != DefaultMaxHedges ||
+		time.Duration(o.Hedge.MinDelay) != 20*time.Millisecond {
+		t.Errorf("unexpected hedge options %+v", o.Hedge)
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	o := New()
+	o.ReadOnlyPOSTPaths = []string{"/api/v1/query"}
+	tests := []struct {
+		method, path string
+		isQuery      bool
+		want         bool
+	}{
+		{method: "GET", path: "/anything", want: true},
+		{method: "HEAD", path: "/anything", want: true},
+		{method: "POST", path: "/api/v1/query", want: true},
+		{method: "POST", path: "/write", isQuery: true, want: true},
+		{method: "POST", path: "/write"},
+		{method: "PUT", path: "/api/v1/query"},
+		{method: "DELETE", path: "/anything"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if got := o.IsReadOnly(r, test.isQuery); got != test.want {
+			t.Errorf("%s %s: expected %t got %t", test.method, test.path, test.want, got)
+		}
+	}
+	var nilOpts *Options
+	if nilOpts.IsReadOnly(httptest.NewRequest("GET", "/", nil), false) {
+		t.Error("expected nil options to never be read-only")
+	}
+}
+
+func TestRetryStatus(t *testing.T) {
+	o := New()
+	if !o.RetryStatus(503) || o.RetryStatus(500) {
+		t.Error("unexpected status codes before initialization")
+	}
+	o.StatusCodes = []int{500}
+	o.Initialize("test")
+	if !o.RetryStatus(500) || o.RetryStatus(503) {
+		t.Error("unexpected status codes after initialization")
+	}
+}
+
+func TestHedgeDelay(t *testing.T) {
+	o := New()
+	if _, ok := o.HedgeDelay(); ok {
+		t.Error("expected no hedge delay when hedging is disabled")
+	}
+	o.Hedge = NewHedgeOptions()
+	o.Hedge.MinDelay = 0
+	if _, ok := o.HedgeDelay(); ok {
+		t.Error("expected no hedge delay without latency samples")
+	}
+	for i := 1; i <= 100; i++ {
+		o.Latency().Observe(time.Duration(i) * time.Millisecond)
+	}
+	c := o.Clone()
+	if d, ok := c.HedgeDelay(); !ok || d != 95*time.Millisecond {
+		t.Errorf("expected clone to share latencies with a p95 of 95ms, got %s", d)
+	}
+	c.Hedge.MinDelay = timeconv.Duration(200 * time.Millisecond)
+	if d, _ := c.HedgeDelay(); d != 200*time.Millisecond {
+		t.Errorf("expected min_delay of 200ms, got %s", d)
+	}
+	if o.Hedge.MinDelay != 0 {
+		t.Error("expected clone hedge options to be independent")
+	}
+}
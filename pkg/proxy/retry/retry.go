@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package retry provides jittered backoff and upstream latency tracking for
+// retried and hedged upstream requests
+package retry
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSampleSize is the number of recent latency samples retained by
+	// a LatencyTracker
+	DefaultSampleSize = 512
+	// MinSamples is the number of latency samples a LatencyTracker needs
+	// before it reports a percentile
+	MinSamples = 20
+)
+
+// Backoff returns the jittered delay before the nth retry (starting at 1).
+// The delay doubles from base with each retry up to maxDelay, and a random
+// amount of up to half of it is subtracted so that concurrent retries
+// spread out
+func Backoff(n int, base, maxDelay time.Duration) time.Duration {
+	if base <= 0 || n < 1 {
+		return 0
+	}
+	d := base
+	for i := 1; i < n && d < maxDelay; i++ {
+		d *= 2
+	}
+	if maxDelay > 0 && d > maxDelay {
+		d = maxDelay
+	}
+	half := d / 2
+	return d - half + rand.N(half+1)
+}
+
+// LatencyTracker keeps a ring of recent upstream latencies. Percentiles are
+// memoized and only recomputed once enough new samples have been observed, so
+// they can be requested on every upstream request.
+type LatencyTracker struct {
+	mtx     sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+	// stale is the number of samples observed since the memoized percentile
+	// was computed. It is recomputed once stale reaches recomputeAt
+	stale       int
+	recomputeAt int
+	cachedP     float64
+	cached      time.Duration
+	cacheIsSet  bool
+}
+
+// NewLatencyTracker returns a new LatencyTracker retaining up to size samples
+func NewLatencyTracker(size int) *LatencyTracker {
+	if size <= 0 {
+		size = DefaultSampleSize
+	}
+	return &LatencyTracker{samples: make([]time.Duration, size),
+		recomputeAt: max(1, size/16)}
+}
+
+// Observe records an upstream latency
+func (t *LatencyTracker) Observe(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mtx.Lock()
+	t.samples[t.next] = d
+	t.stale++
+	t.next++
+	if t.next == len(t.samples) {
+		t.next = 0
+		t.full = true
+	}
+	t.mtx.Unlock()
+}
+
+// Len returns the number of retained samples
+func (t *LatencyTracker) Len() int {
+	if t == nil {
+		return 0
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.full {
+		return len(t.samples)
+	}
+	return t.next
+}
+
+// Percentile returns the latency at percentile p (0 < p <= 1) of the
+// retained samples, or false if fewer than MinSamples have been observed. The
+// result is recomputed after every 1/16th of the sample size of new samples.
+func (t *LatencyTracker) Percentile(p float64) (time.Duration, bool) {
+	if t == nil {
+		return 0, false
+	}
+	t.mtx.Lock()
+	n := t.next
+	if t.full {
+		n = len(t.samples)
+	}
+	if n < MinSamples {
+		t.mtx.Unlock()
+		return 0, false
+	}
+	if t.cacheIsSet && t.cachedP == p && t.stale < t.recomputeAt {
+		d := t.cached
+		t.mtx.Unlock()
+		return d, true
+	}
+	sorted := slices.Clone(t.samples[:n])
+	t.stale = 0
+	t.mtx.Unlock()
+	slices.Sort(sorted)
+	i := int(p*float64(n)+0.5) - 1
+	i = max(0, min(i, n-1))
+	d := sorted[i]
+	t.mtx.Lock()
+	t.cachedP, t.cached, t.cacheIsSet = p, d, true
+	t.mtx.Unlock()
+	return d, true
+}
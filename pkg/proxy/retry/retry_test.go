@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	if d := Backoff(1, 0, time.Second); d != 0 {
+		t.Errorf("expected 0 for unset base, got %s", d)
+	}
+	tests := []struct {
+		n        int
+		min, max time.Duration
+	}{
+		{n: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{n: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{n: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{n: 10, min: 250 * time.Millisecond, max: 500 * time.Millisecond},
+	}
+	for _, test := range tests {
+		for range 50 {
+			d := Backoff(test.n, 100*time.Millisecond, 500*time.Millisecond)
+			if d < test.min || d > test.max {
+				t.Fatalf("retry %d: expected backoff in [%s, %s], got %s",
+					test.n, test.min, test.max, d)
+			}
+		}
+	}
+}
+
+func TestLatencyTracker(t *testing.T) {
+	lt := NewLatencyTracker(100)
+	for i := 1; i < MinSamples; i++ {
+		lt.Observe(time.Duration(i) * time.Millisecond)
+	}
+	if _, ok := lt.Percentile(0.9); ok {
+		t.Fatal("expected no percentile before MinSamples")
+	}
+	for i := MinSamples; i <= 100; i++ {
+		lt.Observe(time.Duration(i) * time.Millisecond)
+	}
+	if n := lt.Len(); n != 100 {
+		t.Errorf("expected 100 samples, got %d", n)
+	}
+	d, ok := lt.Percentile(0.95)
+	if !ok || d != 95*time.Millisecond {
+		t.Errorf("expected p95 of 95ms, got %s %t", d, ok)
+	}
+	// overwrite the ring with slower samples
+	for range 100 {
+		lt.Observe(time.Second)
+	}
+	if d, _ := lt.Percentile(0.5); d != time.Second {
+		t.Errorf("expected p50 of 1s after wrap, got %s", d)
+	}
+
+	// the percentile is memoized until enough new samples are observed
+	for range 5 {
+		lt.Observe(time.Millisecond)
+	}
+	if d, _ := lt.Percentile(0.5); d != time.Second {
+		t.Errorf("expected memoized p50 of 1s, got %s", d)
+	}
+	for range 50 {
+		lt.Observe(time.Millisecond)
+	}
+	if d, _ := lt.Percentile(0.5); d != time.Millisecond {
+		t.Errorf("expected recomputed p50 of 1ms, got %s", d)
+	}
+
+	var nilTracker *LatencyTracker
+	nilTracker.Observe(time.Second)
+	if _, ok := nilTracker.Percentile(0.5); ok || nilTracker.Len() != 0 {
+		t.Error("expected nil tracker to report nothing")
+	}
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for mirroring traffic to a shadow
+// backend
+package options
+
+import (
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// DefaultPercent is the default percentage of requests that are mirrored
+	DefaultPercent = 100
+	// DefaultTimeout is the default timeout for mirrored requests
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxConcurrent is the default number of in-flight mirrored
+	// requests, beyond which requests are not mirrored
+	DefaultMaxConcurrent = 10
+	// DefaultTolerance is the default tolerance for comparing values
+	DefaultTolerance = 0.001
+	// DefaultMaxBodyBytes is the default maximum size of a response body
+	// that is compared
+	DefaultMaxBodyBytes = 16 * 1024 * 1024
+)
+
+var (
+	ErrMissingBackend       = errors.New("shadow backend field is required")
+	ErrInvalidPercent       = errors.New("shadow percent must be greater than 0 and at most 100")
+	ErrInvalidTimeout       = errors.New("shadow timeout must be greater than 0")
+	ErrInvalidMaxConcurrent = errors.New("shadow max_concurrent must be greater than 0")
+	ErrInvalidTolerance     = errors.New("shadow compare tolerance must not be negative")
+	ErrInvalidMaxBodyBytes  = errors.New("shadow compare max_body_bytes must be greater than 0")
+)
+
+// Options defines the mirroring of a Backend's requests to a shadow Backend
+type Options struct {
+	// Backend is the name of the Backend that receives mirrored requests
+	Backend string `yaml:"backend,omitempty"`
+	// Percent is the percentage of requests that are mirrored. The default
+	// is 100
+	Percent float64 `yaml:"percent,omitempty"`
+	// Timeout is the timeout for mirrored requests. The default is 30s
+	Timeout timeconv.Duration `yaml:"timeout,omitempty"`
+	// MaxConcurrent is the number of in-flight mirrored requests, beyond
+	// which requests are not mirrored. The default is 10
+	MaxConcurrent int `yaml:"max_concurrent,omitempty"`
+	// Compare configures comparing mirrored responses against the primary
+	// response. Responses are discarded without comparison when unset
+	Compare *CompareOptions `yaml:"compare,omitempty"`
+
+	// Handler is the router of the shadow Backend
+	Handler http.Handler `yaml:"-"`
+
+	slots *sharedSlots
+}
+
+// CompareOptions defines the comparison of mirrored responses against the
+// primary response
+type CompareOptions struct {
+	// Tolerance is the difference, either absolute or relative to the larger
+	// value, within which values match. The default is 0.001
+	Tolerance float64 `yaml:"tolerance,omitempty"`
+	// MaxBodyBytes is the maximum size of a response body that is compared.
+	// The default is 16MiB
+	MaxBodyBytes int `yaml:"max_body_bytes,omitempty"`
+}
+
+// sharedSlots holds the in-flight mirrored request slots for an Options and
+// is shared by its clones
+type sharedSlots struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{
+		Percent:       DefaultPercent,
+		Timeout:       timeconv.Duration(DefaultTimeout),
+		MaxConcurrent: DefaultMaxConcurrent,
+		slots:         &sharedSlots{},
+	}
+}
+
+// NewCompareOptions returns a new CompareOptions with the default values
+func NewCompareOptions() *CompareOptions {
+	return &CompareOptions{
+		Tolerance:    DefaultTolerance,
+		MaxBodyBytes: DefaultMaxBodyBytes,
+	}
+}
+
+// Clone returns a copy of the Options that shares its in-flight slots
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	out := pointers.Clone(o)
+	out.Compare = pointers.Clone(o.Compare)
+	return out
+}
+
+// Initialize prepares the Options for use
+func (o *Options) Initialize(_ string) error {
+	if o == nil {
+		return nil
+	}
+	if o.slots == nil {
+		o.slots = &sharedSlots{}
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.Backend == "" {
+		return false, ErrMissingBackend
+	}
+	if o.Percent <= 0 || o.Percent > 100 {
+		return false, ErrInvalidPercent
+	}
+	if o.Timeout <= 0 {
+		return false, ErrInvalidTimeout
+	}
+	if o.MaxConcurrent <= 0 {
+		return false, ErrInvalidMaxConcurrent
+	}
+	if c := o.Compare; c != nil {
+		if c.Tolerance < 0 {
+			return false, ErrInvalidTolerance
+		}
+		if c.MaxBodyBytes <= 0 {
+			return false, ErrInvalidMaxBodyBytes
+		}
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a shadow configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
+
+// UnmarshalYAML applies defaults before decoding a compare configuration block.
+func (o *CompareOptions) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions CompareOptions
+	lo := loadOptions(*(NewCompareOptions()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = CompareOptions(lo)
+	return nil
+}
+
+// Sample returns true if a request should be mirrored, per Percent
+func (o *Options) Sample() bool {
+	if o == nil {
+		return false
+	}
+	return o.Percent >= 100 || rand.Float64()*100 < o.Percent
+}
+
+// Acquire reserves an in-flight mirrored request slot, returning a function
+// that releases it, or false if MaxConcurrent requests are in flight
+func (o *Options) Acquire() (func(), bool) {
+	if o == nil || o.slots == nil {
+		return nil, false
+	}
+	s := o.slots
+	s.once.Do(func() {
+		s.ch = make(chan struct{}, max(o.MaxConcurrent, 1))
+	})
+	select {
+	case s.ch <- struct{}{}:
+		return func() { <-s.ch }, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Options)
+		wantErr error
+	}{
+		{name: "defaults", mutate: func(*Options) {}},
+		{name: "backend", mutate: func(o *Options) { o.Backend = "" },
+			wantErr: ErrMissingBackend},
+		{name: "percent", mutate: func(o *Options) { o.Percent = 101 },
+			wantErr: ErrInvalidPercent},
+		{name: "timeout", mutate: func(o *Options) { o.Timeout = 0 },
+			wantErr: ErrInvalidTimeout},
+		{name: "max concurrent", mutate: func(o *Options) { o.MaxConcurrent = 0 },
+			wantErr: ErrInvalidMaxConcurrent},
+		{name: "compare defaults", mutate: func(o *Options) { o.Compare = NewCompareOptions() }},
+		{name: "tolerance", mutate: func(o *Options) {
+			o.Compare = NewCompareOptions()
+			o.Compare.Tolerance = -1
+		}, wantErr: ErrInvalidTolerance},
+		{name: "max body bytes", mutate: func(o *Options) {
+			o.Compare = NewCompareOptions()
+			o.Compare.MaxBodyBytes = 0
+		}, wantErr: ErrInvalidMaxBodyBytes},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := New()
+			o.Backend = "shadow"
+			test.mutate(o)
+			_, err := o.Validate()
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("expected %v got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	o := &Options{}
+	err := yaml.Unmarshal([]byte(`
+backend: prom-next
+percent: 5
+compare:
+  tolerance: 0.01
+`), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Backend != "prom-next" || o.Percent != 5 ||
+		o.MaxConcurrent != DefaultMaxConcurrent {
+		t.Errorf("unexpected options %+v", o)
+	}
+	if o.Compare == nil || o.Compare.Tolerance != 0.01 ||
+		o.Compare.MaxBodyBytes != DefaultMaxBodyBytes {
+		t.Errorf("unexpected compare options %+v", o.Compare)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	o := New()
+	o.MaxConcurrent = 2
+	c := o.Clone()
+	r1, ok1 := o.Acquire()
+	r2, ok2 := c.Acquire()
+	if !ok1 || !ok2 {
+		t.Fatal("expected two slots")
+	}
+	if _, ok := o.Acquire(); ok {
+		t.Fatal("expected clones to share slots")
+	}
+	r1()
+	r3, ok := c.Acquire()
+	if !ok {
+		t.Fatal("expected a released slot")
+	}
+	r2()
+	r3()
+
+	var nilOpts *Options
+	if _, ok := nilOpts.Acquire(); ok || nilOpts.Sample() {
+		t.Error("expected nil options to never mirror")
+	}
+}
+
+func TestSample(t *testing.T) {
+	o := New()
+	if !o.Sample() {
+		t.Error("expected 100 percent to always sample")
+	}
+	o.Percent = 50
+	var n int
+	for range 10000 {
+		if o.Sample() {
+			n++
+		}
+	}
+	if n < 4000 || n > 6000 {
+		t.Errorf("expected about half of requests to sample, got %d", n)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for tenant-partitioned caching
+package options
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Source identifies where the tenant identity of a request is read from
+type Source string
+
+const (
+	// SourceUsername reads the tenant from the Authenticator's username
+	SourceUsername Source = "username"
+	// SourceHeader reads the tenant from a request header
+	SourceHeader Source = "header"
+)
+
+// DefaultHeader is the default tenant header, as used by Mimir and Cortex
+const DefaultHeader = "X-Scope-OrgID"
+
+// Options defines how a backend partitions its cache entries by tenant
+type Options struct {
+	// Source is where the tenant is read from: 'username' or 'header'. The
+	// default is header
+	Source Source `yaml:"source,omitempty"`
+	// Header is the request header providing the tenant when Source is
+	// 'header'. The default is X-Scope-OrgID
+	Header string `yaml:"header,omitempty"`
+	// MetricsTenants is the list of tenants whose cache usage is recorded in
+	// per-tenant metrics. Usage by all other tenants is recorded under the
+	// OtherTenantsLabel, which bounds the metrics' cardinality. When empty,
+	// per-tenant metrics are disabled
+	MetricsTenants []string `yaml:"metrics_tenants,omitempty"`
+}
+
+// OtherTenantsLabel is the metrics label for tenants not in MetricsTenants
+const OtherTenantsLabel = "_other"
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{Source: SourceHeader, Header: DefaultHeader}
+}
+
+// Clone returns an exact copy of the Options
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	c := pointers.Clone(o)
+	c.MetricsTenants = slices.Clone(o.MetricsTenants)
+	return c
+}
+
+// MetricsLabel returns the tenant label to use in per-tenant metrics, and
+// false if per-tenant metrics are disabled
+func (o *Options) MetricsLabel(tenant string) (string, bool) {
+	if o == nil || len(o.MetricsTenants) == 0 {
+		return "", false
+	}
+	if tenant != "" && slices.Contains(o.MetricsTenants, tenant) {
+		return tenant, true
+	}
+	return OtherTenantsLabel, true
+}
+
+// Initialize normalizes the configured values
+func (o *Options) Initialize(_ string) error {
+	if o == nil {
+		return nil
+	}
+	o.Source = Source(strings.ToLower(string(o.Source)))
+	if o.Source == "" {
+		o.Source = SourceHeader
+	}
+	if o.Header == "" {
+		o.Header = DefaultHeader
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	switch Source(strings.ToLower(string(o.Source))) {
+	case "", SourceUsername, SourceHeader:
+	default:
+		return false, fmt.Errorf("invalid tenancy source: %s", o.Source)
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a tenancy configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
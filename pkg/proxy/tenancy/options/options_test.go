@@ -0,0 +1,110 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"slices"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestInitialize(t *testing.T) {
+	o := &Options{}
+	if err := o.Initialize(""); err != nil {
+		t.Fatal(err)
+	}
+	if o.Source != SourceHeader || o.Header != DefaultHeader {
+		t.Errorf("unexpected defaults: %#v", o)
+	}
+	o = &Options{Source: "USERNAME", Header: "x-tenant"}
+	if err := o.Initialize(""); err != nil {
+		t.Fatal(err)
+	}
+	if o.Source != SourceUsername || o.Header != "x-tenant" {
+		t.Errorf("unexpected normalized values: %#v", o)
+	}
+	var nilOpts *Options
+	if err := nilOpts.Initialize(""); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for source, valid := range map[Source]bool{
+		"":             true,
+		SourceHeader:   true,
+		SourceUsername: true,
+		"Header":       true,
+		"cookie":       false,
+	} {
+		if ok, err := (&Options{Source: source}).Validate(); ok != valid ||
+			(err == nil) != valid {
+			t.Errorf("source %q: expected valid=%t got %t (%v)", source, valid, ok, err)
+		}
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	o := &Options{}
+	if err := yaml.Unmarshal([]byte("metrics_tenants: [a, b]"), o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Source != SourceHeader || o.Header != DefaultHeader ||
+		len(o.MetricsTenants) != 2 {
+		t.Errorf("unexpected options: %#v", o)
+	}
+}
+
+func TestClone(t *testing.T) {
+	var nilOpts *Options
+	if nilOpts.Clone() != nil {
+		t.Error("expected nil clone")
+	}
+	o := &Options{Source: SourceUsername, Header: "X-Tenant",
+		MetricsTenants: []string{"a"}}
+	c := o.Clone()
+	if c == o || c.Source != o.Source || c.Header != o.Header ||
+		!slices.Equal(c.MetricsTenants, o.MetricsTenants) {
+		t.Error("unexpected clone")
+	}
+	c.MetricsTenants[0] = "b"
+	if o.MetricsTenants[0] != "a" {
+		t.Error("expected clone to copy MetricsTenants")
+	}
+}
+
+func TestMetricsLabel(t *testing.T) {
+	var nilOpts *Options
+	if _, ok := nilOpts.MetricsLabel("a"); ok {
+		t.Error("expected metrics to be disabled")
+	}
+	if _, ok := New().MetricsLabel("a"); ok {
+		t.Error("expected metrics to be disabled by default")
+	}
+	o := &Options{MetricsTenants: []string{"a", "b"}}
+	for tenant, want := range map[string]string{
+		"a": "a",
+		"b": "b",
+		"c": OtherTenantsLabel,
+		"":  OtherTenantsLabel,
+	} {
+		if got, ok := o.MetricsLabel(tenant); !ok || got != want {
+			t.Errorf("MetricsLabel(%q): expected %q got %q", tenant, want, got)
+		}
+	}
+}
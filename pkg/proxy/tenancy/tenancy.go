@@ -0,0 +1,171 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tenancy partitions cache entries by the tenant identity of a request
+package tenancy
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/cache"
+	"github.com/trickstercache/trickster/v2/pkg/checksum/md5"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/tenancy/options"
+)
+
+// GenerationRefreshInterval is how long a tenant's cache generation is reused
+// before being reloaded from the cache. A purge by another Trickster instance
+// sharing the cache takes effect on this instance within this interval.
+const GenerationRefreshInterval = 5 * time.Second
+
+const initialGeneration = "0"
+
+const (
+	// maxGenerations bounds the number of memoized tenant generations. Once
+	// reached, generations for additional tenants are read from the cache on
+	// each request until idle entries are swept.
+	maxGenerations = 10000
+	// generationIdleTimeout is how long past its expiration a memoized
+	// generation is retained before being swept
+	generationIdleTimeout = time.Minute
+)
+
+type generation struct {
+	value   string
+	expires time.Time
+}
+
+// generationMemo is a size-bounded memo of tenant cache generations by their
+// cache key. Entries that have been idle for generationIdleTimeout are
+// periodically swept, so the memo does not grow with every tenant ever seen.
+type generationMemo struct {
+	mtx       sync.Mutex
+	entries   map[string]*generation
+	nextSweep time.Time
+}
+
+// generations memoizes tenant cache generations by their cache key
+var generations = &generationMemo{entries: make(map[string]*generation)}
+
+func (m *generationMemo) load(key string) (*generation, bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	g, ok := m.entries[key]
+	return g, ok
+}
+
+func (m *generationMemo) store(key string, g *generation, now time.Time) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if now.After(m.nextSweep) {
+		cutoff := now.Add(-generationIdleTimeout)
+		for k, v := range m.entries {
+			if v.expires.Before(cutoff) {
+				delete(m.entries, k)
+			}
+		}
+		m.nextSweep = now.Add(GenerationRefreshInterval)
+	}
+	if _, ok := m.entries[key]; !ok && len(m.entries) >= maxGenerations {
+		return
+	}
+	m.entries[key] = g
+}
+
+// Tenant returns the tenant identity of the request, or an empty string when
+// the request has no tenant
+func Tenant(o *options.Options, r *http.Request) string {
+	if o == nil || r == nil {
+		return ""
+	}
+	if o.Source == options.SourceUsername {
+		rsc := request.GetResources(r)
+		if rsc == nil || rsc.AuthResult == nil ||
+			rsc.AuthResult.Status != types.AuthSuccess {
+			return ""
+		}
+		return rsc.AuthResult.Username
+	}
+	return r.Header.Get(o.Header)
+}
+
+// KeyPart returns the cache key component partitioning the tenant's entries,
+// which includes the tenant's current cache generation
+func KeyPart(c cache.Cache, backendName, cacheKeyPrefix, tenant string) string {
+	return ".tenant." + tenant + "." +
+		currentGeneration(c, generationKey(backendName, cacheKeyPrefix, tenant))
+}
+
+// Purge invalidates all of the tenant's cache entries for the backend by
+// advancing the tenant's cache generation. The previous generation's entries
+// are no longer reachable and will expire per their TTLs. ttl should be at
+// least the backend's max_ttl, so the generation outlives any cached object.
+func Purge(c cache.Cache, backendName, cacheKeyPrefix, tenant string,
+	ttl time.Duration,
+) error {
+	key := generationKey(backendName, cacheKeyPrefix, tenant)
+	value := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := c.Store(key, []byte(value), ttl); err != nil {
+		return err
+	}
+	now := time.Now()
+	generations.store(key, &generation{value: value,
+		expires: now.Add(GenerationRefreshInterval)}, now)
+	return nil
+}
+
+func generationKey(backendName, cacheKeyPrefix, tenant string) string {
+	return backendName + "." + cacheKeyPrefix + ".tenant." + md5.Checksum(tenant)
+}
+
+func currentGeneration(c cache.Cache, key string) string {
+	if c == nil {
+		return initialGeneration
+	}
+	now := time.Now()
+	var prev *generation
+	if g, ok := generations.load(key); ok {
+		prev = g
+		if now.Before(prev.expires) {
+			return prev.value
+		}
+	}
+	value := initialGeneration
+	b, _, err := c.Retrieve(key)
+	switch {
+	case err == nil && len(b) > 0:
+		value = string(b)
+	case err != nil && !errors.Is(err, cache.ErrKNF):
+		logger.Warn("tenant cache generation lookup failed",
+			logging.Pairs{"key": key, "detail": err.Error()})
+		// keep using the last known generation rather than reverting to the
+		// initial generation, which could serve entries from before a purge
+		if prev != nil {
+			return prev.value
+		}
+		return value
+	}
+	generations.store(key, &generation{value: value,
+		expires: now.Add(GenerationRefreshInterval)}, now)
+	return value
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tenancy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/cache"
+	co "github.com/trickstercache/trickster/v2/pkg/cache/options"
+	"github.com/trickstercache/trickster/v2/pkg/cache/status"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/tenancy/options"
+)
+
+type testCache struct {
+	mu   sync.Mutex
+	data map[string][]byte
+	err  error
+}
+
+func newTestCache() *testCache {
+	return &testCache{data: map[string][]byte{}}
+}
+
+func (c *testCache) Connect() error { return nil }
+func (c *testCache) Store(k string, b []byte, _ time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data[k] = b
+	return nil
+}
+
+func (c *testCache) Retrieve(k string) ([]byte, status.LookupStatus, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, status.LookupStatusError, c.err
+	}
+	if b, ok := c.data[k]; ok {
+		return b, status.LookupStatusHit, nil
+	}
+	return nil, status.LookupStatusKeyMiss, cache.ErrKNF
+}
+
+func (c *testCache) Remove(keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range keys {
+		delete(c.data, k)
+	}
+	return nil
+}
+
+func (c *testCache) Close() error               { return nil }
+func (c *testCache) Configuration() *co.Options { return &co.Options{Provider: "test"} }
+
+func TestTenant(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	r.Header.Set(options.DefaultHeader, "tenant-a")
+	r = request.SetResources(r, &request.Resources{
+		AuthResult: &types.AuthResult{Username: "alice", Status: types.AuthSuccess},
+	})
+
+	if got := Tenant(nil, r); got != "" {
+		t.Errorf("expected empty tenant, got %q", got)
+	}
+	if got := Tenant(options.New(), r); got != "tenant-a" {
+		t.Errorf("expected tenant-a, got %q", got)
+	}
+	o := &options.Options{Source: options.SourceUsername}
+	if got := Tenant(o, r); got != "alice" {
+		t.Errorf("expected alice, got %q", got)
+	}
+	request.GetResources(r).AuthResult.Status = types.AuthObserved
+	if got := Tenant(o, r); got != "" {
+		t.Errorf("expected empty tenant for unverified user, got %q", got)
+	}
+}
+
+func TestKeyPartAndPurge(t *testing.T) {
+	c := newTestCache()
+	a := KeyPart(c, "test-keypart", "prefix", "a")
+	b := KeyPart(c, "test-keypart", "prefix", "b")
+	if a != ".tenant.a.0" || b != ".tenant.b.0" {
+		t.Fatalf("unexpected key parts %q %q", a, b)
+	}
+	if got := KeyPart(nil, "test-keypart", "prefix", "a"); got != a {
+		t.Errorf("expected %q got %q", a, got)
+	}
+	if err := Purge(c, "test-keypart", "prefix", "a", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	purged := KeyPart(c, "test-keypart", "prefix", "a")
+	if purged == a {
+		t.Error("expected purge to change tenant a's key part")
+	}
+	if got := KeyPart(c, "test-keypart", "prefix", "b"); got != b {
+		t.Errorf("expected tenant b's key part to be unchanged, got %q", got)
+	}
+
+	// another instance's purge is read from the cache once the memo expires
+	key := generationKey("test-keypart", "prefix", "a")
+	c.Store(key, []byte("12345"), time.Hour)
+	if got := KeyPart(c, "test-keypart", "prefix", "a"); got != purged {
+		t.Errorf("expected memoized key part %q got %q", purged, got)
+	}
+	expireGeneration(key)
+	if got := KeyPart(c, "test-keypart", "prefix", "a"); got != ".tenant.a.12345" {
+		t.Errorf("expected reloaded key part, got %q", got)
+	}
+
+	// lookup failures retain the last known generation
+	expireGeneration(key)
+	c.err = errors.New("cache unavailable")
+	if got := KeyPart(c, "test-keypart", "prefix", "a"); got != ".tenant.a.12345" {
+		t.Errorf("expected last known key part, got %q", got)
+	}
+}
+
+func expireGeneration(key string) {
+	if g, ok := generations.load(key); ok {
+		g.expires = time.Time{}
+	}
+}
+
+func TestGenerationMemoBounds(t *testing.T) {
+	m := &generationMemo{entries: make(map[string]*generation)}
+	now := time.Now()
+	for i := range maxGenerations + 10 {
+		m.store(strconv.Itoa(i), &generation{value: "1",
+			expires: now.Add(GenerationRefreshInterval)}, now)
+	}
+	if len(m.entries) != maxGenerations {
+		t.Errorf("expected %d entries got %d", maxGenerations, len(m.entries))
+	}
+	// existing entries can still be updated when the memo is full
+	m.store("0", &generation{value: "2",
+		expires: now.Add(GenerationRefreshInterval)}, now)
+	if g, _ := m.load("0"); g.value != "2" {
+		t.Errorf("expected updated generation, got %q", g.value)
+	}
+	// idle entries are swept
+	later := now.Add(GenerationRefreshInterval + generationIdleTimeout + time.Second)
+	m.store("new", &generation{value: "1",
+		expires: later.Add(GenerationRefreshInterval)}, later)
+	if len(m.entries) != 1 {
+		t.Errorf("expected idle entries to be swept, got %d", len(m.entries))
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ClientAuth is a listener's policy for requesting and verifying TLS client
+// certificates
+type ClientAuth string
+
+const (
+	// ClientAuthNone does not request a client certificate (default)
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthRequest requests, but does not require or verify, a client
+	// certificate
+	ClientAuthRequest ClientAuth = "request"
+	// ClientAuthRequireAny requires a client certificate, but does not verify it
+	ClientAuthRequireAny ClientAuth = "require_any"
+	// ClientAuthVerifyIfGiven verifies a client certificate when one is
+	// provided, but does not require one
+	ClientAuthVerifyIfGiven ClientAuth = "verify_if_given"
+	// ClientAuthRequireAndVerify requires and verifies a client certificate
+	ClientAuthRequireAndVerify ClientAuth = "require_and_verify"
+)
+
+var clientAuthTypes = map[ClientAuth]tls.ClientAuthType{
+	"":                         tls.NoClientCert,
+	ClientAuthNone:             tls.NoClientCert,
+	ClientAuthRequest:          tls.RequestClientCert,
+	ClientAuthRequireAny:       tls.RequireAnyClientCert,
+	ClientAuthVerifyIfGiven:    tls.VerifyClientCertIfGiven,
+	ClientAuthRequireAndVerify: tls.RequireAndVerifyClientCert,
+}
+
+// ErrNoClientCAs is returned when a verifying client auth mode has no client
+// certificate authorities configured
+var ErrNoClientCAs = errors.New("client certificate verification requires client CA paths")
+
+// ErrClientAuthNotVerified is returned when an mTLS Authenticator is used on a
+// listener whose client auth mode does not verify client certificates, since
+// such requests never have a verified certificate chain to authenticate
+var ErrClientAuthNotVerified = errors.New(
+	"mtls authenticators require tls_client_auth of verify_if_given or require_and_verify")
+
+// Type returns the crypto/tls ClientAuthType for the ClientAuth value
+func (c ClientAuth) Type() (tls.ClientAuthType, error) {
+	t, ok := clientAuthTypes[c]
+	if !ok {
+		return tls.NoClientCert, fmt.Errorf("invalid tls client auth mode: %s", c)
+	}
+	return t, nil
+}
+
+// Verifies returns true if the ClientAuth verifies provided client certificates
+func (c ClientAuth) Verifies() bool {
+	return c == ClientAuthVerifyIfGiven || c == ClientAuthRequireAndVerify
+}
+
+// LoadCertPool returns a new CertPool containing the PEM-encoded certificates
+// from each of the provided paths
+func LoadCertPool(paths []string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates could be parsed from %s", path)
+		}
+	}
+	return pool, nil
+}
+
+// ValidateClientAuth returns an error if the mode is invalid or the client
+// certificate authority bundles are missing or unreadable
+func ValidateClientAuth(mode ClientAuth, caPaths []string) error {
+	return ApplyClientAuth(&tls.Config{}, mode, caPaths)
+}
+
+// ApplyClientAuth configures cfg to request and verify client certificates
+// per the provided mode and certificate authority bundle paths
+func ApplyClientAuth(cfg *tls.Config, mode ClientAuth, caPaths []string) error {
+	t, err := mode.Type()
+	if err != nil {
+		return err
+	}
+	if mode.Verifies() && len(caPaths) == 0 {
+		return ErrNoClientCAs
+	}
+	cfg.ClientAuth = t
+	if len(caPaths) > 0 {
+		if cfg.ClientCAs, err = LoadCertPool(caPaths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
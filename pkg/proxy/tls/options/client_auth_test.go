@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"crypto/tls"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tlstest "github.com/trickstercache/trickster/v2/pkg/testutil/tls"
+)
+
+func TestClientAuthType(t *testing.T) {
+	tests := map[ClientAuth]tls.ClientAuthType{
+		"":                         tls.NoClientCert,
+		ClientAuthNone:             tls.NoClientCert,
+		ClientAuthRequest:          tls.RequestClientCert,
+		ClientAuthRequireAny:       tls.RequireAnyClientCert,
+		ClientAuthVerifyIfGiven:    tls.VerifyClientCertIfGiven,
+		ClientAuthRequireAndVerify: tls.RequireAndVerifyClientCert,
+	}
+	for mode, expected := range tests {
+		got, err := mode.Type()
+		if err != nil {
+			t.Error(err)
+		}
+		if got != expected {
+			t.Errorf("%s: expected %v got %v", mode, expected, got)
+		}
+	}
+	if _, err := ClientAuth("sometimes").Type(); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
+
+func TestApplyClientAuth(t *testing.T) {
+	_, ca, err := tlstest.GetTestKeyAndCert(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	td := t.TempDir()
+	caPath := filepath.Join(td, "ca.pem")
+	if err := os.WriteFile(caPath, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	badPath := filepath.Join(td, "bad.pem")
+	if err := os.WriteFile(badPath, []byte("not a cert"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &tls.Config{}
+	if err := ApplyClientAuth(cfg, ClientAuthRequireAndVerify, []string{caPath}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert || cfg.ClientCAs == nil {
+		t.Errorf("unexpected config %v %v", cfg.ClientAuth, cfg.ClientCAs)
+	}
+	if err := ValidateClientAuth(ClientAuthVerifyIfGiven, nil); !errors.Is(err, ErrNoClientCAs) {
+		t.Errorf("expected %v got %v", ErrNoClientCAs, err)
+	}
+	if err := ValidateClientAuth(ClientAuthRequireAndVerify, []string{badPath}); err == nil {
+		t.Error("expected error for unparseable CA bundle")
+	}
+	if err := ValidateClientAuth(ClientAuthVerifyIfGiven, []string{filepath.Join(td, "missing.pem")}); err == nil {
+		t.Error("expected error for missing CA bundle")
+	}
+	if err := ValidateClientAuth(ClientAuthNone, nil); err != nil {
+		t.Error(err)
+	}
+}
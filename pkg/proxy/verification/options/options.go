@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines configurations for verifying cached timeseries
+// against the origin
+package options
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// DefaultPercent is the default percentage of cache hits that are verified
+	DefaultPercent = 1
+	// DefaultTolerance is the default tolerance for comparing values
+	DefaultTolerance = 0.001
+	// DefaultMaxConcurrent is the default number of in-flight verifications,
+	// beyond which cache hits are not verified
+	DefaultMaxConcurrent = 2
+)
+
+var (
+	ErrInvalidPercent       = errors.New("cache_verification percent must be greater than 0 and at most 100")
+	ErrInvalidTolerance     = errors.New("cache_verification tolerance must not be negative")
+	ErrInvalidMaxConcurrent = errors.New("cache_verification max_concurrent must be greater than 0")
+)
+
+// Options defines the verification of a sample of timeseries cache hits
+// against the origin
+type Options struct {
+	// Percent is the percentage of cache hits that are verified. The default
+	// is 1
+	Percent float64 `yaml:"percent,omitempty"`
+	// Tolerance is the difference, either absolute or relative to the larger
+	// value, within which values match. The default is 0.001
+	Tolerance float64 `yaml:"tolerance,omitempty"`
+	// MaxConcurrent is the number of in-flight verifications, beyond which
+	// cache hits are not verified. The default is 2
+	MaxConcurrent int `yaml:"max_concurrent,omitempty"`
+
+	slots *sharedSlots
+}
+
+// sharedSlots holds the in-flight verification slots for an Options and is
+// shared by its clones
+type sharedSlots struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Options with the default values
+func New() *Options {
+	return &Options{
+		Percent:       DefaultPercent,
+		Tolerance:     DefaultTolerance,
+		MaxConcurrent: DefaultMaxConcurrent,
+		slots:         &sharedSlots{},
+	}
+}
+
+// Clone returns a copy of the Options that shares its in-flight slots
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	return pointers.Clone(o)
+}
+
+// Initialize prepares the Options for use
+func (o *Options) Initialize(_ string) error {
+	if o == nil {
+		return nil
+	}
+	if o.slots == nil {
+		o.slots = &sharedSlots{}
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.Percent <= 0 || o.Percent > 100 {
+		return false, ErrInvalidPercent
+	}
+	if o.Tolerance < 0 {
+		return false, ErrInvalidTolerance
+	}
+	if o.MaxConcurrent <= 0 {
+		return false, ErrInvalidMaxConcurrent
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a cache_verification
+// configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
+
+// Sample returns true if a cache hit should be verified, per Percent
+func (o *Options) Sample() bool {
+	if o == nil {
+		return false
+	}
+	return o.Percent >= 100 || rand.Float64()*100 < o.Percent
+}
+
+// Acquire reserves an in-flight verification slot, returning a function that
+// releases it, or false if MaxConcurrent verifications are in flight
+func (o *Options) Acquire() (func(), bool) {
+	if o == nil || o.slots == nil {
+		return nil, false
+	}
+	s := o.slots
+	s.once.Do(func() {
+		s.ch = make(chan struct{}, max(o.MaxConcurrent, 1))
+	})
+	select {
+	case s.ch <- struct{}{}:
+		return func() { <-s.ch }, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"errors"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(*Options)
+		wantErr error
+	}{
+		{name: "defaults", mutate: func(*Options) {}},
+		{name: "percent", mutate: func(o *Options) { o.Percent = 0 },
+			wantErr: ErrInvalidPercent},
+		{name: "tolerance", mutate: func(o *Options) { o.Tolerance = -1 },
+			wantErr: ErrInvalidTolerance},
+		{name: "max concurrent", mutate: func(o *Options) { o.MaxConcurrent = 0 },
+			wantErr: ErrInvalidMaxConcurrent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			o := New()
+			test.mutate(o)
+			_, err := o.Validate()
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("expected %v got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalYAML(t *testing.T) {
+	o := &Options{}
+	err := yaml.Unmarshal([]byte(`
+percent: 5
+tolerance: 0.01
+`), o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Percent != 5 || o.Tolerance != 0.01 ||
+		o.MaxConcurrent != DefaultMaxConcurrent {
+		t.Errorf("unexpected options %+v", o)
+	}
+}
+
+func TestAcquire(t *testing.T) {
+	o := New()
+	o.MaxConcurrent = 1
+	c := o.Clone()
+	r1, ok := o.Acquire()
+	if !ok {
+		t.Fatal("expected a slot")
+	}
+	if _, ok := c.Acquire(); ok {
+		t.Fatal("expected clones to share slots")
+	}
+	r1()
+	r2, ok := c.Acquire()
+	if !ok {
+		t.Fatal("expected a released slot")
+	}
+	r2()
+
+	var nilOpts *Options
+	if _, ok := nilOpts.Acquire(); ok || nilOpts.Sample() {
+		t.Error("expected nil options to never verify")
+	}
+}
+
+func TestSample(t *testing.T) {
+	o := New()
+	o.Percent = 100
+	if !o.Sample() {
+		t.Error("expected 100 percent to always sample")
+	}
+	o.Percent = 50
+	var n int
+	for range 10000 {
+		if o.Sample() {
+			n++
+		}
+	}
+	if n < 4000 || n > 6000 {
+		t.Errorf("expected about half of cache hits to sample, got %d", n)
+	}
+}
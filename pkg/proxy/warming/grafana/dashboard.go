@@ -0,0 +1,210 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package grafana expands the panel targets of exported Grafana dashboards into
+// timeseries queries that can be issued against Trickster to prewarm its caches
+package grafana
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// Dashboard represents the subset of an exported Grafana dashboard that is
+// needed to expand its panel targets into queries
+type Dashboard struct {
+	Title      string     `json:"title"`
+	Panels     []*Panel   `json:"panels"`
+	Rows       []*Row     `json:"rows"`
+	Templating Templating `json:"templating"`
+	Time       TimeRange  `json:"time"`
+	Inputs     []*Input   `json:"__inputs"`
+}
+
+// Row represents a legacy (schema version < 16) dashboard row
+type Row struct {
+	Panels []*Panel `json:"panels"`
+}
+
+// Panel represents a dashboard panel. Collapsed rows nest their Panels.
+type Panel struct {
+	Title         string      `json:"title"`
+	Type          string      `json:"type"`
+	Datasource    *Datasource `json:"datasource"`
+	Targets       []*Target   `json:"targets"`
+	Panels        []*Panel    `json:"panels"`
+	Interval      string      `json:"interval"`
+	MaxDataPoints int         `json:"maxDataPoints"`
+}
+
+// Target represents a single query of a panel
+type Target struct {
+	RefID      string      `json:"refId"`
+	Datasource *Datasource `json:"datasource"`
+	Hide       bool        `json:"hide"`
+	Expr       string      `json:"expr"`
+	Query      string      `json:"query"`
+	RawSQL     string      `json:"rawSql"`
+	Interval   string      `json:"interval"`
+}
+
+// Datasource references a Grafana datasource, either by name (legacy string
+// form) or by UID and plugin type
+type Datasource struct {
+	UID  string `json:"uid"`
+	Type string `json:"type"`
+}
+
+// UnmarshalJSON unmarshals a Datasource from its string or object form
+func (ds *Datasource) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		ds.UID = s
+		return nil
+	}
+	type alias Datasource
+	var a alias
+	if err := json.Unmarshal(b, &a); err != nil {
+		return err
+	}
+	*ds = Datasource(a)
+	return nil
+}
+
+// Templating contains the dashboard's template variables
+type Templating struct {
+	List []*Variable `json:"list"`
+}
+
+// Variable represents a dashboard template variable
+type Variable struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Query    json.RawMessage `json:"query"`
+	Current  Current         `json:"current"`
+	Options  []*Option       `json:"options"`
+	AllValue string          `json:"allValue"`
+}
+
+// Current is the currently-selected value of a template variable
+type Current struct {
+	Value Values `json:"value"`
+}
+
+// Option is a selectable value of a template variable
+type Option struct {
+	Value Values `json:"value"`
+}
+
+// Values is a list of variable values that unmarshals from either a string
+// or a list of strings
+type Values []string
+
+// UnmarshalJSON unmarshals Values from a string or a list of strings
+func (v *Values) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = Values{s}
+		return nil
+	}
+	var l []string
+	if err := json.Unmarshal(b, &l); err != nil {
+		return err
+	}
+	*v = l
+	return nil
+}
+
+// TimeRange is the dashboard's default time range, e.g., now-6h to now
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Input describes a datasource placeholder of a dashboard exported for
+// sharing externally, e.g., ${DS_PROMETHEUS}
+type Input struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	PluginID string `json:"pluginId"`
+}
+
+// Parse parses a Dashboard from its JSON representation. Both the bare
+// dashboard model and the API form ({"dashboard": {...}, "meta": {...}}) are
+// supported.
+func Parse(b []byte) (*Dashboard, error) {
+	var w struct {
+		Dashboard *Dashboard `json:"dashboard"`
+	}
+	if err := json.Unmarshal(b, &w); err != nil {
+		return nil, err
+	}
+	if w.Dashboard != nil {
+		return w.Dashboard, nil
+	}
+	d := &Dashboard{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Load reads and parses the Dashboard JSON file at path
+func Load(path string) (*Dashboard, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// AllPanels returns a flattened list of every panel in the dashboard,
+// including those in legacy rows and collapsed rows
+func (d *Dashboard) AllPanels() []*Panel {
+	out := make([]*Panel, 0, len(d.Panels))
+	var walk func([]*Panel)
+	walk = func(panels []*Panel) {
+		for _, p := range panels {
+			if p == nil {
+				continue
+			}
+			out = append(out, p)
+			walk(p.Panels)
+		}
+	}
+	walk(d.Panels)
+	for _, r := range d.Rows {
+		if r != nil {
+			walk(r.Panels)
+		}
+	}
+	return out
+}
+
+// variableName returns the name of the variable referenced by s when s is
+// exactly a variable reference like $name, ${name} or [[name]]
+func variableName(s string) (string, bool) {
+	switch {
+	case strings.HasPrefix(s, "${") && strings.HasSuffix(s, "}"):
+		return s[2 : len(s)-1], true
+	case strings.HasPrefix(s, "[[") && strings.HasSuffix(s, "]]"):
+		return s[2 : len(s)-2], true
+	case strings.HasPrefix(s, "$") && len(s) > 1:
+		return s[1:], true
+	}
+	return "", false
+}
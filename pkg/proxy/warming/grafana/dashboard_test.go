@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grafana
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	bare := `{"title":"a","panels":[{"title":"p","datasource":"Prometheus"}]}`
+	wrapped := `{"meta":{},"dashboard":` + bare + `}`
+	for _, s := range []string{bare, wrapped} {
+		d, err := Parse([]byte(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Title != "a" || len(d.Panels) != 1 {
+			t.Fatalf("unexpected dashboard: %+v", d)
+		}
+		if ds := d.Panels[0].Datasource; ds == nil || ds.UID != "Prometheus" {
+			t.Errorf("expected string datasource, got %+v", ds)
+		}
+	}
+	if _, err := Parse([]byte(`{`)); err == nil {
+		t.Error("expected error for invalid json")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	d, err := Load("testdata/dashboard.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Title != "Service Overview" {
+		t.Errorf("expected %s got %s", "Service Overview", d.Title)
+	}
+	if _, err := Load("testdata/nonexistent.json"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestAllPanels(t *testing.T) {
+	d := &Dashboard{
+		Panels: []*Panel{{Title: "a", Panels: []*Panel{{Title: "b"}}}, nil},
+		Rows:   []*Row{{Panels: []*Panel{{Title: "c"}}}, nil},
+	}
+	p := d.AllPanels()
+	if len(p) != 3 {
+		t.Fatalf("expected %d got %d", 3, len(p))
+	}
+	for i, title := range []string{"a", "b", "c"} {
+		if p[i].Title != title {
+			t.Errorf("expected %s got %s", title, p[i].Title)
+		}
+	}
+}
+
+func TestVariableName(t *testing.T) {
+	tests := []struct {
+		in, name string
+		ok       bool
+	}{
+		{"$ds", "ds", true},
+		{"${DS_PROMETHEUS}", "DS_PROMETHEUS", true},
+		{"[[ds]]", "ds", true},
+		{"prometheus-1", "", false},
+		{"$", "", false},
+	}
+	for _, test := range tests {
+		name, ok := variableName(test.in)
+		if name != test.name || ok != test.ok {
+			t.Errorf("%s: expected %s/%t got %s/%t", test.in, test.name,
+				test.ok, name, ok)
+		}
+	}
+}
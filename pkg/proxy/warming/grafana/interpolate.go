@@ -0,0 +1,168 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grafana
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+)
+
+// variableRE matches $name, ${name}, ${name:format}, [[name]] and [[name:format]]
+var variableRE = regexp.MustCompile(
+	`\$(\w+)|\$\{(\w+)(?::(\w+))?\}|\[\[(\w+)(?::(\w+))?\]\]`)
+
+// macroRE matches the ClickHouse datasource's function-style time macros
+var macroRE = regexp.MustCompile(`\$(__timeFilter|__timeInterval)\(([^)]+)\)`)
+
+// builtins returns Grafana's global variables and the provider's time range
+// macros for the query range and step
+func builtins(provider string, start, end time.Time,
+	step time.Duration) map[string]string {
+	rng := end.Sub(start)
+	rate := max(step+DefaultMinInterval, 4*DefaultMinInterval)
+	out := map[string]string{
+		"__interval":      formatInterval(step),
+		"__interval_ms":   strconv.FormatInt(step.Milliseconds(), 10),
+		"__interval_s":    strconv.FormatInt(int64(step.Seconds()), 10),
+		"__rate_interval": formatInterval(rate),
+		"__range":         formatInterval(rng),
+		"__range_s":       strconv.FormatInt(int64(rng.Seconds()), 10),
+		"__range_ms":      strconv.FormatInt(rng.Milliseconds(), 10),
+		"__from":          strconv.FormatInt(start.UnixMilli(), 10),
+		"__to":            strconv.FormatInt(end.UnixMilli(), 10),
+	}
+	switch provider {
+	case providers.InfluxDB:
+		out["timeFilter"] = fmt.Sprintf("time >= %dms and time <= %dms",
+			start.UnixMilli(), end.UnixMilli())
+	case providers.ClickHouse:
+		out["__fromTime"] = fmt.Sprintf("toDateTime(%d)", start.Unix())
+		out["__toTime"] = fmt.Sprintf("toDateTime(%d)", end.Unix())
+	}
+	return out
+}
+
+// interpolate replaces the macros and variable references in stmt. Multi-value
+// variables are formatted the way Grafana formats them for the provider unless
+// the reference specifies a format. Unknown references are left unchanged.
+func interpolate(stmt, provider string, vars map[string][]string,
+	globals map[string]string) string {
+	if provider == providers.ClickHouse {
+		stmt = macroRE.ReplaceAllStringFunc(stmt, func(m string) string {
+			sm := macroRE.FindStringSubmatch(m)
+			col := strings.TrimSpace(sm[2])
+			if sm[1] == "__timeInterval" {
+				return fmt.Sprintf("toStartOfInterval(%s, INTERVAL %s second)",
+					col, globals["__interval_s"])
+			}
+			return fmt.Sprintf("%s >= %s AND %s <= %s",
+				col, globals["__fromTime"], col, globals["__toTime"])
+		})
+	}
+	return variableRE.ReplaceAllStringFunc(stmt, func(m string) string {
+		sm := variableRE.FindStringSubmatch(m)
+		var name, format string
+		switch {
+		case sm[1] != "":
+			name = sm[1]
+		case sm[2] != "":
+			name, format = sm[2], sm[3]
+		default:
+			name, format = sm[4], sm[5]
+		}
+		if v, ok := globals[name]; ok {
+			return v
+		}
+		vals, ok := vars[name]
+		if !ok || len(vals) == 0 {
+			return m
+		}
+		return formatValues(vals, format, provider)
+	})
+}
+
+// formatValues formats variable values per Grafana's variable format options
+func formatValues(vals []string, format, provider string) string {
+	if format == "" {
+		if len(vals) == 1 {
+			return vals[0]
+		}
+		if provider == providers.ClickHouse {
+			format = "singlequote"
+		} else {
+			format = "regex"
+		}
+	}
+	switch format {
+	case "regex":
+		if len(vals) == 1 {
+			return regexp.QuoteMeta(vals[0])
+		}
+		q := make([]string, len(vals))
+		for i, v := range vals {
+			q[i] = regexp.QuoteMeta(v)
+		}
+		return "(" + strings.Join(q, "|") + ")"
+	case "pipe":
+		return strings.Join(vals, "|")
+	case "csv":
+		return strings.Join(vals, ",")
+	case "singlequote":
+		return quoteEach(vals, "'", `\'`)
+	case "sqlstring":
+		return quoteEach(vals, "'", "''")
+	case "doublequote":
+		return quoteEach(vals, `"`, `\"`)
+	case "glob":
+		if len(vals) == 1 {
+			return vals[0]
+		}
+		return "{" + strings.Join(vals, ",") + "}"
+	}
+	return strings.Join(vals, ",")
+}
+
+func quoteEach(vals []string, q, escaped string) string {
+	out := make([]string, len(vals))
+	for i, v := range vals {
+		out[i] = q + strings.ReplaceAll(v, q, escaped) + q
+	}
+	return strings.Join(out, ",")
+}
+
+// formatInterval formats d the way Grafana formats intervals, e.g., 30s or 5m
+func formatInterval(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d <= 0:
+		return "0s"
+	case d%day == 0:
+		return fmt.Sprintf("%dd", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
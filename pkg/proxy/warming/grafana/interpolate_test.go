@@ -0,0 +1,89 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grafana
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+)
+
+func TestFormatValues(t *testing.T) {
+	tests := []struct {
+		vals             []string
+		format, provider string
+		expected         string
+	}{
+		{[]string{"a.b"}, "", providers.Prometheus, "a.b"},
+		{[]string{"a.b", "c"}, "", providers.Prometheus, `(a\.b|c)`},
+		{[]string{"a", "b"}, "", providers.ClickHouse, "'a','b'"},
+		{[]string{"a", "b"}, "pipe", providers.Prometheus, "a|b"},
+		{[]string{"a", "b"}, "csv", providers.Prometheus, "a,b"},
+		{[]string{"o'k"}, "sqlstring", providers.ClickHouse, "'o''k'"},
+		{[]string{"o'k"}, "singlequote", providers.ClickHouse, `'o\'k'`},
+		{[]string{"a", "b"}, "doublequote", providers.InfluxDB, `"a","b"`},
+		{[]string{"a", "b"}, "glob", providers.Prometheus, "{a,b}"},
+		{[]string{"a", "b"}, "unknown", providers.Prometheus, "a,b"},
+	}
+	for i, test := range tests {
+		if v := formatValues(test.vals, test.format, test.provider); v != test.expected {
+			t.Errorf("%d: expected %s got %s", i, test.expected, v)
+		}
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	vars := map[string][]string{"a": {"1"}, "b": {"x", "y"}}
+	start, end := time.Unix(0, 0), time.Unix(3600, 0)
+	g := builtins(providers.Prometheus, start, end, time.Minute)
+	const in = `$a ${a} [[a]] ${b:csv} [[b:pipe]] $missing $__interval $__range_s`
+	const expected = `1 1 1 x,y x|y $missing 1m 3600`
+	if v := interpolate(in, providers.Prometheus, vars, g); v != expected {
+		t.Errorf("expected %s got %s", expected, v)
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                      "0s",
+		500 * time.Millisecond: "500ms",
+		30 * time.Second:       "30s",
+		5 * time.Minute:        "5m",
+		2 * time.Hour:          "2h",
+		48 * time.Hour:         "2d",
+	}
+	for in, expected := range tests {
+		if v := formatInterval(in); v != expected {
+			t.Errorf("%s: expected %s got %s", in, expected, v)
+		}
+	}
+}
+
+func TestRoundInterval(t *testing.T) {
+	tests := map[time.Duration]time.Duration{
+		5 * time.Millisecond: time.Millisecond,
+		43 * time.Second:     30 * time.Second,
+		10 * time.Minute:     10 * time.Minute,
+		365 * 24 * time.Hour: 30 * 24 * time.Hour,
+	}
+	for in, expected := range tests {
+		if v := roundInterval(in); v != expected {
+			t.Errorf("%s: expected %s got %s", in, expected, v)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grafana
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+)
+
+const (
+	// DefaultRange is the query range used when none is provided and the
+	// dashboard's default time range can't be determined
+	DefaultRange = 6 * time.Hour
+	// DefaultMaxDataPoints approximates the number of data points Grafana
+	// requests for a full-width panel
+	DefaultMaxDataPoints = 1000
+	// DefaultMinInterval is the lower bound of a query's step, matching the
+	// default scrape interval assumed by Grafana's Prometheus datasource
+	DefaultMinInterval = 15 * time.Second
+)
+
+// Options control how dashboard targets are expanded into Queries
+type Options struct {
+	// Ranges are the durations, ending at Now, for which each target is
+	// expanded. When empty, the dashboard's default time range is used.
+	Ranges []time.Duration
+	// Variables are template variable values that override the values
+	// saved in the dashboard
+	Variables map[string][]string
+	// MaxDataPoints is used to calculate the step of panels that don't
+	// specify their own maxDataPoints
+	MaxDataPoints int
+	// MinInterval is the minimum step of panels that don't specify their
+	// own min interval
+	MinInterval time.Duration
+	// Now is the end time of each query range
+	Now time.Time
+}
+
+// Query is a single panel target expanded for a given time range
+type Query struct {
+	Dashboard  string
+	Panel      string
+	RefID      string
+	Datasource string
+	Provider   string
+	Statement  string
+	Start      time.Time
+	End        time.Time
+	Step       time.Duration
+}
+
+// Expand returns the Queries for every visible, supported panel target in the
+// dashboard, for each of the configured ranges. Targets whose datasource is
+// not a Prometheus, InfluxDB or ClickHouse datasource are omitted.
+func (d *Dashboard) Expand(o *Options) []*Query {
+	if o == nil {
+		o = &Options{}
+	}
+	now := o.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	ranges := o.Ranges
+	if len(ranges) == 0 {
+		ranges = []time.Duration{d.defaultRange()}
+	}
+	vars := d.variables(o.Variables)
+	var out []*Query
+	for _, p := range d.AllPanels() {
+		for _, t := range p.Targets {
+			if t == nil || t.Hide {
+				continue
+			}
+			ds := t.Datasource
+			if ds == nil || (ds.UID == "" && ds.Type == "") {
+				ds = p.Datasource
+			}
+			name, provider := d.resolveDatasource(ds, vars, t)
+			if provider == "" {
+				continue
+			}
+			stmt := t.statement(provider)
+			if stmt == "" {
+				continue
+			}
+			for _, rng := range ranges {
+				start := now.Add(-rng)
+				step := p.step(t, rng, o)
+				out = append(out, &Query{
+					Dashboard:  d.Title,
+					Panel:      p.Title,
+					RefID:      t.RefID,
+					Datasource: name,
+					Provider:   provider,
+					Statement: interpolate(stmt, provider, vars,
+						builtins(provider, start, now, step)),
+					Start: start,
+					End:   now,
+					Step:  step,
+				})
+			}
+		}
+	}
+	return out
+}
+
+// defaultRange returns the duration of the dashboard's default time range
+// when it is relative to now (e.g., now-6h), or DefaultRange otherwise
+func (d *Dashboard) defaultRange() time.Duration {
+	if s, ok := strings.CutPrefix(d.Time.From, "now-"); ok {
+		if dur, err := timeconv.ParseDuration(s); err == nil && dur > 0 {
+			return dur
+		}
+	}
+	return DefaultRange
+}
+
+// variables returns the values of each template variable, giving precedence
+// to the provided overrides
+func (d *Dashboard) variables(overrides map[string][]string) map[string][]string {
+	out := make(map[string][]string, len(d.Templating.List)+len(overrides))
+	for _, v := range d.Templating.List {
+		if v == nil || v.Name == "" {
+			continue
+		}
+		out[v.Name] = v.values()
+	}
+	for k, v := range overrides {
+		out[k] = v
+	}
+	return out
+}
+
+// values returns the variable's currently-selected values, expanding the
+// All selection to the custom all value or to every option
+func (v *Variable) values() []string {
+	if len(v.Current.Value) != 1 || v.Current.Value[0] != "$__all" {
+		return v.Current.Value
+	}
+	if v.AllValue != "" {
+		return []string{v.AllValue}
+	}
+	out := make([]string, 0, len(v.Options))
+	for _, o := range v.Options {
+		if o == nil {
+			continue
+		}
+		for _, val := range o.Value {
+			if val != "$__all" {
+				out = append(out, val)
+			}
+		}
+	}
+	return out
+}
+
+// resolveDatasource returns the name (or UID) and Trickster provider of the
+// datasource, following datasource variables and exported inputs. When the
+// plugin type can't be determined, it's inferred from the target's fields.
+func (d *Dashboard) resolveDatasource(ds *Datasource,
+	vars map[string][]string, t *Target) (string, string) {
+	var name, typ string
+	if ds != nil {
+		name, typ = ds.UID, ds.Type
+	}
+	if vn, ok := variableName(name); ok {
+		name = ""
+		for _, in := range d.Inputs {
+			if in != nil && in.Name == vn {
+				typ = in.PluginID
+			}
+		}
+		for _, v := range d.Templating.List {
+			if v == nil || v.Name != vn || v.Type != "datasource" {
+				continue
+			}
+			var q string
+			if json.Unmarshal(v.Query, &q) == nil && q != "" {
+				typ = q
+			}
+			if vals := vars[vn]; len(vals) > 0 {
+				name = vals[0]
+			}
+		}
+	}
+	if typ == "" {
+		return name, t.inferProvider()
+	}
+	return name, providerForPlugin(typ)
+}
+
+// providerForPlugin returns the Trickster provider for a Grafana datasource
+// plugin type, or an empty string if the plugin type isn't supported
+func providerForPlugin(typ string) string {
+	switch {
+	case typ == providers.Prometheus:
+		return providers.Prometheus
+	case typ == providers.InfluxDB:
+		return providers.InfluxDB
+	case strings.HasSuffix(typ, "clickhouse-datasource"):
+		return providers.ClickHouse
+	}
+	return ""
+}
+
+func (t *Target) inferProvider() string {
+	switch {
+	case t.Expr != "":
+		return providers.Prometheus
+	case t.RawSQL != "":
+		return providers.ClickHouse
+	case t.Query != "":
+		return providers.InfluxDB
+	}
+	return ""
+}
+
+func (t *Target) statement(provider string) string {
+	switch provider {
+	case providers.Prometheus:
+		return t.Expr
+	case providers.ClickHouse:
+		if t.RawSQL != "" {
+			return t.RawSQL
+		}
+	}
+	return t.Query
+}
+
+// step calculates the query step the same way Grafana does: the range divided
+// by the max data points, bounded by the min interval and then rounded
+func (p *Panel) step(t *Target, rng time.Duration, o *Options) time.Duration {
+	mdp := p.MaxDataPoints
+	if mdp <= 0 {
+		mdp = o.MaxDataPoints
+	}
+	if mdp <= 0 {
+		mdp = DefaultMaxDataPoints
+	}
+	minInterval := o.MinInterval
+	if minInterval <= 0 {
+		minInterval = DefaultMinInterval
+	}
+	for _, s := range []string{t.Interval, p.Interval} {
+		s = strings.TrimPrefix(s, ">")
+		if d, err := timeconv.ParseDuration(s); err == nil && d > 0 {
+			minInterval = d
+			break
+		}
+	}
+	step := roundInterval(rng / time.Duration(mdp))
+	if step < minInterval {
+		step = minInterval
+	}
+	return step
+}
+
+// intervalSteps mirrors the rounding table of Grafana's kbn.roundInterval
+var intervalSteps = []struct {
+	upTo, value time.Duration
+}{
+	{10 * time.Millisecond, time.Millisecond},
+	{15 * time.Millisecond, 10 * time.Millisecond},
+	{35 * time.Millisecond, 20 * time.Millisecond},
+	{75 * time.Millisecond, 50 * time.Millisecond},
+	{150 * time.Millisecond, 100 * time.Millisecond},
+	{350 * time.Millisecond, 200 * time.Millisecond},
+	{750 * time.Millisecond, 500 * time.Millisecond},
+	{1500 * time.Millisecond, time.Second},
+	{3500 * time.Millisecond, 2 * time.Second},
+	{7500 * time.Millisecond, 5 * time.Second},
+	{12500 * time.Millisecond, 10 * time.Second},
+	{17500 * time.Millisecond, 15 * time.Second},
+	{25 * time.Second, 20 * time.Second},
+	{45 * time.Second, 30 * time.Second},
+	{90 * time.Second, time.Minute},
+	{210 * time.Second, 2 * time.Minute},
+	{450 * time.Second, 5 * time.Minute},
+	{750 * time.Second, 10 * time.Minute},
+	{1050 * time.Second, 15 * time.Minute},
+	{1500 * time.Second, 20 * time.Minute},
+	{2700 * time.Second, 30 * time.Minute},
+	{5400 * time.Second, time.Hour},
+	{9000 * time.Second, 2 * time.Hour},
+	{16200 * time.Second, 3 * time.Hour},
+	{24300 * time.Second, 6 * time.Hour},
+	{64800 * time.Second, 12 * time.Hour},
+	{129600 * time.Second, 24 * time.Hour},
+	{604800 * time.Second, 7 * 24 * time.Hour},
+}
+
+func roundInterval(d time.Duration) time.Duration {
+	for _, s := range intervalSteps {
+		if d <= s.upTo {
+			return s.value
+		}
+	}
+	return 30 * 24 * time.Hour
+}
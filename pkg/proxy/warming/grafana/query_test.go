@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grafana
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+)
+
+func TestExpand(t *testing.T) {
+	d, err := Load("testdata/dashboard.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	q := d.Expand(&Options{Now: now})
+	if len(q) != 3 {
+		t.Fatalf("expected %d got %d", 3, len(q))
+	}
+	start := now.Add(-12 * time.Hour)
+	tests := []struct {
+		provider, datasource, statement string
+		step                            time.Duration
+	}{
+		{
+			providers.Prometheus, "",
+			`sum(rate(http_requests_total{job=~"(api|web)",instance=~"(host-1|host-2)"}[1m]))`,
+			30 * time.Second,
+		},
+		{
+			providers.InfluxDB, "influx-1",
+			`SELECT mean("value") FROM "writes" WHERE time >= 1699956800000ms and time <= 1700000000000ms GROUP BY time(5m)`,
+			5 * time.Minute,
+		},
+		{
+			providers.ClickHouse, "clickhouse-prod",
+			`SELECT toStartOfInterval(ts, INTERVAL 30 second) t, count() FROM events WHERE ts >= toDateTime(1699956800) AND ts <= toDateTime(1700000000) AND job IN ('api','web') GROUP BY t`,
+			30 * time.Second,
+		},
+	}
+	for i, test := range tests {
+		if q[i].Provider != test.provider {
+			t.Errorf("%d: expected %s got %s", i, test.provider, q[i].Provider)
+		}
+		if q[i].Datasource != test.datasource {
+			t.Errorf("%d: expected %s got %s", i, test.datasource, q[i].Datasource)
+		}
+		if q[i].Statement != test.statement {
+			t.Errorf("%d: expected\n%s\ngot\n%s", i, test.statement, q[i].Statement)
+		}
+		if q[i].Step != test.step {
+			t.Errorf("%d: expected %s got %s", i, test.step, q[i].Step)
+		}
+		if !q[i].Start.Equal(start) || !q[i].End.Equal(now) {
+			t.Errorf("%d: unexpected range %s - %s", i, q[i].Start, q[i].End)
+		}
+	}
+}
+
+func TestExpandOptions(t *testing.T) {
+	d, err := Load("testdata/dashboard.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := d.Expand(&Options{
+		Ranges:    []time.Duration{time.Hour, 24 * time.Hour},
+		Variables: map[string][]string{"job": {"db"}},
+	})
+	if len(q) != 6 {
+		t.Fatalf("expected %d got %d", 6, len(q))
+	}
+	const expected = `sum(rate(http_requests_total{job=~"db",instance=~"(host-1|host-2)"}[1m]))`
+	if q[0].Statement != expected {
+		t.Errorf("expected %s got %s", expected, q[0].Statement)
+	}
+	if q[1].End.Sub(q[1].Start) != 24*time.Hour {
+		t.Errorf("expected %s got %s", 24*time.Hour, q[1].End.Sub(q[1].Start))
+	}
+	if q[1].Step != time.Minute {
+		t.Errorf("expected %s got %s", time.Minute, q[1].Step)
+	}
+}
+
+func TestDefaultRange(t *testing.T) {
+	tests := []struct {
+		from     string
+		expected time.Duration
+	}{
+		{"now-1h", time.Hour},
+		{"now-7d", 7 * 24 * time.Hour},
+		{"now/d", DefaultRange},
+		{"", DefaultRange},
+	}
+	for _, test := range tests {
+		d := &Dashboard{Time: TimeRange{From: test.from}}
+		if v := d.defaultRange(); v != test.expected {
+			t.Errorf("%s: expected %s got %s", test.from, test.expected, v)
+		}
+	}
+}
+
+func TestPanelStep(t *testing.T) {
+	o := &Options{}
+	tests := []struct {
+		panel    *Panel
+		target   *Target
+		rng      time.Duration
+		expected time.Duration
+	}{
+		{&Panel{}, &Target{}, time.Hour, 15 * time.Second},
+		{&Panel{}, &Target{}, 7 * 24 * time.Hour, 10 * time.Minute},
+		{&Panel{Interval: "1m"}, &Target{}, time.Hour, time.Minute},
+		{&Panel{Interval: "1m"}, &Target{Interval: ">2m"}, time.Hour, 2 * time.Minute},
+		{&Panel{MaxDataPoints: 10}, &Target{}, time.Hour, 5 * time.Minute},
+	}
+	for i, test := range tests {
+		if v := test.panel.step(test.target, test.rng, o); v != test.expected {
+			t.Errorf("%d: expected %s got %s", i, test.expected, v)
+		}
+	}
+}
+
+func TestProviderForPlugin(t *testing.T) {
+	tests := map[string]string{
+		"prometheus":                       providers.Prometheus,
+		"influxdb":                         providers.InfluxDB,
+		"grafana-clickhouse-datasource":    providers.ClickHouse,
+		"vertamedia-clickhouse-datasource": providers.ClickHouse,
+		"loki":                             "",
+	}
+	for in, expected := range tests {
+		if v := providerForPlugin(in); v != expected {
+			t.Errorf("%s: expected %s got %s", in, expected, v)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+	"golang.org/x/sync/errgroup"
+)
+
+// Request returns the HTTP request for the Query. base is the URL of the
+// Trickster backend serving the Query's datasource (e.g.,
+// http://trickster:8480/prom1); any query parameters it includes, such as an
+// InfluxDB db, are carried into the request.
+func (q *Query) Request(ctx context.Context, base *url.URL) (*http.Request, error) {
+	u := *base
+	v := base.Query()
+	path := strings.TrimSuffix(u.Path, "/")
+	switch q.Provider {
+	case providers.Prometheus:
+		path += "/api/v1/query_range"
+		v.Set("query", q.Statement)
+		v.Set("start", strconv.FormatInt(q.Start.Unix(), 10))
+		v.Set("end", strconv.FormatInt(q.End.Unix(), 10))
+		v.Set("step", strconv.FormatFloat(q.Step.Seconds(), 'f', -1, 64))
+	case providers.InfluxDB:
+		path += "/query"
+		v.Set("q", q.Statement)
+		if !v.Has("epoch") {
+			v.Set("epoch", "ms")
+		}
+	case providers.ClickHouse:
+		path += "/"
+		v.Set("query", q.Statement)
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", q.Provider)
+	}
+	u.Path = path
+	u.RawPath = ""
+	u.RawQuery = v.Encode()
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+}
+
+// Run issues each request using the client, with at most concurrency requests
+// in flight. The returned errors correspond by index to reqs, and are nil for
+// requests that received a 2xx response. Response bodies are fully read so
+// Trickster completes its cache writes.
+func Run(ctx context.Context, client *http.Client, reqs []*http.Request,
+	concurrency int) []error {
+	errs := make([]error, len(reqs))
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var eg errgroup.Group
+	eg.SetLimit(concurrency)
+	for i, r := range reqs {
+		if ctx.Err() != nil {
+			errs[i] = ctx.Err()
+			continue
+		}
+		eg.Go(func() error {
+			errs[i] = do(client, r.WithContext(ctx))
+			return nil
+		})
+	}
+	eg.Wait()
+	return errs
+}
+
+func do(client *http.Client, r *http.Request) error {
+	resp, err := client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err = io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return nil
+}
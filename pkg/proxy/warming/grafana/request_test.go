@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends/providers"
+)
+
+func TestQueryRequest(t *testing.T) {
+	start, end := time.Unix(100, 0), time.Unix(3700, 0)
+	tests := []struct {
+		provider, base, expected string
+	}{
+		{
+			providers.Prometheus, "http://trickster:8480/prom1/",
+			"http://trickster:8480/prom1/api/v1/query_range?end=3700&query=up&start=100&step=15",
+		},
+		{
+			providers.InfluxDB, "http://trickster:8480/influx1?db=telegraf",
+			"http://trickster:8480/influx1/query?db=telegraf&epoch=ms&q=up",
+		},
+		{
+			providers.ClickHouse, "http://trickster:8480/ch1",
+			"http://trickster:8480/ch1/?query=up",
+		},
+	}
+	for _, test := range tests {
+		q := &Query{Provider: test.provider, Statement: "up", Start: start,
+			End: end, Step: 15 * time.Second}
+		base, _ := url.Parse(test.base)
+		r, err := q.Request(context.Background(), base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.URL.String() != test.expected {
+			t.Errorf("expected %s got %s", test.expected, r.URL.String())
+		}
+	}
+	q := &Query{Provider: "loki"}
+	if _, err := q.Request(context.Background(), &url.URL{}); err == nil {
+		t.Error("expected error for unsupported provider")
+	}
+}
+
+func TestRun(t *testing.T) {
+	var inflight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter,
+		r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer ts.Close()
+	reqs := make([]*http.Request, 8)
+	for i := range reqs {
+		u := ts.URL
+		if i == 3 {
+			u += "?fail=1"
+		}
+		reqs[i], _ = http.NewRequest(http.MethodGet, u, nil)
+	}
+	errs := Run(context.Background(), ts.Client(), reqs, 2)
+	for i, err := range errs {
+		if (i == 3) != (err != nil) {
+			t.Errorf("%d: unexpected error state: %v", i, err)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Errorf("expected at most %d concurrent requests, got %d", 2, p)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range Run(ctx, ts.Client(), reqs[:2], 0) {
+		if err == nil {
+			t.Error("expected error for canceled context")
+		}
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package options defines the configuration for timeseries cache warming.
+package options
+
+import (
+	"errors"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/config/types"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/util/pointers"
+
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// DefaultInterval is the default interval between warming scheduler cycles
+	DefaultInterval = 15 * time.Second
+	// DefaultLeadTime is the default amount of time before a query's expected
+	// next request at which the query is re-issued
+	DefaultLeadTime = 30 * time.Second
+	// DefaultMaxUpstreamQPS is the default upstream request budget for warming
+	DefaultMaxUpstreamQPS = 1.0
+	// DefaultMaxQueries is the default max number of queries warmed per cycle
+	DefaultMaxQueries = 50
+	// DefaultMaxTrackedQueries is the default max number of tracked queries
+	DefaultMaxTrackedQueries = 1000
+	// DefaultMinRequests is the default number of client requests a query must
+	// receive before it is eligible for warming
+	DefaultMinRequests = 3
+	// DefaultTrackingTTL is the default duration a tracked query is retained
+	// after its most recent client request
+	DefaultTrackingTTL = 48 * time.Hour
+)
+
+var (
+	// ErrInvalidInterval is an error for an invalid warming interval
+	ErrInvalidInterval = errors.New("warming interval must be greater than 0")
+	// ErrInvalidLeadTime is an error for an invalid warming lead time
+	ErrInvalidLeadTime = errors.New("warming lead_time must be greater than or equal to 0")
+	// ErrInvalidMaxUpstreamQPS is an error for an invalid warming QPS budget
+	ErrInvalidMaxUpstreamQPS = errors.New("warming max_upstream_qps must be greater than 0")
+	// ErrInvalidMaxQueries is an error for an invalid warming max queries value
+	ErrInvalidMaxQueries = errors.New("warming max_queries must be greater than 0")
+	// ErrInvalidMaxTrackedQueries is an error for an invalid warming max tracked queries value
+	ErrInvalidMaxTrackedQueries = errors.New("warming max_tracked_queries must be greater than or equal to max_queries")
+)
+
+// Options defines the Cache Warming Options for a Backend
+type Options struct {
+	// Interval defines how often the scheduler evaluates tracked queries for warming
+	Interval timeconv.Duration `yaml:"interval,omitempty"`
+	// LeadTime defines how long before a query's expected next client request
+	// that the scheduler should re-issue it
+	LeadTime timeconv.Duration `yaml:"lead_time,omitempty"`
+	// MaxUpstreamQPS is the maximum rate at which warming requests are issued
+	MaxUpstreamQPS float64 `yaml:"max_upstream_qps,omitempty"`
+	// MaxQueries is the maximum number of queries warmed in a single cycle,
+	// selected by descending request frequency
+	MaxQueries int `yaml:"max_queries,omitempty"`
+	// MaxTrackedQueries is the maximum number of distinct queries tracked
+	MaxTrackedQueries int `yaml:"max_tracked_queries,omitempty"`
+	// MinRequests is the number of client requests a query must receive before
+	// it is eligible for warming
+	MinRequests int `yaml:"min_requests,omitempty"`
+	// TrackingTTL is how long a query remains tracked after its most recent client request
+	TrackingTTL timeconv.Duration `yaml:"tracking_ttl,omitempty"`
+}
+
+var _ types.ConfigOptions[Options] = &Options{}
+
+// New returns a new Warming Options with default values
+func New() *Options {
+	return &Options{
+		Interval:          timeconv.Duration(DefaultInterval),
+		LeadTime:          timeconv.Duration(DefaultLeadTime),
+		MaxUpstreamQPS:    DefaultMaxUpstreamQPS,
+		MaxQueries:        DefaultMaxQueries,
+		MaxTrackedQueries: DefaultMaxTrackedQueries,
+		MinRequests:       DefaultMinRequests,
+		TrackingTTL:       timeconv.Duration(DefaultTrackingTTL),
+	}
+}
+
+// Clone returns an exact copy of the Options
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+	return pointers.Clone(o)
+}
+
+// Initialize applies defaults to unset values
+func (o *Options) Initialize(_ string) error {
+	if o == nil {
+		return nil
+	}
+	if o.MinRequests < 1 {
+		o.MinRequests = 1
+	}
+	if o.TrackingTTL <= 0 {
+		o.TrackingTTL = timeconv.Duration(DefaultTrackingTTL)
+	}
+	return nil
+}
+
+// Validate validates the Options
+func (o *Options) Validate() (bool, error) {
+	if o == nil {
+		return true, nil
+	}
+	if o.Interval <= 0 {
+		return false, ErrInvalidInterval
+	}
+	if o.LeadTime < 0 {
+		return false, ErrInvalidLeadTime
+	}
+	if o.MaxUpstreamQPS <= 0 {
+		return false, ErrInvalidMaxUpstreamQPS
+	}
+	if o.MaxQueries <= 0 {
+		return false, ErrInvalidMaxQueries
+	}
+	if o.MaxTrackedQueries < o.MaxQueries {
+		return false, ErrInvalidMaxTrackedQueries
+	}
+	return true, nil
+}
+
+// UnmarshalYAML applies defaults before decoding a Warming configuration block.
+func (o *Options) UnmarshalYAML(value *yaml.Node) error {
+	type loadOptions Options
+	lo := loadOptions(*(New()))
+	if err := value.Decode(&lo); err != nil {
+		return err
+	}
+	*o = Options(lo)
+	return nil
+}
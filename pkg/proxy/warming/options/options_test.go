@@ -0,0 +1,93 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package options
+
+import (
+	"testing"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+
+	"go.yaml.in/yaml/v3"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mod     func(*Options)
+		wantErr error
+	}{
+		{name: "defaults", mod: func(*Options) {}},
+		{name: "interval", mod: func(o *Options) { o.Interval = 0 }, wantErr: ErrInvalidInterval},
+		{name: "lead time", mod: func(o *Options) { o.LeadTime = -1 }, wantErr: ErrInvalidLeadTime},
+		{name: "qps", mod: func(o *Options) { o.MaxUpstreamQPS = 0 }, wantErr: ErrInvalidMaxUpstreamQPS},
+		{name: "max queries", mod: func(o *Options) { o.MaxQueries = 0 }, wantErr: ErrInvalidMaxQueries},
+		{name: "max tracked", mod: func(o *Options) { o.MaxTrackedQueries = 1 },
+			wantErr: ErrInvalidMaxTrackedQueries},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := New()
+			tc.mod(o)
+			_, err := o.Validate()
+			if err != tc.wantErr {
+				t.Errorf("expected %v got %v", tc.wantErr, err)
+			}
+		})
+	}
+	var o *Options
+	if _, err := o.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOptionsInitialize(t *testing.T) {
+	o := &Options{}
+	if err := o.Initialize(""); err != nil {
+		t.Fatal(err)
+	}
+	if o.MinRequests != 1 {
+		t.Errorf("expected 1 got %d", o.MinRequests)
+	}
+	if o.TrackingTTL != timeconv.Duration(DefaultTrackingTTL) {
+		t.Errorf("expected %v got %v", DefaultTrackingTTL, o.TrackingTTL)
+	}
+}
+
+func TestOptionsUnmarshalAndClone(t *testing.T) {
+	o := &Options{}
+	if err := yaml.Unmarshal([]byte("lead_time: 1m\nmax_upstream_qps: 5\n"), o); err != nil {
+		t.Fatal(err)
+	}
+	if time.Duration(o.LeadTime) != time.Minute {
+		t.Errorf("expected 1m got %v", o.LeadTime)
+	}
+	if o.MaxUpstreamQPS != 5 {
+		t.Errorf("expected 5 got %v", o.MaxUpstreamQPS)
+	}
+	if o.MaxQueries != DefaultMaxQueries {
+		t.Errorf("expected default max queries got %d", o.MaxQueries)
+	}
+	c := o.Clone()
+	if c == o || *c != *o {
+		t.Error("clone mismatch")
+	}
+	var n *Options
+	if n.Clone() != nil {
+		t.Error("expected nil clone")
+	}
+}
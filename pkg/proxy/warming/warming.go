@@ -0,0 +1,456 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package warming tracks the most frequently requested timeseries queries
+// for each backend and periodically re-issues them in the background, shortly
+// before clients typically request them again, so the latest extents are
+// already cached when the clients arrive.
+package warming
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends"
+	"github.com/trickstercache/trickster/v2/pkg/cache/status"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	ao "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/retry"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/warming/options"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/util/safego"
+)
+
+// minObservedInterval is the smallest gap between two client requests for the
+// same query that contributes to its expected request interval. Closer
+// requests are treated as the same load (e.g., several users opening the same
+// dashboard at once).
+const minObservedInterval = time.Second
+
+// maxFailureBackoff is the longest a query is held back from warming after
+// consecutive failed warms, such as while the origin is unavailable
+const maxFailureBackoff = 10 * time.Minute
+
+// DispatchFunc handles a warming request exactly as it would a client request
+type DispatchFunc func(http.ResponseWriter, *http.Request)
+
+// Warmer tracks and warms popular timeseries queries for a single Backend
+type Warmer struct {
+	name    string
+	options *options.Options
+
+	mtx     sync.Mutex
+	entries map[string]*entry
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	// now is swapped out in tests
+	now func() time.Time
+}
+
+type entry struct {
+	key        string
+	req        *http.Request
+	body       []byte
+	rsc        *request.Resources
+	trq        *timeseries.TimeRangeQuery
+	dispatch   DispatchFunc
+	duration   time.Duration
+	count      int
+	lastSeen   time.Time
+	interval   time.Duration
+	lastWarmed time.Time
+	warmed     bool
+	// failures is the number of consecutive failed warms, which hold the
+	// entry back from warming until retryAt
+	failures int
+	retryAt  time.Time
+}
+
+// dueAt returns the time at which the entry should next be warmed
+func (e *entry) dueAt(lead time.Duration) time.Time {
+	return e.lastSeen.Add(e.interval - lead)
+}
+
+// New returns a new Warmer for the named Backend
+func New(name string, o *options.Options) *Warmer {
+	return &Warmer{
+		name:    name,
+		options: o,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// Observe records a client request for the timeseries query identified by key,
+// so that it can be considered for warming. r must be the request as handed to
+// dispatch, and trq its parsed TimeRangeQuery. The request is only retained
+// once the query has been requested often enough to be a warming candidate.
+func (w *Warmer) Observe(r *http.Request, trq *timeseries.TimeRangeQuery,
+	key string, cacheStatus status.LookupStatus, dispatch DispatchFunc,
+) {
+	if w == nil || r == nil || trq == nil || dispatch == nil ||
+		IsWarmingRequest(r.Context()) {
+		return
+	}
+	rsc := request.GetResources(r)
+	if rsc == nil || rsc.IsMergeMember {
+		return
+	}
+	e := w.track(key, cacheStatus)
+	if e == nil {
+		return
+	}
+
+	body, _ := request.GetBody(r)
+	req, err := request.CloneWithoutResources(r)
+	if err != nil {
+		return
+	}
+	// a warming request must never be treated as a cache purge
+	req.Header.Del(headers.NameCacheControl)
+	req.Header.Del(headers.NamePragma)
+	// client credentials are not retained, since the request is replayed
+	// long after the client's credentials may have been revoked
+	stripCredentials(req, rsc)
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	e.req = req
+	e.body = body
+	e.rsc = rsc.Clone()
+	e.trq = trq.Clone()
+	e.dispatch = dispatch
+	e.duration = trq.Extent.End.Sub(trq.Extent.Start)
+}
+
+// stripCredentials removes client credentials from a request retained for
+// warming, including those read by the path's or backend's Authenticator
+func stripCredentials(r *http.Request, rsc *request.Resources) {
+	headers.StripCredentials(r.Header)
+	var aos []*ao.Options
+	if rsc.PathConfig != nil {
+		aos = append(aos, rsc.PathConfig.AuthOptions)
+	}
+	if rsc.BackendOptions != nil {
+		aos = append(aos, rsc.BackendOptions.AuthOptions)
+	}
+	for _, o := range aos {
+		if o == nil {
+			continue
+		}
+		if cr, ok := o.Authenticator.(types.CredentialRemover); ok {
+			cr.RemoveCredentials(r)
+		}
+	}
+}
+
+// track records a client request for the query identified by key, and returns
+// its entry if the query is a warming candidate, or nil otherwise
+func (w *Warmer) track(key string, cacheStatus status.LookupStatus) *entry {
+	now := w.now()
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	e, ok := w.entries[key]
+	if !ok {
+		if len(w.entries) >= w.options.MaxTrackedQueries {
+			w.evictOldest()
+		}
+		e = &entry{key: key}
+		w.entries[key] = e
+		metrics.WarmingTrackedQueries.WithLabelValues(w.name).Set(float64(len(w.entries)))
+	} else {
+		metrics.WarmingClientRequests.WithLabelValues(w.name,
+			strconv.FormatBool(e.warmed), cacheStatus.String()).Inc()
+		if gap := now.Sub(e.lastSeen); gap >= minObservedInterval {
+			if e.interval == 0 {
+				e.interval = gap
+			} else {
+				// exponentially-weighted, so the expected interval follows
+				// changes in client behavior without being thrown by outliers
+				e.interval = (e.interval*3 + gap) / 4
+			}
+		}
+	}
+	e.count++
+	e.lastSeen = now
+	e.warmed = false
+	if e.count < w.options.MinRequests ||
+		e.interval <= time.Duration(w.options.LeadTime) {
+		return nil
+	}
+	return e
+}
+
+// evictOldest removes the least-recently requested entry. The caller must hold the lock.
+func (w *Warmer) evictOldest() {
+	var oldest *entry
+	for _, e := range w.entries {
+		if oldest == nil || e.lastSeen.Before(oldest.lastSeen) {
+			oldest = e
+		}
+	}
+	if oldest != nil {
+		delete(w.entries, oldest.key)
+	}
+}
+
+// candidates prunes expired entries and returns those due for warming,
+// ordered by descending request count
+func (w *Warmer) candidates(now time.Time) []*entry {
+	ttl := time.Duration(w.options.TrackingTTL)
+	lead := time.Duration(w.options.LeadTime)
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	out := make([]*entry, 0, len(w.entries))
+	for k, e := range w.entries {
+		if now.Sub(e.lastSeen) > ttl {
+			delete(w.entries, k)
+			continue
+		}
+		if e.req == nil || e.warmed || e.count < w.options.MinRequests ||
+			e.interval <= lead || now.Before(e.dueAt(lead)) ||
+			now.Before(e.retryAt) {
+			continue
+		}
+		out = append(out, e)
+	}
+	metrics.WarmingTrackedQueries.WithLabelValues(w.name).Set(float64(len(w.entries)))
+	slices.SortFunc(out, func(a, b *entry) int {
+		if a.count != b.count {
+			return b.count - a.count
+		}
+		return a.dueAt(lead).Compare(b.dueAt(lead))
+	})
+	return out
+}
+
+// Start starts the Warmer's background scheduler
+func (w *Warmer) Start() {
+	if w == nil || w.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.done = make(chan struct{})
+	safego.Go(func(r any, stack []byte) {
+		logger.Error("cache warming goroutine panic", logging.Pairs{
+			"backendName": w.name,
+			"panic":       r,
+			"stack":       string(stack),
+		})
+	}, func() {
+		defer close(w.done)
+		w.run(ctx)
+	})
+}
+
+// Stop stops the Warmer's background scheduler and waits for it to exit
+func (w *Warmer) Stop() {
+	if w == nil || w.cancel == nil {
+		return
+	}
+	w.cancel()
+	<-w.done
+	w.cancel = nil
+	metrics.WarmingTrackedQueries.DeleteLabelValues(w.name)
+}
+
+func (w *Warmer) run(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(w.options.Interval))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.cycle(ctx)
+		}
+	}
+}
+
+// cycle warms the due candidates, sequentially and paced by the QPS budget.
+// Candidates that don't fit in the cycle's budget are deferred to the next.
+func (w *Warmer) cycle(ctx context.Context) {
+	start := w.now()
+	c := w.candidates(start)
+	if len(c) == 0 {
+		return
+	}
+	spacing := time.Duration(float64(time.Second) / w.options.MaxUpstreamQPS)
+	budget := int(time.Duration(w.options.Interval) / spacing)
+	budget = max(budget, 1)
+	budget = min(budget, w.options.MaxQueries)
+	if len(c) > budget {
+		metrics.WarmingDeferrals.WithLabelValues(w.name).Add(float64(len(c) - budget))
+		c = c[:budget]
+	}
+	for i, e := range c {
+		if i > 0 {
+			t := time.NewTimer(spacing)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+		w.warm(ctx, e)
+	}
+}
+
+// warm re-issues the entry's query, shifted to end at the current time
+func (w *Warmer) warm(ctx context.Context, e *entry) {
+	w.mtx.Lock()
+	req := e.req.Clone(WithWarmingFlag(ctx))
+	body := e.body
+	rsc := e.rsc.Clone()
+	trq := e.trq.Clone()
+	dispatch := e.dispatch
+	d := e.duration
+	w.mtx.Unlock()
+
+	rsc.TS = nil
+	rsc.Response = nil
+	req = request.SetResources(req, rsc)
+	if len(body) > 0 {
+		request.SetBody(req, body)
+	}
+	now := w.now()
+	ext := &timeseries.Extent{Start: now.Add(-d), End: now}
+	if ts, ok := rsc.BackendClient.(backends.TimeseriesBackend); ok {
+		if err := ts.SetExtent(req, trq, ext); err != nil {
+			w.failed(e, now)
+			logger.Debug("could not rewrite cache warming request",
+				logging.Pairs{"backendName": w.name, "error": err.Error()})
+			return
+		}
+	}
+	rw := &discardWriter{h: make(http.Header)}
+	dispatch(rw, req)
+	if rw.code != 0 && rw.code != http.StatusOK {
+		w.failed(e, now)
+		logger.Debug("cache warming request failed",
+			logging.Pairs{"backendName": w.name, "statusCode": rw.code})
+		return
+	}
+	metrics.WarmingRequests.WithLabelValues(w.name, "success").Inc()
+	w.mtx.Lock()
+	e.warmed = true
+	e.lastWarmed = now
+	e.failures = 0
+	e.retryAt = time.Time{}
+	w.mtx.Unlock()
+}
+
+// failed records a failed warm of the entry, and holds it back from warming
+// for an exponentially increasing backoff
+func (w *Warmer) failed(e *entry, now time.Time) {
+	metrics.WarmingRequests.WithLabelValues(w.name, "error").Inc()
+	w.mtx.Lock()
+	e.failures++
+	e.retryAt = now.Add(retry.Backoff(e.failures,
+		time.Duration(w.options.Interval), maxFailureBackoff))
+	w.mtx.Unlock()
+}
+
+// Len returns the number of tracked queries
+func (w *Warmer) Len() int {
+	if w == nil {
+		return 0
+	}
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return len(w.entries)
+}
+
+// discardWriter is an http.ResponseWriter that records the status code and
+// discards the body of a warming response
+type discardWriter struct {
+	h    http.Header
+	code int
+}
+
+func (d *discardWriter) Header() http.Header {
+	return d.h
+}
+
+func (d *discardWriter) Write(b []byte) (int, error) {
+	if d.code == 0 {
+		d.code = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (d *discardWriter) WriteHeader(code int) {
+	if d.code == 0 {
+		d.code = code
+	}
+}
+
+// Lookup is a map of Warmers keyed by Backend name
+type Lookup map[string]*Warmer
+
+var active atomic.Pointer[Lookup]
+
+// StartAll creates and starts a Warmer for each Backend with warming
+// configured, and makes them the active set used by Get.
+func StartAll(clients backends.Backends) Lookup {
+	out := make(Lookup)
+	for k, c := range clients {
+		if c == nil {
+			continue
+		}
+		o := c.Configuration()
+		if o == nil || o.Warming == nil || o.ProxyOnly {
+			continue
+		}
+		if _, ok := c.(backends.TimeseriesBackend); !ok {
+			continue
+		}
+		w := New(k, o.Warming)
+		w.Start()
+		out[k] = w
+	}
+	active.Store(&out)
+	return out
+}
+
+// Stop stops all Warmers in the Lookup
+func (l Lookup) Stop() {
+	for _, w := range l {
+		w.Stop()
+	}
+}
+
+// Get returns the active Warmer for the named Backend, or nil if there is none
+func Get(backendName string) *Warmer {
+	l := active.Load()
+	if l == nil {
+		return nil
+	}
+	return (*l)[backendName]
+}
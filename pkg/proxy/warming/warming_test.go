@@ -0,0 +1,252 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package warming
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/cache/status"
+	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/warming/options"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func testWarmer(t *testing.T) (*Warmer, *clock) {
+	t.Helper()
+	o := options.New()
+	o.MinRequests = 2
+	o.LeadTime = timeconv.Duration(10 * time.Second)
+	o.MaxUpstreamQPS = 1000
+	if err := o.Initialize(""); err != nil {
+		t.Fatal(err)
+	}
+	c := &clock{t: time.Unix(1700000000, 0)}
+	w := New("test", o)
+	w.now = c.now
+	return w, c
+}
+
+func testRequest(method string) (*http.Request, *timeseries.TimeRangeQuery) {
+	r := httptest.NewRequest(method,
+		"http://0/api/v1/query_range?query=up&start=1&end=3601&step=15", nil)
+	r.Header.Set("Cache-Control", "no-cache")
+	rsc := request.NewResources(bo.New(), nil, nil, nil, nil, nil)
+	r = request.SetResources(r, rsc)
+	trq := &timeseries.TimeRangeQuery{
+		Statement: "up",
+		Extent:    timeseries.Extent{Start: time.Unix(1, 0), End: time.Unix(3601, 0)},
+		Step:      15 * time.Second,
+	}
+	return r, trq
+}
+
+func TestObserveAndCandidates(t *testing.T) {
+	w, c := testWarmer(t)
+	var calls int
+	dispatch := func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		if !IsWarmingRequest(r.Context()) {
+			t.Error("expected warming flag in dispatched request context")
+		}
+		if r.Header.Get("Cache-Control") != "" {
+			t.Error("expected Cache-Control to be stripped")
+		}
+		if request.GetResources(r) == nil {
+			t.Error("expected resources in dispatched request context")
+		}
+		rw.WriteHeader(http.StatusOK)
+	}
+	r, trq := testRequest(http.MethodGet)
+	w.Observe(r, trq, "key1", status.LookupStatusKeyMiss, dispatch)
+	if w.Len() != 1 {
+		t.Fatalf("expected 1 tracked query, got %d", w.Len())
+	}
+	// the request is not retained until the query is a warming candidate
+	if w.entries["key1"].req != nil {
+		t.Error("expected request not to be retained")
+	}
+	// only one request observed, so not yet eligible
+	c.t = c.t.Add(time.Minute)
+	if n := len(w.candidates(c.t)); n != 0 {
+		t.Fatalf("expected 0 candidates, got %d", n)
+	}
+	w.Observe(r, trq, "key1", status.LookupStatusPartialHit, dispatch)
+	// the expected interval is now 1m, so the query is due 50s after the last request
+	c.t = c.t.Add(40 * time.Second)
+	if n := len(w.candidates(c.t)); n != 0 {
+		t.Fatalf("expected 0 candidates before due time, got %d", n)
+	}
+	c.t = c.t.Add(15 * time.Second)
+	if n := len(w.candidates(c.t)); n != 1 {
+		t.Fatalf("expected 1 candidate after due time, got %d", n)
+	}
+	w.cycle(context.Background())
+	if calls != 1 {
+		t.Fatalf("expected 1 dispatch, got %d", calls)
+	}
+	// a warmed query is not warmed again until it is requested again
+	if n := len(w.candidates(c.t)); n != 0 {
+		t.Fatalf("expected 0 candidates after warming, got %d", n)
+	}
+	// warming requests are never tracked
+	wr := r.WithContext(WithWarmingFlag(r.Context()))
+	w.Observe(wr, trq, "key2", status.LookupStatusKeyMiss, dispatch)
+	if w.Len() != 1 {
+		t.Fatalf("expected 1 tracked query, got %d", w.Len())
+	}
+	// expired queries are pruned
+	c.t = c.t.Add(time.Duration(w.options.TrackingTTL) + time.Second)
+	w.candidates(c.t)
+	if w.Len() != 0 {
+		t.Fatalf("expected 0 tracked queries, got %d", w.Len())
+	}
+}
+
+func TestObserveEvictsOldest(t *testing.T) {
+	w, c := testWarmer(t)
+	w.options.MaxTrackedQueries = 2
+	dispatch := func(http.ResponseWriter, *http.Request) {}
+	r, trq := testRequest(http.MethodGet)
+	for _, k := range []string{"a", "b", "c"} {
+		w.Observe(r, trq, k, status.LookupStatusKeyMiss, dispatch)
+		c.t = c.t.Add(time.Second)
+	}
+	if w.Len() != 2 {
+		t.Fatalf("expected 2 tracked queries, got %d", w.Len())
+	}
+	if _, ok := w.entries["a"]; ok {
+		t.Error("expected oldest entry to be evicted")
+	}
+}
+
+func TestObserveStripsCredentials(t *testing.T) {
+	w, c := testWarmer(t)
+	dispatch := func(http.ResponseWriter, *http.Request) {}
+	r, trq := testRequest(http.MethodGet)
+	creds := []string{"Authorization", "Proxy-Authorization", "Cookie", "X-API-Key"}
+	for _, h := range creds {
+		r.Header.Set(h, "secret")
+	}
+	r.Header.Set("Accept", "application/json")
+	w.Observe(r, trq, "key1", status.LookupStatusKeyMiss, dispatch)
+	c.t = c.t.Add(time.Minute)
+	w.Observe(r, trq, "key1", status.LookupStatusPartialHit, dispatch)
+	e := w.entries["key1"]
+	if e == nil || e.req == nil {
+		t.Fatal("expected request to be retained")
+	}
+	for _, h := range creds {
+		if v := e.req.Header.Get(h); v != "" {
+			t.Errorf("expected %s to be stripped, got %q", h, v)
+		}
+		if r.Header.Get(h) == "" {
+			t.Errorf("expected %s to remain on the client request", h)
+		}
+	}
+	if e.req.Header.Get("Accept") == "" {
+		t.Error("expected non-credential headers to be retained")
+	}
+}
+
+func TestCycleBudget(t *testing.T) {
+	w, c := testWarmer(t)
+	w.options.MaxQueries = 2
+	var calls int
+	dispatch := func(rw http.ResponseWriter, _ *http.Request) {
+		calls++
+		rw.WriteHeader(http.StatusBadGateway)
+	}
+	r, trq := testRequest(http.MethodGet)
+	for _, k := range []string{"a", "b", "c"} {
+		w.Observe(r, trq, k, status.LookupStatusKeyMiss, dispatch)
+	}
+	c.t = c.t.Add(time.Minute)
+	for _, k := range []string{"a", "b", "c"} {
+		w.Observe(r, trq, k, status.LookupStatusKeyMiss, dispatch)
+	}
+	c.t = c.t.Add(time.Minute)
+	w.cycle(context.Background())
+	if calls != 2 {
+		t.Fatalf("expected 2 dispatches, got %d", calls)
+	}
+	// failed warms are backed off, leaving only the deferred candidate
+	if n := len(w.candidates(c.t)); n != 1 {
+		t.Fatalf("expected 1 candidate, got %d", n)
+	}
+	c.t = c.t.Add(time.Duration(w.options.Interval))
+	if n := len(w.candidates(c.t)); n != 3 {
+		t.Fatalf("expected 3 candidates, got %d", n)
+	}
+	w.options.MaxQueries = 3
+	w.cycle(context.Background())
+	if calls != 5 {
+		t.Fatalf("expected 5 dispatches, got %d", calls)
+	}
+	// the backoff doubles with consecutive failures
+	var twice int
+	for _, e := range w.entries {
+		if e.failures == 2 {
+			twice++
+		}
+	}
+	if twice != 2 {
+		t.Fatalf("expected 2 queries with 2 failures, got %d", twice)
+	}
+	c.t = c.t.Add(time.Duration(w.options.Interval))
+	if n := len(w.candidates(c.t)); n != 1 {
+		t.Fatalf("expected 1 candidate, got %d", n)
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	w, _ := testWarmer(t)
+	w.Start()
+	w.Start() // no-op when already started
+	w.Stop()
+	w.Stop() // no-op when already stopped
+	var nw *Warmer
+	nw.Start()
+	nw.Stop()
+	if nw.Len() != 0 {
+		t.Error("expected 0")
+	}
+}
+
+func TestStartAllAndGet(t *testing.T) {
+	l := StartAll(nil)
+	defer l.Stop()
+	if len(l) != 0 {
+		t.Fatalf("expected empty lookup, got %d", len(l))
+	}
+	if Get("test") != nil {
+		t.Error("expected nil warmer")
+	}
+}
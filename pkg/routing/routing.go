@@ -37,6 +37,7 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
 	"github.com/trickstercache/trickster/v2/pkg/observability/tracing"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/handler"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/authorization"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/health"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/methods"
@@ -62,6 +63,16 @@ func attachAuthenticator(h http.Handler, pathOptions *po.Options, backendOptions
 	return h
 }
 
+// attachAuthorizer attaches authorization middleware to the handler when the
+// backend is governed by an authorization policy
+func attachAuthorizer(h http.Handler, conf *config.Config, backendOptions *bo.Options) http.Handler {
+	if conf == nil || conf.Authorization == nil {
+		return h
+	}
+	return authorization.Middleware(conf.Authorization, backendOptions.Name,
+		backendOptions.Provider, h)
+}
+
 // RegisterProxyRoutes iterates the Trickster Configuration and
 // registers the routes for the configured backends
 func RegisterProxyRoutes(conf *config.Config, clients backends.Backends,
@@ -215,6 +226,12 @@ func registerBackendRoutes(r router.Router, metricsRouter router.Router,
 
 		o.Paths = client.DefaultPathConfigs(o).Overlay(o.Paths)
 
+		if o.Shadow != nil {
+			if sc, ok := clients[o.Shadow.Backend]; ok && sc != nil {
+				o.Shadow.Handler = sc.Router()
+			}
+		}
+
 		h := client.Handlers()
 
 		RegisterPathRoutes(r, conf, h, client, o, c, tracers)
@@ -264,6 +281,13 @@ func RegisterPathRoutes(r router.Router, conf *config.Config, handlers handlers.
 		if tr != nil {
 			h = middleware.Trace(tr, h)
 		}
+		// attach shadow traffic mirroring (inner than the rate limiter so that
+		// only admitted requests are mirrored)
+		h = middleware.Shadow(h)
+		// attach rate limiter (must wrap inner than the authenticator to key by user)
+		h = middleware.RateLimit(h)
+		// attach authorizer (must wrap inner than the authenticator)
+		h = attachAuthorizer(h, conf, o)
 		// attach authenticator
 		h = attachAuthenticator(h, po1, o)
 		// attach compression handler
@@ -358,6 +382,13 @@ func registerDefaultBackendRoutes(routerFor func(*bo.Options) router.Router, con
 		if tr != nil {
 			h = middleware.Trace(tr, h)
 		}
+		// attach shadow traffic mirroring (inner than the rate limiter so that
+		// only admitted requests are mirrored)
+		h = middleware.Shadow(h)
+		// attach rate limiter (must wrap inner than the authenticator to key by user)
+		h = middleware.RateLimit(h)
+		// attach authorizer (must wrap inner than the authenticator)
+		h = attachAuthorizer(h, conf, o)
 		// attach authenticator
 		h = attachAuthenticator(h, po, o)
 		// add Backend, Cache, and Path Configs to the HTTP Request's context (must wrap outer than LimitQueryRange)
@@ -684,6 +684,23 @@ func (ds *DataSet) SeriesCount() int {
 	return cnt
 }
 
+// LimitSeries removes all but the first n Series across all Results in the
+// DataSet, and returns the number of Series removed
+func (ds *DataSet) LimitSeries(n int) int {
+	var removed int
+	for _, r := range ds.Results {
+		if r == nil {
+			continue
+		}
+		if len(r.SeriesList) > n {
+			removed += len(r.SeriesList) - n
+			r.SeriesList = r.SeriesList[:n]
+		}
+		n -= len(r.SeriesList)
+	}
+	return removed
+}
+
 // ValueCount returns the count of all values across all Series in the DataSet
 func (ds *DataSet) ValueCount() int64 {
 	var cnt int64
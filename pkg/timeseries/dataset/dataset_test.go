@@ -226,6 +226,24 @@ func TestSeriesCount(t *testing.T) {
 	}
 }
 
+func TestLimitSeries(t *testing.T) {
+	ds := &DataSet{Results: Results{
+		{SeriesList: SeriesList{&Series{}, &Series{}}},
+		nil,
+		{SeriesList: SeriesList{&Series{}, &Series{}}},
+	}}
+	if n := ds.LimitSeries(5); n != 0 || ds.SeriesCount() != 4 {
+		t.Errorf("expected 0 removed got %d", n)
+	}
+	if n := ds.LimitSeries(3); n != 1 || ds.SeriesCount() != 3 {
+		t.Errorf("expected 1 removed got %d", n)
+	}
+	if n := ds.LimitSeries(1); n != 2 || len(ds.Results[0].SeriesList) != 1 ||
+		len(ds.Results[2].SeriesList) != 0 {
+		t.Errorf("expected 2 removed got %d", n)
+	}
+}
+
 func TestMerge(t *testing.T) {
 	t.Run("empty dataset", func(t *testing.T) {
 		ds := &DataSet{}
@@ -0,0 +1,198 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/epoch"
+)
+
+// Diff describes the differences between an expected and an actual DataSet
+type Diff struct {
+	// ExpectedSeries is the number of series in the expected DataSet
+	ExpectedSeries int
+	// ActualSeries is the number of series in the actual DataSet
+	ActualSeries int
+	// MissingSeries is the number of expected series absent from the actual
+	// DataSet
+	MissingSeries int
+	// ExtraSeries is the number of actual series absent from the expected
+	// DataSet
+	ExtraSeries int
+	// MissingPoints is the number of points in expected series that are
+	// absent from the matching actual series
+	MissingPoints int
+	// ExtraPoints is the number of points in actual series that are absent
+	// from the matching expected series
+	ExtraPoints int
+	// ChangedValues is the number of points present in both DataSets whose
+	// values differ beyond the tolerance
+	ChangedValues int
+	// MissingEpochs is the sorted list of distinct timestamps of the
+	// MissingPoints
+	MissingEpochs []epoch.Epoch
+}
+
+// SeriesMismatch returns true if the DataSets have different series
+func (d *Diff) SeriesMismatch() bool {
+	return d.ExpectedSeries != d.ActualSeries || d.MissingSeries > 0 ||
+		d.ExtraSeries > 0
+}
+
+// PointMismatch returns true if the series present in both DataSets have
+// different points or values
+func (d *Diff) PointMismatch() bool {
+	return d.MissingPoints > 0 || d.ExtraPoints > 0 || d.ChangedValues > 0
+}
+
+// Equal returns true if the DataSets are equivalent
+func (d *Diff) Equal() bool {
+	return !d.SeriesMismatch() && !d.PointMismatch()
+}
+
+// Compare returns the differences between the expected and actual DataSets,
+// matching series by Result StatementID and Series Header hash, and points by
+// timestamp. Values match when they differ by no more than tolerance, either
+// absolutely or relative to the larger value
+func Compare(expected, actual *DataSet, tolerance float64) *Diff {
+	d := &Diff{}
+	if expected != nil {
+		d.ExpectedSeries = expected.SeriesCount()
+	}
+	if actual != nil {
+		d.ActualSeries = actual.SeriesCount()
+	}
+	lookup := make(SeriesLookup, d.ActualSeries)
+	if actual != nil {
+		for _, r := range actual.Results {
+			if r == nil {
+				continue
+			}
+			for _, s := range r.SeriesList {
+				if s != nil {
+					lookup[SeriesLookupKey{r.StatementID, s.Header.CalculateHash()}] = s
+				}
+			}
+		}
+	}
+	missing := make(map[epoch.Epoch]struct{})
+	if expected != nil {
+		for _, r := range expected.Results {
+			if r == nil {
+				continue
+			}
+			for _, s := range r.SeriesList {
+				if s == nil {
+					continue
+				}
+				k := SeriesLookupKey{r.StatementID, s.Header.CalculateHash()}
+				as, ok := lookup[k]
+				if !ok {
+					d.MissingSeries++
+					continue
+				}
+				delete(lookup, k)
+				d.comparePoints(s.Points, as.Points, tolerance, missing)
+			}
+		}
+	}
+	d.ExtraSeries = len(lookup)
+	if len(missing) > 0 {
+		d.MissingEpochs = make([]epoch.Epoch, 0, len(missing))
+		for e := range missing {
+			d.MissingEpochs = append(d.MissingEpochs, e)
+		}
+		slices.Sort(d.MissingEpochs)
+	}
+	return d
+}
+
+func (d *Diff) comparePoints(expected, actual Points, tolerance float64,
+	missing map[epoch.Epoch]struct{},
+) {
+	byEpoch := make(map[epoch.Epoch]Point, len(actual))
+	for _, p := range actual {
+		byEpoch[p.Epoch] = p
+	}
+	for _, p := range expected {
+		ap, ok := byEpoch[p.Epoch]
+		if !ok {
+			d.MissingPoints++
+			missing[p.Epoch] = struct{}{}
+			continue
+		}
+		delete(byEpoch, p.Epoch)
+		if !valuesMatch(p.Values, ap.Values, tolerance) {
+			d.ChangedValues++
+		}
+	}
+	d.ExtraPoints += len(byEpoch)
+}
+
+func valuesMatch(v1, v2 []any, tolerance float64) bool {
+	if len(v1) != len(v2) {
+		return false
+	}
+	for i := range v1 {
+		f1, ok1 := toFloat(v1[i])
+		f2, ok2 := toFloat(v2[i])
+		if ok1 && ok2 {
+			if !WithinTolerance(f1, f2, tolerance) {
+				return false
+			}
+			continue
+		}
+		if v1[i] != v2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WithinTolerance returns true if a and b differ by no more than tolerance,
+// either absolutely or relative to the larger of their magnitudes
+func WithinTolerance(a, b, tolerance float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	if a == b {
+		return true
+	}
+	diff := math.Abs(a - b)
+	return diff <= tolerance ||
+		diff <= tolerance*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataset
+
+import (
+	"math"
+	"testing"
+
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/epoch"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testDiffDataSet(series map[string][]string) *DataSet {
+	r := &Result{}
+	for name, values := range series {
+		s := &Series{
+			Header: SeriesHeader{Name: name, Tags: Tags{"job": name}},
+		}
+		for i, v := range values {
+			if v == "" {
+				continue
+			}
+			s.Points = append(s.Points, Point{
+				Epoch:  epoch.Epoch(i * 1000),
+				Values: []any{v},
+			})
+		}
+		r.SeriesList = append(r.SeriesList, s)
+	}
+	return &DataSet{Results: Results{r}}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name             string
+		expected, actual map[string][]string
+		want             Diff
+	}{
+		{
+			name:     "equal",
+			expected: map[string][]string{"a": {"1", "2"}, "b": {"3"}},
+			actual:   map[string][]string{"a": {"1", "2.0000001"}, "b": {"3"}},
+			want:     Diff{ExpectedSeries: 2, ActualSeries: 2},
+		},
+		{
+			name:     "points",
+			expected: map[string][]string{"a": {"1", "2", "NaN", "4"}},
+			actual:   map[string][]string{"a": {"1", "5", "", "", "9"}},
+			want: Diff{ExpectedSeries: 1, ActualSeries: 1, ChangedValues: 1,
+				MissingPoints: 2, ExtraPoints: 1,
+				MissingEpochs: []epoch.Epoch{2000, 3000}},
+		},
+		{
+			name:     "series",
+			expected: map[string][]string{"a": {"1"}, "b": {"1"}},
+			actual:   map[string][]string{"a": {"1"}, "c": {"1"}, "d": {"1"}},
+			want: Diff{ExpectedSeries: 2, ActualSeries: 3, MissingSeries: 1,
+				ExtraSeries: 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := Compare(testDiffDataSet(test.expected),
+				testDiffDataSet(test.actual), 0.001)
+			require.Equal(t, test.want, *d)
+			require.Equal(t, test.name == "equal", d.Equal())
+		})
+	}
+	d := Compare(nil, testDiffDataSet(map[string][]string{"a": {"1"}}), 0)
+	require.True(t, d.SeriesMismatch())
+	require.Equal(t, 1, d.ExtraSeries)
+}
+
+func TestWithinTolerance(t *testing.T) {
+	tests := []struct {
+		a, b, tolerance float64
+		want            bool
+	}{
+		{a: 1, b: 1, want: true},
+		{a: 1, b: 1.0005, tolerance: 0.001, want: true},
+		{a: 1, b: 1.01, tolerance: 0.001},
+		{a: 10000, b: 10005, tolerance: 0.001, want: true},
+		{a: 0, b: 0.0005, tolerance: 0.001, want: true},
+		{a: math.NaN(), b: math.NaN(), want: true},
+		{a: math.NaN(), b: 1},
+		{a: math.Inf(1), b: math.Inf(1), want: true},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.want, WithinTolerance(test.a, test.b, test.tolerance),
+			"%f %f %f", test.a, test.b, test.tolerance)
+	}
+}
@@ -21,25 +21,46 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	lo "github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
 	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 )
 
 // Decorate decorates a function in such a way that it captures both the
 // returned status and the time used to execute a request from the front end
-// perspective
+// perspective, and writes the request to the access log, if configured
 func Decorate(backendName, backendProvider, path string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		observer := &responseObserver{
-			w,
-			"2xx",
-			http.StatusOK,
+			ResponseWriter: w,
+			status:         "2xx",
+			code:           http.StatusOK,
 		}
 
 		n := time.Now()
 		next.ServeHTTP(observer, r)
+		elapsed := time.Since(n)
+
+		if a := logger.AccessLogger(); a != nil {
+			a.Log(lo.AccessKindClient, logging.Pairs{
+				"backendName":     backendName,
+				"backendProvider": backendProvider,
+				"path":            path,
+				"method":          r.Method,
+				"uri":             r.URL.RequestURI(),
+				"userAgent":       r.UserAgent(),
+				"clientIP":        headers.ClientIP(r, "both"),
+				"code":            observer.code,
+				"size":            int(observer.bytesWritten),
+				"durationMS":      int(elapsed.Milliseconds()),
+				"result":          observer.Header().Get(headers.NameTricksterResult),
+			})
+		}
 
 		metrics.FrontendRequestDuration.WithLabelValues(backendName, backendProvider,
-			r.Method, path, observer.status).Observe(time.Since(n).Seconds())
+			r.Method, path, observer.status).Observe(elapsed.Seconds())
 		metrics.FrontendRequestStatus.WithLabelValues(backendName, backendProvider,
 			r.Method, path, observer.status).Inc()
 		metrics.FrontendRequestWrittenBytes.WithLabelValues(backendName, backendProvider,
@@ -51,12 +72,14 @@ type responseObserver struct {
 	http.ResponseWriter
 
 	status       string
+	code         int
 	bytesWritten float64
 }
 
 func (w *responseObserver) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 	w.status = fmt.Sprintf("%dxx", statusCode/100)
+	w.code = statusCode
 }
 
 func (w *responseObserver) Write(b []byte) (int, error) {
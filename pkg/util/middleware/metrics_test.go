@@ -17,11 +17,16 @@
 package middleware
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	lo "github.com/trickstercache/trickster/v2/pkg/observability/logging/options"
 	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
 
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
@@ -120,3 +125,31 @@ func TestDecorate(t *testing.T) {
 		})
 	}
 }
+
+func TestDecorateAccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger.SetAccessLogger(logging.StreamAccessLogger(buf, lo.NewAccessLog()))
+	t.Cleanup(func() { logger.SetAccessLogger(nil) })
+	// trust the test request's peer so its forwarding headers are honored
+	tp, err := headers.ParseTrustedProxies([]string{"192.0.2.0/24"})
+	require.NoError(t, err)
+	headers.SetTrustedProxies(tp)
+	t.Cleanup(func() { headers.SetTrustedProxies(nil) })
+
+	h := Decorate("backend1", "providerA", "/testpath",
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(headers.NameTricksterResult, "engine=HTTPProxy; status=proxy-only")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}))
+	r := httptest.NewRequest(http.MethodGet, "/testpath?q=1", nil)
+	r.Header.Set("X-Forwarded-For", "10.0.0.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	out := buf.String()
+	for _, s := range []string{"kind=client", "backendName=backend1",
+		"clientIP=10.0.0.1", "code=404", "size=9", "path=/testpath",
+		"uri=/testpath?q=1", `result="engine=HTTPProxy; status=proxy-only"`} {
+		require.Contains(t, out, s)
+	}
+}
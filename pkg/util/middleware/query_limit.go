@@ -18,6 +18,7 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/trickstercache/trickster/v2/pkg/backends"
@@ -25,10 +26,14 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
 	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
 	tctx "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/querycost"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
 )
 
-// LimitQueryRange intercepts requests to enforce a maximum query time range if configured.
+// LimitQueryRange intercepts requests to enforce a maximum query time range and
+// number of points per series, if configured.
 func LimitQueryRange(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rsc, ok := tctx.Resources(r.Context()).(*request.Resources)
@@ -38,7 +43,9 @@ func LimitQueryRange(next http.Handler) http.Handler {
 		}
 
 		limit := time.Duration(rsc.BackendOptions.MaxQueryRange)
-		if limit <= 0 {
+		qc := rsc.BackendOptions.QueryCost
+		if limit <= 0 && (qc == nil || qc.MaxPoints <= 0) &&
+			rsc.BackendOptions.MaxPointsPerSeries <= 0 {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -47,16 +54,12 @@ func LimitQueryRange(next http.Handler) http.Handler {
 			trq, _, _, err := tsClient.ParseTimeRangeQuery(r)
 			if err == nil && trq != nil {
 				duration := trq.Extent.End.Sub(trq.Extent.Start)
-				if duration > limit {
+				if limit > 0 && duration > limit {
 					metrics.ProxyQueryRangeRejections.WithLabelValues(rsc.BackendOptions.Name).Inc()
-					clientIP := r.Header.Get("X-Forwarded-For")
-					if clientIP == "" {
-						clientIP = r.RemoteAddr
-					}
 					logger.Warn("query rejected due to max_query_range limit",
 						logging.Pairs{
 							"backendName": rsc.BackendOptions.Name,
-							"clientIP":    clientIP,
+							"clientIP":    headers.ClientIP(r, rsc.BackendOptions.ForwardedHeaders),
 							"path":        r.URL.Path,
 							"statement":   trq.Statement,
 							"start":       trq.Extent.Start.String(),
@@ -67,9 +70,75 @@ func LimitQueryRange(next http.Handler) http.Handler {
 					http.Error(w, "query time range exceeds the allowed limit of "+limit.String(), http.StatusBadRequest)
 					return
 				}
+				clampQueryStep(w, r, rsc, tsClient, trq)
+				if !limitQueryPoints(w, r, rsc, tsClient, trq) {
+					return
+				}
 			}
 		}
 
 		next.ServeHTTP(w, r)
 	})
 }
+
+// clampQueryStep raises the query's step to the smallest standard step that
+// satisfies max_points_per_series, if configured, and reports the effective
+// step in the response headers.
+func clampQueryStep(w http.ResponseWriter, r *http.Request, rsc *request.Resources,
+	client backends.TimeseriesBackend, trq *timeseries.TimeRangeQuery,
+) {
+	maxPoints := int64(rsc.BackendOptions.MaxPointsPerSeries)
+	ss, ok := client.(backends.StepSetter)
+	if !ok || maxPoints <= 0 {
+		return
+	}
+	step, ok := querycost.ClampStep(trq, maxPoints)
+	if !ok {
+		return
+	}
+	if err := ss.SetStep(r, trq, step); err != nil {
+		logger.Error("could not rewrite query step",
+			logging.Pairs{"error": err.Error(), "backend": client.Name()})
+		return
+	}
+	logger.Debug("query step raised due to max_points_per_series limit",
+		logging.Pairs{
+			"backendName": rsc.BackendOptions.Name,
+			"path":        r.URL.Path,
+			"statement":   trq.Statement,
+			"step":        trq.Step.String(),
+			"effective":   step.String(),
+			"limit":       maxPoints,
+		})
+	trq.Step = step
+	w.Header().Set(headers.NameTricksterEffectiveStep,
+		strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+}
+
+// limitQueryPoints enforces the query_cost max_points limit, clamping the
+// query's time range to its most recent points when configured to. It returns
+// false if the request was rejected.
+func limitQueryPoints(w http.ResponseWriter, r *http.Request, rsc *request.Resources,
+	client backends.TimeseriesBackend, trq *timeseries.TimeRangeQuery,
+) bool {
+	qc := rsc.BackendOptions.QueryCost
+	if qc == nil || qc.MaxPoints <= 0 {
+		return true
+	}
+	points := querycost.Points(trq)
+	if points <= qc.MaxPoints {
+		return true
+	}
+	if qc.Clamps() {
+		e := timeseries.Extent{
+			Start: trq.Extent.End.Add(-trq.Step * time.Duration(qc.MaxPoints-1)),
+			End:   trq.Extent.End,
+		}
+		if err := client.SetExtent(r, trq, &e); err == nil {
+			querycost.LogClamp(r, rsc, trq, querycost.LimitPoints, points, qc.MaxPoints)
+			return true
+		}
+	}
+	querycost.Reject(w, r, rsc, trq, querycost.LimitPoints, points, qc.MaxPoints)
+	return false
+}
@@ -27,6 +27,8 @@ import (
 	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
 	"github.com/trickstercache/trickster/v2/pkg/parsing/timeconv"
 	tctx "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	qco "github.com/trickstercache/trickster/v2/pkg/proxy/querycost/options"
 	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
 	"github.com/trickstercache/trickster/v2/pkg/timeseries"
 
@@ -37,6 +39,12 @@ import (
 type mockTimeseriesBackend struct {
 	backends.TimeseriesBackend
 	parseTRQFunc func(*http.Request) (*timeseries.TimeRangeQuery, *timeseries.RequestOptions, bool, error)
+	extent       *timeseries.Extent
+}
+
+func (m *mockTimeseriesBackend) SetExtent(_ *http.Request, _ *timeseries.TimeRangeQuery, e *timeseries.Extent) error {
+	m.extent = e
+	return nil
 }
 
 func (m *mockTimeseriesBackend) ParseTimeRangeQuery(r *http.Request) (*timeseries.TimeRangeQuery, *timeseries.RequestOptions, bool, error) {
@@ -135,3 +143,116 @@ func TestLimitQueryRange(t *testing.T) {
 		assert.Equal(t, float64(1), val)
 	})
 }
+
+func TestLimitQueryPoints(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
+	end := time.Unix(1700000000, 0)
+	newBackend := func() *mockTimeseriesBackend {
+		return &mockTimeseriesBackend{
+			parseTRQFunc: func(req *http.Request) (*timeseries.TimeRangeQuery, *timeseries.RequestOptions, bool, error) {
+				return &timeseries.TimeRangeQuery{
+					Extent: timeseries.Extent{Start: end.Add(-2 * time.Hour), End: end},
+					Step:   time.Minute,
+				}, nil, false, nil
+			},
+		}
+	}
+	serve := func(qc *qco.Options, b *mockTimeseriesBackend) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/query", nil)
+		rec := httptest.NewRecorder()
+		backendOpts := &bo.Options{Name: "test", QueryCost: qc}
+		resources := request.NewResources(backendOpts, nil, nil, nil, b, nil)
+		r = r.WithContext(tctx.WithResources(r.Context(), resources))
+		LimitQueryRange(nextHandler).ServeHTTP(rec, r)
+		return rec
+	}
+
+	t.Run("within allowed limit", func(t *testing.T) {
+		qc := qco.New()
+		qc.MaxPoints = 121
+		rec := serve(qc, newBackend())
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("exceeds allowed limit", func(t *testing.T) {
+		metrics.ProxyQueryCostRejections.Reset()
+		qc := qco.New()
+		qc.MaxPoints = 100
+		rec := serve(qc, newBackend())
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(),
+			"query would return 121 points per series, exceeding the allowed limit of 100")
+		val := testutil.ToFloat64(metrics.ProxyQueryCostRejections.WithLabelValues("test", "points"))
+		assert.Equal(t, float64(1), val)
+	})
+
+	t.Run("clamps to allowed limit", func(t *testing.T) {
+		qc := qco.New()
+		qc.MaxPoints = 100
+		qc.Action = qco.ActionClamp
+		b := newBackend()
+		rec := serve(qc, b)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, &timeseries.Extent{Start: end.Add(-99 * time.Minute), End: end}, b.extent)
+	})
+}
+
+type mockStepSetterBackend struct {
+	*mockTimeseriesBackend
+	step time.Duration
+}
+
+func (m *mockStepSetterBackend) SetStep(_ *http.Request, _ *timeseries.TimeRangeQuery, step time.Duration) error {
+	m.step = step
+	return nil
+}
+
+func TestClampQueryStep(t *testing.T) {
+	nextHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	end := time.Unix(1700000000, 0)
+	serve := func(maxPoints int, qc *qco.Options) (*httptest.ResponseRecorder, *mockStepSetterBackend) {
+		b := &mockStepSetterBackend{mockTimeseriesBackend: &mockTimeseriesBackend{
+			parseTRQFunc: func(req *http.Request) (*timeseries.TimeRangeQuery, *timeseries.RequestOptions, bool, error) {
+				return &timeseries.TimeRangeQuery{
+					Extent: timeseries.Extent{Start: end.Add(-24 * time.Hour), End: end},
+					Step:   time.Second,
+				}, nil, false, nil
+			},
+		}}
+		r := httptest.NewRequest(http.MethodGet, "/query", nil)
+		rec := httptest.NewRecorder()
+		backendOpts := &bo.Options{Name: "test", MaxPointsPerSeries: maxPoints, QueryCost: qc}
+		resources := request.NewResources(backendOpts, nil, nil, nil, b, nil)
+		r = r.WithContext(tctx.WithResources(r.Context(), resources))
+		LimitQueryRange(nextHandler).ServeHTTP(rec, r)
+		return rec, b
+	}
+
+	t.Run("within allowed limit", func(t *testing.T) {
+		rec, b := serve(86401, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, time.Duration(0), b.step)
+		assert.Empty(t, rec.Header().Get(headers.NameTricksterEffectiveStep))
+	})
+
+	t.Run("raises step", func(t *testing.T) {
+		rec, b := serve(11000, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 10*time.Second, b.step)
+		assert.Equal(t, "10", rec.Header().Get(headers.NameTricksterEffectiveStep))
+	})
+
+	t.Run("raised step satisfies max_points", func(t *testing.T) {
+		qc := qco.New()
+		qc.MaxPoints = 11000
+		rec, _ := serve(11000, qc)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	tctx "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/handlers/trickster/failures"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/ratelimit"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+)
+
+// RateLimit intercepts requests to enforce the requests budget of the
+// backend or path rate limit, if configured.
+func RateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rsc, ok := tctx.Resources(r.Context()).(*request.Resources)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		o, key := request.RateLimit(r, rsc)
+		if o == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if ok, wait := o.AllowRequest(key); !ok {
+			metrics.ProxyRateLimited.WithLabelValues(rsc.BackendOptions.Name, "requests").Inc()
+			logger.Debug("request rejected due to rate limit",
+				logging.Pairs{
+					"backendName": rsc.BackendOptions.Name,
+					"path":        r.URL.Path,
+					"key":         key,
+					"retryAfter":  wait.String(),
+				})
+			failures.HandleTooManyRequests(w, ratelimit.RetryAfterSeconds(wait))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	auth "github.com/trickstercache/trickster/v2/pkg/proxy/authenticator/types"
+	tctx "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	po "github.com/trickstercache/trickster/v2/pkg/proxy/paths/options"
+	rlo "github.com/trickstercache/trickster/v2/pkg/proxy/ratelimit/options"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	newOptions := func(rate float64, burst int) *rlo.Options {
+		o := rlo.New()
+		o.KeyBy = rlo.KeyByUser
+		o.Requests = &rlo.Budget{Rate: rate, Burst: burst}
+		require.NoError(t, o.Initialize(""))
+		return o
+	}
+	serveAs := func(o *bo.Options, p *po.Options, username string,
+		status auth.AuthResultStatus,
+	) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/query", nil)
+		rsc := request.NewResources(o, p, nil, nil, nil, nil)
+		if username != "" {
+			rsc.AuthResult = &auth.AuthResult{Username: username, Status: status}
+		}
+		r = r.WithContext(tctx.WithResources(r.Context(), rsc))
+		w := httptest.NewRecorder()
+		RateLimit(next).ServeHTTP(w, r)
+		return w
+	}
+	serve := func(o *bo.Options, p *po.Options, username string) *httptest.ResponseRecorder {
+		return serveAs(o, p, username, auth.AuthSuccess)
+	}
+
+	t.Run("no limit configured", func(t *testing.T) {
+		o := &bo.Options{Name: "rl-none"}
+		for range 3 {
+			require.Equal(t, http.StatusOK, serve(o, nil, "").Code)
+		}
+	})
+
+	t.Run("keyed by user", func(t *testing.T) {
+		o := &bo.Options{Name: "rl-user", RateLimit: newOptions(0.25, 2)}
+		require.Equal(t, http.StatusOK, serve(o, nil, "alice").Code)
+		require.Equal(t, http.StatusOK, serve(o, nil, "alice").Code)
+		w := serve(o, nil, "alice")
+		require.Equal(t, http.StatusTooManyRequests, w.Code)
+		require.Equal(t, "4", w.Header().Get(headers.NameRetryAfter))
+		require.Equal(t, http.StatusOK, serve(o, nil, "bob").Code)
+		require.Equal(t, 1.0, testutil.ToFloat64(
+			metrics.ProxyRateLimited.WithLabelValues("rl-user", "requests")))
+	})
+
+	t.Run("unverified user keyed by client ip", func(t *testing.T) {
+		o := &bo.Options{Name: "rl-observed", RateLimit: newOptions(0.25, 2)}
+		require.Equal(t, http.StatusOK, serveAs(o, nil, "alice", auth.AuthObserved).Code)
+		require.Equal(t, http.StatusOK, serveAs(o, nil, "bob", auth.AuthObserved).Code)
+		require.Equal(t, http.StatusTooManyRequests,
+			serveAs(o, nil, "carol", auth.AuthObserved).Code)
+		require.Equal(t, http.StatusOK, serve(o, nil, "carol").Code)
+	})
+
+	t.Run("path overrides backend", func(t *testing.T) {
+		o := &bo.Options{Name: "rl-path", RateLimit: newOptions(1, 1)}
+		// an empty path block disables the backend limit
+		p := &po.Options{RateLimit: rlo.New()}
+		for range 3 {
+			require.Equal(t, http.StatusOK, serve(o, p, "alice").Code)
+		}
+		require.Equal(t, http.StatusOK, serve(o, nil, "alice").Code)
+		require.Equal(t, http.StatusTooManyRequests, serve(o, nil, "alice").Code)
+	})
+}
@@ -0,0 +1,257 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/trickstercache/trickster/v2/pkg/backends"
+	"github.com/trickstercache/trickster/v2/pkg/encoding"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging"
+	"github.com/trickstercache/trickster/v2/pkg/observability/logging/logger"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	tctx "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/headers"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/response/capture"
+	sho "github.com/trickstercache/trickster/v2/pkg/proxy/shadow/options"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries"
+	"github.com/trickstercache/trickster/v2/pkg/timeseries/dataset"
+)
+
+// Shadow asynchronously mirrors a sample of requests to the backend's shadow
+// backend, if configured. Mirrored responses are discarded, or compared
+// against the primary response when the shadow options enable comparison.
+func Shadow(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rsc, ok := tctx.Resources(r.Context()).(*request.Resources)
+		if !ok || rsc.BackendOptions == nil || tctx.ShadowFlag(r.Context()) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		o := rsc.BackendOptions
+		so := o.Shadow
+		if so == nil || so.Handler == nil || !so.Sample() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		release, ok := so.Acquire()
+		if !ok {
+			metrics.ProxyShadowRequests.WithLabelValues(o.Name, so.Backend, "dropped").Inc()
+			next.ServeHTTP(w, r)
+			return
+		}
+		mr, err := request.CloneWithoutResources(r)
+		if err != nil {
+			release()
+			metrics.ProxyShadowRequests.WithLabelValues(o.Name, so.Backend, "error").Inc()
+			logger.Warn("could not mirror request to shadow backend",
+				logging.Pairs{
+					"backendName": o.Name, "shadowBackend": so.Backend,
+					"detail": err.Error(),
+				})
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(tctx.WithShadowFlag(context.Background(), true),
+			time.Duration(so.Timeout))
+		mr = mr.WithContext(ctx)
+		mr.Header.Del(headers.NameAcceptEncoding)
+		metrics.ProxyShadowRequests.WithLabelValues(o.Name, so.Backend, "mirrored").Inc()
+
+		var sw http.ResponseWriter = &discardWriter{header: make(http.Header)}
+		var sc *capture.CaptureResponseWriter
+		if so.Compare != nil {
+			sc = capture.NewCaptureResponseWriterWithLimit(so.Compare.MaxBodyBytes)
+			sw = sc
+		}
+		done := make(chan bool, 1)
+		go func() {
+			defer release()
+			defer cancel()
+			completed := false
+			defer func() {
+				if rec := recover(); rec != nil {
+					logger.Error("panic serving shadow request",
+						logging.Pairs{"shadowBackend": so.Backend, "detail": rec})
+				}
+				done <- completed
+			}()
+			so.Handler.ServeHTTP(sw, mr)
+			completed = true
+		}()
+
+		if sc == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		pw := &teeWriter{ResponseWriter: w,
+			capture: capture.NewCaptureResponseWriterWithLimit(so.Compare.MaxBodyBytes)}
+		next.ServeHTTP(pw, r)
+		go func() {
+			if <-done {
+				compareShadow(r, rsc, so, pw, sc)
+				return
+			}
+			metrics.ProxyShadowComparisons.WithLabelValues(o.Name, so.Backend, "skipped").Inc()
+		}()
+	})
+}
+
+// compareShadow compares the captured primary and shadow responses, and
+// reports any mismatches
+func compareShadow(r *http.Request, rsc *request.Resources, so *sho.Options,
+	primary *teeWriter, sc *capture.CaptureResponseWriter,
+) {
+	o := rsc.BackendOptions
+	if primary.capture.Truncated() || sc.Truncated() {
+		metrics.ProxyShadowComparisons.WithLabelValues(o.Name, so.Backend, "skipped").Inc()
+		return
+	}
+	ps, ss := primary.capture.StatusCode(), sc.StatusCode()
+	var reasons []string
+	d := &dataset.Diff{}
+	if ps != ss {
+		reasons = append(reasons, "status")
+	} else if ps >= http.StatusOK && ps < http.StatusMultipleChoices {
+		if pts, sts, ok := unmarshalShadowPair(rsc, primary, sc); ok {
+			pds, ok1 := pts.(*dataset.DataSet)
+			sds, ok2 := sts.(*dataset.DataSet)
+			if ok1 && ok2 {
+				d = dataset.Compare(pds, sds, so.Compare.Tolerance)
+			} else {
+				d.ExpectedSeries, d.ActualSeries = pts.SeriesCount(), sts.SeriesCount()
+			}
+			if d.SeriesMismatch() {
+				reasons = append(reasons, "series_count")
+			}
+			if d.PointMismatch() {
+				reasons = append(reasons, "values")
+			}
+		}
+	}
+	if len(reasons) == 0 {
+		metrics.ProxyShadowComparisons.WithLabelValues(o.Name, so.Backend, "match").Inc()
+		return
+	}
+	metrics.ProxyShadowComparisons.WithLabelValues(o.Name, so.Backend, "mismatch").Inc()
+	for _, reason := range reasons {
+		metrics.ProxyShadowMismatches.WithLabelValues(o.Name, so.Backend, reason).Inc()
+	}
+	logger.Warn("shadow response mismatch",
+		logging.Pairs{
+			"backendName":   o.Name,
+			"shadowBackend": so.Backend,
+			"url":           r.URL.String(),
+			"reasons":       strings.Join(reasons, ","),
+			"primaryStatus": ps,
+			"shadowStatus":  ss,
+			"primarySeries": d.ExpectedSeries,
+			"shadowSeries":  d.ActualSeries,
+			"missingSeries": d.MissingSeries,
+			"extraSeries":   d.ExtraSeries,
+			"missingPoints": d.MissingPoints,
+			"extraPoints":   d.ExtraPoints,
+			"changedValues": d.ChangedValues,
+		})
+}
+
+// unmarshalShadowPair decodes the primary and shadow response bodies into
+// Timeseries using the primary backend's modeler. It returns false if the
+// backend is not a time series backend or either body cannot be decoded
+func unmarshalShadowPair(rsc *request.Resources, primary *teeWriter,
+	sc *capture.CaptureResponseWriter,
+) (timeseries.Timeseries, timeseries.Timeseries, bool) {
+	tsb, ok := rsc.BackendClient.(backends.TimeseriesBackend)
+	if !ok || tsb.Modeler() == nil || tsb.Modeler().WireUnmarshaler == nil {
+		return nil, nil, false
+	}
+	unmarshal := tsb.Modeler().WireUnmarshaler
+	trq := rsc.TimeRangeQuery
+	if trq == nil {
+		trq = &timeseries.TimeRangeQuery{}
+	}
+	pb, err := encoding.DecompressResponseBody(primary.encoding, primary.capture.Body())
+	if err != nil {
+		return nil, nil, false
+	}
+	sb, err := encoding.DecompressResponseBody(
+		sc.Header().Get(headers.NameContentEncoding), sc.Body())
+	if err != nil {
+		return nil, nil, false
+	}
+	pts, err := unmarshal(pb, trq)
+	if err != nil {
+		return nil, nil, false
+	}
+	sts, err := unmarshal(sb, trq)
+	if err != nil {
+		return nil, nil, false
+	}
+	return pts, sts, true
+}
+
+// teeWriter writes a response to the client while capturing a copy of it
+type teeWriter struct {
+	http.ResponseWriter
+	capture     *capture.CaptureResponseWriter
+	encoding    string
+	wroteHeader bool
+}
+
+func (w *teeWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		// record the encoding before outer writers (e.g., compression) can
+		// alter the shared header map
+		w.encoding = w.Header().Get(headers.NameContentEncoding)
+		w.capture.WriteHeader(code)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *teeWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.capture.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *teeWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// discardWriter is a ResponseWriter that discards the response
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardWriter) WriteHeader(int) {}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2018 The Trickster Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	bo "github.com/trickstercache/trickster/v2/pkg/backends/options"
+	"github.com/trickstercache/trickster/v2/pkg/backends/prometheus"
+	"github.com/trickstercache/trickster/v2/pkg/observability/metrics"
+	tctx "github.com/trickstercache/trickster/v2/pkg/proxy/context"
+	"github.com/trickstercache/trickster/v2/pkg/proxy/request"
+	sho "github.com/trickstercache/trickster/v2/pkg/proxy/shadow/options"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testShadowVector = `{"status":"success","data":{"resultType":"vector","result":[` +
+	`{"metric":{"job":"a"},"value":[1700000000,"%s"]},` +
+	`{"metric":{"job":"b"},"value":[1700000000,"2"]}]}}`
+
+func TestShadow(t *testing.T) {
+	client, err := prometheus.NewClient("shadow-primary", nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	type mirrored struct {
+		body   string
+		shadow bool
+	}
+	newOptions := func(name string, compare bool,
+		respond func(http.ResponseWriter),
+	) (*bo.Options, chan mirrored) {
+		ch := make(chan mirrored, 1)
+		so := sho.New()
+		so.Backend = name + "-next"
+		if compare {
+			so.Compare = sho.NewCompareOptions()
+		}
+		require.NoError(t, so.Initialize(name))
+		so.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			respond(w)
+			ch <- mirrored{body: string(b), shadow: tctx.ShadowFlag(r.Context())}
+		})
+		return &bo.Options{Name: name, Shadow: so}, ch
+	}
+	serve := func(o *bo.Options, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/query",
+			strings.NewReader(body))
+		rsc := request.NewResources(o, nil, nil, nil, client, nil)
+		r = r.WithContext(tctx.WithResources(r.Context(), rsc))
+		w := httptest.NewRecorder()
+		Shadow(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			require.Equal(t, body, string(b))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(strings.Replace(testShadowVector, "%s", "1", 1)))
+		})).ServeHTTP(w, r)
+		return w
+	}
+	waitFor := func(f func() float64, want float64) {
+		t.Helper()
+		require.Eventually(t, func() bool { return f() == want },
+			time.Second, 5*time.Millisecond)
+	}
+
+	t.Run("mirror without compare", func(t *testing.T) {
+		o, ch := newOptions("shadow-discard", false, func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		w := serve(o, "query=up")
+		require.Equal(t, http.StatusOK, w.Code)
+		m := <-ch
+		require.Equal(t, "query=up", m.body)
+		require.True(t, m.shadow)
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.ProxyShadowRequests.
+			WithLabelValues("shadow-discard", "shadow-discard-next", "mirrored")))
+	})
+
+	t.Run("compare match", func(t *testing.T) {
+		o, ch := newOptions("shadow-match", true, func(w http.ResponseWriter) {
+			w.Write([]byte(strings.Replace(testShadowVector, "%s", "1.0000001", 1)))
+		})
+		serve(o, "query=up")
+		<-ch
+		waitFor(func() float64 {
+			return testutil.ToFloat64(metrics.ProxyShadowComparisons.
+				WithLabelValues("shadow-match", "shadow-match-next", "match"))
+		}, 1)
+	})
+
+	t.Run("compare mismatch", func(t *testing.T) {
+		o, ch := newOptions("shadow-mismatch", true, func(w http.ResponseWriter) {
+			w.Write([]byte(strings.Replace(testShadowVector, "%s", "5", 1)))
+		})
+		serve(o, "query=up")
+		<-ch
+		waitFor(func() float64 {
+			return testutil.ToFloat64(metrics.ProxyShadowMismatches.
+				WithLabelValues("shadow-mismatch", "shadow-mismatch-next", "values"))
+		}, 1)
+		require.Equal(t, 0.0, testutil.ToFloat64(metrics.ProxyShadowMismatches.
+			WithLabelValues("shadow-mismatch", "shadow-mismatch-next", "series_count")))
+
+		o, ch = newOptions("shadow-status", true, func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		serve(o, "query=up")
+		<-ch
+		waitFor(func() float64 {
+			return testutil.ToFloat64(metrics.ProxyShadowMismatches.
+				WithLabelValues("shadow-status", "shadow-status-next", "status"))
+		}, 1)
+	})
+
+	t.Run("concurrency limit", func(t *testing.T) {
+		block := make(chan struct{})
+		o, ch := newOptions("shadow-limit", false, func(http.ResponseWriter) {
+			<-block
+		})
+		o.Shadow.MaxConcurrent = 1
+		serve(o, "query=up")
+		serve(o, "query=up")
+		close(block)
+		<-ch
+		require.Equal(t, 1.0, testutil.ToFloat64(metrics.ProxyShadowRequests.
+			WithLabelValues("shadow-limit", "shadow-limit-next", "dropped")))
+	})
+
+	t.Run("mirrored requests are not mirrored again", func(t *testing.T) {
+		o, _ := newOptions("shadow-loop", false, func(http.ResponseWriter) {})
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/query", nil)
+		ctx := tctx.WithShadowFlag(r.Context(), true)
+		r = r.WithContext(tctx.WithResources(ctx,
+			request.NewResources(o, nil, nil, nil, client, nil)))
+		Shadow(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).
+			ServeHTTP(httptest.NewRecorder(), r)
+		require.Equal(t, 0.0, testutil.ToFloat64(metrics.ProxyShadowRequests.
+			WithLabelValues("shadow-loop", "shadow-loop-next", "mirrored")))
+	})
+}